@@ -0,0 +1,165 @@
+package nelchanbot
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// loadAliases loads the user defined aliases from the backend into the router
+func (n *Nelchan) loadAliases() {
+	aliases, err := n.CommandAPIClient.ListAliases(ListAliasesRequest{})
+	if err != nil {
+		fmt.Println("error loading aliases:", err)
+		return
+	}
+
+	mapping := make(map[string]string, len(aliases))
+	for _, alias := range aliases {
+		mapping[alias.Alias] = alias.CommandName
+	}
+	n.CommandRouter.SetAliases(mapping)
+	fmt.Printf("loaded %d aliases\n", len(mapping))
+}
+
+// handleAliasCommand handles the !alias command
+// Usage: !alias <alias> <command_name>
+// Works for both text and code commands
+func (n *Nelchan) handleAliasCommand(s *discordgo.Session, m *discordgo.MessageCreate, cmd *SlashCommand) {
+	alias, target := cmd.GetArg(0), cmd.GetArg(1)
+	if alias == "" || target == "" {
+		_, _ = s.ChannelMessageSend(m.ChannelID, "使い方: !alias <エイリアス> <コマンド名>")
+		return
+	}
+
+	if message := n.checkNewCommandName(alias); message != "" {
+		_, _ = s.ChannelMessageSend(m.ChannelID, message)
+		return
+	}
+
+	// Point aliases of aliases at the command itself
+	target = n.CommandRouter.ResolveAlias(target)
+
+	err := n.CommandAPIClient.AddAlias(AddAliasRequest{
+		Alias:       alias,
+		CommandName: target,
+		AuthorID:    m.Author.ID,
+	})
+	switch {
+	case errors.Is(err, ErrCommandNotFound):
+		_, _ = s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("コマンド「%s」は見つかりませんでした", target))
+		return
+	case errors.Is(err, ErrNameInUse):
+		_, _ = s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("「%s」は既にコマンド名かエイリアスとして使われています", alias))
+		return
+	case err != nil:
+		fmt.Println("error adding alias,", err)
+		_, _ = s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("エラー: %s", err.Error()))
+		return
+	}
+
+	n.CommandRouter.SetAlias(alias, target)
+	_, _ = s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("エイリアス「%s」→「%s」を追加しました", alias, target))
+	fmt.Printf("alias added: %s -> %s, by=%s\n", alias, target, m.Author.ID)
+}
+
+// handleUnaliasCommand handles the !unalias command
+// Usage: !unalias <alias>
+// The author of the alias, the author of the command and admins can remove it
+func (n *Nelchan) handleUnaliasCommand(s *discordgo.Session, m *discordgo.MessageCreate, cmd *SlashCommand) {
+	name := cmd.GetArg(0)
+	if name == "" {
+		_, _ = s.ChannelMessageSend(m.ChannelID, "使い方: !unalias <エイリアス>")
+		return
+	}
+
+	aliases, err := n.CommandAPIClient.ListAliases(ListAliasesRequest{})
+	if err != nil {
+		fmt.Println("error listing aliases,", err)
+		_, _ = s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("エラー: %s", err.Error()))
+		return
+	}
+
+	var alias *CommandAlias
+	for idx := range aliases {
+		if aliases[idx].Alias == name {
+			alias = &aliases[idx]
+			break
+		}
+	}
+	if alias == nil {
+		_, _ = s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("エイリアス「%s」は見つかりませんでした", name))
+		return
+	}
+
+	if alias.AuthorID != m.Author.ID {
+		info, err := n.CommandAPIClient.GetCommand(GetCommandRequest{
+			CommandName: alias.CommandName,
+		})
+		if err != nil {
+			fmt.Println("error getting command,", err)
+			_, _ = s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("エラー: %s", err.Error()))
+			return
+		}
+		if info == nil || !isCommandOwner(info, m.Author.ID, n.isAdmin(s, m.Author.ID, m.ChannelID)) {
+			_, _ = s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("エイリアス「%s」を削除できるのは作成者、コマンドの登録者か管理者だけです", name))
+			return
+		}
+	}
+
+	if _, err := n.CommandAPIClient.RemoveAlias(RemoveAliasRequest{Alias: name}); err != nil {
+		fmt.Println("error removing alias,", err)
+		_, _ = s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("エラー: %s", err.Error()))
+		return
+	}
+
+	n.CommandRouter.RemoveAlias(name)
+	_, _ = s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("エイリアス「%s」を削除しました", name))
+}
+
+// handleAliasesCommand handles the !aliases command
+// Usage: !aliases - List every alias
+// Usage: !aliases <command_name> - List the aliases of a command
+func (n *Nelchan) handleAliasesCommand(s *discordgo.Session, m *discordgo.MessageCreate, cmd *SlashCommand) {
+	commandName := cmd.GetArg(0)
+	if commandName != "" {
+		commandName = n.CommandRouter.ResolveAlias(commandName)
+	}
+
+	aliases, err := n.CommandAPIClient.ListAliases(ListAliasesRequest{
+		CommandName: commandName,
+	})
+	if err != nil {
+		fmt.Println("error listing aliases,", err)
+		_, _ = s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("エラー: %s", err.Error()))
+		return
+	}
+
+	if err := n.sendMessage(s, m.ChannelID, FormatAliases(commandName, aliases)); err != nil {
+		fmt.Println("error sending message,", err)
+	}
+}
+
+// FormatAliases formats aliases for !aliases
+// commandName is empty when every alias is listed
+func FormatAliases(commandName string, aliases []CommandAlias) string {
+	if len(aliases) == 0 {
+		if commandName != "" {
+			return fmt.Sprintf("コマンド「%s」のエイリアスはありません", commandName)
+		}
+		return "エイリアスはありません"
+	}
+
+	var b strings.Builder
+	if commandName != "" {
+		fmt.Fprintf(&b, "コマンド「%s」のエイリアス:", commandName)
+	} else {
+		b.WriteString("エイリアス一覧:")
+	}
+	for _, alias := range aliases {
+		fmt.Fprintf(&b, "\n- `%s` → `%s`", alias.Alias, alias.CommandName)
+	}
+	return b.String()
+}
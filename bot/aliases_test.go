@@ -0,0 +1,71 @@
+package nelchanbot
+
+import "testing"
+
+func TestCommandRouterResolveAlias(t *testing.T) {
+	r := NewCommandRouter(NewCommandParser(), nil)
+	r.SetAliases(map[string]string{"hi": "hello"})
+	r.SetAlias("d", "dice")
+
+	tests := []struct {
+		name string
+		want string
+	}{
+		{name: "hi", want: "hello"},
+		{name: "d", want: "dice"},
+		{name: "hello", want: "hello"},
+		{name: "unknown", want: "unknown"},
+	}
+	for _, tt := range tests {
+		if got := r.ResolveAlias(tt.name); got != tt.want {
+			t.Errorf("ResolveAlias(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+
+	r.RemoveAlias("d")
+	if got := r.ResolveAlias("d"); got != "d" {
+		t.Errorf("ResolveAlias(%q) after RemoveAlias = %q, want %q", "d", got, "d")
+	}
+}
+
+func TestFormatAliases(t *testing.T) {
+	aliases := []CommandAlias{
+		{Alias: "d", CommandName: "dice"},
+		{Alias: "hi", CommandName: "hello"},
+	}
+
+	tests := []struct {
+		name        string
+		commandName string
+		aliases     []CommandAlias
+		want        string
+	}{
+		{
+			name:    "all",
+			aliases: aliases,
+			want:    "エイリアス一覧:\n- `d` → `dice`\n- `hi` → `hello`",
+		},
+		{
+			name:        "one command",
+			commandName: "dice",
+			aliases:     aliases[:1],
+			want:        "コマンド「dice」のエイリアス:\n- `d` → `dice`",
+		},
+		{
+			name: "none",
+			want: "エイリアスはありません",
+		},
+		{
+			name:        "none for command",
+			commandName: "dice",
+			want:        "コマンド「dice」のエイリアスはありません",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := FormatAliases(tt.commandName, tt.aliases); got != tt.want {
+				t.Errorf("FormatAliases() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,369 @@
+package nelchanbot
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// Discord mention patterns accepted for user/channel/role args
+var (
+	userMentionRe    = regexp.MustCompile(`^<@!?(\d+)>$`)
+	channelMentionRe = regexp.MustCompile(`^<#(\d+)>$`)
+	roleMentionRe    = regexp.MustCompile(`^<@&(\d+)>$`)
+	snowflakeRe      = regexp.MustCompile(`^\d+$`)
+)
+
+// ArgValidationError describes why the args of an invocation don't match the declared ArgOption schema
+type ArgValidationError struct {
+	Problems []string
+}
+
+func (e *ArgValidationError) Error() string {
+	return strings.Join(e.Problems, "\n")
+}
+
+// ResolveArgs validates named args against the declared ArgOption schema and converts them to their declared types
+// - Every required arg must be present and non-empty
+// - Values must parse as their declared type and satisfy choices, ranges and lengths
+// - Omitted optional args receive their default value, if any
+// Args that aren't declared in the schema are passed through unchanged.
+// Returns an *ArgValidationError listing every problem found
+func ResolveArgs(options []ArgOption, named map[string]any) (map[string]any, error) {
+	resolved := make(map[string]any, len(named))
+	for key, value := range named {
+		resolved[key] = value
+	}
+
+	var problems []string
+	for _, opt := range options {
+		value, exists := named[opt.Name]
+		if !exists || value == "" {
+			if opt.Required {
+				problems = append(problems, fmt.Sprintf("引数 `%s` は必須です", opt.Name))
+			} else if opt.Default != nil {
+				resolved[opt.Name] = defaultArgValue(opt)
+			}
+			continue
+		}
+
+		converted, err := convertArgValue(opt, value)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("引数 `%s` の値 `%v` は%s", opt.Name, value, err.Error()))
+			continue
+		}
+		resolved[opt.Name] = converted
+	}
+
+	if len(problems) > 0 {
+		return nil, &ArgValidationError{Problems: problems}
+	}
+	return resolved, nil
+}
+
+// convertArgValue converts a raw arg value to the ArgOption's type and checks its constraints
+// Raw values are strings for "!" invocations and already typed values for slash commands
+func convertArgValue(opt ArgOption, value any) (any, error) {
+	converted, err := convertArgType(opt.Type, value)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(opt.Choices) > 0 {
+		choice, ok := matchArgChoice(opt, value, converted)
+		if !ok {
+			return nil, fmt.Errorf("選択肢（%s）にありません", strings.Join(choiceNames(opt.Choices), ", "))
+		}
+		converted = choice
+	}
+
+	switch v := converted.(type) {
+	case int64:
+		return v, checkArgRange(opt, float64(v))
+	case float64:
+		return v, checkArgRange(opt, v)
+	case string:
+		return v, checkArgLength(opt, v)
+	}
+	return converted, nil
+}
+
+// convertArgType converts a raw value to the Go type used for the given ArgOption type
+// Values of a type the ArgOption type doesn't accept, such as a bare --flag given to an integer arg, are errors
+func convertArgType(argType string, value any) (any, error) {
+	switch argType {
+	case "string":
+		if _, ok := value.(string); !ok {
+			return nil, errors.New("文字列ではありません")
+		}
+	case "integer":
+		switch v := value.(type) {
+		case string:
+			i, err := strconv.ParseInt(v, 10, 64)
+			if err != nil {
+				return nil, errors.New("整数ではありません")
+			}
+			return i, nil
+		case float64:
+			if v != math.Trunc(v) {
+				return nil, errors.New("整数ではありません")
+			}
+			return int64(v), nil
+		case int64:
+			return v, nil
+		}
+		return nil, errors.New("整数ではありません")
+	case "number":
+		switch v := value.(type) {
+		case string:
+			f, err := strconv.ParseFloat(v, 64)
+			if err != nil || math.IsNaN(f) || math.IsInf(f, 0) {
+				return nil, errors.New("数値ではありません")
+			}
+			return f, nil
+		case int64:
+			return float64(v), nil
+		case float64:
+			return v, nil
+		}
+		return nil, errors.New("数値ではありません")
+	case "boolean", "bool":
+		switch v := value.(type) {
+		case string:
+			b, ok := parseBoolArg(v)
+			if !ok {
+				return nil, errors.New("真偽値（true/false）ではありません")
+			}
+			return b, nil
+		case bool:
+			return v, nil
+		}
+		return nil, errors.New("真偽値（true/false）ではありません")
+	case "user":
+		return parseSnowflakeValue(value, userMentionRe, "ユーザー")
+	case "channel":
+		return parseSnowflakeValue(value, channelMentionRe, "チャンネル")
+	case "role":
+		return parseSnowflakeValue(value, roleMentionRe, "ロール")
+	case "mentionable":
+		if id, err := parseSnowflakeValue(value, roleMentionRe, "ロール"); err == nil {
+			return id, nil
+		}
+		return parseSnowflakeValue(value, userMentionRe, "ユーザーまたはロール")
+	}
+	return value, nil
+}
+
+// parseSnowflakeValue is parseSnowflakeArg for raw values, which must be strings
+func parseSnowflakeValue(value any, mentionRe *regexp.Regexp, label string) (string, error) {
+	s, ok := value.(string)
+	if !ok {
+		return "", fmt.Errorf("%sのメンションまたはIDではありません", label)
+	}
+	return parseSnowflakeArg(s, mentionRe, label)
+}
+
+// parseSnowflakeArg extracts a Discord ID from a mention or a bare ID
+func parseSnowflakeArg(s string, mentionRe *regexp.Regexp, label string) (string, error) {
+	if matches := mentionRe.FindStringSubmatch(s); len(matches) > 1 {
+		return matches[1], nil
+	}
+	if snowflakeRe.MatchString(s) {
+		return s, nil
+	}
+	return "", fmt.Errorf("%sのメンションまたはIDではありません", label)
+}
+
+// matchArgChoice finds the choice matching a value, either by value or, for text input, by choice name
+func matchArgChoice(opt ArgOption, raw, converted any) (any, bool) {
+	for _, choice := range opt.Choices {
+		choiceValue, err := convertArgType(opt.Type, normalizeSchemaValue(choice.Value))
+		if err != nil {
+			continue
+		}
+		if choiceValue == converted {
+			return choiceValue, true
+		}
+		if s, ok := raw.(string); ok && strings.EqualFold(s, choice.Name) {
+			return choiceValue, true
+		}
+	}
+	return nil, false
+}
+
+// defaultArgValue returns the default of an ArgOption converted to its declared type
+// Defaults that don't convert are passed through unchanged
+func defaultArgValue(opt ArgOption) any {
+	converted, err := convertArgType(opt.Type, normalizeSchemaValue(opt.Default))
+	if err != nil {
+		return opt.Default
+	}
+	return converted
+}
+
+// normalizeSchemaValue turns JSON numbers from the args comment into strings so they convert like "!" input
+func normalizeSchemaValue(value any) any {
+	if f, ok := value.(float64); ok {
+		return strconv.FormatFloat(f, 'f', -1, 64)
+	}
+	return value
+}
+
+func choiceNames(choices []ArgChoice) []string {
+	names := make([]string, len(choices))
+	for i, choice := range choices {
+		names[i] = choice.Name
+	}
+	return names
+}
+
+func checkArgRange(opt ArgOption, v float64) error {
+	if opt.MinValue != nil && v < *opt.MinValue {
+		return fmt.Errorf("%v 以上である必要があります", *opt.MinValue)
+	}
+	if opt.MaxValue != nil && v > *opt.MaxValue {
+		return fmt.Errorf("%v 以下である必要があります", *opt.MaxValue)
+	}
+	return nil
+}
+
+func checkArgLength(opt ArgOption, s string) error {
+	length := utf8.RuneCountInString(s)
+	if opt.MinLength != nil && length < *opt.MinLength {
+		return fmt.Errorf("%d 文字以上である必要があります", *opt.MinLength)
+	}
+	if opt.MaxLength != nil && length > *opt.MaxLength {
+		return fmt.Errorf("%d 文字以下である必要があります", *opt.MaxLength)
+	}
+	return nil
+}
+
+// parseBoolArg parses the boolean spellings accepted for boolean args
+func parseBoolArg(s string) (bool, bool) {
+	switch strings.ToLower(s) {
+	case "true", "yes", "on", "1":
+		return true, true
+	case "false", "no", "off", "0":
+		return false, true
+	}
+	return false, false
+}
+
+// attachmentArgValue converts a Discord attachment to the value passed to code commands
+func attachmentArgValue(a *discordgo.MessageAttachment) map[string]any {
+	return map[string]any{
+		"id":           a.ID,
+		"filename":     a.Filename,
+		"url":          a.URL,
+		"content_type": a.ContentType,
+		"size":         a.Size,
+	}
+}
+
+// BindAttachmentArgs assigns message attachments to the attachment args of the schema in declaration order
+// Attachment args already present in named are left untouched
+func BindAttachmentArgs(options []ArgOption, attachments []*discordgo.MessageAttachment, named map[string]any) map[string]any {
+	if named == nil {
+		named = make(map[string]any)
+	}
+
+	next := 0
+	for _, opt := range options {
+		if opt.Type != "attachment" {
+			continue
+		}
+		if _, exists := named[opt.Name]; exists {
+			continue
+		}
+		if next >= len(attachments) {
+			break
+		}
+		named[opt.Name] = attachmentArgValue(attachments[next])
+		next++
+	}
+	return named
+}
+
+// FormatArgsUsage builds a usage message for a code command from its ArgOption schema
+// Example:
+//
+//	使い方: !greet <name> [count]
+//	- `name` (string, 必須): 名前
+//	- `count` (integer, 任意, 1〜10, 既定値: 1): 回数
+func FormatArgsUsage(commandName string, options []ArgOption) string {
+	var b strings.Builder
+
+	b.WriteString("使い方: !" + commandName)
+	for _, opt := range options {
+		if opt.Required {
+			b.WriteString(" <" + opt.Name + ">")
+		} else {
+			b.WriteString(" [" + opt.Name + "]")
+		}
+	}
+
+	for _, opt := range options {
+		fmt.Fprintf(&b, "\n- `%s` (%s)", opt.Name, strings.Join(argAttributes(opt), ", "))
+		if opt.Description != "" {
+			b.WriteString(": " + opt.Description)
+		}
+		if len(opt.Choices) > 0 {
+			b.WriteString(" [選択肢: " + strings.Join(choiceNames(opt.Choices), ", ") + "]")
+		}
+	}
+
+	return b.String()
+}
+
+// argAttributes lists the type, requirement and constraints of an ArgOption for usage messages
+func argAttributes(opt ArgOption) []string {
+	argType := opt.Type
+	if argType == "" {
+		argType = "string"
+	}
+
+	requirement := "任意"
+	if opt.Required {
+		requirement = "必須"
+	}
+
+	attributes := []string{argType, requirement}
+	if opt.MinValue != nil || opt.MaxValue != nil {
+		attributes = append(attributes, formatBounds(opt.MinValue, opt.MaxValue, "%v"))
+	}
+	if opt.MinLength != nil || opt.MaxLength != nil {
+		var minLength, maxLength *float64
+		if opt.MinLength != nil {
+			v := float64(*opt.MinLength)
+			minLength = &v
+		}
+		if opt.MaxLength != nil {
+			v := float64(*opt.MaxLength)
+			maxLength = &v
+		}
+		attributes = append(attributes, formatBounds(minLength, maxLength, "%v文字"))
+	}
+	if opt.Default != nil {
+		attributes = append(attributes, fmt.Sprintf("既定値: %v", opt.Default))
+	}
+	return attributes
+}
+
+// formatBounds formats an inclusive range such as "1〜10", "1〜" or "〜10"
+func formatBounds(lower, upper *float64, format string) string {
+	var b strings.Builder
+	if lower != nil {
+		fmt.Fprintf(&b, format, *lower)
+	}
+	b.WriteString("〜")
+	if upper != nil {
+		fmt.Fprintf(&b, format, *upper)
+	}
+	return b.String()
+}
@@ -0,0 +1,175 @@
+package nelchanbot
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func floatPtr(f float64) *float64 {
+	return &f
+}
+
+func intPtr(i int) *int {
+	return &i
+}
+
+func TestResolveArgs(t *testing.T) {
+	options := []ArgOption{
+		{Name: "name", Type: "string", Required: true, MaxLength: intPtr(5)},
+		{Name: "count", Type: "integer", MinValue: floatPtr(1), MaxValue: floatPtr(10), Default: float64(1)},
+		{Name: "ratio", Type: "number"},
+		{Name: "loud", Type: "boolean"},
+		{Name: "color", Type: "string", Choices: []ArgChoice{{Name: "赤", Value: "red"}, {Name: "blue", Value: "blue"}}},
+		{Name: "target", Type: "user"},
+	}
+
+	tests := []struct {
+		name             string
+		named            map[string]any
+		expected         map[string]any
+		expectedProblems []string
+	}{
+		{
+			name:     "string values are converted",
+			named:    map[string]any{"name": "ねる", "count": "3", "ratio": "0.5", "loud": "true"},
+			expected: map[string]any{"name": "ねる", "count": int64(3), "ratio": 0.5, "loud": true},
+		},
+		{
+			name:     "default applied for omitted optional arg",
+			named:    map[string]any{"name": "ねる"},
+			expected: map[string]any{"name": "ねる", "count": int64(1)},
+		},
+		{
+			name:     "slash values are typed",
+			named:    map[string]any{"name": "ねる", "count": float64(3), "loud": true},
+			expected: map[string]any{"name": "ねる", "count": int64(3), "loud": true},
+		},
+		{
+			name:     "choice matched by name",
+			named:    map[string]any{"name": "ねる", "color": "赤"},
+			expected: map[string]any{"name": "ねる", "count": int64(1), "color": "red"},
+		},
+		{
+			name:     "user mention resolves to ID",
+			named:    map[string]any{"name": "ねる", "target": "<@!1234>"},
+			expected: map[string]any{"name": "ねる", "count": int64(1), "target": "1234"},
+		},
+		{
+			name:     "undeclared args pass through",
+			named:    map[string]any{"name": "ねる", "extra": "x"},
+			expected: map[string]any{"name": "ねる", "count": int64(1), "extra": "x"},
+		},
+		{
+			name:             "missing required arg",
+			named:            map[string]any{"count": "3"},
+			expectedProblems: []string{"引数 `name` は必須です"},
+		},
+		{
+			name:             "empty required arg",
+			named:            map[string]any{"name": ""},
+			expectedProblems: []string{"引数 `name` は必須です"},
+		},
+		{
+			name:  "mistyped args",
+			named: map[string]any{"name": "ねる", "count": "1.5", "ratio": "half", "loud": "maybe"},
+			expectedProblems: []string{
+				"引数 `count` の値 `1.5` は整数ではありません",
+				"引数 `ratio` の値 `half` は数値ではありません",
+				"引数 `loud` の値 `maybe` は真偽値（true/false）ではありません",
+			},
+		},
+		{
+			name:  "constraints",
+			named: map[string]any{"name": "ながすぎる名前", "count": "11", "color": "green", "target": "ねる"},
+			expectedProblems: []string{
+				"引数 `name` の値 `ながすぎる名前` は5 文字以下である必要があります",
+				"引数 `count` の値 `11` は10 以下である必要があります",
+				"引数 `color` の値 `green` は選択肢（赤, blue）にありません",
+				"引数 `target` の値 `ねる` はユーザーのメンションまたはIDではありません",
+			},
+		},
+		{
+			name:  "flags given to typed args",
+			named: map[string]any{"name": true, "count": true, "ratio": true, "target": true},
+			expectedProblems: []string{
+				"引数 `name` の値 `true` は文字列ではありません",
+				"引数 `count` の値 `true` は整数ではありません",
+				"引数 `ratio` の値 `true` は数値ではありません",
+				"引数 `target` の値 `true` はユーザーのメンションまたはIDではありません",
+			},
+		},
+		{
+			name:     "flag given to a boolean arg",
+			named:    map[string]any{"name": "ねる", "loud": true},
+			expected: map[string]any{"name": "ねる", "count": int64(1), "loud": true},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := ResolveArgs(options, tt.named)
+			if tt.expectedProblems == nil {
+				if err != nil {
+					t.Fatalf("ResolveArgs() error = %v, want nil", err)
+				}
+				if !reflect.DeepEqual(result, tt.expected) {
+					t.Errorf("ResolveArgs() = %#v, want %#v", result, tt.expected)
+				}
+				return
+			}
+
+			var validationErr *ArgValidationError
+			if !errors.As(err, &validationErr) {
+				t.Fatalf("ResolveArgs() error = %v, want *ArgValidationError", err)
+			}
+			if !reflect.DeepEqual(validationErr.Problems, tt.expectedProblems) {
+				t.Errorf("ResolveArgs().Problems = %q, want %q", validationErr.Problems, tt.expectedProblems)
+			}
+		})
+	}
+}
+
+func TestBindAttachmentArgs(t *testing.T) {
+	options := []ArgOption{
+		{Name: "text", Type: "string"},
+		{Name: "file", Type: "attachment"},
+	}
+	attachments := []*discordgo.MessageAttachment{
+		{ID: "1", Filename: "data.csv", URL: "https://cdn.example/data.csv", ContentType: "text/csv", Size: 10},
+	}
+
+	named := BindAttachmentArgs(options, attachments, map[string]any{"text": "hello"})
+	expected := map[string]any{
+		"text": "hello",
+		"file": map[string]any{
+			"id":           "1",
+			"filename":     "data.csv",
+			"url":          "https://cdn.example/data.csv",
+			"content_type": "text/csv",
+			"size":         10,
+		},
+	}
+	if !reflect.DeepEqual(named, expected) {
+		t.Errorf("BindAttachmentArgs() = %v, want %v", named, expected)
+	}
+}
+
+func TestFormatArgsUsage(t *testing.T) {
+	options := []ArgOption{
+		{Name: "name", Type: "string", Description: "名前", Required: true},
+		{Name: "count", Type: "integer", MinValue: floatPtr(1), MaxValue: floatPtr(10), Default: float64(1)},
+		{Name: "color", Choices: []ArgChoice{{Name: "赤", Value: "red"}, {Name: "blue", Value: "blue"}}},
+	}
+
+	expected := "使い方: !greet <name> [count] [color]\n" +
+		"- `name` (string, 必須): 名前\n" +
+		"- `count` (integer, 任意, 1〜10, 既定値: 1)\n" +
+		"- `color` (string, 任意) [選択肢: 赤, blue]"
+
+	if result := FormatArgsUsage("greet", options); result != expected {
+		t.Errorf("FormatArgsUsage() = %q, want %q", result, expected)
+	}
+}
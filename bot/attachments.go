@@ -0,0 +1,93 @@
+package nelchanbot
+
+import (
+	"cmp"
+	"fmt"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// Limits on attachment content downloaded for a code command
+// Larger files are still passed, but only as metadata with their URL
+const (
+	maxCommandAttachmentBytes      = 2 << 20 // per file
+	maxCommandAttachmentTotalBytes = 4 << 20 // per run, including the replied-to message
+)
+
+// attachmentDownloader downloads an attachment, failing if it's larger than limit bytes
+type attachmentDownloader func(url string, limit int64) ([]byte, error)
+
+// CommandAttachments converts message attachments to the values passed to a code command
+// Content is downloaded in order while it fits in budget, which is reduced by each downloaded file
+func CommandAttachments(attachments []*discordgo.MessageAttachment, budget *int, download attachmentDownloader) []CommandAttachment {
+	result := make([]CommandAttachment, 0, len(attachments))
+	for _, a := range attachments {
+		attachment := CommandAttachment{
+			ID:          a.ID,
+			Filename:    a.Filename,
+			URL:         a.URL,
+			ContentType: a.ContentType,
+			Size:        a.Size,
+		}
+		if a.Size <= maxCommandAttachmentBytes && a.Size <= *budget {
+			data, err := download(a.URL, int64(a.Size))
+			if err != nil {
+				fmt.Printf("error downloading attachment %s: %v\n", a.Filename, err)
+			} else {
+				attachment.Data = data
+				*budget -= len(data)
+			}
+		}
+		result = append(result, attachment)
+	}
+	return result
+}
+
+// NewRepliedMessage converts a message to the value passed to a code command
+func NewRepliedMessage(m *discordgo.Message, budget *int, download attachmentDownloader) *RepliedMessage {
+	replied := &RepliedMessage{
+		ID:          m.ID,
+		ChannelID:   m.ChannelID,
+		Content:     m.Content,
+		Attachments: CommandAttachments(m.Attachments, budget, download),
+	}
+	if m.Author != nil {
+		replied.AuthorID = m.Author.ID
+		replied.AuthorName = cmp.Or(m.Author.GlobalName, m.Author.Username)
+		replied.AuthorBot = m.Author.Bot
+	}
+	return replied
+}
+
+// repliedMessage returns the message m replied to, or nil if m isn't a reply
+// The gateway usually includes the replied-to message; it's fetched when it doesn't
+func repliedMessage(s *discordgo.Session, m *discordgo.Message) *discordgo.Message {
+	if m.Type != discordgo.MessageTypeReply {
+		return nil
+	}
+	if m.ReferencedMessage != nil {
+		return m.ReferencedMessage
+	}
+	ref := m.MessageReference
+	if ref == nil || ref.MessageID == "" {
+		return nil
+	}
+
+	replied, err := s.ChannelMessage(cmp.Or(ref.ChannelID, m.ChannelID), ref.MessageID)
+	if err != nil {
+		// The replied-to message may have been deleted
+		fmt.Printf("error getting replied message %s: %v\n", ref.MessageID, err)
+		return nil
+	}
+	return replied
+}
+
+// withMessageInput adds the attachments of the invoking message and the message it replied to to a run request
+func withMessageInput(s *discordgo.Session, m *discordgo.Message, request RunCommandRequest) RunCommandRequest {
+	budget := maxCommandAttachmentTotalBytes
+	request.Attachments = CommandAttachments(m.Attachments, &budget, downloadAttachment)
+	if replied := repliedMessage(s, m); replied != nil {
+		request.Replied = NewRepliedMessage(replied, &budget, downloadAttachment)
+	}
+	return request
+}
@@ -0,0 +1,77 @@
+package nelchanbot
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func TestCommandAttachments(t *testing.T) {
+	var downloaded []string
+	download := func(url string, limit int64) ([]byte, error) {
+		downloaded = append(downloaded, url)
+		if url == "broken" {
+			return nil, errors.New("not found")
+		}
+		return []byte(strings.Repeat("a", int(limit))), nil
+	}
+
+	attachments := []*discordgo.MessageAttachment{
+		{ID: "1", Filename: "data.csv", URL: "small", ContentType: "text/csv", Size: 1 << 20},
+		{ID: "2", Filename: "video.mp4", URL: "large", Size: maxCommandAttachmentBytes + 1},
+		{ID: "3", Filename: "broken.txt", URL: "broken", Size: 10},
+		{ID: "4", Filename: "b.csv", URL: "fits", Size: 2 << 20},
+		{ID: "5", Filename: "c.csv", URL: "over budget", Size: 2 << 20},
+	}
+	budget := maxCommandAttachmentTotalBytes
+	got := CommandAttachments(attachments, &budget, download)
+
+	if len(got) != len(attachments) {
+		t.Fatalf("CommandAttachments() returned %d attachments, want %d", len(got), len(attachments))
+	}
+	if got[0].Filename != "data.csv" || got[0].ContentType != "text/csv" || len(got[0].Data) != 1<<20 {
+		t.Errorf("CommandAttachments()[0] = %+v, want data.csv with its content", got[0])
+	}
+	for _, idx := range []int{1, 2, 4} {
+		if got[idx].Data != nil {
+			t.Errorf("CommandAttachments()[%d] has content, want metadata only", idx)
+		}
+	}
+	if len(got[3].Data) != 2<<20 {
+		t.Errorf("CommandAttachments()[3] has %d bytes, want %d", len(got[3].Data), 2<<20)
+	}
+	if want := []string{"small", "broken", "fits"}; strings.Join(downloaded, ",") != strings.Join(want, ",") {
+		t.Errorf("downloaded %v, want %v", downloaded, want)
+	}
+	if budget != maxCommandAttachmentTotalBytes-3<<20 {
+		t.Errorf("budget = %d, want %d", budget, maxCommandAttachmentTotalBytes-3<<20)
+	}
+}
+
+func TestNewRepliedMessage(t *testing.T) {
+	download := func(url string, limit int64) ([]byte, error) {
+		return []byte("id,name\n"), nil
+	}
+	m := &discordgo.Message{
+		ID:          "200",
+		ChannelID:   "10",
+		Content:     "これを集計して",
+		Author:      &discordgo.User{ID: "100", Username: "neru"},
+		Attachments: []*discordgo.MessageAttachment{{ID: "1", Filename: "data.csv", Size: 8}},
+	}
+
+	budget := maxCommandAttachmentTotalBytes
+	got := NewRepliedMessage(m, &budget, download)
+	if got.ID != "200" || got.ChannelID != "10" || got.Content != "これを集計して" {
+		t.Errorf("NewRepliedMessage() = %+v, want the message fields", got)
+	}
+	// Falls back to the username without a display name
+	if got.AuthorID != "100" || got.AuthorName != "neru" || got.AuthorBot {
+		t.Errorf("NewRepliedMessage() author = %q %q %v, want 100 neru false", got.AuthorID, got.AuthorName, got.AuthorBot)
+	}
+	if len(got.Attachments) != 1 || string(got.Attachments[0].Data) != "id,name\n" {
+		t.Errorf("NewRepliedMessage() attachments = %+v, want data.csv with its content", got.Attachments)
+	}
+}
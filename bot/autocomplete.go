@@ -0,0 +1,259 @@
+package nelchanbot
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+const (
+	// autocompleteTimeout leaves headroom under Discord's 3 second interaction deadline
+	autocompleteTimeout = 2500 * time.Millisecond
+	// maxAutocompleteChoices is the maximum number of choices Discord accepts
+	maxAutocompleteChoices = 25
+	// maxChoiceNameLength is the maximum length of a choice name
+	maxChoiceNameLength = 100
+	// maxChoiceValueLength is the maximum length of a string choice value
+	// Discord rejects the whole response if one value is longer, and a cut value would submit something else
+	maxChoiceValueLength = 100
+)
+
+// handleAutocomplete responds to autocomplete interactions for slash command options
+// Suggestions that take longer than autocompleteTimeout are dropped and an empty list is returned
+func (n *Nelchan) handleAutocomplete(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	done := make(chan []*discordgo.ApplicationCommandOptionChoice, 1)
+	go func() {
+		done <- n.autocompleteChoices(s, i)
+	}()
+
+	var choices []*discordgo.ApplicationCommandOptionChoice
+	select {
+	case choices = <-done:
+	case <-time.After(autocompleteTimeout):
+		fmt.Printf("autocomplete for /%s timed out\n", i.ApplicationCommandData().Name)
+	}
+
+	err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionApplicationCommandAutocompleteResult,
+		Data: &discordgo.InteractionResponseData{
+			Choices: choices,
+		},
+	})
+	if err != nil {
+		fmt.Printf("error responding to autocomplete: %v\n", err)
+	}
+}
+
+// autocompleteChoices computes the suggestions for the focused option of an autocomplete interaction
+func (n *Nelchan) autocompleteChoices(s *discordgo.Session, i *discordgo.InteractionCreate) []*discordgo.ApplicationCommandOptionChoice {
+	data := i.ApplicationCommandData()
+
+	focused := focusedOption(data.Options)
+	if focused == nil {
+		return nil
+	}
+
+	query := fmt.Sprintf("%v", focused.Value)
+
+	// Built-in commands take a registered command name
+	if isBuiltinSlashCommand(data.Name) {
+		if focused.Name == "command_name" {
+			return n.autocompleteFromSource(s, i, AutocompleteSourceCommands, query)
+		}
+		return nil
+	}
+
+	info := n.lookupCodeCommand(data.Name)
+	if info == nil {
+		return nil
+	}
+
+	for _, arg := range n.commandArgsSchema(info) {
+		if arg.Name != focused.Name || arg.Autocomplete == nil {
+			continue
+		}
+
+		var suggestions []*discordgo.ApplicationCommandOptionChoice
+		switch {
+		case arg.Autocomplete.Command != "":
+			suggestions = n.autocompleteFromCommand(s, i, arg.Autocomplete.Command, focused.Name, query)
+		case arg.Autocomplete.Source != "":
+			suggestions = n.autocompleteFromSource(s, i, arg.Autocomplete.Source, query)
+		default:
+			suggestions = filterAutocompleteValues(arg.Autocomplete.Values, query)
+		}
+		return typedChoices(arg, suggestions)
+	}
+	return nil
+}
+
+// focusedOption returns the focused option, looking into the options of subcommands
+func focusedOption(options []*discordgo.ApplicationCommandInteractionDataOption) *discordgo.ApplicationCommandInteractionDataOption {
+	for _, opt := range options {
+		if opt.Focused {
+			return opt
+		}
+		if opt.Type == discordgo.ApplicationCommandOptionSubCommand || opt.Type == discordgo.ApplicationCommandOptionSubCommandGroup {
+			if focused := focusedOption(opt.Options); focused != nil {
+				return focused
+			}
+		}
+	}
+	return nil
+}
+
+// isBuiltinSlashCommand reports whether name is one of builtinSlashCommands
+func isBuiltinSlashCommand(name string) bool {
+	for _, cmd := range builtinSlashCommands {
+		if cmd.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// autocompleteFromSource returns suggestions from a built-in source
+func (n *Nelchan) autocompleteFromSource(s *discordgo.Session, i *discordgo.InteractionCreate, source, query string) []*discordgo.ApplicationCommandOptionChoice {
+	switch source {
+	case AutocompleteSourceCommands:
+		commands, err := n.CommandAPIClient.ListCommands(ListCommandsRequest{
+			Query: query,
+			Limit: maxAutocompleteChoices,
+		})
+		if err != nil {
+			fmt.Println("error listing commands for autocomplete:", err)
+			return nil
+		}
+
+		choices := make([]*discordgo.ApplicationCommandOptionChoice, 0, len(commands))
+		for _, cmd := range commands {
+			choices = append(choices, newAutocompleteChoice(cmd.Name, cmd.Name))
+		}
+		return choices
+
+	case AutocompleteSourceMembers:
+		if i.GuildID == "" {
+			return nil
+		}
+		members, err := s.GuildMembersSearch(i.GuildID, query, maxAutocompleteChoices)
+		if err != nil {
+			fmt.Println("error searching guild members for autocomplete:", err)
+			return nil
+		}
+
+		choices := make([]*discordgo.ApplicationCommandOptionChoice, 0, len(members))
+		for _, member := range members {
+			name := member.Nick
+			if name == "" {
+				name = member.User.GlobalName
+			}
+			if name == "" {
+				name = member.User.Username
+			}
+			choices = append(choices, newAutocompleteChoice(name, member.User.ID))
+		}
+		return choices
+	}
+
+	fmt.Printf("unknown autocomplete source: %s\n", source)
+	return nil
+}
+
+// autocompleteFromCommand runs a code command and turns each printed line into a suggestion
+// A line may be "name<TAB>value" to show a different name than the submitted value
+func (n *Nelchan) autocompleteFromCommand(s *discordgo.Session, i *discordgo.InteractionCreate, commandName, optionName, query string) []*discordgo.ApplicationCommandOptionChoice {
+	ctx := interactionContext(s, RunSourceAutocomplete, i)
+	vars := ctx.Vars()
+	vars["autocomplete_option"] = optionName
+	vars["autocomplete_query"] = query
+
+	result, err := n.CommandAPIClient.RunCommand(RunCommandRequest{
+		CommandName: commandName,
+		IsCode:      true,
+		Vars:        vars,
+		Args:        []string{query},
+		Context:     &ctx,
+	})
+	if err != nil {
+		fmt.Println("error running autocomplete command:", err)
+		return nil
+	}
+	if result == nil {
+		fmt.Printf("autocomplete command %s not found\n", commandName)
+		return nil
+	}
+
+	var choices []*discordgo.ApplicationCommandOptionChoice
+	for _, line := range strings.Split(result.Content, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		name, value, hasValue := strings.Cut(line, "\t")
+		if !hasValue {
+			value = name
+		}
+		if utf8.RuneCountInString(value) > maxChoiceValueLength {
+			continue
+		}
+		choices = append(choices, newAutocompleteChoice(name, value))
+		if len(choices) == maxAutocompleteChoices {
+			break
+		}
+	}
+	return choices
+}
+
+// filterAutocompleteValues returns the static values containing the query (case-insensitive)
+func filterAutocompleteValues(values []string, query string) []*discordgo.ApplicationCommandOptionChoice {
+	query = strings.ToLower(query)
+
+	var choices []*discordgo.ApplicationCommandOptionChoice
+	for _, value := range values {
+		if !strings.Contains(strings.ToLower(value), query) || utf8.RuneCountInString(value) > maxChoiceValueLength {
+			continue
+		}
+		choices = append(choices, newAutocompleteChoice(value, value))
+		if len(choices) == maxAutocompleteChoices {
+			break
+		}
+	}
+	return choices
+}
+
+// typedChoices converts suggestion values to the option's type, dropping the ones that don't convert
+// Discord rejects autocomplete results whose values don't match the option type or are too long
+func typedChoices(arg ArgOption, choices []*discordgo.ApplicationCommandOptionChoice) []*discordgo.ApplicationCommandOptionChoice {
+	typed := make([]*discordgo.ApplicationCommandOptionChoice, 0, len(choices))
+	for _, choice := range choices {
+		value, err := convertArgType(arg.Type, choice.Value)
+		if err != nil {
+			continue
+		}
+		if s, ok := value.(string); ok && utf8.RuneCountInString(s) > maxChoiceValueLength {
+			continue
+		}
+		typed = append(typed, &discordgo.ApplicationCommandOptionChoice{
+			Name:  choice.Name,
+			Value: value,
+		})
+	}
+	if len(typed) > maxAutocompleteChoices {
+		typed = typed[:maxAutocompleteChoices]
+	}
+	return typed
+}
+
+// newAutocompleteChoice creates a choice, truncating the name to Discord's limit
+func newAutocompleteChoice(name, value string) *discordgo.ApplicationCommandOptionChoice {
+	if utf8.RuneCountInString(name) > maxChoiceNameLength {
+		name = string([]rune(name)[:maxChoiceNameLength-1]) + "…"
+	}
+	return &discordgo.ApplicationCommandOptionChoice{
+		Name:  name,
+		Value: value,
+	}
+}
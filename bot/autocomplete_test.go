@@ -0,0 +1,88 @@
+package nelchanbot
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func TestFilterAutocompleteValues(t *testing.T) {
+	values := []string{"Apple", "banana", "pineapple"}
+
+	choices := filterAutocompleteValues(values, "app")
+	expected := []*discordgo.ApplicationCommandOptionChoice{
+		{Name: "Apple", Value: "Apple"},
+		{Name: "pineapple", Value: "pineapple"},
+	}
+	if !reflect.DeepEqual(choices, expected) {
+		t.Errorf("filterAutocompleteValues() = %+v, want %+v", choices, expected)
+	}
+
+	many := make([]string, 30)
+	for i := range many {
+		many[i] = "x"
+	}
+	if got := len(filterAutocompleteValues(many, "")); got != maxAutocompleteChoices {
+		t.Errorf("len(filterAutocompleteValues()) = %d, want %d", got, maxAutocompleteChoices)
+	}
+
+	// Values too long for Discord are left out
+	long := strings.Repeat("あ", maxChoiceValueLength+1)
+	if got := filterAutocompleteValues([]string{long, "あ"}, "あ"); len(got) != 1 || got[0].Value != "あ" {
+		t.Errorf("filterAutocompleteValues() with a long value = %+v, want only the short one", got)
+	}
+}
+
+func TestTypedChoices(t *testing.T) {
+	choices := []*discordgo.ApplicationCommandOptionChoice{
+		{Name: "one", Value: "1"},
+		{Name: "not a number", Value: "x"},
+	}
+
+	typed := typedChoices(ArgOption{Name: "n", Type: "integer"}, choices)
+	expected := []*discordgo.ApplicationCommandOptionChoice{
+		{Name: "one", Value: int64(1)},
+	}
+	if !reflect.DeepEqual(typed, expected) {
+		t.Errorf("typedChoices() = %+v, want %+v", typed, expected)
+	}
+
+	long := []*discordgo.ApplicationCommandOptionChoice{
+		{Name: "long", Value: strings.Repeat("a", maxChoiceValueLength+1)},
+		{Name: "short", Value: "a"},
+	}
+	typed = typedChoices(ArgOption{Name: "s", Type: "string"}, long)
+	if len(typed) != 1 || typed[0].Name != "short" {
+		t.Errorf("typedChoices() with a long value = %+v, want only the short one", typed)
+	}
+}
+
+func TestNewAutocompleteChoiceTruncatesName(t *testing.T) {
+	choice := newAutocompleteChoice(strings.Repeat("あ", 150), "v")
+	if got := len([]rune(choice.Name)); got != maxChoiceNameLength {
+		t.Errorf("len(Name) = %d, want %d", got, maxChoiceNameLength)
+	}
+}
+
+func TestFocusedOption(t *testing.T) {
+	focused := &discordgo.ApplicationCommandInteractionDataOption{Name: "command_name", Focused: true}
+	options := []*discordgo.ApplicationCommandInteractionDataOption{
+		{
+			Name: "add",
+			Type: discordgo.ApplicationCommandOptionSubCommand,
+			Options: []*discordgo.ApplicationCommandInteractionDataOption{
+				{Name: "cron", Value: "0 9 * * *"},
+				focused,
+			},
+		},
+	}
+
+	if got := focusedOption(options); got != focused {
+		t.Errorf("focusedOption() = %+v, want %+v", got, focused)
+	}
+	if got := focusedOption(options[0].Options[:1]); got != nil {
+		t.Errorf("focusedOption() without focus = %+v, want nil", got)
+	}
+}
@@ -0,0 +1,386 @@
+package nelchanbot
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	// commandBundleVersion is the version of the bundle format written by export
+	commandBundleVersion = 1
+	// maxBundleSize is the maximum size of a bundle accepted by import, compressed or not
+	maxBundleSize = 8 << 20
+	// bundleJSONName is the name of the bundle inside a zip bundle
+	bundleJSONName = "bundle.json"
+)
+
+// Bundle formats
+const (
+	BundleFormatJSON = "json"
+	BundleFormatZip  = "zip"
+)
+
+// Import conflict policies, deciding what happens when a command of the same name exists
+const (
+	ImportPolicySkip      = "skip"      // keep the existing command
+	ImportPolicyOverwrite = "overwrite" // replace the existing command with the bundled one
+	ImportPolicyRename    = "rename"    // import the bundled command under a free name
+)
+
+// Import actions
+const (
+	ImportCreate    = "create"
+	ImportOverwrite = "overwrite"
+	ImportRename    = "rename"
+	ImportSkip      = "skip"
+)
+
+// CommandBundle is a set of exported commands that can be imported into another bot instance
+type CommandBundle struct {
+	Version    int             `json:"version"`
+	ExportedAt string          `json:"exported_at"`
+	Commands   []BundleCommand `json:"commands"`
+}
+
+// BundleCommand is one command in a bundle
+type BundleCommand struct {
+	Name       string          `json:"name"`
+	Type       string          `json:"type"` // "text" or "code"
+	Content    string          `json:"content"`
+	Metadata   CommandMetadata `json:"metadata"`
+	ArgsSchema []ArgOption     `json:"args_schema,omitempty"`
+
+	// Every response of a text command with more than one, Content is the first one
+	Responses    []TextResponse `json:"responses,omitempty"`
+	ResponseMode string         `json:"response_mode,omitempty"`
+}
+
+// IsCode reports whether the bundled command is a code command
+func (c BundleCommand) IsCode() bool {
+	return c.Type == "code"
+}
+
+// NewCommandBundle builds a bundle from exported commands
+func NewCommandBundle(commands []GetCommandInfo, now time.Time) CommandBundle {
+	bundle := CommandBundle{
+		Version:    commandBundleVersion,
+		ExportedAt: now.UTC().Format(time.RFC3339),
+		Commands:   make([]BundleCommand, len(commands)),
+	}
+	for idx, cmd := range commands {
+		commandType := "text"
+		if cmd.IsCode {
+			commandType = "code"
+		}
+		bundle.Commands[idx] = BundleCommand{
+			Name:       cmd.Name,
+			Type:       commandType,
+			Content:    cmd.Content,
+			Metadata:   cmd.Metadata,
+			ArgsSchema: cmd.ArgsSchema,
+		}
+		if !cmd.IsCode && len(cmd.Responses) > 1 {
+			bundle.Commands[idx].Responses = cmd.Responses
+			bundle.Commands[idx].ResponseMode = cmd.ResponseMode
+		}
+	}
+	return bundle
+}
+
+// EncodeCommandBundle encodes a bundle as JSON or as a zip containing the JSON plus one readable file per command
+// Returns the file name and content of the attachment
+func EncodeCommandBundle(bundle CommandBundle, format string) (string, []byte, error) {
+	data, err := json.MarshalIndent(bundle, "", "  ")
+	if err != nil {
+		return "", nil, fmt.Errorf("error marshalling bundle: %w", err)
+	}
+
+	date := strings.SplitN(bundle.ExportedAt, "T", 2)[0]
+	if format != BundleFormatZip {
+		return fmt.Sprintf("nelchan-commands-%s.json", date), data, nil
+	}
+
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	if err := addZipFile(w, bundleJSONName, data); err != nil {
+		return "", nil, err
+	}
+	for _, cmd := range bundle.Commands {
+		ext := ".txt"
+		if cmd.IsCode() {
+			ext = ".py"
+		}
+		if err := addZipFile(w, "commands/"+cmd.Name+ext, []byte(cmd.Content)); err != nil {
+			return "", nil, err
+		}
+	}
+	if err := w.Close(); err != nil {
+		return "", nil, fmt.Errorf("error closing zip: %w", err)
+	}
+	return fmt.Sprintf("nelchan-commands-%s.zip", date), buf.Bytes(), nil
+}
+
+// addZipFile writes one file into a zip
+func addZipFile(w *zip.Writer, name string, content []byte) error {
+	f, err := w.Create(name)
+	if err != nil {
+		return fmt.Errorf("error adding %s to zip: %w", name, err)
+	}
+	if _, err := f.Write(content); err != nil {
+		return fmt.Errorf("error writing %s to zip: %w", name, err)
+	}
+	return nil
+}
+
+// DecodeCommandBundle decodes a JSON bundle or a zip bundle containing bundle.json, and validates it
+func DecodeCommandBundle(data []byte) (*CommandBundle, error) {
+	if bytes.HasPrefix(data, []byte("PK\x03\x04")) {
+		extracted, err := readZipBundle(data)
+		if err != nil {
+			return nil, err
+		}
+		data = extracted
+	}
+
+	var bundle CommandBundle
+	if err := json.Unmarshal(data, &bundle); err != nil {
+		return nil, fmt.Errorf("バンドルの JSON を読み込めません: %w", err)
+	}
+
+	if bundle.Version < 1 || bundle.Version > commandBundleVersion {
+		return nil, fmt.Errorf("対応していないバンドルのバージョンです: %d", bundle.Version)
+	}
+
+	seen := make(map[string]bool, len(bundle.Commands))
+	var problems []string
+	for idx, cmd := range bundle.Commands {
+		switch {
+		case cmd.Name == "":
+			problems = append(problems, fmt.Sprintf("%d 番目のコマンドに名前がありません", idx+1))
+		case seen[cmd.Name]:
+			problems = append(problems, fmt.Sprintf("コマンド「%s」が重複しています", cmd.Name))
+		case cmd.Type != "text" && cmd.Type != "code":
+			problems = append(problems, fmt.Sprintf("コマンド「%s」の種類が不正です: %q", cmd.Name, cmd.Type))
+		case cmd.Content == "":
+			problems = append(problems, fmt.Sprintf("コマンド「%s」の内容が空です", cmd.Name))
+		case cmd.ResponseMode != "" && responseModeNames[cmd.ResponseMode] == "":
+			problems = append(problems, fmt.Sprintf("コマンド「%s」の応答の選び方が不正です: %q", cmd.Name, cmd.ResponseMode))
+		}
+		seen[cmd.Name] = true
+	}
+	if len(problems) > 0 {
+		return nil, errors.New(strings.Join(problems, "\n"))
+	}
+	return &bundle, nil
+}
+
+// readZipBundle extracts bundle.json from a zip bundle
+func readZipBundle(data []byte) ([]byte, error) {
+	r, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("zip を読み込めません: %w", err)
+	}
+
+	f, err := r.Open(bundleJSONName)
+	if err != nil {
+		return nil, fmt.Errorf("zip に %s がありません", bundleJSONName)
+	}
+	defer f.Close()
+
+	// Guard against zip bombs
+	extracted, err := io.ReadAll(io.LimitReader(f, maxBundleSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("%s を読み込めません: %w", bundleJSONName, err)
+	}
+	if len(extracted) > maxBundleSize {
+		return nil, fmt.Errorf("%s が大きすぎます", bundleJSONName)
+	}
+	return extracted, nil
+}
+
+// downloadAttachment downloads an attachment, failing if it's larger than limit bytes
+func downloadAttachment(url string, limit int64) ([]byte, error) {
+	client := &http.Client{Timeout: 30 * time.Second}
+	response, err := client.Get(url)
+	if err != nil {
+		return nil, fmt.Errorf("error downloading attachment: %w", err)
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code downloading attachment: %d", response.StatusCode)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(response.Body, limit+1))
+	if err != nil {
+		return nil, fmt.Errorf("error reading attachment: %w", err)
+	}
+	if int64(len(data)) > limit {
+		return nil, fmt.Errorf("ファイルが大きすぎます（最大 %d MB）", limit>>20)
+	}
+	return data, nil
+}
+
+// ImportAction is what importing one bundled command will do
+type ImportAction struct {
+	Command  BundleCommand
+	Kind     string          // one of the Import action constants
+	Target   string          // name the command is imported as
+	Existing *GetCommandInfo // command being overwritten
+	Reason   string          // why the command is skipped
+}
+
+// importPlanner decides what to do with each command of a bundle
+type importPlanner struct {
+	// lookup returns the registered command of a name, or nil
+	lookup func(name string) (*GetCommandInfo, error)
+	// checkName returns why a name can't be used for a command, or an empty string
+	checkName func(name string) string
+	// isAlias reports whether a name is used by an alias
+	isAlias func(name string) bool
+	// canOverwrite reports whether the importing user may overwrite a command
+	canOverwrite func(info *GetCommandInfo) bool
+}
+
+// maxRenameAttempts is the number of suffixes tried for a free name under the rename policy
+const maxRenameAttempts = 100
+
+// plan decides the action for each bundled command under a conflict policy
+func (p importPlanner) plan(commands []BundleCommand, policy string) ([]ImportAction, error) {
+	// Names taken by earlier commands of the bundle itself
+	planned := make(map[string]bool, len(commands))
+	actions := make([]ImportAction, 0, len(commands))
+
+	for _, cmd := range commands {
+		action, err := p.planCommand(cmd, policy, planned)
+		if err != nil {
+			return nil, err
+		}
+		if action.Kind != ImportSkip {
+			planned[action.Target] = true
+		}
+		actions = append(actions, action)
+	}
+	return actions, nil
+}
+
+// planCommand decides the action for one bundled command
+func (p importPlanner) planCommand(cmd BundleCommand, policy string, planned map[string]bool) (ImportAction, error) {
+	action := ImportAction{Command: cmd, Target: cmd.Name}
+
+	reason := p.checkName(cmd.Name)
+	var existing *GetCommandInfo
+	if reason == "" {
+		info, err := p.lookup(cmd.Name)
+		if err != nil {
+			return ImportAction{}, err
+		}
+		existing = info
+	}
+
+	switch {
+	case reason == "" && existing == nil && !planned[cmd.Name]:
+		// A new command takes over its name from an alias, like a registration
+		action.Kind = ImportCreate
+		return action, nil
+	case policy == ImportPolicyRename:
+		return p.planRename(action, planned)
+	case reason != "":
+		action.Kind = ImportSkip
+		action.Reason = reason
+	case existing == nil:
+		action.Kind = ImportSkip
+		action.Reason = "バンドル内の別のコマンドと名前が重複しています"
+	case policy == ImportPolicyOverwrite && p.canOverwrite(existing):
+		action.Kind = ImportOverwrite
+		action.Existing = existing
+	case policy == ImportPolicyOverwrite:
+		action.Kind = ImportSkip
+		action.Reason = "上書きできるのは登録者か管理者だけです"
+	default:
+		action.Kind = ImportSkip
+		action.Reason = "同じ名前のコマンドがあります"
+	}
+	return action, nil
+}
+
+// planRename finds a free name of the form <name>_<n> for a conflicting command
+func (p importPlanner) planRename(action ImportAction, planned map[string]bool) (ImportAction, error) {
+	for suffix := 2; suffix < maxRenameAttempts+2; suffix++ {
+		name := fmt.Sprintf("%s_%d", action.Command.Name, suffix)
+		if planned[name] || p.isAlias(name) {
+			continue
+		}
+		if reason := p.checkName(name); reason != "" {
+			action.Kind = ImportSkip
+			action.Reason = reason
+			return action, nil
+		}
+		info, err := p.lookup(name)
+		if err != nil {
+			return ImportAction{}, err
+		}
+		if info == nil {
+			action.Kind = ImportRename
+			action.Target = name
+			return action, nil
+		}
+	}
+
+	action.Kind = ImportSkip
+	action.Reason = "空いている名前が見つかりません"
+	return action, nil
+}
+
+// FormatImportResult summarizes the actions of an import, one line per command
+// Example:
+//
+//	インポート結果: 作成 1 / 上書き 1 / 名前変更 1 / スキップ 1
+//	＋ hello
+//	↻ dice（上書き）
+//	→ greet を greet_2 として作成
+//	− foo（同じ名前のコマンドがあります）
+func FormatImportResult(actions []ImportAction, failed map[string]error, dryRun bool) string {
+	counts := make(map[string]int)
+	var lines strings.Builder
+	for _, action := range actions {
+		if err, ok := failed[action.Target]; ok && action.Kind != ImportSkip {
+			counts["failed"]++
+			fmt.Fprintf(&lines, "⚠️ %s（失敗: %s）\n", action.Target, err.Error())
+			continue
+		}
+
+		counts[action.Kind]++
+		switch action.Kind {
+		case ImportCreate:
+			fmt.Fprintf(&lines, "＋ %s\n", action.Target)
+		case ImportOverwrite:
+			fmt.Fprintf(&lines, "↻ %s（上書き）\n", action.Target)
+		case ImportRename:
+			fmt.Fprintf(&lines, "→ %s を %s として作成\n", action.Command.Name, action.Target)
+		case ImportSkip:
+			fmt.Fprintf(&lines, "− %s（%s）\n", action.Command.Name, action.Reason)
+		}
+	}
+
+	title := "インポート結果"
+	if dryRun {
+		title = "インポートのプレビュー（ドライラン、まだ何も変更されていません）"
+	}
+	summary := fmt.Sprintf("%s: 作成 %d / 上書き %d / 名前変更 %d / スキップ %d",
+		title, counts[ImportCreate], counts[ImportOverwrite], counts[ImportRename], counts[ImportSkip])
+	if counts["failed"] > 0 {
+		summary += fmt.Sprintf(" / 失敗 %d", counts["failed"])
+	}
+	if len(actions) == 0 {
+		return summary + "\nバンドルにコマンドがありません"
+	}
+	return summary + "\n" + strings.TrimSuffix(lines.String(), "\n")
+}
@@ -0,0 +1,191 @@
+package nelchanbot
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testBundle() CommandBundle {
+	return NewCommandBundle([]GetCommandInfo{
+		{
+			Name:       "dice",
+			IsCode:     true,
+			Content:    "print(6)",
+			ArgsSchema: []ArgOption{{Name: "sides", Type: "integer"}},
+			Metadata:   CommandMetadata{Description: "サイコロ", Tags: []string{"game"}},
+		},
+		{Name: "hello", Content: "こんにちは"},
+		{
+			Name:         "omikuji",
+			Content:      "大吉",
+			Responses:    []TextResponse{{Text: "大吉", Weight: 1}, {Text: "凶", Weight: 3}},
+			ResponseMode: ResponseModeWeighted,
+		},
+	}, time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC))
+}
+
+func TestCommandBundleRoundTrip(t *testing.T) {
+	bundle := testBundle()
+
+	for _, format := range []string{BundleFormatJSON, BundleFormatZip} {
+		t.Run(format, func(t *testing.T) {
+			fileName, data, err := EncodeCommandBundle(bundle, format)
+			if err != nil {
+				t.Fatalf("EncodeCommandBundle() error = %v", err)
+			}
+			if want := "nelchan-commands-2026-10-18." + format; fileName != want {
+				t.Errorf("file name = %q, want %q", fileName, want)
+			}
+
+			got, err := DecodeCommandBundle(data)
+			if err != nil {
+				t.Fatalf("DecodeCommandBundle() error = %v", err)
+			}
+			if !reflect.DeepEqual(*got, bundle) {
+				t.Errorf("DecodeCommandBundle() = %+v, want %+v", *got, bundle)
+			}
+		})
+	}
+}
+
+func TestDecodeCommandBundleInvalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		wantErr string
+	}{
+		{
+			name:    "not json",
+			data:    "hello",
+			wantErr: "JSON を読み込めません",
+		},
+		{
+			name:    "unsupported version",
+			data:    `{"version": 2, "commands": []}`,
+			wantErr: "バージョン",
+		},
+		{
+			name:    "duplicate name",
+			data:    `{"version": 1, "commands": [{"name": "a", "type": "text", "content": "x"}, {"name": "a", "type": "text", "content": "y"}]}`,
+			wantErr: "コマンド「a」が重複しています",
+		},
+		{
+			name:    "unknown type",
+			data:    `{"version": 1, "commands": [{"name": "a", "type": "python", "content": "x"}]}`,
+			wantErr: "種類が不正です",
+		},
+		{
+			name:    "unknown response mode",
+			data:    `{"version": 1, "commands": [{"name": "a", "type": "text", "content": "x", "response_mode": "shuffle"}]}`,
+			wantErr: "応答の選び方が不正です",
+		},
+		{
+			name:    "empty content",
+			data:    `{"version": 1, "commands": [{"name": "a", "type": "code", "content": ""}]}`,
+			wantErr: "内容が空です",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := DecodeCommandBundle([]byte(tt.data))
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("DecodeCommandBundle() error = %v, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestImportPlannerPlan(t *testing.T) {
+	existing := map[string]*GetCommandInfo{
+		"dice":   {Name: "dice", AuthorID: "100", Locked: true},
+		"dice_2": {Name: "dice_2", AuthorID: "100", Locked: true},
+		"mine":   {Name: "mine", AuthorID: "200", Locked: true},
+	}
+	planner := importPlanner{
+		lookup: func(name string) (*GetCommandInfo, error) {
+			return existing[name], nil
+		},
+		checkName: func(name string) string {
+			if name == "help" {
+				return "「help」はビルトインコマンドの名前です"
+			}
+			return ""
+		},
+		isAlias: func(name string) bool {
+			return name == "dice_3"
+		},
+		canOverwrite: func(info *GetCommandInfo) bool {
+			return canManageCommand(info, "200", false)
+		},
+	}
+
+	commands := []BundleCommand{
+		{Name: "new", Type: "text", Content: "x"},
+		{Name: "dice", Type: "code", Content: "x"},
+		{Name: "mine", Type: "text", Content: "x"},
+		{Name: "help", Type: "text", Content: "x"},
+	}
+
+	tests := []struct {
+		policy string
+		want   []string // "<kind> <target>"
+	}{
+		{
+			policy: ImportPolicySkip,
+			want:   []string{"create new", "skip dice", "skip mine", "skip help"},
+		},
+		{
+			policy: ImportPolicyOverwrite,
+			want:   []string{"create new", "skip dice", "overwrite mine", "skip help"},
+		},
+		{
+			policy: ImportPolicyRename,
+			want:   []string{"create new", "rename dice_4", "rename mine_2", "rename help_2"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.policy, func(t *testing.T) {
+			actions, err := planner.plan(commands, tt.policy)
+			if err != nil {
+				t.Fatalf("plan() error = %v", err)
+			}
+
+			got := make([]string, len(actions))
+			for idx, action := range actions {
+				got[idx] = action.Kind + " " + action.Target
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("plan() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatImportResult(t *testing.T) {
+	actions := []ImportAction{
+		{Command: BundleCommand{Name: "hello"}, Kind: ImportCreate, Target: "hello"},
+		{Command: BundleCommand{Name: "dice"}, Kind: ImportOverwrite, Target: "dice"},
+		{Command: BundleCommand{Name: "greet"}, Kind: ImportRename, Target: "greet_2"},
+		{Command: BundleCommand{Name: "foo"}, Kind: ImportSkip, Target: "foo", Reason: "同じ名前のコマンドがあります"},
+	}
+
+	want := "インポート結果: 作成 1 / 上書き 1 / 名前変更 1 / スキップ 1\n" +
+		"＋ hello\n" +
+		"↻ dice（上書き）\n" +
+		"→ greet を greet_2 として作成\n" +
+		"− foo（同じ名前のコマンドがあります）"
+	if got := FormatImportResult(actions, nil, false); got != want {
+		t.Errorf("FormatImportResult() = %q, want %q", got, want)
+	}
+
+	failed := map[string]error{"hello": errors.New("boom")}
+	got := FormatImportResult(actions[:1], failed, true)
+	if !strings.HasPrefix(got, "インポートのプレビュー") || !strings.Contains(got, "⚠️ hello（失敗: boom）") || !strings.Contains(got, "失敗 1") {
+		t.Errorf("FormatImportResult() with failure = %q", got)
+	}
+}
@@ -3,11 +3,19 @@ package nelchanbot
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 )
 
+var (
+	// ErrCommandNotFound is returned when the target command of a request doesn't exist
+	ErrCommandNotFound = errors.New("command not found")
+	// ErrNameInUse is returned when a new name is already used by a command or an alias
+	ErrNameInUse = errors.New("name already in use")
+)
+
 type CommandAPIClient struct {
 	CodeSandboxURL string
 	APIKey         string
@@ -36,10 +44,39 @@ func (c *CommandAPIClient) doRequest(method, url string, body []byte) (*http.Res
 }
 
 type RegisterCommandRequest struct {
-	CommandName    string `json:"command_name"`
-	CommandContent string `json:"command_content"`
-	IsCode         bool   `json:"isCode"`
-	AuthorID       string `json:"author_id"`
+	CommandName    string      `json:"command_name"`
+	CommandContent string      `json:"command_content"`
+	IsCode         bool        `json:"isCode"`
+	AuthorID       string      `json:"author_id"`
+	ArgsSchema     []ArgOption `json:"args_schema,omitempty"`
+
+	// Metadata replaces the stored metadata, which is kept when nil
+	Metadata *CommandMetadata `json:"metadata,omitempty"`
+
+	// Append adds the content as another response of an existing text command
+	Append bool `json:"append,omitempty"`
+	// Weight of the content for ResponseModeWeighted, 1 if zero
+	Weight int `json:"weight,omitempty"`
+	// Responses replaces all responses of a text command, used by imports
+	Responses []TextResponse `json:"responses,omitempty"`
+	// ResponseMode replaces how a text command picks a response, kept when empty
+	ResponseMode string `json:"response_mode,omitempty"`
+
+	// Admin lets the backend overwrite a locked command the author doesn't own
+	Admin bool `json:"admin,omitempty"`
+}
+
+// How a text command with several responses picks one
+const (
+	ResponseModeRandom     = "random"      // any response with the same chance
+	ResponseModeRoundRobin = "round_robin" // the responses in order
+	ResponseModeWeighted   = "weighted"    // any response with a chance proportional to its weight
+)
+
+// TextResponse represents one of the responses of a text command
+type TextResponse struct {
+	Text   string `json:"text"`
+	Weight int    `json:"weight"`
 }
 
 func (c *CommandAPIClient) RegisterCommand(request RegisterCommandRequest) error {
@@ -82,6 +119,34 @@ type RunCommandRequest struct {
 	IsCode      bool              `json:"is_code"`
 	Vars        map[string]string `json:"vars"`
 	Args        []string          `json:"args"`
+	Named       map[string]any    `json:"named,omitempty"` // --flag, --key=value and key:value args
+	// Files attached to the invoking message
+	Attachments []CommandAttachment `json:"attachments,omitempty"`
+	// Message the invoking message replied to, or the target of a message context menu
+	Replied *RepliedMessage `json:"replied,omitempty"`
+	// Who ran the command, where and how; Vars holds its scalar fields
+	Context *ExecutionContext `json:"context,omitempty"`
+}
+
+// CommandAttachment is a message attachment passed to a code command
+type CommandAttachment struct {
+	ID          string `json:"id"`
+	Filename    string `json:"filename"`
+	URL         string `json:"url"`
+	ContentType string `json:"content_type,omitempty"`
+	Size        int    `json:"size"`
+	Data        []byte `json:"data,omitempty"` // nil if the file was too large or couldn't be downloaded
+}
+
+// RepliedMessage is a message passed to a code command along with the invocation
+type RepliedMessage struct {
+	ID          string              `json:"id"`
+	ChannelID   string              `json:"channel_id"`
+	Content     string              `json:"content"`
+	AuthorID    string              `json:"author_id"`
+	AuthorName  string              `json:"author_name"`
+	AuthorBot   bool                `json:"author_bot"`
+	Attachments []CommandAttachment `json:"attachments"`
 }
 
 func (c *CommandAPIClient) RunCommand(request RunCommandRequest) (*CommandResult, error) {
@@ -103,6 +168,10 @@ func (c *CommandAPIClient) RunCommand(request RunCommandRequest) (*CommandResult
 		return nil, fmt.Errorf("error reading response body: %w", err)
 	}
 
+	if response.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+
 	if response.StatusCode != 200 {
 		return nil, fmt.Errorf("unexpected status code: %d", response.StatusCode)
 	}
@@ -129,6 +198,22 @@ type CommandResult struct {
 	ID      string `json:"id"`
 	Name    string `json:"name"`
 	Content string `json:"content"`
+
+	// All responses of a text command, Content is the first one
+	Responses      []TextResponse `json:"responses,omitempty"`
+	ResponseMode   string         `json:"response_mode,omitempty"`
+	ResponseCursor int            `json:"response_cursor,omitempty"` // the response to pick for ResponseModeRoundRobin
+
+	// Files a code command saved with save_file() or save_figure()
+	Artifacts []CommandArtifact `json:"artifacts,omitempty"`
+}
+
+// CommandArtifact is a file saved by a code command
+type CommandArtifact struct {
+	Name        string `json:"name"`
+	ContentType string `json:"content_type"`
+	Size        int    `json:"size"`
+	Data        []byte `json:"data,omitempty"` // nil if the backend didn't read the file as it was too large
 }
 
 type GetCommandRequest struct {
@@ -141,9 +226,18 @@ type GetCommandResponse struct {
 }
 
 type GetCommandInfo struct {
-	Name    string `json:"name"`
-	IsCode  bool   `json:"isCode"`
-	Content string `json:"content"`
+	Name       string          `json:"name"`
+	IsCode     bool            `json:"isCode"`
+	Content    string          `json:"content"`
+	AuthorID   string          `json:"author_id"`
+	ArgsSchema []ArgOption     `json:"args_schema"`
+	Metadata   CommandMetadata `json:"metadata"`
+	LastUsedAt string          `json:"last_used_at"`
+	Locked     bool            `json:"locked"` // only the owner and admins may change a locked command
+
+	// All responses of a text command, Content is the first one
+	Responses    []TextResponse `json:"responses"`
+	ResponseMode string         `json:"response_mode"`
 }
 
 func (c *CommandAPIClient) GetCommand(request GetCommandRequest) (*GetCommandInfo, error) {
@@ -182,11 +276,1422 @@ func (c *CommandAPIClient) GetCommand(request GetCommandRequest) (*GetCommandInf
 	return getCommandResponse.Command, nil
 }
 
+// CommandVersion represents an immutable revision of a command
+// Content and ArgsSchema are only filled in by GetCommandVersion
+type CommandVersion struct {
+	Version    int         `json:"version"`
+	IsCode     bool        `json:"isCode"`
+	AuthorID   string      `json:"author_id"`
+	CreatedAt  string      `json:"created_at"`
+	Content    string      `json:"content"`
+	ArgsSchema []ArgOption `json:"args_schema"`
+}
+
+// CommandHistoryRequest represents a request to list the versions of a command
+type CommandHistoryRequest struct {
+	CommandName string `json:"command_name"`
+	Limit       int    `json:"limit,omitempty"`
+}
+
+// CommandHistoryResponse represents a response from command history
+type CommandHistoryResponse struct {
+	Error    *string          `json:"error"`
+	Versions []CommandVersion `json:"versions"`
+}
+
+// GetCommandHistory lists the versions of a command, newest first
+// Returns nil if the command doesn't exist
+func (c *CommandAPIClient) GetCommandHistory(request CommandHistoryRequest) ([]CommandVersion, error) {
+	url := c.CodeSandboxURL + "/command_history"
+
+	requestBodyJSON, err := json.Marshal(request)
+	if err != nil {
+		return nil, fmt.Errorf("error marshalling request body: %w", err)
+	}
+
+	response, err := c.doRequest("POST", url, requestBodyJSON)
+	if err != nil {
+		return nil, fmt.Errorf("error sending request: %w", err)
+	}
+	defer response.Body.Close()
+
+	respBody, err := io.ReadAll(response.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error reading response body: %w", err)
+	}
+
+	if response.StatusCode == 404 {
+		return nil, nil // Command not found
+	}
+
+	if response.StatusCode != 200 {
+		return nil, fmt.Errorf("unexpected status code: %d, body: %s", response.StatusCode, string(respBody))
+	}
+
+	var historyResponse CommandHistoryResponse
+	if err := json.Unmarshal(respBody, &historyResponse); err != nil {
+		return nil, fmt.Errorf("error unmarshalling response body: %w", err)
+	}
+
+	if historyResponse.Error != nil {
+		return nil, fmt.Errorf("API error: %s", *historyResponse.Error)
+	}
+
+	return historyResponse.Versions, nil
+}
+
+// CommandVersionRequest represents a request to get a version of a command
+type CommandVersionRequest struct {
+	CommandName string `json:"command_name"`
+	Version     int    `json:"version,omitempty"` // latest version if zero
+}
+
+// CommandVersionResponse represents a response from command version
+type CommandVersionResponse struct {
+	Error   *string         `json:"error"`
+	Version *CommandVersion `json:"version"`
+}
+
+// GetCommandVersion gets a version of a command with its content
+// Returns nil if the command or version doesn't exist
+func (c *CommandAPIClient) GetCommandVersion(request CommandVersionRequest) (*CommandVersion, error) {
+	url := c.CodeSandboxURL + "/command_version"
+
+	requestBodyJSON, err := json.Marshal(request)
+	if err != nil {
+		return nil, fmt.Errorf("error marshalling request body: %w", err)
+	}
+
+	response, err := c.doRequest("POST", url, requestBodyJSON)
+	if err != nil {
+		return nil, fmt.Errorf("error sending request: %w", err)
+	}
+	defer response.Body.Close()
+
+	respBody, err := io.ReadAll(response.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error reading response body: %w", err)
+	}
+
+	if response.StatusCode == 404 {
+		return nil, nil // Version not found
+	}
+
+	if response.StatusCode != 200 {
+		return nil, fmt.Errorf("unexpected status code: %d, body: %s", response.StatusCode, string(respBody))
+	}
+
+	var versionResponse CommandVersionResponse
+	if err := json.Unmarshal(respBody, &versionResponse); err != nil {
+		return nil, fmt.Errorf("error unmarshalling response body: %w", err)
+	}
+
+	if versionResponse.Error != nil {
+		return nil, fmt.Errorf("API error: %s", *versionResponse.Error)
+	}
+
+	return versionResponse.Version, nil
+}
+
+// DeleteCommandRequest represents a request to delete a registered command
+type DeleteCommandRequest struct {
+	CommandName string `json:"command_name"`
+}
+
+// DeleteCommandResponse represents a response from delete command
+type DeleteCommandResponse struct {
+	Error *string `json:"error"`
+}
+
+// DeleteCommand deletes a registered command along with its recorded slash commands
+// Returns false if the command doesn't exist
+func (c *CommandAPIClient) DeleteCommand(request DeleteCommandRequest) (bool, error) {
+	url := c.CodeSandboxURL + "/delete_command"
+
+	requestBodyJSON, err := json.Marshal(request)
+	if err != nil {
+		return false, fmt.Errorf("error marshalling request body: %w", err)
+	}
+
+	response, err := c.doRequest("POST", url, requestBodyJSON)
+	if err != nil {
+		return false, fmt.Errorf("error sending request: %w", err)
+	}
+	defer response.Body.Close()
+
+	respBody, err := io.ReadAll(response.Body)
+	if err != nil {
+		return false, fmt.Errorf("error reading response body: %w", err)
+	}
+
+	if response.StatusCode == 404 {
+		return false, nil // Command not found
+	}
+
+	if response.StatusCode != 200 {
+		return false, fmt.Errorf("unexpected status code: %d, body: %s", response.StatusCode, string(respBody))
+	}
+
+	var deleteResponse DeleteCommandResponse
+	if err := json.Unmarshal(respBody, &deleteResponse); err != nil {
+		return false, fmt.Errorf("error unmarshalling response body: %w", err)
+	}
+
+	if deleteResponse.Error != nil {
+		return false, fmt.Errorf("API error: %s", *deleteResponse.Error)
+	}
+
+	return true, nil
+}
+
+// RenameCommandRequest represents a request to rename a command
+type RenameCommandRequest struct {
+	CommandName string `json:"command_name"`
+	NewName     string `json:"new_name"`
+}
+
+// RenameCommand renames a command, keeping its content, history and aliases
+// Returns ErrCommandNotFound or ErrNameInUse when the rename isn't possible
+func (c *CommandAPIClient) RenameCommand(request RenameCommandRequest) error {
+	return c.postNameChange("/rename_command", request)
+}
+
+// AddAliasRequest represents a request to add an alias for a command
+type AddAliasRequest struct {
+	Alias       string `json:"alias"`
+	CommandName string `json:"command_name"`
+	AuthorID    string `json:"author_id"`
+}
+
+// AddAlias adds an alias for a command
+// Returns ErrCommandNotFound or ErrNameInUse when the alias can't be added
+func (c *CommandAPIClient) AddAlias(request AddAliasRequest) error {
+	return c.postNameChange("/alias", request)
+}
+
+// postNameChange posts a rename or alias request, mapping conflicts to ErrCommandNotFound and ErrNameInUse
+func (c *CommandAPIClient) postNameChange(path string, request any) error {
+	url := c.CodeSandboxURL + path
+
+	requestBodyJSON, err := json.Marshal(request)
+	if err != nil {
+		return fmt.Errorf("error marshalling request body: %w", err)
+	}
+
+	response, err := c.doRequest("POST", url, requestBodyJSON)
+	if err != nil {
+		return fmt.Errorf("error sending request: %w", err)
+	}
+	defer response.Body.Close()
+
+	respBody, err := io.ReadAll(response.Body)
+	if err != nil {
+		return fmt.Errorf("error reading response body: %w", err)
+	}
+
+	switch response.StatusCode {
+	case 200:
+		return nil
+	case 404:
+		return ErrCommandNotFound
+	case 409:
+		return ErrNameInUse
+	}
+	return fmt.Errorf("unexpected status code: %d, body: %s", response.StatusCode, string(respBody))
+}
+
+// RemoveAliasRequest represents a request to remove an alias
+type RemoveAliasRequest struct {
+	Alias string `json:"alias"`
+}
+
+// RemoveAlias removes an alias
+// Returns false if the alias doesn't exist
+func (c *CommandAPIClient) RemoveAlias(request RemoveAliasRequest) (bool, error) {
+	url := c.CodeSandboxURL + "/remove_alias"
+
+	requestBodyJSON, err := json.Marshal(request)
+	if err != nil {
+		return false, fmt.Errorf("error marshalling request body: %w", err)
+	}
+
+	response, err := c.doRequest("POST", url, requestBodyJSON)
+	if err != nil {
+		return false, fmt.Errorf("error sending request: %w", err)
+	}
+	defer response.Body.Close()
+
+	respBody, err := io.ReadAll(response.Body)
+	if err != nil {
+		return false, fmt.Errorf("error reading response body: %w", err)
+	}
+
+	if response.StatusCode == 404 {
+		return false, nil // Alias not found
+	}
+
+	if response.StatusCode != 200 {
+		return false, fmt.Errorf("unexpected status code: %d, body: %s", response.StatusCode, string(respBody))
+	}
+
+	return true, nil
+}
+
+// ExportCommandsResponse represents a response from export commands
+type ExportCommandsResponse struct {
+	Error    *string          `json:"error"`
+	Commands []GetCommandInfo `json:"commands"`
+}
+
+// ExportCommands gets every command with its content, metadata and args schema
+// Author, lock state and last use aren't included
+func (c *CommandAPIClient) ExportCommands() ([]GetCommandInfo, error) {
+	url := c.CodeSandboxURL + "/export_commands"
+
+	response, err := c.doRequest("POST", url, []byte("{}"))
+	if err != nil {
+		return nil, fmt.Errorf("error sending request: %w", err)
+	}
+	defer response.Body.Close()
+
+	respBody, err := io.ReadAll(response.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error reading response body: %w", err)
+	}
+
+	if response.StatusCode != 200 {
+		return nil, fmt.Errorf("unexpected status code: %d, body: %s", response.StatusCode, string(respBody))
+	}
+
+	var exportResponse ExportCommandsResponse
+	if err := json.Unmarshal(respBody, &exportResponse); err != nil {
+		return nil, fmt.Errorf("error unmarshalling response body: %w", err)
+	}
+
+	if exportResponse.Error != nil {
+		return nil, fmt.Errorf("API error: %s", *exportResponse.Error)
+	}
+
+	return exportResponse.Commands, nil
+}
+
+// SetCommandLockedRequest represents a request to lock or unlock a command
+type SetCommandLockedRequest struct {
+	CommandName string `json:"command_name"`
+	Locked      bool   `json:"locked"`
+}
+
+// SetCommandLocked locks or unlocks a command
+// Returns false if the command doesn't exist
+func (c *CommandAPIClient) SetCommandLocked(request SetCommandLockedRequest) (bool, error) {
+	return c.postCommandUpdate("/lock_command", request)
+}
+
+// TransferCommandRequest represents a request to transfer a command to another owner
+type TransferCommandRequest struct {
+	CommandName string `json:"command_name"`
+	AuthorID    string `json:"author_id"` // Discord user ID of the new owner
+}
+
+// TransferCommand transfers a command to another owner
+// Returns false if the command doesn't exist
+func (c *CommandAPIClient) TransferCommand(request TransferCommandRequest) (bool, error) {
+	return c.postCommandUpdate("/transfer_command", request)
+}
+
+// postCommandUpdate posts a request that updates a command or another record
+// Returns false if the record doesn't exist
+func (c *CommandAPIClient) postCommandUpdate(path string, request any) (bool, error) {
+	url := c.CodeSandboxURL + path
+
+	requestBodyJSON, err := json.Marshal(request)
+	if err != nil {
+		return false, fmt.Errorf("error marshalling request body: %w", err)
+	}
+
+	response, err := c.doRequest("POST", url, requestBodyJSON)
+	if err != nil {
+		return false, fmt.Errorf("error sending request: %w", err)
+	}
+	defer response.Body.Close()
+
+	respBody, err := io.ReadAll(response.Body)
+	if err != nil {
+		return false, fmt.Errorf("error reading response body: %w", err)
+	}
+
+	if response.StatusCode == 404 {
+		return false, nil // Command not found
+	}
+
+	if response.StatusCode != 200 {
+		return false, fmt.Errorf("unexpected status code: %d, body: %s", response.StatusCode, string(respBody))
+	}
+
+	return true, nil
+}
+
+// ListAliasesRequest represents a request to list aliases
+type ListAliasesRequest struct {
+	CommandName string `json:"command_name,omitempty"` // every alias if empty
+}
+
+// ListAliasesResponse represents a response from list aliases
+type ListAliasesResponse struct {
+	Error   *string        `json:"error"`
+	Aliases []CommandAlias `json:"aliases"`
+}
+
+// CommandAlias represents an alias of a registered command
+type CommandAlias struct {
+	Alias       string `json:"alias"`
+	CommandName string `json:"command_name"`
+	AuthorID    string `json:"author_id"`
+}
+
+// ListAliases lists aliases, optionally only those of one command
+func (c *CommandAPIClient) ListAliases(request ListAliasesRequest) ([]CommandAlias, error) {
+	url := c.CodeSandboxURL + "/list_aliases"
+
+	requestBodyJSON, err := json.Marshal(request)
+	if err != nil {
+		return nil, fmt.Errorf("error marshalling request body: %w", err)
+	}
+
+	response, err := c.doRequest("POST", url, requestBodyJSON)
+	if err != nil {
+		return nil, fmt.Errorf("error sending request: %w", err)
+	}
+	defer response.Body.Close()
+
+	respBody, err := io.ReadAll(response.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error reading response body: %w", err)
+	}
+
+	if response.StatusCode != 200 {
+		return nil, fmt.Errorf("unexpected status code: %d, body: %s", response.StatusCode, string(respBody))
+	}
+
+	var listResponse ListAliasesResponse
+	if err := json.Unmarshal(respBody, &listResponse); err != nil {
+		return nil, fmt.Errorf("error unmarshalling response body: %w", err)
+	}
+
+	if listResponse.Error != nil {
+		return nil, fmt.Errorf("API error: %s", *listResponse.Error)
+	}
+
+	return listResponse.Aliases, nil
+}
+
+// SlashCommandRecord represents a Discord application command created for a command
+type SlashCommandRecord struct {
+	CommandName          string `json:"command_name,omitempty"`
+	GuildID              string `json:"guild_id"`
+	ApplicationCommandID string `json:"application_command_id"`
+}
+
+// RecordSlashCommandResponse represents a response from record slash command
+type RecordSlashCommandResponse struct {
+	Error *string `json:"error"`
+}
+
+// RecordSlashCommand records the Discord application command created for a command in a guild
+func (c *CommandAPIClient) RecordSlashCommand(record SlashCommandRecord) error {
+	url := c.CodeSandboxURL + "/slash_command"
+
+	requestBodyJSON, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("error marshalling request body: %w", err)
+	}
+
+	response, err := c.doRequest("POST", url, requestBodyJSON)
+	if err != nil {
+		return fmt.Errorf("error sending request: %w", err)
+	}
+	defer response.Body.Close()
+
+	respBody, err := io.ReadAll(response.Body)
+	if err != nil {
+		return fmt.Errorf("error reading response body: %w", err)
+	}
+
+	if response.StatusCode != 200 {
+		return fmt.Errorf("unexpected status code: %d, body: %s", response.StatusCode, string(respBody))
+	}
+
+	var recordResponse RecordSlashCommandResponse
+	if err := json.Unmarshal(respBody, &recordResponse); err != nil {
+		return fmt.Errorf("error unmarshalling response body: %w", err)
+	}
+
+	if recordResponse.Error != nil {
+		return fmt.Errorf("API error: %s", *recordResponse.Error)
+	}
+
+	return nil
+}
+
+// Entry points a command run is recorded from
+const (
+	RunSourceMessage     = "message"      // !<command>
+	RunSourceText        = "text"         // text command without prefix
+	RunSourceExec        = "exec"         // !exec <command>
+	RunSourceMention     = "mention"      // mention command
+	RunSourceSlash       = "slash"        // /<command>
+	RunSourceSchedule    = "schedule"     // scheduled by /schedule
+	RunSourceEvent       = "event"        // event trigger set by /trigger
+	RunSourceAuto        = "auto"         // auto responder set by /responder
+	RunSourceButton      = "button"       // button of structured output
+	RunSourceContextMenu = "context_menu" // message context menu
+	// Suggestions for a slash command option; runs for suggestions aren't recorded
+	RunSourceAutocomplete = "autocomplete"
+)
+
+// CommandRun represents one run of a registered command for the usage statistics
+type CommandRun struct {
+	CommandName string `json:"command_name"`
+	UserID      string `json:"user_id"`
+	ChannelID   string `json:"channel_id,omitempty"`
+	GuildID     string `json:"guild_id,omitempty"`
+	Source      string `json:"source"` // one of the RunSource constants
+	LatencyMs   int64  `json:"latency_ms"`
+	Success     bool   `json:"success"`
+	Error       string `json:"error,omitempty"`
+}
+
+// RecordCommandRunResponse represents a response from record run
+type RecordCommandRunResponse struct {
+	Error *string `json:"error"`
+}
+
+// RecordCommandRun records a run of a command and updates its use count and last used time
+func (c *CommandAPIClient) RecordCommandRun(run CommandRun) error {
+	url := c.CodeSandboxURL + "/record_run"
+
+	requestBodyJSON, err := json.Marshal(run)
+	if err != nil {
+		return fmt.Errorf("error marshalling request body: %w", err)
+	}
+
+	response, err := c.doRequest("POST", url, requestBodyJSON)
+	if err != nil {
+		return fmt.Errorf("error sending request: %w", err)
+	}
+	defer response.Body.Close()
+
+	respBody, err := io.ReadAll(response.Body)
+	if err != nil {
+		return fmt.Errorf("error reading response body: %w", err)
+	}
+
+	if response.StatusCode != 200 {
+		return fmt.Errorf("unexpected status code: %d, body: %s", response.StatusCode, string(respBody))
+	}
+
+	var recordResponse RecordCommandRunResponse
+	if err := json.Unmarshal(respBody, &recordResponse); err != nil {
+		return fmt.Errorf("error unmarshalling response body: %w", err)
+	}
+
+	if recordResponse.Error != nil {
+		return fmt.Errorf("API error: %s", *recordResponse.Error)
+	}
+
+	return nil
+}
+
+// CommandStatsRequest represents a request for the usage statistics of the last 30 days
+type CommandStatsRequest struct {
+	GuildID string `json:"guild_id,omitempty"` // only runs in this guild, all guilds if empty
+}
+
+// CommandRunCount is the number of runs of a command
+type CommandRunCount struct {
+	Name         string `json:"name"`
+	Runs         int    `json:"runs"`
+	Failures     int    `json:"failures"`
+	AvgLatencyMs int    `json:"avgLatencyMs"`
+}
+
+// AuthorRunCount is the number of runs of the commands registered by a user
+type AuthorRunCount struct {
+	AuthorID string `json:"authorId"`
+	Runs     int    `json:"runs"`
+	Commands int    `json:"commands"`
+}
+
+// DailyRunCount is the number of runs on a day (UTC, "2006-01-02")
+type DailyRunCount struct {
+	Date     string `json:"date"`
+	Runs     int    `json:"runs"`
+	Failures int    `json:"failures"`
+}
+
+// CommandStats represents the usage statistics of the last 30 days
+type CommandStats struct {
+	TopCommands  []CommandRunCount `json:"topCommands"`
+	TopAuthors   []AuthorRunCount  `json:"topAuthors"`
+	FailureRates []CommandRunCount `json:"failureRates"` // commands with the highest failure rate
+	Daily        []DailyRunCount   `json:"daily"`        // days without runs are omitted
+}
+
+// CommandStatsResponse represents a response from command stats
+type CommandStatsResponse struct {
+	Error *string       `json:"error"`
+	Stats *CommandStats `json:"stats"`
+}
+
+// GetCommandStats gets the usage statistics of the last 30 days
+func (c *CommandAPIClient) GetCommandStats(request CommandStatsRequest) (*CommandStats, error) {
+	url := c.CodeSandboxURL + "/command_stats"
+
+	requestBodyJSON, err := json.Marshal(request)
+	if err != nil {
+		return nil, fmt.Errorf("error marshalling request body: %w", err)
+	}
+
+	response, err := c.doRequest("POST", url, requestBodyJSON)
+	if err != nil {
+		return nil, fmt.Errorf("error sending request: %w", err)
+	}
+	defer response.Body.Close()
+
+	respBody, err := io.ReadAll(response.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error reading response body: %w", err)
+	}
+
+	if response.StatusCode != 200 {
+		return nil, fmt.Errorf("unexpected status code: %d, body: %s", response.StatusCode, string(respBody))
+	}
+
+	var statsResponse CommandStatsResponse
+	if err := json.Unmarshal(respBody, &statsResponse); err != nil {
+		return nil, fmt.Errorf("error unmarshalling response body: %w", err)
+	}
+
+	if statsResponse.Error != nil {
+		return nil, fmt.Errorf("API error: %s", *statsResponse.Error)
+	}
+
+	return statsResponse.Stats, nil
+}
+
+// Policies for runs missed while the bot was offline
+const (
+	MissedPolicySkip    = "skip"     // skip missed runs and wait for the next one
+	MissedPolicyRunOnce = "run_once" // run once for all missed runs
+)
+
+// Schedule represents a code command run on a cron schedule
+type Schedule struct {
+	ID           int64   `json:"id"`
+	CommandName  string  `json:"command_name"`
+	GuildID      string  `json:"guild_id,omitempty"`
+	ChannelID    string  `json:"channel_id"`
+	Cron         string  `json:"cron"`
+	Timezone     string  `json:"timezone"` // IANA time zone the cron expression is evaluated in
+	Args         string  `json:"args"`     // raw argument text, parsed like "!<command> <args>"
+	MissedPolicy string  `json:"missed_policy"`
+	AuthorID     string  `json:"author_id"`
+	NextRunAt    *string `json:"next_run_at"` // RFC 3339
+	LastRunAt    *string `json:"last_run_at"` // RFC 3339
+	CreatedAt    string  `json:"created_at"`
+}
+
+// ListSchedulesRequest represents a request to list schedules
+type ListSchedulesRequest struct {
+	GuildID string `json:"guild_id,omitempty"` // every schedule if empty
+}
+
+// ListSchedulesResponse represents a response from list schedules
+type ListSchedulesResponse struct {
+	Error     *string    `json:"error"`
+	Schedules []Schedule `json:"schedules"`
+}
+
+// ListSchedules lists schedules, optionally only those of one guild
+func (c *CommandAPIClient) ListSchedules(request ListSchedulesRequest) ([]Schedule, error) {
+	url := c.CodeSandboxURL + "/list_schedules"
+
+	requestBodyJSON, err := json.Marshal(request)
+	if err != nil {
+		return nil, fmt.Errorf("error marshalling request body: %w", err)
+	}
+
+	response, err := c.doRequest("POST", url, requestBodyJSON)
+	if err != nil {
+		return nil, fmt.Errorf("error sending request: %w", err)
+	}
+	defer response.Body.Close()
+
+	respBody, err := io.ReadAll(response.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error reading response body: %w", err)
+	}
+
+	if response.StatusCode != 200 {
+		return nil, fmt.Errorf("unexpected status code: %d, body: %s", response.StatusCode, string(respBody))
+	}
+
+	var listResponse ListSchedulesResponse
+	if err := json.Unmarshal(respBody, &listResponse); err != nil {
+		return nil, fmt.Errorf("error unmarshalling response body: %w", err)
+	}
+
+	if listResponse.Error != nil {
+		return nil, fmt.Errorf("API error: %s", *listResponse.Error)
+	}
+
+	return listResponse.Schedules, nil
+}
+
+// AddScheduleRequest represents a request to add a schedule
+type AddScheduleRequest struct {
+	CommandName  string `json:"command_name"`
+	GuildID      string `json:"guild_id,omitempty"`
+	ChannelID    string `json:"channel_id"`
+	Cron         string `json:"cron"`
+	Timezone     string `json:"timezone"`
+	Args         string `json:"args,omitempty"`
+	MissedPolicy string `json:"missed_policy"`
+	AuthorID     string `json:"author_id"`
+	NextRunAt    string `json:"next_run_at"` // RFC 3339
+}
+
+// AddScheduleResponse represents a response from add schedule
+type AddScheduleResponse struct {
+	Error    *string   `json:"error"`
+	Schedule *Schedule `json:"schedule"`
+}
+
+// AddSchedule adds a schedule
+// Returns nil if the command doesn't exist
+func (c *CommandAPIClient) AddSchedule(request AddScheduleRequest) (*Schedule, error) {
+	url := c.CodeSandboxURL + "/schedule"
+
+	requestBodyJSON, err := json.Marshal(request)
+	if err != nil {
+		return nil, fmt.Errorf("error marshalling request body: %w", err)
+	}
+
+	response, err := c.doRequest("POST", url, requestBodyJSON)
+	if err != nil {
+		return nil, fmt.Errorf("error sending request: %w", err)
+	}
+	defer response.Body.Close()
+
+	respBody, err := io.ReadAll(response.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error reading response body: %w", err)
+	}
+
+	if response.StatusCode == 404 {
+		return nil, nil // Command not found
+	}
+
+	if response.StatusCode != 200 {
+		return nil, fmt.Errorf("unexpected status code: %d, body: %s", response.StatusCode, string(respBody))
+	}
+
+	var addResponse AddScheduleResponse
+	if err := json.Unmarshal(respBody, &addResponse); err != nil {
+		return nil, fmt.Errorf("error unmarshalling response body: %w", err)
+	}
+
+	if addResponse.Error != nil {
+		return nil, fmt.Errorf("API error: %s", *addResponse.Error)
+	}
+
+	return addResponse.Schedule, nil
+}
+
+// RemoveScheduleRequest represents a request to remove a schedule
+type RemoveScheduleRequest struct {
+	ID int64 `json:"id"`
+}
+
+// RemoveSchedule removes a schedule
+// Returns false if the schedule doesn't exist
+func (c *CommandAPIClient) RemoveSchedule(request RemoveScheduleRequest) (bool, error) {
+	return c.postCommandUpdate("/remove_schedule", request)
+}
+
+// ScheduleRanRequest represents a request to store the run times of a schedule
+type ScheduleRanRequest struct {
+	ID        int64  `json:"id"`
+	LastRunAt string `json:"last_run_at,omitempty"` // RFC 3339, unchanged if empty
+	NextRunAt string `json:"next_run_at"`           // RFC 3339
+}
+
+// MarkScheduleRan stores the last and next run times of a schedule
+// Returns false if the schedule doesn't exist
+func (c *CommandAPIClient) MarkScheduleRan(request ScheduleRanRequest) (bool, error) {
+	return c.postCommandUpdate("/schedule_ran", request)
+}
+
+// How a reminder is delivered
+const (
+	ReminderDeliveryReply = "reply" // reply to the message that set it, or post to its channel
+	ReminderDeliveryDM    = "dm"    // direct message to the user
+)
+
+// Reminder represents a reminder the bot delivers to a user at a set time
+type Reminder struct {
+	ID        int64  `json:"id"`
+	UserID    string `json:"user_id"`
+	GuildID   string `json:"guild_id,omitempty"`
+	ChannelID string `json:"channel_id"`
+	MessageID string `json:"message_id,omitempty"` // message to reply to, empty when set from a slash command
+	Text      string `json:"text"`
+	RemindAt  string `json:"remind_at"` // RFC 3339
+	Delivery  string `json:"delivery"`  // one of the ReminderDelivery constants
+	CreatedAt string `json:"created_at,omitempty"`
+}
+
+// ListRemindersRequest represents a request to list reminders
+type ListRemindersRequest struct {
+	UserID string `json:"user_id,omitempty"` // every reminder if empty
+}
+
+// ListRemindersResponse represents a response from list reminders
+type ListRemindersResponse struct {
+	Error     *string    `json:"error"`
+	Reminders []Reminder `json:"reminders"`
+}
+
+// ListReminders lists reminders ordered by their time, optionally only those of one user
+func (c *CommandAPIClient) ListReminders(request ListRemindersRequest) ([]Reminder, error) {
+	url := c.CodeSandboxURL + "/list_reminders"
+
+	requestBodyJSON, err := json.Marshal(request)
+	if err != nil {
+		return nil, fmt.Errorf("error marshalling request body: %w", err)
+	}
+
+	response, err := c.doRequest("POST", url, requestBodyJSON)
+	if err != nil {
+		return nil, fmt.Errorf("error sending request: %w", err)
+	}
+	defer response.Body.Close()
+
+	respBody, err := io.ReadAll(response.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error reading response body: %w", err)
+	}
+
+	if response.StatusCode != 200 {
+		return nil, fmt.Errorf("unexpected status code: %d, body: %s", response.StatusCode, string(respBody))
+	}
+
+	var listResponse ListRemindersResponse
+	if err := json.Unmarshal(respBody, &listResponse); err != nil {
+		return nil, fmt.Errorf("error unmarshalling response body: %w", err)
+	}
+
+	if listResponse.Error != nil {
+		return nil, fmt.Errorf("API error: %s", *listResponse.Error)
+	}
+
+	return listResponse.Reminders, nil
+}
+
+// AddReminderResponse represents a response from add reminder
+type AddReminderResponse struct {
+	Error    *string   `json:"error"`
+	Reminder *Reminder `json:"reminder"`
+}
+
+// AddReminder stores a reminder and returns it with its ID
+// The ID and CreatedAt of the request are ignored
+func (c *CommandAPIClient) AddReminder(reminder Reminder) (*Reminder, error) {
+	url := c.CodeSandboxURL + "/reminder"
+
+	requestBodyJSON, err := json.Marshal(reminder)
+	if err != nil {
+		return nil, fmt.Errorf("error marshalling request body: %w", err)
+	}
+
+	response, err := c.doRequest("POST", url, requestBodyJSON)
+	if err != nil {
+		return nil, fmt.Errorf("error sending request: %w", err)
+	}
+	defer response.Body.Close()
+
+	respBody, err := io.ReadAll(response.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error reading response body: %w", err)
+	}
+
+	if response.StatusCode != 200 {
+		return nil, fmt.Errorf("unexpected status code: %d, body: %s", response.StatusCode, string(respBody))
+	}
+
+	var addResponse AddReminderResponse
+	if err := json.Unmarshal(respBody, &addResponse); err != nil {
+		return nil, fmt.Errorf("error unmarshalling response body: %w", err)
+	}
+
+	if addResponse.Error != nil {
+		return nil, fmt.Errorf("API error: %s", *addResponse.Error)
+	}
+
+	return addResponse.Reminder, nil
+}
+
+// RemoveReminderRequest represents a request to remove a reminder
+type RemoveReminderRequest struct {
+	ID int64 `json:"id"`
+}
+
+// RemoveReminder removes a reminder
+// Returns false if the reminder doesn't exist
+func (c *CommandAPIClient) RemoveReminder(request RemoveReminderRequest) (bool, error) {
+	return c.postCommandUpdate("/remove_reminder", request)
+}
+
+// UserTimezoneRequest represents a request to get or set the timezone of a user
+type UserTimezoneRequest struct {
+	UserID   string  `json:"user_id"`
+	Timezone *string `json:"timezone,omitempty"` // IANA time zone, nil to clear when setting
+}
+
+// UserTimezoneResponse represents a response from user timezone
+type UserTimezoneResponse struct {
+	Error    *string `json:"error"`
+	Timezone *string `json:"timezone"`
+}
+
+// GetUserTimezone gets the timezone a user has set
+// Returns an empty string if the user hasn't set one
+func (c *CommandAPIClient) GetUserTimezone(userID string) (string, error) {
+	url := c.CodeSandboxURL + "/user_timezone"
+
+	requestBodyJSON, err := json.Marshal(UserTimezoneRequest{UserID: userID})
+	if err != nil {
+		return "", fmt.Errorf("error marshalling request body: %w", err)
+	}
+
+	response, err := c.doRequest("POST", url, requestBodyJSON)
+	if err != nil {
+		return "", fmt.Errorf("error sending request: %w", err)
+	}
+	defer response.Body.Close()
+
+	respBody, err := io.ReadAll(response.Body)
+	if err != nil {
+		return "", fmt.Errorf("error reading response body: %w", err)
+	}
+
+	if response.StatusCode != 200 {
+		return "", fmt.Errorf("unexpected status code: %d, body: %s", response.StatusCode, string(respBody))
+	}
+
+	var timezoneResponse UserTimezoneResponse
+	if err := json.Unmarshal(respBody, &timezoneResponse); err != nil {
+		return "", fmt.Errorf("error unmarshalling response body: %w", err)
+	}
+
+	if timezoneResponse.Error != nil {
+		return "", fmt.Errorf("API error: %s", *timezoneResponse.Error)
+	}
+
+	if timezoneResponse.Timezone == nil {
+		return "", nil
+	}
+	return *timezoneResponse.Timezone, nil
+}
+
+// SetUserTimezone sets or clears the timezone of a user
+func (c *CommandAPIClient) SetUserTimezone(request UserTimezoneRequest) error {
+	url := c.CodeSandboxURL + "/set_user_timezone"
+
+	requestBodyJSON, err := json.Marshal(request)
+	if err != nil {
+		return fmt.Errorf("error marshalling request body: %w", err)
+	}
+
+	response, err := c.doRequest("POST", url, requestBodyJSON)
+	if err != nil {
+		return fmt.Errorf("error sending request: %w", err)
+	}
+	defer response.Body.Close()
+
+	respBody, err := io.ReadAll(response.Body)
+	if err != nil {
+		return fmt.Errorf("error reading response body: %w", err)
+	}
+
+	if response.StatusCode != 200 {
+		return fmt.Errorf("unexpected status code: %d, body: %s", response.StatusCode, string(respBody))
+	}
+
+	return nil
+}
+
+// Gateway events code commands can be triggered by
+const (
+	TriggerEventReactionAdd    = "reaction_add"
+	TriggerEventReactionRemove = "reaction_remove"
+	TriggerEventMemberJoin     = "member_join"
+	TriggerEventMemberLeave    = "member_leave"
+	TriggerEventThreadCreate   = "thread_create"
+	TriggerEventVoiceState     = "voice_state"
+)
+
+// EventTrigger represents a code command run when a gateway event happens in a guild
+type EventTrigger struct {
+	ID          int64  `json:"id"`
+	GuildID     string `json:"guild_id"`
+	Event       string `json:"event"` // one of the TriggerEvent constants
+	CommandName string `json:"command_name"`
+	ChannelID   string `json:"channel_id,omitempty"` // where output is posted, the channel of the event if empty
+	MessageID   string `json:"message_id,omitempty"` // reaction events: only reactions on this message
+	Emoji       string `json:"emoji,omitempty"`      // reaction events: only this emoji (unicode or custom emoji ID)
+	AuthorID    string `json:"author_id"`
+	CreatedAt   string `json:"created_at,omitempty"`
+}
+
+// ListEventTriggersRequest represents a request to list event triggers
+type ListEventTriggersRequest struct {
+	GuildID string `json:"guild_id,omitempty"` // every trigger if empty
+}
+
+// ListEventTriggersResponse represents a response from list event triggers
+type ListEventTriggersResponse struct {
+	Error    *string        `json:"error"`
+	Triggers []EventTrigger `json:"triggers"`
+}
+
+// ListEventTriggers lists event triggers, optionally only those of one guild
+func (c *CommandAPIClient) ListEventTriggers(request ListEventTriggersRequest) ([]EventTrigger, error) {
+	url := c.CodeSandboxURL + "/list_event_triggers"
+
+	requestBodyJSON, err := json.Marshal(request)
+	if err != nil {
+		return nil, fmt.Errorf("error marshalling request body: %w", err)
+	}
+
+	response, err := c.doRequest("POST", url, requestBodyJSON)
+	if err != nil {
+		return nil, fmt.Errorf("error sending request: %w", err)
+	}
+	defer response.Body.Close()
+
+	respBody, err := io.ReadAll(response.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error reading response body: %w", err)
+	}
+
+	if response.StatusCode != 200 {
+		return nil, fmt.Errorf("unexpected status code: %d, body: %s", response.StatusCode, string(respBody))
+	}
+
+	var listResponse ListEventTriggersResponse
+	if err := json.Unmarshal(respBody, &listResponse); err != nil {
+		return nil, fmt.Errorf("error unmarshalling response body: %w", err)
+	}
+
+	if listResponse.Error != nil {
+		return nil, fmt.Errorf("API error: %s", *listResponse.Error)
+	}
+
+	return listResponse.Triggers, nil
+}
+
+// AddEventTriggerResponse represents a response from add event trigger
+type AddEventTriggerResponse struct {
+	Error   *string       `json:"error"`
+	Trigger *EventTrigger `json:"trigger"`
+}
+
+// AddEventTrigger stores an event trigger and returns it with its ID
+// Returns nil if the command doesn't exist
+func (c *CommandAPIClient) AddEventTrigger(trigger EventTrigger) (*EventTrigger, error) {
+	url := c.CodeSandboxURL + "/event_trigger"
+
+	requestBodyJSON, err := json.Marshal(trigger)
+	if err != nil {
+		return nil, fmt.Errorf("error marshalling request body: %w", err)
+	}
+
+	response, err := c.doRequest("POST", url, requestBodyJSON)
+	if err != nil {
+		return nil, fmt.Errorf("error sending request: %w", err)
+	}
+	defer response.Body.Close()
+
+	respBody, err := io.ReadAll(response.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error reading response body: %w", err)
+	}
+
+	if response.StatusCode == 404 {
+		return nil, nil // Command not found
+	}
+
+	if response.StatusCode != 200 {
+		return nil, fmt.Errorf("unexpected status code: %d, body: %s", response.StatusCode, string(respBody))
+	}
+
+	var addResponse AddEventTriggerResponse
+	if err := json.Unmarshal(respBody, &addResponse); err != nil {
+		return nil, fmt.Errorf("error unmarshalling response body: %w", err)
+	}
+
+	if addResponse.Error != nil {
+		return nil, fmt.Errorf("API error: %s", *addResponse.Error)
+	}
+
+	return addResponse.Trigger, nil
+}
+
+// RemoveEventTriggerRequest represents a request to remove an event trigger
+type RemoveEventTriggerRequest struct {
+	ID int64 `json:"id"`
+}
+
+// RemoveEventTrigger removes an event trigger
+// Returns false if the trigger doesn't exist
+func (c *CommandAPIClient) RemoveEventTrigger(request RemoveEventTriggerRequest) (bool, error) {
+	return c.postCommandUpdate("/remove_event_trigger", request)
+}
+
+// Modes of matching messages against the pattern of an auto responder
+const (
+	ResponderModeExact    = "exact"    // the first word is the pattern, the rest are args
+	ResponderModePrefix   = "prefix"   // the message starts with the pattern, the rest is args
+	ResponderModeContains = "contains" // the pattern appears anywhere in the message
+	ResponderModeRegex    = "regex"    // the pattern is a regular expression, its capture groups are args
+	ResponderModeWhole    = "whole"    // the whole message is the pattern
+)
+
+// AutoResponder represents a command run when a message in a guild matches a pattern
+type AutoResponder struct {
+	ID          int64  `json:"id"`
+	GuildID     string `json:"guild_id"`
+	Mode        string `json:"mode"` // one of the ResponderMode constants
+	Pattern     string `json:"pattern"`
+	CommandName string `json:"command_name"`
+	IsCode      bool   `json:"is_code,omitempty"` // whether the command is a code command, set by the backend
+	AuthorID    string `json:"author_id"`
+	CreatedAt   string `json:"created_at,omitempty"`
+}
+
+// AutoResponderChannel represents a channel where auto responders are enabled
+type AutoResponderChannel struct {
+	ChannelID string `json:"channel_id"`
+	GuildID   string `json:"guild_id"`
+	EnabledBy string `json:"enabled_by"`
+	CreatedAt string `json:"created_at,omitempty"`
+}
+
+// ListAutoRespondersRequest represents a request to list auto responders
+type ListAutoRespondersRequest struct {
+	GuildID string `json:"guild_id,omitempty"` // every responder if empty
+}
+
+// ListAutoRespondersResponse represents a response from list auto responders
+type ListAutoRespondersResponse struct {
+	Error      *string                `json:"error"`
+	Responders []AutoResponder        `json:"responders"`
+	Channels   []AutoResponderChannel `json:"channels"`
+}
+
+// ListAutoResponders lists auto responders, optionally only those of one guild, and every channel they are enabled in
+func (c *CommandAPIClient) ListAutoResponders(request ListAutoRespondersRequest) ([]AutoResponder, []AutoResponderChannel, error) {
+	url := c.CodeSandboxURL + "/list_auto_responders"
+
+	requestBodyJSON, err := json.Marshal(request)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error marshalling request body: %w", err)
+	}
+
+	response, err := c.doRequest("POST", url, requestBodyJSON)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error sending request: %w", err)
+	}
+	defer response.Body.Close()
+
+	respBody, err := io.ReadAll(response.Body)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error reading response body: %w", err)
+	}
+
+	if response.StatusCode != 200 {
+		return nil, nil, fmt.Errorf("unexpected status code: %d, body: %s", response.StatusCode, string(respBody))
+	}
+
+	var listResponse ListAutoRespondersResponse
+	if err := json.Unmarshal(respBody, &listResponse); err != nil {
+		return nil, nil, fmt.Errorf("error unmarshalling response body: %w", err)
+	}
+
+	if listResponse.Error != nil {
+		return nil, nil, fmt.Errorf("API error: %s", *listResponse.Error)
+	}
+
+	return listResponse.Responders, listResponse.Channels, nil
+}
+
+// AddAutoResponderResponse represents a response from add auto responder
+type AddAutoResponderResponse struct {
+	Error     *string        `json:"error"`
+	Responder *AutoResponder `json:"responder"`
+}
+
+// AddAutoResponder stores an auto responder and returns it with its ID
+// Returns nil if the command doesn't exist
+func (c *CommandAPIClient) AddAutoResponder(responder AutoResponder) (*AutoResponder, error) {
+	url := c.CodeSandboxURL + "/auto_responder"
+
+	requestBodyJSON, err := json.Marshal(responder)
+	if err != nil {
+		return nil, fmt.Errorf("error marshalling request body: %w", err)
+	}
+
+	response, err := c.doRequest("POST", url, requestBodyJSON)
+	if err != nil {
+		return nil, fmt.Errorf("error sending request: %w", err)
+	}
+	defer response.Body.Close()
+
+	respBody, err := io.ReadAll(response.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error reading response body: %w", err)
+	}
+
+	if response.StatusCode == 404 {
+		return nil, nil // Command not found
+	}
+
+	if response.StatusCode != 200 {
+		return nil, fmt.Errorf("unexpected status code: %d, body: %s", response.StatusCode, string(respBody))
+	}
+
+	var addResponse AddAutoResponderResponse
+	if err := json.Unmarshal(respBody, &addResponse); err != nil {
+		return nil, fmt.Errorf("error unmarshalling response body: %w", err)
+	}
+
+	if addResponse.Error != nil {
+		return nil, fmt.Errorf("API error: %s", *addResponse.Error)
+	}
+
+	return addResponse.Responder, nil
+}
+
+// RemoveAutoResponderRequest represents a request to remove an auto responder
+type RemoveAutoResponderRequest struct {
+	ID int64 `json:"id"`
+}
+
+// RemoveAutoResponder removes an auto responder
+// Returns false if the responder doesn't exist
+func (c *CommandAPIClient) RemoveAutoResponder(request RemoveAutoResponderRequest) (bool, error) {
+	return c.postCommandUpdate("/remove_auto_responder", request)
+}
+
+// SetAutoResponderChannelRequest represents a request to enable or disable auto responders in a channel
+type SetAutoResponderChannelRequest struct {
+	ChannelID string `json:"channel_id"`
+	GuildID   string `json:"guild_id"`
+	Enabled   bool   `json:"enabled"`
+	UserID    string `json:"user_id"`
+}
+
+// SetAutoResponderChannel enables or disables auto responders in a channel
+func (c *CommandAPIClient) SetAutoResponderChannel(request SetAutoResponderChannelRequest) error {
+	_, err := c.postCommandUpdate("/auto_responder_channel", request)
+	return err
+}
+
+// RemoveTextResponseRequest represents a request to remove one response of a text command
+type RemoveTextResponseRequest struct {
+	CommandName string `json:"command_name"`
+	Index       int    `json:"index"` // 1-based, as shown by !responses
+}
+
+// RemoveTextResponse removes one response of a text command
+// Returns false if the command or the response doesn't exist
+func (c *CommandAPIClient) RemoveTextResponse(request RemoveTextResponseRequest) (bool, error) {
+	return c.postCommandUpdate("/remove_text_response", request)
+}
+
+// SetTextResponseModeRequest represents a request to change how a text command picks a response
+type SetTextResponseModeRequest struct {
+	CommandName string `json:"command_name"`
+	Mode        string `json:"mode"` // one of the ResponseMode constants
+}
+
+// SetTextResponseMode changes how a text command picks a response
+// Returns false if the command doesn't exist
+func (c *CommandAPIClient) SetTextResponseMode(request SetTextResponseModeRequest) (bool, error) {
+	return c.postCommandUpdate("/text_response_mode", request)
+}
+
+// ListSlashCommandsRequest represents a request to list the slash commands recorded for a command
+type ListSlashCommandsRequest struct {
+	CommandName string `json:"command_name"`
+}
+
+// ListSlashCommandsResponse represents a response from list slash commands
+type ListSlashCommandsResponse struct {
+	Error         *string              `json:"error"`
+	SlashCommands []SlashCommandRecord `json:"slash_commands"`
+}
+
+// ListSlashCommands lists the Discord application commands recorded for a command
+func (c *CommandAPIClient) ListSlashCommands(request ListSlashCommandsRequest) ([]SlashCommandRecord, error) {
+	url := c.CodeSandboxURL + "/list_slash_commands"
+
+	requestBodyJSON, err := json.Marshal(request)
+	if err != nil {
+		return nil, fmt.Errorf("error marshalling request body: %w", err)
+	}
+
+	response, err := c.doRequest("POST", url, requestBodyJSON)
+	if err != nil {
+		return nil, fmt.Errorf("error sending request: %w", err)
+	}
+	defer response.Body.Close()
+
+	respBody, err := io.ReadAll(response.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error reading response body: %w", err)
+	}
+
+	if response.StatusCode != 200 {
+		return nil, fmt.Errorf("unexpected status code: %d, body: %s", response.StatusCode, string(respBody))
+	}
+
+	var listResponse ListSlashCommandsResponse
+	if err := json.Unmarshal(respBody, &listResponse); err != nil {
+		return nil, fmt.Errorf("error unmarshalling response body: %w", err)
+	}
+
+	if listResponse.Error != nil {
+		return nil, fmt.Errorf("API error: %s", *listResponse.Error)
+	}
+
+	return listResponse.SlashCommands, nil
+}
+
+// Command listing orders
+const (
+	CommandSortName    = "name"
+	CommandSortRecent  = "recent"
+	CommandSortPopular = "popular"
+	CommandSortAuthor  = "author"
+)
+
+// ListCommandsRequest represents a request to list registered commands
+type ListCommandsRequest struct {
+	Query    string `json:"query,omitempty"`     // substring of the command name
+	Type     string `json:"type,omitempty"`      // "text" or "code", empty for both
+	AuthorID string `json:"author_id,omitempty"` // only commands registered by this user
+	Sort     string `json:"sort,omitempty"`      // one of the CommandSort constants, name by default
+	Limit    int    `json:"limit,omitempty"`
+	Offset   int    `json:"offset,omitempty"`
+}
+
+// SearchCommandsRequest represents a request to search registered commands
+type SearchCommandsRequest struct {
+	Keyword string `json:"keyword"` // substring of the name, description or content
+	Limit   int    `json:"limit,omitempty"`
+	Offset  int    `json:"offset,omitempty"`
+}
+
+// ListCommandsResponse represents a response from list or search commands
+type ListCommandsResponse struct {
+	Error    *string          `json:"error"`
+	Commands []CommandSummary `json:"commands"`
+	Total    int              `json:"total"`
+}
+
+// CommandSummary represents a registered command in a listing
+type CommandSummary struct {
+	Name        string `json:"name"`
+	IsCode      bool   `json:"isCode"`
+	Description string `json:"description"`
+	Category    string `json:"category"`
+	AuthorID    string `json:"authorId"`
+	UseCount    int    `json:"useCount"`
+	UpdatedAt   string `json:"updatedAt"`
+}
+
+// CommandPage is one page of a command listing
+type CommandPage struct {
+	Commands []CommandSummary
+	Total    int // number of commands matching the filter across all pages
+}
+
+// ListCommands lists registered commands whose name contains the query
+func (c *CommandAPIClient) ListCommands(request ListCommandsRequest) ([]CommandSummary, error) {
+	page, err := c.ListCommandsPage(request)
+	if err != nil {
+		return nil, err
+	}
+	return page.Commands, nil
+}
+
+// ListCommandsPage lists registered commands matching the filters along with the total count
+func (c *CommandAPIClient) ListCommandsPage(request ListCommandsRequest) (*CommandPage, error) {
+	return c.postCommandListing("/list_commands", request)
+}
+
+// SearchCommands searches registered commands by name, description and content
+func (c *CommandAPIClient) SearchCommands(request SearchCommandsRequest) (*CommandPage, error) {
+	return c.postCommandListing("/search_commands", request)
+}
+
+// postCommandListing posts a list or search request and returns the page of commands
+func (c *CommandAPIClient) postCommandListing(path string, request any) (*CommandPage, error) {
+	url := c.CodeSandboxURL + path
+
+	requestBodyJSON, err := json.Marshal(request)
+	if err != nil {
+		return nil, fmt.Errorf("error marshalling request body: %w", err)
+	}
+
+	response, err := c.doRequest("POST", url, requestBodyJSON)
+	if err != nil {
+		return nil, fmt.Errorf("error sending request: %w", err)
+	}
+	defer response.Body.Close()
+
+	respBody, err := io.ReadAll(response.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error reading response body: %w", err)
+	}
+
+	if response.StatusCode != 200 {
+		return nil, fmt.Errorf("unexpected status code: %d, body: %s", response.StatusCode, string(respBody))
+	}
+
+	var listResponse ListCommandsResponse
+	if err := json.Unmarshal(respBody, &listResponse); err != nil {
+		return nil, fmt.Errorf("error unmarshalling response body: %w", err)
+	}
+
+	if listResponse.Error != nil {
+		return nil, fmt.Errorf("API error: %s", *listResponse.Error)
+	}
+
+	return &CommandPage{
+		Commands: listResponse.Commands,
+		Total:    listResponse.Total,
+	}, nil
+}
+
 // SmartRegisterRequest represents a request to smart register a command
 type SmartRegisterRequest struct {
 	CommandName string `json:"command_name"`
 	Description string `json:"description"`
 	AuthorID    string `json:"author_id"`
+	Admin       bool   `json:"admin,omitempty"` // see RegisterCommandRequest.Admin
 }
 
 // SmartRegisterResponse represents a response from smart register
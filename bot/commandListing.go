@@ -0,0 +1,274 @@
+package nelchanbot
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+const (
+	// listComponentPrefix is the custom_id prefix of the !list buttons ("list:<type>:<sort>:<author>:<page>")
+	listComponentPrefix = "list"
+	// searchComponentPrefix is the custom_id prefix of the !search buttons ("search:<keyword>:<page>")
+	searchComponentPrefix = "search"
+	// listPageSize is the number of commands shown per listing page
+	listPageSize = 10
+	// maxSearchKeywordLength is the maximum keyword length in bytes, so the keyword fits in a button custom_id
+	maxSearchKeywordLength = 80
+)
+
+// ParseListArgs parses the arguments of !list into a listing request
+// Accepts "text" or "code", a user mention, ID or "me", and one of "name", "recent", "popular" or "author" in any order
+func ParseListArgs(args []string, userID string) (ListCommandsRequest, error) {
+	var request ListCommandsRequest
+	for _, arg := range args {
+		switch strings.ToLower(arg) {
+		case "text", "code":
+			request.Type = strings.ToLower(arg)
+		case CommandSortName, CommandSortRecent, CommandSortPopular, CommandSortAuthor:
+			request.Sort = strings.ToLower(arg)
+		case "me":
+			request.AuthorID = userID
+		default:
+			authorID, err := parseSnowflakeArg(arg, userMentionRe, "ユーザー")
+			if err != nil {
+				return ListCommandsRequest{}, fmt.Errorf("「%s」は指定できません（text|code、@ユーザー、name|recent|popular|author のいずれか）", arg)
+			}
+			request.AuthorID = authorID
+		}
+	}
+	return request, nil
+}
+
+// listCustomID encodes the filters of a listing into a button custom_id prefix; the page is appended by paginationButtons
+func listCustomID(request ListCommandsRequest) string {
+	return strings.Join([]string{listComponentPrefix, request.Type, request.Sort, request.AuthorID}, ":")
+}
+
+// listTitle describes the filters of a listing
+func listTitle(request ListCommandsRequest) string {
+	title := "コマンド一覧"
+	var filters []string
+	switch request.Type {
+	case "text":
+		filters = append(filters, "テキスト")
+	case "code":
+		filters = append(filters, "コード")
+	}
+	switch request.Sort {
+	case CommandSortRecent:
+		filters = append(filters, "更新順")
+	case CommandSortPopular:
+		filters = append(filters, "人気順")
+	case CommandSortAuthor:
+		filters = append(filters, "登録者順")
+	}
+	if len(filters) > 0 {
+		title += "（" + strings.Join(filters, "・") + "）"
+	}
+	return title
+}
+
+// RenderCommandPage renders one page of a command listing with its pagination buttons
+// customIDPrefix identifies the listing so a button click can fetch the neighbouring page
+func RenderCommandPage(title, description string, page *CommandPage, pageIndex int, customIDPrefix string) (*discordgo.MessageEmbed, []discordgo.MessageComponent) {
+	pages := pageCount(page.Total, listPageSize)
+
+	var b strings.Builder
+	if description != "" {
+		b.WriteString(description + "\n\n")
+	}
+	if len(page.Commands) == 0 {
+		b.WriteString("コマンドが見つかりませんでした")
+	}
+	for _, cmd := range page.Commands {
+		fmt.Fprintf(&b, "`%s`", commandInvocation(cmd.Name, cmd.IsCode))
+		if cmd.Description != "" {
+			b.WriteString(" " + cmd.Description)
+		}
+		if cmd.AuthorID != "" {
+			fmt.Fprintf(&b, " ・ <@%s>", cmd.AuthorID)
+		}
+		if cmd.UseCount > 0 {
+			fmt.Fprintf(&b, " ・ %d回", cmd.UseCount)
+		}
+		b.WriteString("\n")
+	}
+
+	embed := &discordgo.MessageEmbed{
+		Title:       title,
+		Description: strings.TrimSuffix(b.String(), "\n"),
+		Footer: &discordgo.MessageEmbedFooter{
+			Text: fmt.Sprintf("ページ %d/%d ・ 全%d件", pageIndex+1, pages, page.Total),
+		},
+	}
+	return embed, paginationButtons(customIDPrefix, pageIndex, pages)
+}
+
+// fetchCommandPage fetches one page of a listing, falling back to the last page when the page is past the end
+func fetchCommandPage(pageIndex int, fetch func(offset int) (*CommandPage, error)) (*CommandPage, int, error) {
+	pageIndex = max(pageIndex, 0)
+	page, err := fetch(pageIndex * listPageSize)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// Commands may have been deleted since the buttons were rendered
+	if len(page.Commands) == 0 && page.Total > 0 && pageIndex > 0 {
+		pageIndex = pageCount(page.Total, listPageSize) - 1
+		page, err = fetch(pageIndex * listPageSize)
+		if err != nil {
+			return nil, 0, err
+		}
+	}
+	return page, pageIndex, nil
+}
+
+// listMessage renders a page of the !list listing
+func (n *Nelchan) listMessage(request ListCommandsRequest, pageIndex int) (*discordgo.MessageEmbed, []discordgo.MessageComponent, error) {
+	page, pageIndex, err := fetchCommandPage(pageIndex, func(offset int) (*CommandPage, error) {
+		request.Limit = listPageSize
+		request.Offset = offset
+		return n.CommandAPIClient.ListCommandsPage(request)
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var description string
+	if request.AuthorID != "" {
+		description = fmt.Sprintf("登録者: <@%s>", request.AuthorID)
+	}
+	embed, components := RenderCommandPage(listTitle(request), description, page, pageIndex, listCustomID(request))
+	return embed, components, nil
+}
+
+// searchMessage renders a page of the !search results
+func (n *Nelchan) searchMessage(keyword string, pageIndex int) (*discordgo.MessageEmbed, []discordgo.MessageComponent, error) {
+	page, pageIndex, err := fetchCommandPage(pageIndex, func(offset int) (*CommandPage, error) {
+		return n.CommandAPIClient.SearchCommands(SearchCommandsRequest{
+			Keyword: keyword,
+			Limit:   listPageSize,
+			Offset:  offset,
+		})
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	title := fmt.Sprintf("「%s」の検索結果", keyword)
+	embed, components := RenderCommandPage(title, "", page, pageIndex, searchComponentPrefix+":"+keyword)
+	return embed, components, nil
+}
+
+// sendListingMessage sends a listing page, or the error that prevented rendering it
+func sendListingMessage(s *discordgo.Session, channelID string, embed *discordgo.MessageEmbed, components []discordgo.MessageComponent, err error) {
+	if err != nil {
+		fmt.Println("error listing commands,", err)
+		_, _ = s.ChannelMessageSend(channelID, fmt.Sprintf("エラー: %s", err.Error()))
+		return
+	}
+
+	_, err = s.ChannelMessageSendComplex(channelID, &discordgo.MessageSend{
+		Embeds:          []*discordgo.MessageEmbed{embed},
+		Components:      components,
+		AllowedMentions: &discordgo.MessageAllowedMentions{},
+	})
+	if err != nil {
+		fmt.Println("error sending command listing,", err)
+	}
+}
+
+// handleListCommand handles the !list command
+// Usage: !list [text|code] [@user|me] [name|recent|popular|author]
+func (n *Nelchan) handleListCommand(s *discordgo.Session, m *discordgo.MessageCreate, cmd *SlashCommand) {
+	request, err := ParseListArgs(cmd.Args, m.Author.ID)
+	if err != nil {
+		_, _ = s.ChannelMessageSend(m.ChannelID, err.Error())
+		return
+	}
+
+	embed, components, err := n.listMessage(request, 0)
+	sendListingMessage(s, m.ChannelID, embed, components, err)
+}
+
+// handleSearchCommand handles the !search command
+// Usage: !search <keyword> - Search commands by name, description and content
+func (n *Nelchan) handleSearchCommand(s *discordgo.Session, m *discordgo.MessageCreate, cmd *SlashCommand) {
+	keyword := cmd.GetArgsFrom(0)
+	if keyword == "" {
+		_, _ = s.ChannelMessageSend(m.ChannelID, "使い方: !search <キーワード>")
+		return
+	}
+	if len(keyword) > maxSearchKeywordLength {
+		_, _ = s.ChannelMessageSend(m.ChannelID, "キーワードが長すぎます")
+		return
+	}
+
+	embed, components, err := n.searchMessage(keyword, 0)
+	sendListingMessage(s, m.ChannelID, embed, components, err)
+}
+
+// handleListComponent handles the pagination buttons of !list ("list:<type>:<sort>:<author>:<page>")
+func (n *Nelchan) handleListComponent(s *discordgo.Session, i *discordgo.InteractionCreate, args []string) {
+	if len(args) != 4 {
+		return
+	}
+	pageIndex, err := strconv.Atoi(args[3])
+	if err != nil {
+		return
+	}
+
+	request := ListCommandsRequest{
+		Type:     args[0],
+		Sort:     args[1],
+		AuthorID: args[2],
+	}
+	embed, components, err := n.listMessage(request, pageIndex)
+	n.updateListingMessage(s, i, embed, components, err)
+}
+
+// handleSearchComponent handles the pagination buttons of !search ("search:<keyword>:<page>")
+func (n *Nelchan) handleSearchComponent(s *discordgo.Session, i *discordgo.InteractionCreate, args []string) {
+	if len(args) < 2 {
+		return
+	}
+	pageIndex, err := strconv.Atoi(args[len(args)-1])
+	if err != nil {
+		return
+	}
+
+	// The keyword itself may contain ":"
+	keyword := strings.Join(args[:len(args)-1], ":")
+	embed, components, err := n.searchMessage(keyword, pageIndex)
+	n.updateListingMessage(s, i, embed, components, err)
+}
+
+// updateListingMessage replaces a listing message with another page in response to a button click
+func (n *Nelchan) updateListingMessage(s *discordgo.Session, i *discordgo.InteractionCreate, embed *discordgo.MessageEmbed, components []discordgo.MessageComponent, err error) {
+	if err != nil {
+		fmt.Println("error listing commands,", err)
+		_ = s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseChannelMessageWithSource,
+			Data: &discordgo.InteractionResponseData{
+				Content: fmt.Sprintf("エラー: %s", err.Error()),
+				Flags:   discordgo.MessageFlagsEphemeral,
+			},
+		})
+		return
+	}
+
+	err = s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseUpdateMessage,
+		Data: &discordgo.InteractionResponseData{
+			Embeds:          []*discordgo.MessageEmbed{embed},
+			Components:      components,
+			AllowedMentions: &discordgo.MessageAllowedMentions{},
+		},
+	})
+	if err != nil {
+		fmt.Printf("error updating command listing: %v\n", err)
+	}
+}
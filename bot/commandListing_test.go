@@ -0,0 +1,102 @@
+package nelchanbot
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func TestParseListArgs(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		want    ListCommandsRequest
+		wantErr bool
+	}{
+		{
+			name: "no args",
+			args: nil,
+			want: ListCommandsRequest{},
+		},
+		{
+			name: "type and sort",
+			args: []string{"code", "popular"},
+			want: ListCommandsRequest{Type: "code", Sort: CommandSortPopular},
+		},
+		{
+			name: "author mention",
+			args: []string{"<@!123>", "text"},
+			want: ListCommandsRequest{Type: "text", AuthorID: "123"},
+		},
+		{
+			name: "me and uppercase sort",
+			args: []string{"me", "RECENT"},
+			want: ListCommandsRequest{AuthorID: "999", Sort: CommandSortRecent},
+		},
+		{
+			name: "sort by author",
+			args: []string{"author"},
+			want: ListCommandsRequest{Sort: CommandSortAuthor},
+		},
+		{
+			name:    "unknown arg",
+			args:    []string{"fancy"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseListArgs(tt.args, "999")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseListArgs() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseListArgs() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRenderCommandPage(t *testing.T) {
+	page := &CommandPage{
+		Commands: []CommandSummary{
+			{Name: "dice", IsCode: true, Description: "サイコロを振る", AuthorID: "123", UseCount: 5},
+			{Name: "hello", AuthorID: "456"},
+		},
+		Total: 12,
+	}
+
+	embed, components := RenderCommandPage("コマンド一覧", "", page, 1, listCustomID(ListCommandsRequest{Type: "code", AuthorID: "123"}))
+
+	for _, want := range []string{"`!dice` サイコロを振る ・ <@123> ・ 5回", "`hello` ・ <@456>"} {
+		if !strings.Contains(embed.Description, want) {
+			t.Errorf("description does not contain %q:\n%s", want, embed.Description)
+		}
+	}
+	if embed.Footer.Text != "ページ 2/2 ・ 全12件" {
+		t.Errorf("footer = %q", embed.Footer.Text)
+	}
+
+	buttons := components[0].(discordgo.ActionsRow).Components
+	prev := buttons[0].(discordgo.Button)
+	next := buttons[1].(discordgo.Button)
+	if prev.CustomID != "list:code::123:0" || prev.Disabled {
+		t.Errorf("prev = %q (disabled %v), want %q enabled", prev.CustomID, prev.Disabled, "list:code::123:0")
+	}
+	if !next.Disabled {
+		t.Error("next button is enabled on the last page")
+	}
+}
+
+func TestRenderCommandPageEmpty(t *testing.T) {
+	embed, components := RenderCommandPage("「x」の検索結果", "", &CommandPage{}, 0, searchComponentPrefix+":x")
+	if embed.Description != "コマンドが見つかりませんでした" {
+		t.Errorf("description = %q", embed.Description)
+	}
+	if components != nil {
+		t.Errorf("components = %v, want nil", components)
+	}
+}
@@ -0,0 +1,744 @@
+package nelchanbot
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// isAdmin reports whether the user is the bot owner or has the Administrator permission in the channel
+func (n *Nelchan) isAdmin(s *discordgo.Session, userID, channelID string) bool {
+	if n.Config.BotOwnerUserID != "" && userID == n.Config.BotOwnerUserID {
+		return true
+	}
+
+	permissions, err := s.UserChannelPermissions(userID, channelID)
+	if err != nil {
+		fmt.Printf("error getting permissions of %s in %s: %v\n", userID, channelID, err)
+		return false
+	}
+	return permissions&discordgo.PermissionAdministrator != 0
+}
+
+// isInteractionAdmin reports whether the user of an interaction is the bot owner or a server administrator
+// Interactions carry the member's permissions, so no lookup is needed
+func (n *Nelchan) isInteractionAdmin(i *discordgo.InteractionCreate) bool {
+	user := interactionUser(i)
+	if n.Config.BotOwnerUserID != "" && user.ID == n.Config.BotOwnerUserID {
+		return true
+	}
+	return i.Member != nil && i.Member.Permissions&discordgo.PermissionAdministrator != 0
+}
+
+// interactionUser returns the user who triggered an interaction in a guild or a DM
+func interactionUser(i *discordgo.InteractionCreate) *discordgo.User {
+	if i.Member != nil {
+		return i.Member.User
+	}
+	return i.User
+}
+
+// canManageCommand reports whether a user may change or delete a command
+// Locked commands may only be changed by their owner and admins, unlocked ones by everyone
+func canManageCommand(info *GetCommandInfo, userID string, admin bool) bool {
+	return !info.Locked || isCommandOwner(info, userID, admin)
+}
+
+// isCommandOwner reports whether a user may lock, unlock or transfer a command
+// Only the owner of the command and admins may do so, whether the command is locked or not
+func isCommandOwner(info *GetCommandInfo, userID string, admin bool) bool {
+	return admin || (info.AuthorID != "" && info.AuthorID == userID)
+}
+
+// checkOverwrite returns a message refusing to register a command over one the user may not change
+// Returns "" when the name is free or the existing command may be changed
+func (n *Nelchan) checkOverwrite(commandName, userID string, admin bool) (string, error) {
+	info, err := n.CommandAPIClient.GetCommand(GetCommandRequest{
+		CommandName: commandName,
+	})
+	if err != nil {
+		return "", err
+	}
+	if info != nil && !canManageCommand(info, userID, admin) {
+		return fmt.Sprintf("コマンド「%s」を変更できるのは登録者か管理者だけです", commandName), nil
+	}
+	return "", nil
+}
+
+// allowOverwrite reports whether the author of the message may register commandName
+// Replies to the message and returns false when it would overwrite a command the author may not change
+func (n *Nelchan) allowOverwrite(s *discordgo.Session, m *discordgo.MessageCreate, commandName string, admin bool) bool {
+	message, err := n.checkOverwrite(commandName, m.Author.ID, admin)
+	if err != nil {
+		fmt.Println("error getting command,", err)
+		_, _ = s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("エラー: %s", err.Error()))
+		return false
+	}
+	if message != "" {
+		_, _ = s.ChannelMessageSend(m.ChannelID, message)
+		return false
+	}
+	return true
+}
+
+// unregisterCommand deletes a command and the Discord application commands created for it
+// Returns the message to show to the user
+func (n *Nelchan) unregisterCommand(s *discordgo.Session, commandName, userID string, admin bool) (string, error) {
+	info, err := n.CommandAPIClient.GetCommand(GetCommandRequest{
+		CommandName: commandName,
+	})
+	if err != nil {
+		return "", err
+	}
+	if info == nil {
+		return fmt.Sprintf("コマンド「%s」は見つかりませんでした", commandName), nil
+	}
+
+	if !canManageCommand(info, userID, admin) {
+		return fmt.Sprintf("コマンド「%s」を削除できるのは登録者か管理者だけです", commandName), nil
+	}
+
+	// Only code commands are registered as slash commands
+	deletedSlash := 0
+	if info.IsCode {
+		deletedSlash = n.deleteSlashCommands(s, commandName)
+	}
+
+	deleted, err := n.CommandAPIClient.DeleteCommand(DeleteCommandRequest{
+		CommandName: commandName,
+	})
+	if err != nil {
+		return "", err
+	}
+	if !deleted {
+		return fmt.Sprintf("コマンド「%s」は見つかりませんでした", commandName), nil
+	}
+
+	// The backend removes the aliases, schedules, triggers and auto responders of the command with it
+	n.loadAliases()
+	n.loadSchedules()
+	n.loadTriggers()
+	n.loadResponders()
+
+	// Don't leave the mention pointing at a command that no longer exists
+	mentionCmd, err := n.CommandAPIClient.GetMentionCommand()
+	if err != nil {
+		fmt.Println("error getting mention command:", err)
+	} else if mentionCmd != nil && *mentionCmd == commandName {
+		if err := n.CommandAPIClient.SetMentionCommand(nil); err != nil {
+			fmt.Println("error clearing mention command:", err)
+		}
+	}
+
+	fmt.Printf("unregistered command %s by %s (slash commands deleted: %d)\n", commandName, userID, deletedSlash)
+
+	if deletedSlash > 0 {
+		return fmt.Sprintf("コマンド「%s」を削除しました（スラッシュコマンド%d個も削除）", commandName, deletedSlash), nil
+	}
+	return fmt.Sprintf("コマンド「%s」を削除しました", commandName), nil
+}
+
+// deleteSlashCommands deletes the Discord application commands created for a command
+// Commands registered before their guilds were recorded are looked up in every guild the bot is in
+// Returns the number of application commands deleted
+func (n *Nelchan) deleteSlashCommands(s *discordgo.Session, commandName string) int {
+	deleted := 0
+	for _, record := range n.slashCommandRecords(s, commandName) {
+		if n.deleteSlashCommand(s, commandName, record) {
+			deleted++
+		}
+	}
+	return deleted
+}
+
+// slashCommandRecords returns the Discord application commands created for a command
+// Commands registered before their guilds were recorded are looked up in every guild the bot is in
+func (n *Nelchan) slashCommandRecords(s *discordgo.Session, commandName string) []SlashCommandRecord {
+	records, err := n.CommandAPIClient.ListSlashCommands(ListSlashCommandsRequest{
+		CommandName: commandName,
+	})
+	if err != nil {
+		fmt.Printf("error listing slash commands of %s: %v\n", commandName, err)
+	}
+	if len(records) == 0 {
+		records = n.findGuildSlashCommands(s, commandName)
+	}
+	return records
+}
+
+// deleteSlashCommand deletes a single application command
+// Returns false if it couldn't be deleted or was already gone
+func (n *Nelchan) deleteSlashCommand(s *discordgo.Session, commandName string, record SlashCommandRecord) bool {
+	err := s.ApplicationCommandDelete(s.State.User.ID, record.GuildID, record.ApplicationCommandID)
+	var restErr *discordgo.RESTError
+	if errors.As(err, &restErr) && restErr.Response != nil && restErr.Response.StatusCode == http.StatusNotFound {
+		// Already deleted on Discord's side
+		return false
+	}
+	if err != nil {
+		fmt.Printf("error deleting slash command /%s from guild %s: %v\n", commandName, record.GuildID, err)
+		return false
+	}
+	fmt.Printf("deleted slash command /%s from guild %s\n", commandName, record.GuildID)
+	return true
+}
+
+// findGuildSlashCommands finds the guild application commands named commandName in every guild the bot is in
+func (n *Nelchan) findGuildSlashCommands(s *discordgo.Session, commandName string) []SlashCommandRecord {
+	if isBuiltinSlashCommand(commandName) {
+		return nil
+	}
+
+	var records []SlashCommandRecord
+	for _, guild := range s.State.Guilds {
+		commands, err := s.ApplicationCommands(s.State.User.ID, guild.ID)
+		if err != nil {
+			fmt.Printf("error getting guild commands of %s: %v\n", guild.ID, err)
+			continue
+		}
+		for _, cmd := range commands {
+			if cmd.Name == commandName {
+				records = append(records, SlashCommandRecord{
+					GuildID:              guild.ID,
+					ApplicationCommandID: cmd.ID,
+				})
+			}
+		}
+	}
+	return records
+}
+
+// handleUnregisterCommand handles the !unregister command
+// Usage: !unregister <command_name>
+// Only the author of the command or an admin can delete it
+func (n *Nelchan) handleUnregisterCommand(s *discordgo.Session, m *discordgo.MessageCreate, cmd *SlashCommand) {
+	commandName := cmd.GetArg(0)
+	if commandName == "" {
+		_, _ = s.ChannelMessageSend(m.ChannelID, "使い方: !unregister <コマンド名>")
+		return
+	}
+
+	admin := n.isAdmin(s, m.Author.ID, m.ChannelID)
+	message, err := n.unregisterCommand(s, commandName, m.Author.ID, admin)
+	if err != nil {
+		fmt.Println("error unregistering command,", err)
+		_, _ = s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("エラー: %s", err.Error()))
+		return
+	}
+
+	_, _ = s.ChannelMessageSend(m.ChannelID, message)
+}
+
+// handleUnregisterSlashCommand handles the /unregister slash command
+func (n *Nelchan) handleUnregisterSlashCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	data := i.ApplicationCommandData()
+
+	var commandNameOpt string
+	for _, opt := range data.Options {
+		if opt.Name == "command_name" {
+			commandNameOpt = opt.StringValue()
+		}
+	}
+
+	// Defer response as deleting slash commands may take a while
+	err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
+	})
+	if err != nil {
+		fmt.Printf("error deferring interaction response: %v\n", err)
+		return
+	}
+
+	user := interactionUser(i)
+	message, err := n.unregisterCommand(s, commandNameOpt, user.ID, n.isInteractionAdmin(i))
+	if err != nil {
+		fmt.Println("error unregistering command,", err)
+		message = fmt.Sprintf("エラー: %s", err.Error())
+	}
+
+	_, err = s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+		Content: &message,
+	})
+	if err != nil {
+		fmt.Printf("error editing interaction response: %v\n", err)
+	}
+	fmt.Printf("slash command /unregister executed: name=%s\n", commandNameOpt)
+}
+
+// updateCommand stores new content for an existing command, which the backend keeps as a new version
+// The slash command is re-synced when the args schema of a code command changed
+// Returns a warning to append to the reply when the slash command couldn't be synced
+func (n *Nelchan) updateCommand(s *discordgo.Session, guildID string, info *GetCommandInfo, content string, isCode bool, userID string, admin bool) (string, error) {
+	request := RegisterCommandRequest{
+		CommandName:    info.Name,
+		CommandContent: content,
+		IsCode:         isCode,
+		AuthorID:       userID,
+		Admin:          admin,
+	}
+
+	// The content of a text command is its first response, the other responses are kept
+	if !isCode && !info.IsCode && len(info.Responses) > 1 {
+		request.Responses = slices.Clone(info.Responses)
+		request.Responses[0].Text = content
+	}
+
+	// Code commands carry their args and metadata in comments, text commands keep theirs
+	var newSpec slashCommandSpec
+	if isCode {
+		metadata := n.CommandParser.ExtractMetadataFromComment(content)
+		request.ArgsSchema = n.CommandParser.ExtractArgsFromComment(content)
+		request.Metadata = &metadata
+		newSpec = slashCommandSpec{
+			Description: slashCommandDescription(info.Name, metadata),
+			Args:        request.ArgsSchema,
+		}
+	}
+
+	if err := n.CommandAPIClient.RegisterCommand(request); err != nil {
+		return "", err
+	}
+
+	if err := n.syncSlashCommand(s, guildID, info.Name, n.slashCommandSpecOf(info), newSpec); err != nil {
+		fmt.Printf("error syncing slash command /%s: %v\n", info.Name, err)
+		return fmt.Sprintf("\n⚠️ スラッシュコマンドの更新に失敗: %s", err.Error()), nil
+	}
+	return "", nil
+}
+
+// slashCommandSpec is the part of a code command that its slash command is built from
+type slashCommandSpec struct {
+	Description string
+	Args        []ArgOption
+}
+
+// slashCommandSpecOf returns the slash command spec of a stored command
+// Text commands have no slash command and return an empty spec
+func (n *Nelchan) slashCommandSpecOf(info *GetCommandInfo) slashCommandSpec {
+	if !info.IsCode {
+		return slashCommandSpec{}
+	}
+	return slashCommandSpec{
+		Description: slashCommandDescription(info.Name, info.Metadata),
+		Args:        n.commandArgsSchema(info),
+	}
+}
+
+// syncSlashCommand brings the slash commands of a command in line with its new args schema and description
+// The command is re-registered in every guild it was registered in plus the current guild,
+// and deleted everywhere when the schema was removed
+func (n *Nelchan) syncSlashCommand(s *discordgo.Session, guildID, commandName string, oldSpec, newSpec slashCommandSpec) error {
+	if reflect.DeepEqual(oldSpec, newSpec) {
+		return nil
+	}
+
+	if newSpec.Args == nil {
+		n.deleteSlashCommands(s, commandName)
+		return nil
+	}
+
+	records, err := n.CommandAPIClient.ListSlashCommands(ListSlashCommandsRequest{
+		CommandName: commandName,
+	})
+	if err != nil {
+		return err
+	}
+
+	var guildIDs []string
+	seen := make(map[string]bool)
+	for _, record := range records {
+		if !seen[record.GuildID] {
+			seen[record.GuildID] = true
+			guildIDs = append(guildIDs, record.GuildID)
+		}
+	}
+	if guildID != "" && !seen[guildID] {
+		guildIDs = append(guildIDs, guildID)
+	}
+
+	var errs []error
+	for _, id := range guildIDs {
+		if err := n.registerSlashCommand(s, id, commandName, newSpec.Description, newSpec.Args); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// handleEditCommand handles the !edit command
+// Usage: !edit <command_name> <content>
+// Replaces the text or code of an existing command, keeping its type
+func (n *Nelchan) handleEditCommand(s *discordgo.Session, m *discordgo.MessageCreate, _ *SlashCommand) {
+	// Re-parse with body support for code
+	cmd := n.CommandParser.ParseSlashCommandWithBody(m.Content, 2)
+	if cmd == nil || len(cmd.Args) < 2 {
+		_, _ = s.ChannelMessageSend(m.ChannelID, "使い方: !edit <コマンド名> <内容>")
+		return
+	}
+
+	commandName := cmd.GetArg(0)
+	info, ok := n.getManageableCommand(s, m, commandName)
+	if !ok {
+		return
+	}
+
+	content := cmd.GetArg(1)
+	if info.IsCode {
+		content = n.CommandParser.ExtractCodeFromBackticks(content)
+	}
+	if content == "" {
+		_, _ = s.ChannelMessageSend(m.ChannelID, "使い方: !edit <コマンド名> <内容>")
+		return
+	}
+	if content == info.Content {
+		_, _ = s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("コマンド「%s」に変更はありません", commandName))
+		return
+	}
+
+	warning, err := n.updateCommand(s, m.GuildID, info, content, info.IsCode, m.Author.ID, n.isAdmin(s, m.Author.ID, m.ChannelID))
+	if err != nil {
+		fmt.Println("error editing command,", err)
+		_, _ = s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("エラー: %s", err.Error()))
+		return
+	}
+
+	_, _ = s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("コマンド「%s」を更新しました%s", commandName, warning))
+	fmt.Printf("edit command: name=%s, by=%s\n", commandName, m.Author.ID)
+}
+
+// handleHistoryCommand handles the !history command
+// Usage: !history <command_name>
+// Lists the versions of a command, newest first
+func (n *Nelchan) handleHistoryCommand(s *discordgo.Session, m *discordgo.MessageCreate, cmd *SlashCommand) {
+	commandName := cmd.GetArg(0)
+	if commandName == "" {
+		_, _ = s.ChannelMessageSend(m.ChannelID, "使い方: !history <コマンド名>")
+		return
+	}
+
+	versions, err := n.CommandAPIClient.GetCommandHistory(CommandHistoryRequest{
+		CommandName: commandName,
+		Limit:       maxHistoryVersions,
+	})
+	if err != nil {
+		fmt.Println("error getting command history,", err)
+		_, _ = s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("エラー: %s", err.Error()))
+		return
+	}
+	if versions == nil {
+		_, _ = s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("コマンド「%s」は見つかりませんでした", commandName))
+		return
+	}
+
+	// Authors are shown as mentions without notifying them
+	_, err = s.ChannelMessageSendComplex(m.ChannelID, &discordgo.MessageSend{
+		Content:         FormatCommandHistory(commandName, versions),
+		AllowedMentions: &discordgo.MessageAllowedMentions{},
+	})
+	if err != nil {
+		fmt.Println("error sending message,", err)
+	}
+}
+
+// maxHistoryVersions is the number of versions shown by !history
+const maxHistoryVersions = 20
+
+// FormatCommandHistory formats the versions of a command, newest first, for !history
+// Example:
+//
+//	コマンド「greet」の履歴:
+//	- v2（現在） 2026-10-18 12:00:00 <@123> コード
+//	- v1 2026-10-17 09:30:00 <@456> テキスト
+func FormatCommandHistory(commandName string, versions []CommandVersion) string {
+	if len(versions) == 0 {
+		return fmt.Sprintf("コマンド「%s」の履歴はありません", commandName)
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "コマンド「%s」の履歴:", commandName)
+	for idx, version := range versions {
+		fmt.Fprintf(&b, "\n- v%d", version.Version)
+		if idx == 0 {
+			b.WriteString("（現在）")
+		}
+
+		kind := "テキスト"
+		if version.IsCode {
+			kind = "コード"
+		}
+		fmt.Fprintf(&b, " %s <@%s> %s", version.CreatedAt, version.AuthorID, kind)
+	}
+	return b.String()
+}
+
+// handleRollbackCommand handles the !rollback command
+// Usage: !rollback <command_name> <version>
+// Restores the content of an earlier version, which is stored as a new version
+func (n *Nelchan) handleRollbackCommand(s *discordgo.Session, m *discordgo.MessageCreate, cmd *SlashCommand) {
+	commandName := cmd.GetArg(0)
+	version, err := ParseVersionArg(cmd.GetArg(1))
+	if commandName == "" || err != nil {
+		_, _ = s.ChannelMessageSend(m.ChannelID, "使い方: !rollback <コマンド名> <バージョン>")
+		return
+	}
+
+	info, ok := n.getManageableCommand(s, m, commandName)
+	if !ok {
+		return
+	}
+
+	target, err := n.CommandAPIClient.GetCommandVersion(CommandVersionRequest{
+		CommandName: commandName,
+		Version:     version,
+	})
+	if err != nil {
+		fmt.Println("error getting command version,", err)
+		_, _ = s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("エラー: %s", err.Error()))
+		return
+	}
+	if target == nil {
+		_, _ = s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("コマンド「%s」の v%d は見つかりませんでした", commandName, version))
+		return
+	}
+
+	if target.Content == info.Content && target.IsCode == info.IsCode {
+		_, _ = s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("コマンド「%s」は既に v%d と同じ内容です", commandName, version))
+		return
+	}
+
+	warning, err := n.updateCommand(s, m.GuildID, info, target.Content, target.IsCode, m.Author.ID, n.isAdmin(s, m.Author.ID, m.ChannelID))
+	if err != nil {
+		fmt.Println("error rolling back command,", err)
+		_, _ = s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("エラー: %s", err.Error()))
+		return
+	}
+
+	_, _ = s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("コマンド「%s」を v%d の内容に戻しました%s", commandName, version, warning))
+	fmt.Printf("rollback command: name=%s, version=%d, by=%s\n", commandName, version, m.Author.ID)
+}
+
+// ParseVersionArg parses a version number such as "3" or "v3"
+func ParseVersionArg(s string) (int, error) {
+	version, err := strconv.Atoi(strings.TrimPrefix(strings.ToLower(s), "v"))
+	if err != nil || version < 1 {
+		return 0, fmt.Errorf("invalid version: %q", s)
+	}
+	return version, nil
+}
+
+// getManageableCommand gets a command that the author of the message may change
+// Replies to the message and returns false when the command doesn't exist or the user lacks permission
+func (n *Nelchan) getManageableCommand(s *discordgo.Session, m *discordgo.MessageCreate, commandName string) (*GetCommandInfo, bool) {
+	return n.getCommandWithPermission(s, m, commandName, canManageCommand, "変更")
+}
+
+// getOwnedCommand gets a command whose ownership the author of the message may change
+// Replies to the message and returns false when the command doesn't exist or the user isn't the owner or an admin
+func (n *Nelchan) getOwnedCommand(s *discordgo.Session, m *discordgo.MessageCreate, commandName string) (*GetCommandInfo, bool) {
+	return n.getCommandWithPermission(s, m, commandName, isCommandOwner, "管理")
+}
+
+// getCommandWithPermission gets a command and checks that the author of the message passes allowed
+// action names the denied operation in the reply
+func (n *Nelchan) getCommandWithPermission(s *discordgo.Session, m *discordgo.MessageCreate, commandName string, allowed func(*GetCommandInfo, string, bool) bool, action string) (*GetCommandInfo, bool) {
+	info, err := n.CommandAPIClient.GetCommand(GetCommandRequest{
+		CommandName: commandName,
+	})
+	if err != nil {
+		fmt.Println("error getting command,", err)
+		_, _ = s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("エラー: %s", err.Error()))
+		return nil, false
+	}
+	if info == nil {
+		_, _ = s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("コマンド「%s」は見つかりませんでした", commandName))
+		return nil, false
+	}
+
+	if !allowed(info, m.Author.ID, n.isAdmin(s, m.Author.ID, m.ChannelID)) {
+		_, _ = s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("コマンド「%s」を%sできるのは登録者か管理者だけです", commandName, action))
+		return nil, false
+	}
+	return info, true
+}
+
+// handleDiffCommand handles the !diff command
+// Usage: !diff <command_name> - Compare the previous version with the current one
+// Usage: !diff <command_name> <v1> - Compare v1 with the current version
+// Usage: !diff <command_name> <v1> <v2> - Compare v1 with v2
+func (n *Nelchan) handleDiffCommand(s *discordgo.Session, m *discordgo.MessageCreate, cmd *SlashCommand) {
+	const usage = "使い方: !diff <コマンド名> [バージョン1] [バージョン2]"
+
+	commandName := cmd.GetArg(0)
+	if commandName == "" || len(cmd.Args) > 3 {
+		_, _ = s.ChannelMessageSend(m.ChannelID, usage)
+		return
+	}
+
+	versionNumbers := make([]int, 0, 2)
+	for _, arg := range cmd.Args[1:] {
+		version, err := ParseVersionArg(arg)
+		if err != nil {
+			_, _ = s.ChannelMessageSend(m.ChannelID, usage)
+			return
+		}
+		versionNumbers = append(versionNumbers, version)
+	}
+
+	// The newest version is the default right-hand side
+	latest, err := n.CommandAPIClient.GetCommandVersion(CommandVersionRequest{
+		CommandName: commandName,
+	})
+	if err != nil {
+		fmt.Println("error getting command version,", err)
+		_, _ = s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("エラー: %s", err.Error()))
+		return
+	}
+	if latest == nil {
+		_, _ = s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("コマンド「%s」は見つかりませんでした", commandName))
+		return
+	}
+
+	switch len(versionNumbers) {
+	case 0:
+		if latest.Version < 2 {
+			_, _ = s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("コマンド「%s」には比較できる以前のバージョンがありません", commandName))
+			return
+		}
+		versionNumbers = []int{latest.Version - 1, latest.Version}
+	case 1:
+		versionNumbers = append(versionNumbers, latest.Version)
+	}
+
+	versions := make([]*CommandVersion, 2)
+	for idx, number := range versionNumbers {
+		if number == latest.Version {
+			versions[idx] = latest
+			continue
+		}
+		version, err := n.CommandAPIClient.GetCommandVersion(CommandVersionRequest{
+			CommandName: commandName,
+			Version:     number,
+		})
+		if err != nil {
+			fmt.Println("error getting command version,", err)
+			_, _ = s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("エラー: %s", err.Error()))
+			return
+		}
+		if version == nil {
+			_, _ = s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("コマンド「%s」の v%d は見つかりませんでした", commandName, number))
+			return
+		}
+		versions[idx] = version
+	}
+
+	from, to := versions[0], versions[1]
+	diff, err := UnifiedDiff(
+		fmt.Sprintf("%s v%d", commandName, from.Version),
+		fmt.Sprintf("%s v%d", commandName, to.Version),
+		from.Content,
+		to.Content,
+	)
+	if err != nil {
+		_, _ = s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("コマンド「%s」の v%d と v%d は%s", commandName, from.Version, to.Version, err.Error()))
+		return
+	}
+	if diff == "" {
+		_, _ = s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("コマンド「%s」の v%d と v%d に差分はありません", commandName, from.Version, to.Version))
+		return
+	}
+
+	message := fmt.Sprintf("コマンド「%s」の差分（v%d → v%d）:\n```diff\n%s```", commandName, from.Version, to.Version, diff)
+	fileName := fmt.Sprintf("%s_v%d_v%d.diff", commandName, from.Version, to.Version)
+	if err := n.sendMessageOrFile(s, m.ChannelID, message, fileName, diff); err != nil {
+		fmt.Println("error sending message,", err)
+	}
+}
+
+// checkNewCommandName validates a name for a renamed command or an alias
+// Returns a message for the user when the name can't be used
+func (n *Nelchan) checkNewCommandName(name string) string {
+	if strings.ContainsFunc(name, unicode.IsSpace) || strings.HasPrefix(name, "!") {
+		return fmt.Sprintf("「%s」は名前として使えません", name)
+	}
+	if n.CommandRouter.HasCommand(name) || isBuiltinSlashCommand(name) {
+		return fmt.Sprintf("「%s」はビルトインコマンドの名前です", name)
+	}
+	return ""
+}
+
+// handleRenameCommand handles the !rename command
+// Usage: !rename <old_name> <new_name>
+// Keeps the content, history and aliases of the command and moves its slash commands to the new name
+func (n *Nelchan) handleRenameCommand(s *discordgo.Session, m *discordgo.MessageCreate, cmd *SlashCommand) {
+	oldName, newName := cmd.GetArg(0), cmd.GetArg(1)
+	if oldName == "" || newName == "" {
+		_, _ = s.ChannelMessageSend(m.ChannelID, "使い方: !rename <コマンド名> <新しいコマンド名>")
+		return
+	}
+
+	if message := n.checkNewCommandName(newName); message != "" {
+		_, _ = s.ChannelMessageSend(m.ChannelID, message)
+		return
+	}
+
+	info, ok := n.getManageableCommand(s, m, oldName)
+	if !ok {
+		return
+	}
+
+	// Slash commands are recorded by command name, so look them up before renaming
+	spec := n.slashCommandSpecOf(info)
+	var records []SlashCommandRecord
+	if spec.Args != nil {
+		records = n.slashCommandRecords(s, oldName)
+	}
+	if info.Metadata.Description == "" {
+		spec.Description = slashCommandDescription(newName, info.Metadata)
+	}
+
+	err := n.CommandAPIClient.RenameCommand(RenameCommandRequest{
+		CommandName: oldName,
+		NewName:     newName,
+	})
+	switch {
+	case errors.Is(err, ErrCommandNotFound):
+		_, _ = s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("コマンド「%s」は見つかりませんでした", oldName))
+		return
+	case errors.Is(err, ErrNameInUse):
+		_, _ = s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("「%s」は既にコマンド名かエイリアスとして使われています", newName))
+		return
+	case err != nil:
+		fmt.Println("error renaming command,", err)
+		_, _ = s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("エラー: %s", err.Error()))
+		return
+	}
+
+	// Aliases, schedules, triggers and auto responders follow the command, refresh their targets
+	n.loadAliases()
+	n.loadSchedules()
+	n.loadTriggers()
+	n.loadResponders()
+
+	var errs []error
+	for _, record := range records {
+		n.deleteSlashCommand(s, oldName, record)
+		if err := n.registerSlashCommand(s, record.GuildID, newName, spec.Description, spec.Args); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	message := fmt.Sprintf("コマンド「%s」の名前を「%s」に変更しました", oldName, newName)
+	if err := errors.Join(errs...); err != nil {
+		fmt.Printf("error renaming slash command /%s: %v\n", oldName, err)
+		message += fmt.Sprintf("\n⚠️ スラッシュコマンドの更新に失敗: %s", err.Error())
+	}
+	_, _ = s.ChannelMessageSend(m.ChannelID, message)
+	fmt.Printf("rename command: %s -> %s, by=%s\n", oldName, newName, m.Author.ID)
+}
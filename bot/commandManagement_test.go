@@ -0,0 +1,121 @@
+package nelchanbot
+
+import "testing"
+
+func TestCanManageCommand(t *testing.T) {
+	tests := []struct {
+		name   string
+		info   *GetCommandInfo
+		userID string
+		admin  bool
+		want   bool
+	}{
+		{
+			name:   "author",
+			info:   &GetCommandInfo{Name: "hello", AuthorID: "100", Locked: true},
+			userID: "100",
+			want:   true,
+		},
+		{
+			name:   "other user",
+			info:   &GetCommandInfo{Name: "hello", AuthorID: "100", Locked: true},
+			userID: "200",
+			want:   false,
+		},
+		{
+			name:   "admin",
+			info:   &GetCommandInfo{Name: "hello", AuthorID: "100", Locked: true},
+			userID: "200",
+			admin:  true,
+			want:   true,
+		},
+		{
+			name:   "other user on unlocked command",
+			info:   &GetCommandInfo{Name: "hello", AuthorID: "100"},
+			userID: "200",
+			want:   true,
+		},
+		{
+			name:   "unknown author",
+			info:   &GetCommandInfo{Name: "hello", Locked: true},
+			userID: "",
+			want:   false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := canManageCommand(tt.info, tt.userID, tt.admin); got != tt.want {
+				t.Errorf("canManageCommand() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsCommandOwner(t *testing.T) {
+	unlocked := &GetCommandInfo{Name: "hello", AuthorID: "100"}
+
+	tests := []struct {
+		name   string
+		userID string
+		admin  bool
+		want   bool
+	}{
+		{name: "owner", userID: "100", want: true},
+		{name: "other user", userID: "200", want: false},
+		{name: "admin", userID: "200", admin: true, want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isCommandOwner(unlocked, tt.userID, tt.admin); got != tt.want {
+				t.Errorf("isCommandOwner() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseVersionArg(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    int
+		wantErr bool
+	}{
+		{input: "3", want: 3},
+		{input: "v12", want: 12},
+		{input: "V2", want: 2},
+		{input: "0", wantErr: true},
+		{input: "latest", wantErr: true},
+		{input: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := ParseVersionArg(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseVersionArg(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseVersionArg(%q) = %d, want %d", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatCommandHistory(t *testing.T) {
+	versions := []CommandVersion{
+		{Version: 2, IsCode: true, AuthorID: "123", CreatedAt: "2026-10-18 12:00:00"},
+		{Version: 1, IsCode: false, AuthorID: "456", CreatedAt: "2026-10-17 09:30:00"},
+	}
+
+	want := "コマンド「greet」の履歴:\n" +
+		"- v2（現在） 2026-10-18 12:00:00 <@123> コード\n" +
+		"- v1 2026-10-17 09:30:00 <@456> テキスト"
+	if got := FormatCommandHistory("greet", versions); got != want {
+		t.Errorf("FormatCommandHistory() =\n%s\nwant\n%s", got, want)
+	}
+
+	if got := FormatCommandHistory("greet", nil); got != "コマンド「greet」の履歴はありません" {
+		t.Errorf("FormatCommandHistory(nil) = %q", got)
+	}
+}
@@ -2,21 +2,167 @@ package nelchanbot
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"regexp"
+	"slices"
 	"strings"
+	"unicode"
 )
 
 // ArgOption represents a single argument option for slash commands
 type ArgOption struct {
-	Name        string `json:"name"`
-	Type        string `json:"type"` // "string", "number", "boolean"
-	Description string `json:"description,omitempty"`
-	Required    bool   `json:"required,omitempty"`
+	Name        string      `json:"name"`
+	Type        string      `json:"type"` // "string", "integer", "number", "boolean", "user", "channel", "role", "mentionable", "attachment"
+	Description string      `json:"description,omitempty"`
+	Required    bool        `json:"required,omitempty"`
+	Choices     []ArgChoice `json:"choices,omitempty"`
+	MinValue    *float64    `json:"min_value,omitempty"`  // integer/number only
+	MaxValue    *float64    `json:"max_value,omitempty"`  // integer/number only
+	MinLength   *int        `json:"min_length,omitempty"` // string only
+	MaxLength   *int        `json:"max_length,omitempty"` // string only
+	Default     any         `json:"default,omitempty"`    // used when an optional arg is omitted
+
+	Autocomplete *ArgAutocomplete `json:"autocomplete,omitempty"` // string/integer/number only, exclusive with Choices
+}
+
+// Built-in autocomplete sources
+const (
+	AutocompleteSourceCommands = "commands" // registered command names
+	AutocompleteSourceMembers  = "members"  // guild members
+)
+
+// ArgAutocomplete declares where the autocomplete suggestions of an ArgOption come from
+// In the args comment it can be written as:
+// - ["a", "b"]           a static list of suggestions
+// - "commands"           a built-in source ("commands" or "members")
+// - {"command": "name"}  a code command printing one suggestion per line
+type ArgAutocomplete struct {
+	Values  []string `json:"values,omitempty"`
+	Source  string   `json:"source,omitempty"`
+	Command string   `json:"command,omitempty"`
+}
+
+// UnmarshalJSON accepts the list, source name and object forms
+func (a *ArgAutocomplete) UnmarshalJSON(data []byte) error {
+	var values []string
+	if err := json.Unmarshal(data, &values); err == nil {
+		a.Values = values
+		return nil
+	}
+
+	var source string
+	if err := json.Unmarshal(data, &source); err == nil {
+		a.Source = source
+		return nil
+	}
+
+	type rawAutocomplete ArgAutocomplete
+	var raw rawAutocomplete
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	*a = ArgAutocomplete(raw)
+	return nil
+}
+
+// ArgChoice represents one allowed value of an ArgOption
+// In the args comment it can be written as {"name": "赤", "value": "red"} or just "red"
+type ArgChoice struct {
+	Name  string `json:"name"`
+	Value any    `json:"value"`
+}
+
+// UnmarshalJSON accepts both the object form and a bare scalar value
+func (c *ArgChoice) UnmarshalJSON(data []byte) error {
+	var scalar any
+	if err := json.Unmarshal(data, &scalar); err != nil {
+		return err
+	}
+
+	if _, isObject := scalar.(map[string]any); !isObject {
+		c.Name = fmt.Sprintf("%v", scalar)
+		c.Value = scalar
+		return nil
+	}
+
+	type rawChoice ArgChoice
+	var raw rawChoice
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	if raw.Name == "" {
+		raw.Name = fmt.Sprintf("%v", raw.Value)
+	}
+	*c = ArgChoice(raw)
+	return nil
+}
+
+// CommandMetadata describes a registered command for help, slash commands and search
+type CommandMetadata struct {
+	Description string   `json:"description,omitempty"`
+	Usage       string   `json:"usage,omitempty"`
+	Tags        []string `json:"tags,omitempty"`
+	Category    string   `json:"category,omitempty"`
 }
 
+// IsEmpty returns true if no metadata is set
+func (m CommandMetadata) IsEmpty() bool {
+	return m.Description == "" && m.Usage == "" && len(m.Tags) == 0 && m.Category == ""
+}
+
+// FormatCommandMetadata formats the metadata that is set, one field per line
+// Example:
+//
+//	説明: サイコロを振る
+//	使い方: !dice [面の数]
+//	カテゴリ: あそび
+//	タグ: game, random
+func FormatCommandMetadata(m CommandMetadata) string {
+	var lines []string
+	if m.Description != "" {
+		lines = append(lines, "説明: "+m.Description)
+	}
+	if m.Usage != "" {
+		lines = append(lines, "使い方: "+m.Usage)
+	}
+	if m.Category != "" {
+		lines = append(lines, "カテゴリ: "+m.Category)
+	}
+	if len(m.Tags) > 0 {
+		lines = append(lines, "タグ: "+strings.Join(m.Tags, ", "))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// metadataCommentRe matches lines like: # description: サイコロを振る
+var metadataCommentRe = regexp.MustCompile(`(?i)^\s*#\s*(description|usage|tags|category)\s*[:=]\s*(.*?)\s*$`)
+
 // argsCommentRe matches lines like: # args = [...]
 var argsCommentRe = regexp.MustCompile(`^\s*#\s*args\s*=\s*(.+?)\s*$`)
 
+// parseCommentRe matches lines like: # parse = quoted
+var parseCommentRe = regexp.MustCompile(`^\s*#\s*parse\s*=\s*(\w+)\s*$`)
+
+// namedCommentRe matches the line "# named", which lets a command take any named arg
+var namedCommentRe = regexp.MustCompile(`^\s*#\s*named\s*$`)
+
+// ParseMode controls how the arguments of a command are tokenized
+type ParseMode int
+
+const (
+	// ParseModeFields splits arguments on whitespace (default)
+	ParseModeFields ParseMode = iota
+	// ParseModeQuoted splits arguments shell-style, honoring quotes and backslash escapes
+	ParseModeQuoted
+)
+
+// ErrUnterminatedQuote is returned when a quoted argument is not closed
+var ErrUnterminatedQuote = errors.New("unterminated quote")
+
+// namedArgKeyRe matches keys usable in --key=value and key:value tokens
+var namedArgKeyRe = regexp.MustCompile(`^[\p{L}_][\p{L}\p{N}_-]*$`)
+
 type CommandParser struct{}
 
 // SlashCommand represents a parsed slash command
@@ -76,6 +222,259 @@ func (p *CommandParser) ParseSlashCommand(message string) *SlashCommand {
 	}
 }
 
+// ParseSlashCommandWithMode parses a message starting with "!" using the given ParseMode
+// Example (ParseModeQuoted): `!greet "John Smith" 3` -> SlashCommand{Name: "greet", Args: ["John Smith", "3"]}
+// Returns nil if the message doesn't start with "!"
+func (p *CommandParser) ParseSlashCommandWithMode(message string, mode ParseMode) (*SlashCommand, error) {
+	if mode != ParseModeQuoted {
+		return p.ParseSlashCommand(message), nil
+	}
+
+	message = strings.TrimSpace(message)
+
+	// Check if it starts with "!"
+	if !strings.HasPrefix(message, "!") {
+		return nil, nil
+	}
+
+	parts, err := p.SplitQuoted(strings.TrimPrefix(message, "!"))
+	if err != nil {
+		return nil, err
+	}
+	if len(parts) == 0 {
+		return nil, nil
+	}
+
+	return &SlashCommand{
+		Name: parts[0],
+		Args: parts[1:],
+	}, nil
+}
+
+// ArgToken is one argument of a command
+// Quoted is true when the argument starts with a quote or a backslash escape,
+// which keeps it from being read as a named argument
+type ArgToken struct {
+	Text   string
+	Quoted bool
+}
+
+// SplitQuoted splits s into arguments the way a POSIX shell would
+// - Unicode whitespace (including full-width spaces) separates arguments
+// - '...' keeps its content literally
+// - "..." keeps its content, with \" and \\ escaped
+// - Outside of quotes, a backslash escapes the next character
+// Returns ErrUnterminatedQuote if a quote is left open
+func (p *CommandParser) SplitQuoted(s string) ([]string, error) {
+	tokens, err := p.SplitQuotedTokens(s)
+	if err != nil {
+		return nil, err
+	}
+	return tokenTexts(tokens), nil
+}
+
+// SplitQuotedTokens splits s like SplitQuoted, also reporting which arguments start quoted
+func (p *CommandParser) SplitQuotedTokens(s string) ([]ArgToken, error) {
+	args := []ArgToken{}
+	var current strings.Builder
+	inArg := false
+	quoted := false // the current argument started with a quote or an escape
+	var quote rune  // 0 when not inside quotes
+	escaped := false
+
+	for _, r := range s {
+		switch {
+		case escaped:
+			// Inside double quotes only \" and \\ are escapes
+			if quote == '"' && r != '"' && r != '\\' {
+				current.WriteRune('\\')
+			}
+			current.WriteRune(r)
+			escaped = false
+		case quote == '\'':
+			if r == '\'' {
+				quote = 0
+			} else {
+				current.WriteRune(r)
+			}
+		case quote == '"':
+			switch r {
+			case '"':
+				quote = 0
+			case '\\':
+				escaped = true
+			default:
+				current.WriteRune(r)
+			}
+		case r == '\'' || r == '"':
+			quote = r
+			quoted = quoted || !inArg
+			inArg = true
+		case r == '\\':
+			escaped = true
+			quoted = quoted || !inArg
+			inArg = true
+		case unicode.IsSpace(r):
+			if inArg {
+				args = append(args, ArgToken{Text: current.String(), Quoted: quoted})
+				current.Reset()
+				inArg = false
+				quoted = false
+			}
+		default:
+			current.WriteRune(r)
+			inArg = true
+		}
+	}
+
+	if quote != 0 {
+		return nil, ErrUnterminatedQuote
+	}
+	if escaped {
+		// Trailing backslash is kept as is
+		current.WriteRune('\\')
+	}
+	if inArg {
+		args = append(args, ArgToken{Text: current.String(), Quoted: quoted})
+	}
+	return args, nil
+}
+
+// FieldTokens splits s on whitespace into arguments, none of which are quoted
+func (p *CommandParser) FieldTokens(s string) []ArgToken {
+	fields := strings.Fields(s)
+	tokens := make([]ArgToken, len(fields))
+	for i, field := range fields {
+		tokens[i] = ArgToken{Text: field}
+	}
+	return tokens
+}
+
+// tokenTexts returns the text of each token
+func tokenTexts(tokens []ArgToken) []string {
+	texts := make([]string, len(tokens))
+	for i, token := range tokens {
+		texts[i] = token.Text
+	}
+	return texts
+}
+
+// ExtractArgsText returns the raw text following the command name of a message starting with "!"
+// Example: "!greet  John Smith" -> "John Smith"
+// Returns empty string if the message doesn't start with "!" or has no arguments
+func (p *CommandParser) ExtractArgsText(message string) string {
+	message = strings.TrimSpace(message)
+	if !strings.HasPrefix(message, "!") {
+		return ""
+	}
+
+	_, rest := CutFirstField(strings.TrimPrefix(message, "!"))
+	return rest
+}
+
+// CutFirstField splits s into its first whitespace-delimited word and the trimmed remainder
+// Example: "  exec  greet a b" -> ("exec", "greet a b")
+func CutFirstField(s string) (string, string) {
+	s = strings.TrimLeftFunc(s, unicode.IsSpace)
+	end := strings.IndexFunc(s, unicode.IsSpace)
+	if end == -1 {
+		return s, ""
+	}
+	return s[:end], strings.TrimSpace(s[end:])
+}
+
+// ExtractNamedArgs separates named arguments from positional ones
+// Recognized forms:
+// - --flag      -> named["flag"] = true
+// - --key=value -> named["key"] = "value"
+// - key:value   -> named["key"] = "value"
+// Only keys accepted by allowed are named; quoted tokens and other keys stay positional
+// A lone "--" stops named argument parsing; every following token is positional
+func (p *CommandParser) ExtractNamedArgs(args []ArgToken, allowed func(key string) bool) ([]string, map[string]any) {
+	positional := make([]string, 0, len(args))
+	named := make(map[string]any)
+	isKey := func(key string) bool {
+		return namedArgKeyRe.MatchString(key) && allowed(key)
+	}
+
+	for i, arg := range args {
+		if arg.Quoted {
+			positional = append(positional, arg.Text)
+			continue
+		}
+
+		if arg.Text == "--" {
+			positional = append(positional, tokenTexts(args[i+1:])...)
+			break
+		}
+
+		if strings.HasPrefix(arg.Text, "--") {
+			key, value, hasValue := strings.Cut(strings.TrimPrefix(arg.Text, "--"), "=")
+			if isKey(key) {
+				if hasValue {
+					named[key] = value
+				} else {
+					named[key] = true
+				}
+				continue
+			}
+		}
+
+		// key:value, but not URLs such as https://example.com
+		if key, value, ok := strings.Cut(arg.Text, ":"); ok && value != "" && !strings.HasPrefix(value, "//") && isKey(key) {
+			named[key] = value
+			continue
+		}
+
+		positional = append(positional, arg.Text)
+	}
+
+	return positional, named
+}
+
+// NamedArgKeys returns which keys a code command accepts as named args, or nil if it takes none
+// Named args are opt-in so that text like "note:hello" reaches other commands unchanged:
+// a "# named" comment accepts any key, and an args schema accepts the names it declares
+func (p *CommandParser) NamedArgKeys(code string, schema []ArgOption) func(key string) bool {
+	for _, line := range strings.Split(code, "\n") {
+		if namedCommentRe.MatchString(line) {
+			return func(string) bool { return true }
+		}
+	}
+	if len(schema) == 0 {
+		return nil
+	}
+	return func(key string) bool {
+		return slices.ContainsFunc(schema, func(opt ArgOption) bool { return opt.Name == key })
+	}
+}
+
+// BindPositionalArgs assigns positional args to the names declared in an ArgOption list
+// Names already given as named args are skipped, so "!cmd 3 name:foo" binds 3 to the first
+// declared arg that wasn't named explicitly. The named map is updated in place and returned.
+func (p *CommandParser) BindPositionalArgs(options []ArgOption, positional []string, named map[string]any) map[string]any {
+	if named == nil {
+		named = make(map[string]any)
+	}
+
+	next := 0
+	for _, opt := range options {
+		if _, exists := named[opt.Name]; exists {
+			continue
+		}
+		// Attachments can't be typed as text, they are bound from the message instead
+		if opt.Type == "attachment" {
+			continue
+		}
+		if next >= len(positional) {
+			break
+		}
+		named[opt.Name] = positional[next]
+		next++
+	}
+	return named
+}
+
 // ParseSlashCommandWithBody parses a slash command where the body may contain spaces or newlines
 // Example: "!register_code name print('hello\nworld')" -> SlashCommand{Name: "register_code", Args: ["name", "print('hello\nworld')"]}
 // The last argument (body) preserves newlines, useful for code commands
@@ -189,3 +588,80 @@ func (p *CommandParser) ExtractArgsFromComment(code string) []ArgOption {
 	}
 	return nil
 }
+
+// ExtractParseModeFromComment extracts the argument parse mode from a comment line in code
+// Expected format: # parse = quoted
+// Returns ParseModeFields if no parse comment is found or the mode is unknown
+func (p *CommandParser) ExtractParseModeFromComment(code string) ParseMode {
+	for _, line := range strings.Split(code, "\n") {
+		matches := parseCommentRe.FindStringSubmatch(line)
+		if len(matches) < 2 {
+			continue
+		}
+
+		if strings.EqualFold(matches[1], "quoted") {
+			return ParseModeQuoted
+		}
+		return ParseModeFields
+	}
+	return ParseModeFields
+}
+
+// ExtractMetadataFromComment extracts command metadata from comment headers in code
+// Expected format:
+//
+//	# description: サイコロを振る
+//	# usage: !dice [面の数]
+//	# tags: game, random
+//	# category: あそび
+//
+// The first occurrence of each header wins
+func (p *CommandParser) ExtractMetadataFromComment(code string) CommandMetadata {
+	var metadata CommandMetadata
+	for _, line := range strings.Split(code, "\n") {
+		matches := metadataCommentRe.FindStringSubmatch(line)
+		if len(matches) < 3 || matches[2] == "" {
+			continue
+		}
+
+		value := matches[2]
+		switch strings.ToLower(matches[1]) {
+		case "description":
+			if metadata.Description == "" {
+				metadata.Description = value
+			}
+		case "usage":
+			if metadata.Usage == "" {
+				metadata.Usage = value
+			}
+		case "tags":
+			if metadata.Tags == nil {
+				metadata.Tags = ParseTags(value)
+			}
+		case "category":
+			if metadata.Category == "" {
+				metadata.Category = value
+			}
+		}
+	}
+	return metadata
+}
+
+// ParseTags splits a comma separated tag list, accepting "、" as a separator and dropping duplicates
+func ParseTags(s string) []string {
+	fields := strings.FieldsFunc(s, func(r rune) bool {
+		return r == ',' || r == '、'
+	})
+
+	var tags []string
+	seen := make(map[string]bool)
+	for _, field := range fields {
+		tag := strings.TrimSpace(field)
+		if tag == "" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		tags = append(tags, tag)
+	}
+	return tags
+}
@@ -276,6 +276,24 @@ print(args[0])`,
 				{Type: "string", Name: "query", Description: "検索クエリ", Required: true},
 			},
 		},
+		{
+			name: "with choices, range and default",
+			input: `# args = [{"type": "integer", "name": "count", "min_value": 1, "max_value": 10, "default": 1}, {"type": "string", "name": "color", "choices": ["red", {"name": "青", "value": "blue"}]}]
+print(kwargs["count"])`,
+			expected: []ArgOption{
+				{Type: "integer", Name: "count", MinValue: floatPtr(1), MaxValue: floatPtr(10), Default: float64(1)},
+				{Type: "string", Name: "color", Choices: []ArgChoice{{Name: "red", Value: "red"}, {Name: "青", Value: "blue"}}},
+			},
+		},
+		{
+			name:  "with autocomplete",
+			input: `# args = [{"name": "fruit", "autocomplete": ["apple", "banana"]}, {"name": "cmd", "autocomplete": "commands"}, {"name": "city", "autocomplete": {"command": "cities"}}]`,
+			expected: []ArgOption{
+				{Name: "fruit", Autocomplete: &ArgAutocomplete{Values: []string{"apple", "banana"}}},
+				{Name: "cmd", Autocomplete: &ArgAutocomplete{Source: AutocompleteSourceCommands}},
+				{Name: "city", Autocomplete: &ArgAutocomplete{Command: "cities"}},
+			},
+		},
 		{
 			name:     "no args comment",
 			input:    `print("hello")`,
@@ -354,3 +372,465 @@ func TestSlashCommandHelpers(t *testing.T) {
 		}
 	})
 }
+
+func TestSplitQuoted(t *testing.T) {
+	parser := NewCommandParser()
+
+	tests := []struct {
+		name     string
+		input    string
+		expected []string
+		wantErr  bool
+	}{
+		{
+			name:     "plain words",
+			input:    "a b c",
+			expected: []string{"a", "b", "c"},
+		},
+		{
+			name:     "double quoted arg with space",
+			input:    `"John Smith" 3`,
+			expected: []string{"John Smith", "3"},
+		},
+		{
+			name:     "single quoted arg keeps backslash",
+			input:    `'a\b c'`,
+			expected: []string{`a\b c`},
+		},
+		{
+			name:     "escaped quote inside double quotes",
+			input:    `"say \"hi\""`,
+			expected: []string{`say "hi"`},
+		},
+		{
+			name:     "backslash escapes space outside quotes",
+			input:    `hello\ world x`,
+			expected: []string{"hello world", "x"},
+		},
+		{
+			name:     "quotes adjacent to text are joined",
+			input:    `key="a b"c`,
+			expected: []string{"key=a bc"},
+		},
+		{
+			name:     "empty quoted arg",
+			input:    `"" x`,
+			expected: []string{"", "x"},
+		},
+		{
+			name:     "full-width space separates args",
+			input:    "こんにちは　世界",
+			expected: []string{"こんにちは", "世界"},
+		},
+		{
+			name:     "full-width space inside quotes is kept",
+			input:    "\"こんにちは　世界\"",
+			expected: []string{"こんにちは　世界"},
+		},
+		{
+			name:     "empty input",
+			input:    "   ",
+			expected: []string{},
+		},
+		{
+			name:    "unterminated double quote",
+			input:   `"abc`,
+			wantErr: true,
+		},
+		{
+			name:    "unterminated single quote",
+			input:   `it's`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := parser.SplitQuoted(tt.input)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("SplitQuoted(%q) error = nil, want error", tt.input)
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("SplitQuoted(%q) unexpected error: %v", tt.input, err)
+				return
+			}
+			if !reflect.DeepEqual(result, tt.expected) {
+				t.Errorf("SplitQuoted(%q) = %q, want %q", tt.input, result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestParseSlashCommandWithMode(t *testing.T) {
+	parser := NewCommandParser()
+
+	t.Run("fields mode keeps existing behavior", func(t *testing.T) {
+		result, err := parser.ParseSlashCommandWithMode(`!greet "John Smith" 3`, ParseModeFields)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		expected := []string{`"John`, `Smith"`, "3"}
+		if !reflect.DeepEqual(result.Args, expected) {
+			t.Errorf("Args = %q, want %q", result.Args, expected)
+		}
+	})
+
+	t.Run("quoted mode", func(t *testing.T) {
+		result, err := parser.ParseSlashCommandWithMode(`!greet "John Smith" 3`, ParseModeQuoted)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if result.Name != "greet" {
+			t.Errorf("Name = %q, want %q", result.Name, "greet")
+		}
+		expected := []string{"John Smith", "3"}
+		if !reflect.DeepEqual(result.Args, expected) {
+			t.Errorf("Args = %q, want %q", result.Args, expected)
+		}
+	})
+
+	t.Run("quoted mode without prefix", func(t *testing.T) {
+		result, err := parser.ParseSlashCommandWithMode("greet", ParseModeQuoted)
+		if err != nil || result != nil {
+			t.Errorf("ParseSlashCommandWithMode() = %+v, %v, want nil, nil", result, err)
+		}
+	})
+
+	t.Run("quoted mode with unterminated quote", func(t *testing.T) {
+		_, err := parser.ParseSlashCommandWithMode(`!greet "John`, ParseModeQuoted)
+		if err != ErrUnterminatedQuote {
+			t.Errorf("error = %v, want %v", err, ErrUnterminatedQuote)
+		}
+	})
+}
+
+func TestExtractParseModeFromComment(t *testing.T) {
+	parser := NewCommandParser()
+
+	tests := []struct {
+		name     string
+		input    string
+		expected ParseMode
+	}{
+		{
+			name:     "quoted",
+			input:    "# parse = quoted\nprint(arg1)",
+			expected: ParseModeQuoted,
+		},
+		{
+			name:     "quoted with args comment",
+			input:    "# args = [{\"type\": \"string\", \"name\": \"text\"}]\n#parse=quoted\nprint(arg1)",
+			expected: ParseModeQuoted,
+		},
+		{
+			name:     "unknown mode",
+			input:    "# parse = magic\nprint(arg1)",
+			expected: ParseModeFields,
+		},
+		{
+			name:     "no parse comment",
+			input:    "print(arg1)",
+			expected: ParseModeFields,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if result := parser.ExtractParseModeFromComment(tt.input); result != tt.expected {
+				t.Errorf("ExtractParseModeFromComment() = %v, want %v", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestExtractArgsText(t *testing.T) {
+	parser := NewCommandParser()
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{input: "!greet John Smith", expected: "John Smith"},
+		{input: "  !greet   \"John  Smith\"  ", expected: "\"John  Smith\""},
+		{input: "!greet", expected: ""},
+		{input: "greet John", expected: ""},
+		{input: "!greet　山田　太郎", expected: "山田　太郎"},
+	}
+
+	for _, tt := range tests {
+		if result := parser.ExtractArgsText(tt.input); result != tt.expected {
+			t.Errorf("ExtractArgsText(%q) = %q, want %q", tt.input, result, tt.expected)
+		}
+	}
+}
+
+func TestExtractNamedArgs(t *testing.T) {
+	parser := NewCommandParser()
+	anyKey := func(string) bool { return true }
+
+	tests := []struct {
+		name               string
+		input              string
+		allowed            func(string) bool
+		expectedPositional []string
+		expectedNamed      map[string]any
+	}{
+		{
+			name:               "positional only",
+			input:              "a b",
+			allowed:            anyKey,
+			expectedPositional: []string{"a", "b"},
+			expectedNamed:      map[string]any{},
+		},
+		{
+			name:               "flag and key=value",
+			input:              "a --loud --lang=ja b",
+			allowed:            anyKey,
+			expectedPositional: []string{"a", "b"},
+			expectedNamed:      map[string]any{"loud": true, "lang": "ja"},
+		},
+		{
+			name:               "key:value",
+			input:              "count:3 名前:ねる",
+			allowed:            anyKey,
+			expectedPositional: []string{},
+			expectedNamed:      map[string]any{"count": "3", "名前": "ねる"},
+		},
+		{
+			name:               "urls stay positional",
+			input:              "https://example.com",
+			allowed:            anyKey,
+			expectedPositional: []string{"https://example.com"},
+			expectedNamed:      map[string]any{},
+		},
+		{
+			name:               "empty value and negative numbers stay positional",
+			input:              "key: -5 --",
+			allowed:            anyKey,
+			expectedPositional: []string{"key:", "-5"},
+			expectedNamed:      map[string]any{},
+		},
+		{
+			name:               "double dash ends named args",
+			input:              "--x -- --y k:v",
+			allowed:            anyKey,
+			expectedPositional: []string{"--y", "k:v"},
+			expectedNamed:      map[string]any{"x": true},
+		},
+		{
+			name:               "quoted tokens stay positional",
+			input:              `"Time: 10" "--x" key:"a b"`,
+			allowed:            anyKey,
+			expectedPositional: []string{"Time: 10", "--x"},
+			expectedNamed:      map[string]any{"key": "a b"},
+		},
+		{
+			name:               "quoted double dash is positional",
+			input:              `"--" --x`,
+			allowed:            anyKey,
+			expectedPositional: []string{"--"},
+			expectedNamed:      map[string]any{"x": true},
+		},
+		{
+			name:               "only allowed keys are named",
+			input:              "count:3 note:hello --loud --x",
+			allowed:            func(key string) bool { return key == "count" || key == "loud" },
+			expectedPositional: []string{"note:hello", "--x"},
+			expectedNamed:      map[string]any{"count": "3", "loud": true},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tokens, err := parser.SplitQuotedTokens(tt.input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			positional, named := parser.ExtractNamedArgs(tokens, tt.allowed)
+			if !reflect.DeepEqual(positional, tt.expectedPositional) {
+				t.Errorf("ExtractNamedArgs(%q) positional = %q, want %q", tt.input, positional, tt.expectedPositional)
+			}
+			if !reflect.DeepEqual(named, tt.expectedNamed) {
+				t.Errorf("ExtractNamedArgs(%q) named = %v, want %v", tt.input, named, tt.expectedNamed)
+			}
+		})
+	}
+}
+
+func TestSplitQuotedTokensQuoted(t *testing.T) {
+	parser := NewCommandParser()
+
+	tokens, err := parser.SplitQuotedTokens(`plain "quoted" 'single' key:"a b" \--x`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []ArgToken{
+		{Text: "plain"},
+		{Text: "quoted", Quoted: true},
+		{Text: "single", Quoted: true},
+		{Text: "key:a b"},
+		{Text: "--x", Quoted: true},
+	}
+	if !reflect.DeepEqual(tokens, expected) {
+		t.Errorf("SplitQuotedTokens() = %+v, want %+v", tokens, expected)
+	}
+}
+
+func TestNamedArgKeys(t *testing.T) {
+	parser := NewCommandParser()
+
+	if allowed := parser.NamedArgKeys("print(args)", nil); allowed != nil {
+		t.Error("NamedArgKeys() without a schema or # named comment should return nil")
+	}
+
+	allowed := parser.NamedArgKeys("# named\nprint(named)", nil)
+	if allowed == nil || !allowed("anything") {
+		t.Error("NamedArgKeys() with # named should allow any key")
+	}
+
+	allowed = parser.NamedArgKeys("print(named)", []ArgOption{{Name: "count", Type: "number"}})
+	if allowed == nil || !allowed("count") || allowed("note") {
+		t.Error("NamedArgKeys() with a schema should allow only the declared keys")
+	}
+}
+
+func TestBindPositionalArgs(t *testing.T) {
+	parser := NewCommandParser()
+	options := []ArgOption{
+		{Name: "count", Type: "number"},
+		{Name: "name", Type: "string"},
+		{Name: "loud", Type: "boolean"},
+	}
+
+	t.Run("binds in declaration order", func(t *testing.T) {
+		named := parser.BindPositionalArgs(options, []string{"3", "ねる"}, nil)
+		expected := map[string]any{"count": "3", "name": "ねる"}
+		if !reflect.DeepEqual(named, expected) {
+			t.Errorf("BindPositionalArgs() = %v, want %v", named, expected)
+		}
+	})
+
+	t.Run("skips explicitly named args", func(t *testing.T) {
+		named := parser.BindPositionalArgs(options, []string{"ねる", "true"}, map[string]any{"count": "5"})
+		expected := map[string]any{"count": "5", "name": "ねる", "loud": "true"}
+		if !reflect.DeepEqual(named, expected) {
+			t.Errorf("BindPositionalArgs() = %v, want %v", named, expected)
+		}
+	})
+}
+
+func TestExtractMetadataFromComment(t *testing.T) {
+	parser := NewCommandParser()
+
+	tests := []struct {
+		name     string
+		code     string
+		expected CommandMetadata
+	}{
+		{
+			name: "all headers",
+			code: "# description: サイコロを振る\n# usage: !dice [面の数]\n# tags: game, random、fun\n# category: あそび\nprint(1)",
+			expected: CommandMetadata{
+				Description: "サイコロを振る",
+				Usage:       "!dice [面の数]",
+				Tags:        []string{"game", "random", "fun"},
+				Category:    "あそび",
+			},
+		},
+		{
+			name:     "equals sign and case-insensitive key",
+			code:     "# Description = hello\nprint(1)",
+			expected: CommandMetadata{Description: "hello"},
+		},
+		{
+			name:     "first header wins",
+			code:     "# description: first\n# description: second",
+			expected: CommandMetadata{Description: "first"},
+		},
+		{
+			name:     "args comment is not metadata",
+			code:     "# args = []\nprint(1)",
+			expected: CommandMetadata{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := parser.ExtractMetadataFromComment(tt.code)
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("ExtractMetadataFromComment() = %+v, want %+v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestParseTags(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{input: "a, b ,c", expected: []string{"a", "b", "c"}},
+		{input: "あそび、ゲーム", expected: []string{"あそび", "ゲーム"}},
+		{input: "a, a, , b", expected: []string{"a", "b"}},
+		{input: "", expected: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			if got := ParseTags(tt.input); !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("ParseTags(%q) = %q, want %q", tt.input, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestFormatCommandMetadata(t *testing.T) {
+	metadata := CommandMetadata{
+		Description: "サイコロを振る",
+		Tags:        []string{"game", "random"},
+	}
+	expected := "説明: サイコロを振る\nタグ: game, random"
+	if got := FormatCommandMetadata(metadata); got != expected {
+		t.Errorf("FormatCommandMetadata() = %q, want %q", got, expected)
+	}
+}
+
+func TestParseCodeCommandArgs(t *testing.T) {
+	n := &Nelchan{CommandParser: NewCommandParser()}
+
+	t.Run("commands without named args keep every token", func(t *testing.T) {
+		info := &GetCommandInfo{Name: "echo", Content: "print(args)"}
+		positional, named, err := n.parseCodeCommandArgs(info, "note:hello --x")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(positional, []string{"note:hello", "--x"}) || len(named) != 0 {
+			t.Errorf("parseCodeCommandArgs() = %q, %v, want every token positional", positional, named)
+		}
+	})
+
+	t.Run("quoted text is never a named arg", func(t *testing.T) {
+		info := &GetCommandInfo{Name: "say", Content: "# parse = quoted\n# named\nprint(args)"}
+		positional, named, err := n.parseCodeCommandArgs(info, `"Time: 10" lang:ja`)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(positional, []string{"Time: 10"}) || !reflect.DeepEqual(named, map[string]any{"lang": "ja"}) {
+			t.Errorf("parseCodeCommandArgs() = %q, %v", positional, named)
+		}
+	})
+
+	t.Run("schema keys are named and bound", func(t *testing.T) {
+		info := &GetCommandInfo{Name: "count", Content: "print(named)", ArgsSchema: []ArgOption{{Name: "count", Type: "number"}}}
+		positional, named, err := n.parseCodeCommandArgs(info, "note:hello count:3")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(positional, []string{"note:hello"}) || !reflect.DeepEqual(named, map[string]any{"count": "3"}) {
+			t.Errorf("parseCodeCommandArgs() = %q, %v", positional, named)
+		}
+	})
+}
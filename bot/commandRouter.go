@@ -3,6 +3,7 @@ package nelchanbot
 import (
 	"fmt"
 	"strings"
+	"sync"
 
 	"github.com/bwmarrin/discordgo"
 )
@@ -13,14 +14,27 @@ type CommandHandler func(s *discordgo.Session, m *discordgo.MessageCreate, cmd *
 // MentionHandler is the function signature for mention handlers
 type MentionHandler func(s *discordgo.Session, m *discordgo.MessageCreate, args string)
 
+// BuiltinCommand describes a built-in command for the help catalog
+type BuiltinCommand struct {
+	Name        string   // Command name without the prefix
+	Aliases     []string // Other names routed to the same handler
+	Usage       string   // e.g. "!show <コマンド名>"
+	Description string
+}
+
 // CommandRouter handles routing of commands to their respective handlers
 type CommandRouter struct {
 	parser              *CommandParser
 	apiClient           *CommandAPIClient
 	commands            map[string]CommandHandler
+	builtins            []BuiltinCommand
 	codeFallbackHandler CommandHandler
 	textFallbackHandler CommandHandler
 	mentionHandler      MentionHandler
+
+	// aliases maps user defined aliases to registered command names
+	aliasesMu sync.RWMutex
+	aliases   map[string]string
 }
 
 // NewCommandRouter creates a new CommandRouter instance
@@ -29,6 +43,7 @@ func NewCommandRouter(parser *CommandParser, apiClient *CommandAPIClient) *Comma
 		parser:    parser,
 		apiClient: apiClient,
 		commands:  make(map[string]CommandHandler),
+		aliases:   make(map[string]string),
 	}
 }
 
@@ -38,6 +53,73 @@ func (r *CommandRouter) AddCommand(name string, handler CommandHandler) *Command
 	return r
 }
 
+// AddBuiltin registers a described built-in command handler under its name and aliases
+func (r *CommandRouter) AddBuiltin(cmd BuiltinCommand, handler CommandHandler) *CommandRouter {
+	r.builtins = append(r.builtins, cmd)
+	r.AddCommand(cmd.Name, handler)
+	for _, alias := range cmd.Aliases {
+		r.AddCommand(alias, handler)
+	}
+	return r
+}
+
+// Builtins returns the described built-in commands in registration order
+func (r *CommandRouter) Builtins() []BuiltinCommand {
+	return r.builtins
+}
+
+// FindBuiltin returns the built-in command with the given name or alias
+func (r *CommandRouter) FindBuiltin(name string) (BuiltinCommand, bool) {
+	for _, cmd := range r.builtins {
+		if cmd.Name == name {
+			return cmd, true
+		}
+		for _, alias := range cmd.Aliases {
+			if alias == name {
+				return cmd, true
+			}
+		}
+	}
+	return BuiltinCommand{}, false
+}
+
+// HasCommand reports whether a built-in command handler is registered for the given name
+func (r *CommandRouter) HasCommand(name string) bool {
+	_, exists := r.commands[name]
+	return exists
+}
+
+// SetAliases replaces the user defined aliases with the given alias to command name mapping
+func (r *CommandRouter) SetAliases(aliases map[string]string) {
+	r.aliasesMu.Lock()
+	defer r.aliasesMu.Unlock()
+	r.aliases = aliases
+}
+
+// SetAlias adds or replaces a single user defined alias
+func (r *CommandRouter) SetAlias(alias, commandName string) {
+	r.aliasesMu.Lock()
+	defer r.aliasesMu.Unlock()
+	r.aliases[alias] = commandName
+}
+
+// RemoveAlias removes a single user defined alias
+func (r *CommandRouter) RemoveAlias(alias string) {
+	r.aliasesMu.Lock()
+	defer r.aliasesMu.Unlock()
+	delete(r.aliases, alias)
+}
+
+// ResolveAlias returns the command name an alias points to, or the name itself if it isn't an alias
+func (r *CommandRouter) ResolveAlias(name string) string {
+	r.aliasesMu.RLock()
+	defer r.aliasesMu.RUnlock()
+	if target, exists := r.aliases[name]; exists {
+		return target
+	}
+	return name
+}
+
 // SetCodeFallback sets the fallback handler for code commands (! prefix) that are not registered
 func (r *CommandRouter) SetCodeFallback(handler CommandHandler) *CommandRouter {
 	r.codeFallbackHandler = handler
@@ -123,6 +205,9 @@ func (r *CommandRouter) handleCodeCommand(s *discordgo.Session, m *discordgo.Mes
 		return
 	}
 
+	// Built-in commands take precedence over user defined aliases
+	cmd.Name = r.ResolveAlias(cmd.Name)
+
 	// Use fallback handler for unregistered code commands
 	if r.codeFallbackHandler != nil {
 		r.codeFallbackHandler(s, m, cmd)
@@ -144,7 +229,7 @@ func (r *CommandRouter) handleTextCommand(s *discordgo.Session, m *discordgo.Mes
 	// Create a SlashCommand-like structure for text commands
 	parts := strings.SplitN(content, " ", 2)
 	cmd := &SlashCommand{
-		Name: parts[0],
+		Name: r.ResolveAlias(parts[0]),
 		Args: []string{},
 	}
 	if len(parts) > 1 {
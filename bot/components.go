@@ -0,0 +1,84 @@
+package nelchanbot
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// handleComponent handles message component interactions such as button clicks
+// Components are stateless: the custom_id is "<prefix>:<args...>" and carries everything needed to respond
+func (n *Nelchan) handleComponent(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	customID := i.MessageComponentData().CustomID
+	prefix, rest, _ := strings.Cut(customID, ":")
+	args := strings.Split(rest, ":")
+
+	switch prefix {
+	case helpComponentPrefix:
+		n.handleHelpComponent(s, i, args)
+	case listComponentPrefix:
+		n.handleListComponent(s, i, args)
+	case searchComponentPrefix:
+		n.handleSearchComponent(s, i, args)
+	case runButtonComponentPrefix:
+		n.handleRunButton(s, i, args)
+	default:
+		fmt.Printf("unknown component: %s\n", customID)
+	}
+}
+
+// handleModalSubmit handles submitted modals
+// Like components, the custom_id is "<prefix>:<args...>"
+func (n *Nelchan) handleModalSubmit(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	customID := i.ModalSubmitData().CustomID
+	prefix, rest, _ := strings.Cut(customID, ":")
+	args := strings.Split(rest, ":")
+
+	switch prefix {
+	case messageRunModalPrefix:
+		n.handleMessageRunModal(s, i, args)
+	default:
+		fmt.Printf("unknown modal: %s\n", customID)
+	}
+}
+
+// paginationButtons builds previous/next buttons for a paginated message
+// Returns nil when everything fits on one page
+func paginationButtons(customIDPrefix string, page, pageCount int) []discordgo.MessageComponent {
+	if pageCount <= 1 {
+		return nil
+	}
+
+	return []discordgo.MessageComponent{
+		discordgo.ActionsRow{
+			Components: []discordgo.MessageComponent{
+				discordgo.Button{
+					Label:    "◀ 前へ",
+					Style:    discordgo.SecondaryButton,
+					CustomID: fmt.Sprintf("%s:%d", customIDPrefix, page-1),
+					Disabled: page <= 0,
+				},
+				discordgo.Button{
+					Label:    "次へ ▶",
+					Style:    discordgo.SecondaryButton,
+					CustomID: fmt.Sprintf("%s:%d", customIDPrefix, page+1),
+					Disabled: page >= pageCount-1,
+				},
+			},
+		},
+	}
+}
+
+// pageCount returns the number of pages needed for itemCount items
+func pageCount(itemCount, pageSize int) int {
+	if itemCount == 0 {
+		return 1
+	}
+	return (itemCount + pageSize - 1) / pageSize
+}
+
+// clampPage keeps a page index within [0, pages)
+func clampPage(page, pages int) int {
+	return max(0, min(page, pages-1))
+}
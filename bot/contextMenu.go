@@ -0,0 +1,156 @@
+package nelchanbot
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// messageRunCommandName is the message context menu that runs a code command on the chosen message
+const messageRunCommandName = "ねるちゃんで実行"
+
+// messageRunModalPrefix is the custom_id prefix of the modal asking which command to run
+// The custom_id is "runmsg:<message_id>"
+const messageRunModalPrefix = "runmsg"
+
+// Text inputs of the message run modal
+const (
+	messageRunCommandInput = "command"
+	messageRunArgsInput    = "args"
+)
+
+// handleMessageRunCommand handles the message context menu by asking which command to run on the message
+func (n *Nelchan) handleMessageRunCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	data := i.ApplicationCommandData()
+
+	err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseModal,
+		Data: &discordgo.InteractionResponseData{
+			CustomID: fmt.Sprintf("%s:%s", messageRunModalPrefix, data.TargetID),
+			Title:    messageRunCommandName,
+			Components: []discordgo.MessageComponent{
+				discordgo.ActionsRow{
+					Components: []discordgo.MessageComponent{
+						discordgo.TextInput{
+							CustomID:  messageRunCommandInput,
+							Label:     "コマンド名",
+							Style:     discordgo.TextInputShort,
+							Required:  true,
+							MaxLength: 100,
+						},
+					},
+				},
+				discordgo.ActionsRow{
+					Components: []discordgo.MessageComponent{
+						discordgo.TextInput{
+							CustomID:  messageRunArgsInput,
+							Label:     "引数",
+							Style:     discordgo.TextInputParagraph,
+							MaxLength: 1000,
+						},
+					},
+				},
+			},
+		},
+	})
+	if err != nil {
+		fmt.Printf("error showing message run modal: %v\n", err)
+	}
+}
+
+// modalValue returns the value of a text input of a submitted modal
+func modalValue(data discordgo.ModalSubmitInteractionData, customID string) string {
+	for _, component := range data.Components {
+		row, ok := component.(*discordgo.ActionsRow)
+		if !ok {
+			continue
+		}
+		for _, c := range row.Components {
+			if input, ok := c.(*discordgo.TextInput); ok && input.CustomID == customID {
+				return input.Value
+			}
+		}
+	}
+	return ""
+}
+
+// handleMessageRunModal runs the code command entered in the message run modal
+// The chosen message is passed to the command as the replied-to message
+func (n *Nelchan) handleMessageRunModal(s *discordgo.Session, i *discordgo.InteractionCreate, args []string) {
+	messageID := args[0]
+	data := i.ModalSubmitData()
+	commandName := strings.TrimSpace(modalValue(data, messageRunCommandInput))
+	raw := modalValue(data, messageRunArgsInput)
+	if messageID == "" || commandName == "" {
+		return
+	}
+
+	err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
+	})
+	if err != nil {
+		fmt.Printf("error deferring interaction response: %v\n", err)
+		return
+	}
+
+	target, err := s.ChannelMessage(i.ChannelID, messageID)
+	if err != nil {
+		fmt.Printf("error getting message %s: %v\n", messageID, err)
+		editInteractionContent(s, i, "対象のメッセージを取得できませんでした")
+		return
+	}
+
+	commandName = n.CommandRouter.ResolveAlias(commandName)
+	info := n.lookupCodeCommand(commandName)
+	if info == nil {
+		editInteractionContent(s, i, fmt.Sprintf("コードコマンド「%s」は見つかりませんでした", commandName))
+		return
+	}
+
+	positional, named, err := n.parseCodeCommandArgs(info, raw)
+	if err != nil {
+		editInteractionContent(s, i, fmt.Sprintf("引数の解析に失敗しました: %s", err.Error()))
+		return
+	}
+	schema := n.commandArgsSchema(info)
+	named, err = ResolveArgs(schema, BindAttachmentArgs(schema, target.Attachments, named))
+	if err != nil {
+		editInteractionContent(s, i, fmt.Sprintf("引数が正しくありません:\n%s\n\n%s", err.Error(), FormatArgsUsage(info.Name, schema)))
+		return
+	}
+
+	user := interactionUser(i)
+	ctx := interactionContext(s, RunSourceContextMenu, i)
+	budget := maxCommandAttachmentTotalBytes
+	request := RunCommandRequest{
+		CommandName: commandName,
+		IsCode:      true,
+		Vars:        ctx.Vars(),
+		Args:        positional,
+		Named:       named,
+		Replied:     NewRepliedMessage(target, &budget, downloadAttachment),
+		Context:     &ctx,
+	}
+
+	start := time.Now()
+	result, err := n.CommandAPIClient.RunCommand(request)
+	n.recordCommandRun(CommandRun{
+		CommandName: commandName,
+		UserID:      user.ID,
+		ChannelID:   i.ChannelID,
+		GuildID:     i.GuildID,
+		Source:      RunSourceContextMenu,
+	}, start, result, err)
+
+	switch {
+	case err != nil:
+		fmt.Printf("error running command %s on message %s: %v\n", commandName, messageID, err)
+		editInteractionContent(s, i, fmt.Sprintf("エラー: %s", err.Error()))
+	case result == nil:
+		editInteractionContent(s, i, fmt.Sprintf("コードコマンド「%s」は見つかりませんでした", commandName))
+	default:
+		n.editInteractionOutput(s, i, result)
+	}
+}
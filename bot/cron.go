@@ -0,0 +1,187 @@
+package nelchanbot
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// cronSearchYears bounds the search for the next run of a schedule that can never match, such as "0 0 30 2 *"
+const cronSearchYears = 5
+
+// cronMacros are the shorthand expressions accepted in place of five fields
+var cronMacros = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// cronField describes the range and names of one field of a cron expression
+type cronField struct {
+	label    string
+	min, max int
+	names    map[string]int
+}
+
+var cronFields = []cronField{
+	{label: "分", min: 0, max: 59},
+	{label: "時", min: 0, max: 23},
+	{label: "日", min: 1, max: 31},
+	{label: "月", min: 1, max: 12, names: map[string]int{
+		"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+		"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+	}},
+	// 7 is accepted as Sunday and folded into 0
+	{label: "曜日", min: 0, max: 7, names: map[string]int{
+		"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+	}},
+}
+
+// CronSchedule is a parsed five field cron expression: minute hour day-of-month month day-of-week
+type CronSchedule struct {
+	minute, hour, dom, month, dow uint64 // bit n is set when value n matches
+
+	// Like Vixie cron, when both day fields are restricted a day matches if either does
+	domRestricted, dowRestricted bool
+}
+
+// ParseCron parses a cron expression such as "0 9 * * 1-5", "*/15 * * * *" or "@daily"
+// Each field accepts "*", numbers, ranges "a-b", steps "*/n" or "a-b/n" and comma separated lists;
+// months and weekdays also accept English abbreviations ("jan", "mon")
+func ParseCron(expr string) (*CronSchedule, error) {
+	expr = strings.TrimSpace(strings.ToLower(expr))
+	if macro, ok := cronMacros[expr]; ok {
+		expr = macro
+	}
+
+	parts := strings.Fields(expr)
+	if len(parts) != len(cronFields) {
+		return nil, fmt.Errorf("cron 式は「分 時 日 月 曜日」の 5 つの項目で指定してください: %q", expr)
+	}
+
+	var bits [5]uint64
+	for idx, part := range parts {
+		b, err := parseCronField(part, cronFields[idx])
+		if err != nil {
+			return nil, err
+		}
+		bits[idx] = b
+	}
+
+	// Fold Sunday as 7 into 0
+	if bits[4]&(1<<7) != 0 {
+		bits[4] = bits[4]&^(1<<7) | 1
+	}
+
+	return &CronSchedule{
+		minute:        bits[0],
+		hour:          bits[1],
+		dom:           bits[2],
+		month:         bits[3],
+		dow:           bits[4],
+		domRestricted: parts[2] != "*",
+		dowRestricted: parts[4] != "*",
+	}, nil
+}
+
+// parseCronField parses one field into a bit set of matching values
+func parseCronField(field string, spec cronField) (uint64, error) {
+	var bits uint64
+	for _, item := range strings.Split(field, ",") {
+		rangePart, stepPart, hasStep := strings.Cut(item, "/")
+
+		step := 1
+		if hasStep {
+			n, err := strconv.Atoi(stepPart)
+			if err != nil || n <= 0 {
+				return 0, fmt.Errorf("%sの間隔が不正です: %q", spec.label, item)
+			}
+			step = n
+		}
+
+		var start, end int
+		switch {
+		case rangePart == "*":
+			start, end = spec.min, spec.max
+		case strings.Contains(rangePart, "-"):
+			from, to, _ := strings.Cut(rangePart, "-")
+			var err error
+			if start, err = parseCronValue(from, spec); err != nil {
+				return 0, err
+			}
+			if end, err = parseCronValue(to, spec); err != nil {
+				return 0, err
+			}
+			if start > end {
+				return 0, fmt.Errorf("%sの範囲が不正です: %q", spec.label, item)
+			}
+		default:
+			value, err := parseCronValue(rangePart, spec)
+			if err != nil {
+				return 0, err
+			}
+			start, end = value, value
+			// "5/15" means every 15 starting at 5
+			if hasStep {
+				end = spec.max
+			}
+		}
+
+		for v := start; v <= end; v += step {
+			bits |= 1 << v
+		}
+	}
+	return bits, nil
+}
+
+// parseCronValue parses a number or name of a field and checks its range
+func parseCronValue(s string, spec cronField) (int, error) {
+	if value, ok := spec.names[s]; ok {
+		return value, nil
+	}
+	value, err := strconv.Atoi(s)
+	if err != nil || value < spec.min || value > spec.max {
+		return 0, fmt.Errorf("%sは %d〜%d で指定してください: %q", spec.label, spec.min, spec.max, s)
+	}
+	return value, nil
+}
+
+// Next returns the first time after t that matches the schedule, in t's location
+// Returns the zero time if nothing matches within the next few years
+func (c *CronSchedule) Next(t time.Time) time.Time {
+	loc := t.Location()
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(cronSearchYears, 0, 0)
+
+	// Skip whole months, days and hours that can't match before checking minutes
+	for t.Before(limit) {
+		switch {
+		case c.month&(1<<uint(t.Month())) == 0:
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, loc)
+		case !c.matchDay(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, loc)
+		case c.hour&(1<<uint(t.Hour())) == 0:
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, loc)
+		case c.minute&(1<<uint(t.Minute())) == 0:
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// matchDay reports whether the day of t matches the day-of-month and day-of-week fields
+func (c *CronSchedule) matchDay(t time.Time) bool {
+	domMatch := c.dom&(1<<uint(t.Day())) != 0
+	dowMatch := c.dow&(1<<uint(t.Weekday())) != 0
+	if c.domRestricted && c.dowRestricted {
+		return domMatch || dowMatch
+	}
+	return domMatch && dowMatch
+}
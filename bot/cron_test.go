@@ -0,0 +1,93 @@
+package nelchanbot
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCronScheduleNext(t *testing.T) {
+	tokyo, err := time.LoadLocation("Asia/Tokyo")
+	if err != nil {
+		t.Fatalf("LoadLocation() error = %v", err)
+	}
+	// Sunday
+	base := time.Date(2026, 10, 18, 8, 30, 15, 0, tokyo)
+
+	tests := []struct {
+		expr string
+		want time.Time
+	}{
+		{"* * * * *", time.Date(2026, 10, 18, 8, 31, 0, 0, tokyo)},
+		{"*/15 * * * *", time.Date(2026, 10, 18, 8, 45, 0, 0, tokyo)},
+		{"5/20 * * * *", time.Date(2026, 10, 18, 8, 45, 0, 0, tokyo)},
+		{"30 8 * * *", time.Date(2026, 10, 19, 8, 30, 0, 0, tokyo)},
+		{"0 9 * * 1-5", time.Date(2026, 10, 19, 9, 0, 0, 0, tokyo)},
+		{"0 9 * * sat,sun", time.Date(2026, 10, 18, 9, 0, 0, 0, tokyo)},
+		{"0 0 * * 7", time.Date(2026, 10, 25, 0, 0, 0, 0, tokyo)},
+		{"0 12 1 * *", time.Date(2026, 11, 1, 12, 0, 0, 0, tokyo)},
+		{"0 0 29 2 *", time.Date(2028, 2, 29, 0, 0, 0, 0, tokyo)},
+		{"0 0 1 jan *", time.Date(2027, 1, 1, 0, 0, 0, 0, tokyo)},
+		// Both day fields restricted: either matches
+		{"0 0 13 * 5", time.Date(2026, 10, 23, 0, 0, 0, 0, tokyo)},
+		{"@hourly", time.Date(2026, 10, 18, 9, 0, 0, 0, tokyo)},
+		{"@daily", time.Date(2026, 10, 19, 0, 0, 0, 0, tokyo)},
+		{"@weekly", time.Date(2026, 10, 25, 0, 0, 0, 0, tokyo)},
+		{"@monthly", time.Date(2026, 11, 1, 0, 0, 0, 0, tokyo)},
+		{"0 0 30 2 *", time.Time{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			cron, err := ParseCron(tt.expr)
+			if err != nil {
+				t.Fatalf("ParseCron() error = %v", err)
+			}
+			if got := cron.Next(base); !got.Equal(tt.want) {
+				t.Errorf("Next() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCronScheduleNextHalfHourOffset(t *testing.T) {
+	kolkata, err := time.LoadLocation("Asia/Kolkata")
+	if err != nil {
+		t.Fatalf("LoadLocation() error = %v", err)
+	}
+
+	cron, err := ParseCron("0 11 * * *")
+	if err != nil {
+		t.Fatalf("ParseCron() error = %v", err)
+	}
+	want := time.Date(2026, 10, 18, 11, 0, 0, 0, kolkata)
+	if got := cron.Next(time.Date(2026, 10, 18, 10, 15, 0, 0, kolkata)); !got.Equal(want) {
+		t.Errorf("Next() = %v, want %v", got, want)
+	}
+}
+
+func TestParseCronInvalid(t *testing.T) {
+	tests := []struct {
+		expr    string
+		wantErr string
+	}{
+		{"* * * *", "5 つの項目"},
+		{"60 * * * *", "分は 0〜59"},
+		{"* 24 * * *", "時は 0〜23"},
+		{"* * 0 * *", "日は 1〜31"},
+		{"* * * 13 *", "月は 1〜12"},
+		{"* * * * 8", "曜日は 0〜7"},
+		{"*/0 * * * *", "間隔が不正"},
+		{"10-5 * * * *", "範囲が不正"},
+		{"* * * foo *", "月は 1〜12"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			_, err := ParseCron(tt.expr)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ParseCron() error = %v, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,199 @@
+package nelchanbot
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const (
+	// diffContextLines is the number of unchanged lines shown around each change
+	diffContextLines = 3
+	// maxDiffCells limits the LCS table of the changed lines, about 16 MB
+	maxDiffCells = 4 << 20
+)
+
+// ErrDiffTooLarge is returned when the changed parts of two texts are too long to diff
+var ErrDiffTooLarge = errors.New("差分が大きすぎるため表示できません")
+
+// diffOp is a single line of an edit script
+type diffOp struct {
+	kind byte // ' ' for unchanged, '-' for removed, '+' for added
+	text string
+}
+
+// UnifiedDiff renders the changes from one text to another in unified diff format
+// Returns an empty string when the texts are identical, and ErrDiffTooLarge when the changes are too long to diff
+// Example:
+//
+//	--- greet v1
+//	+++ greet v2
+//	@@ -1,2 +1,2 @@
+//	-print("hello")
+//	+print("hi")
+//	 print("bye")
+func UnifiedDiff(fromName, toName, from, to string) (string, error) {
+	if from == to {
+		return "", nil
+	}
+
+	ops, err := diffLines(splitLines(from), splitLines(to))
+	if err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "--- %s\n+++ %s\n", fromName, toName)
+	for _, hunk := range diffHunks(ops, diffContextLines) {
+		writeHunk(&b, ops, hunk)
+	}
+	return b.String(), nil
+}
+
+// splitLines splits text into lines, ignoring a single trailing newline
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
+
+// diffLines computes a line based edit script using the longest common subsequence
+// Unchanged lines at the start and end are matched first, so only the changed middle needs the LCS table
+func diffLines(a, b []string) ([]diffOp, error) {
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	ops := make([]diffOp, 0, len(a)+len(b)-prefix-suffix)
+	for _, line := range a[:prefix] {
+		ops = append(ops, diffOp{' ', line})
+	}
+	middle, err := diffLinesLCS(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix])
+	if err != nil {
+		return nil, err
+	}
+	ops = append(ops, middle...)
+	for _, line := range a[len(a)-suffix:] {
+		ops = append(ops, diffOp{' ', line})
+	}
+	return ops, nil
+}
+
+// diffLinesLCS computes the edit script of a and b from their LCS table
+// Returns ErrDiffTooLarge instead of building a table with more than maxDiffCells cells
+func diffLinesLCS(a, b []string) ([]diffOp, error) {
+	if (len(a)+1)*(len(b)+1) > maxDiffCells {
+		return nil, ErrDiffTooLarge
+	}
+
+	// lcs[i][j] is the length of the LCS of a[i:] and b[j:]
+	lcs := make([][]int32, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int32, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	ops := make([]diffOp, 0, len(a)+len(b))
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			ops = append(ops, diffOp{' ', a[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			ops = append(ops, diffOp{'-', a[i]})
+			i++
+		default:
+			ops = append(ops, diffOp{'+', b[j]})
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		ops = append(ops, diffOp{'-', a[i]})
+	}
+	for ; j < len(b); j++ {
+		ops = append(ops, diffOp{'+', b[j]})
+	}
+	return ops, nil
+}
+
+// diffHunk is a range of ops [start, end) shown together
+type diffHunk struct {
+	start, end int
+}
+
+// diffHunks groups the changes of an edit script into hunks with surrounding context
+// Changes closer than twice the context are merged into a single hunk
+func diffHunks(ops []diffOp, context int) []diffHunk {
+	var hunks []diffHunk
+	for idx, op := range ops {
+		if op.kind == ' ' {
+			continue
+		}
+		start := max(idx-context, 0)
+		end := min(idx+context+1, len(ops))
+		if len(hunks) > 0 && start <= hunks[len(hunks)-1].end {
+			hunks[len(hunks)-1].end = end
+			continue
+		}
+		hunks = append(hunks, diffHunk{start, end})
+	}
+	return hunks
+}
+
+// writeHunk writes a hunk header followed by its lines
+func writeHunk(b *strings.Builder, ops []diffOp, hunk diffHunk) {
+	// Line numbers of the first line of the hunk in each text
+	fromLine, toLine := 1, 1
+	for _, op := range ops[:hunk.start] {
+		if op.kind != '+' {
+			fromLine++
+		}
+		if op.kind != '-' {
+			toLine++
+		}
+	}
+
+	fromCount, toCount := 0, 0
+	for _, op := range ops[hunk.start:hunk.end] {
+		if op.kind != '+' {
+			fromCount++
+		}
+		if op.kind != '-' {
+			toCount++
+		}
+	}
+
+	fmt.Fprintf(b, "@@ -%s +%s @@\n", hunkRange(fromLine, fromCount), hunkRange(toLine, toCount))
+	for _, op := range ops[hunk.start:hunk.end] {
+		b.WriteByte(op.kind)
+		b.WriteString(op.text)
+		b.WriteByte('\n')
+	}
+}
+
+// hunkRange formats the start and length of a hunk side, using the line before for empty ranges
+func hunkRange(start, count int) string {
+	switch count {
+	case 0:
+		return fmt.Sprintf("%d,0", start-1)
+	case 1:
+		return fmt.Sprintf("%d", start)
+	}
+	return fmt.Sprintf("%d,%d", start, count)
+}
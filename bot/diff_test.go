@@ -0,0 +1,113 @@
+package nelchanbot
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestUnifiedDiff(t *testing.T) {
+	tests := []struct {
+		name string
+		from string
+		to   string
+		want string
+	}{
+		{
+			name: "identical",
+			from: "a\nb\n",
+			to:   "a\nb\n",
+			want: "",
+		},
+		{
+			name: "changed line",
+			from: "print(\"hello\")\nprint(\"bye\")",
+			to:   "print(\"hi\")\nprint(\"bye\")",
+			want: "--- old\n+++ new\n" +
+				"@@ -1,2 +1,2 @@\n" +
+				"-print(\"hello\")\n" +
+				"+print(\"hi\")\n" +
+				" print(\"bye\")\n",
+		},
+		{
+			name: "added to empty",
+			from: "",
+			to:   "a\nb",
+			want: "--- old\n+++ new\n" +
+				"@@ -0,0 +1,2 @@\n" +
+				"+a\n" +
+				"+b\n",
+		},
+		{
+			name: "separate hunks",
+			from: "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12",
+			to:   "one\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\ntwelve",
+			want: "--- old\n+++ new\n" +
+				"@@ -1,4 +1,4 @@\n" +
+				"-1\n" +
+				"+one\n" +
+				" 2\n" +
+				" 3\n" +
+				" 4\n" +
+				"@@ -9,4 +9,4 @@\n" +
+				" 9\n" +
+				" 10\n" +
+				" 11\n" +
+				"-12\n" +
+				"+twelve\n",
+		},
+		{
+			name: "nearby changes merge",
+			from: "a\nb\nc\nd\ne",
+			to:   "a\nB\nc\nD\ne",
+			want: "--- old\n+++ new\n" +
+				"@@ -1,5 +1,5 @@\n" +
+				" a\n" +
+				"-b\n" +
+				"+B\n" +
+				" c\n" +
+				"-d\n" +
+				"+D\n" +
+				" e\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := UnifiedDiff("old", "new", tt.from, tt.to)
+			if err != nil {
+				t.Fatalf("UnifiedDiff() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("UnifiedDiff() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUnifiedDiffLargeTexts(t *testing.T) {
+	lines := func(prefix string, n int) string {
+		var b strings.Builder
+		for i := range n {
+			fmt.Fprintf(&b, "%s%d\n", prefix, i)
+		}
+		return b.String()
+	}
+
+	// A small change in a long text only diffs the changed lines
+	from := lines("a", 50000)
+	to := strings.Replace(from, "a25000\n", "b25000\n", 1)
+	got, err := UnifiedDiff("old", "new", from, to)
+	if err != nil {
+		t.Fatalf("UnifiedDiff() error = %v", err)
+	}
+	if !strings.Contains(got, "@@ -24998,7 +24998,7 @@\n") || !strings.Contains(got, "-a25000\n+b25000\n") {
+		t.Errorf("UnifiedDiff() =\n%s", got)
+	}
+
+	// Texts changed everywhere are refused instead of building a huge table
+	if _, err := UnifiedDiff("old", "new", from, lines("b", 50000)); !errors.Is(err, ErrDiffTooLarge) {
+		t.Errorf("UnifiedDiff() error = %v, want ErrDiffTooLarge", err)
+	}
+}
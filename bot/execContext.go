@@ -0,0 +1,240 @@
+package nelchanbot
+
+import (
+	"cmp"
+	"math/rand/v2"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// ExecutionContext describes who ran a code command, where and how
+// It's built the same way for every entry point and passed to the code as the dict ctx
+type ExecutionContext struct {
+	// ID of the invoking message or interaction; generated in the same format for schedules and events
+	InvocationID string          `json:"invocation_id"`
+	Source       string          `json:"source"`     // one of the RunSource constants
+	MessageID    string          `json:"message_id"` // invoking message, or the message with the clicked button
+	Locale       string          `json:"locale"`     // language of the user's client, interactions only
+	User         ContextUser     `json:"user"`
+	Member       *ContextMember  `json:"member"` // nil outside guilds or when the member isn't known
+	Guild        *ContextGuild   `json:"guild"`  // nil in DMs
+	Channel      ContextChannel  `json:"channel"`
+	Mentions     ContextMentions `json:"mentions"` // mentions in the invoking message
+}
+
+// ContextUser is a user in an execution context
+type ContextUser struct {
+	ID          string `json:"id"`
+	Username    string `json:"username"`
+	GlobalName  string `json:"global_name"`
+	DisplayName string `json:"display_name"` // nickname, global name or username, whichever is set first
+	Avatar      string `json:"avatar"`
+	Bot         bool   `json:"bot"`
+}
+
+// ContextMember is the guild member who ran a command
+type ContextMember struct {
+	Nickname string        `json:"nickname"`
+	Roles    []ContextRole `json:"roles"`
+	JoinedAt string        `json:"joined_at"` // RFC 3339, empty when unknown
+}
+
+// ContextRole is a role of the member who ran a command
+type ContextRole struct {
+	ID   string `json:"id"`
+	Name string `json:"name"` // empty when the guild isn't cached
+}
+
+// ContextGuild is the guild a command ran in
+type ContextGuild struct {
+	ID      string `json:"id"`
+	Name    string `json:"name"`
+	Locale  string `json:"locale"`
+	OwnerID string `json:"owner_id"`
+}
+
+// ContextChannel is the channel a command ran in
+type ContextChannel struct {
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	Type     int    `json:"type"`
+	ParentID string `json:"parent_id"` // category, or the parent channel of a thread
+}
+
+// ContextMentions are the mentions in the invoking message
+type ContextMentions struct {
+	Users    []ContextUser `json:"users"`
+	Roles    []string      `json:"roles"`
+	Channels []string      `json:"channels"`
+	Everyone bool          `json:"everyone"`
+}
+
+// discordEpoch is the start of snowflake timestamps in Unix milliseconds
+const discordEpoch = 1420070400000
+
+// channelMentionInTextRe matches channel mentions anywhere in a message
+var channelMentionInTextRe = regexp.MustCompile(`<#(\d+)>`)
+
+// newInvocationID returns a snowflake-like ID for runs without an invoking message or interaction
+func newInvocationID(now time.Time) string {
+	return strconv.FormatInt((now.UnixMilli()-discordEpoch)<<22|rand.Int64N(1<<22), 10)
+}
+
+// Vars returns the context as the variables defined in the code
+// Every key is always present so that code can use them without checking
+func (c *ExecutionContext) Vars() map[string]string {
+	vars := map[string]string{
+		"invocation_id": c.InvocationID,
+		"source":        c.Source,
+		"username":      cmp.Or(c.User.GlobalName, c.User.Username),
+		"user_id":       c.User.ID,
+		"user_avatar":   c.User.Avatar,
+		"display_name":  c.User.DisplayName,
+		"nickname":      "",
+		"guild_id":      "",
+		"guild_name":    "",
+		"channel_id":    c.Channel.ID,
+		"channel_name":  c.Channel.Name,
+		"message_id":    c.MessageID,
+		"locale":        c.Locale,
+	}
+	if c.Member != nil {
+		vars["nickname"] = c.Member.Nickname
+	}
+	if c.Guild != nil {
+		vars["guild_id"] = c.Guild.ID
+		vars["guild_name"] = c.Guild.Name
+	}
+	return vars
+}
+
+// newContextUser converts a user, using the nickname of member for the display name
+func newContextUser(user *discordgo.User, member *discordgo.Member) ContextUser {
+	if user == nil {
+		return ContextUser{}
+	}
+	nickname := ""
+	if member != nil {
+		nickname = member.Nick
+	}
+	return ContextUser{
+		ID:          user.ID,
+		Username:    user.Username,
+		GlobalName:  user.GlobalName,
+		DisplayName: cmp.Or(nickname, user.GlobalName, user.Username),
+		Avatar:      user.Avatar,
+		Bot:         user.Bot,
+	}
+}
+
+// newContextMember converts a member, naming its roles from guild when it's cached
+func newContextMember(member *discordgo.Member, guild *discordgo.Guild) *ContextMember {
+	names := make(map[string]string)
+	if guild != nil {
+		for _, role := range guild.Roles {
+			names[role.ID] = role.Name
+		}
+	}
+
+	roles := make([]ContextRole, 0, len(member.Roles))
+	for _, id := range member.Roles {
+		roles = append(roles, ContextRole{ID: id, Name: names[id]})
+	}
+
+	joinedAt := ""
+	if !member.JoinedAt.IsZero() {
+		joinedAt = member.JoinedAt.Format(time.RFC3339)
+	}
+	return &ContextMember{Nickname: member.Nick, Roles: roles, JoinedAt: joinedAt}
+}
+
+// newContextMentions collects the mentions of a message
+// Discord only lists mentioned channels for crossposts, so they are taken from the content
+func newContextMentions(m *discordgo.Message) ContextMentions {
+	mentions := ContextMentions{
+		Users:    make([]ContextUser, 0, len(m.Mentions)),
+		Roles:    append([]string{}, m.MentionRoles...),
+		Channels: []string{},
+		Everyone: m.MentionEveryone,
+	}
+	for _, user := range m.Mentions {
+		mentions.Users = append(mentions.Users, newContextUser(user, nil))
+	}
+	for _, match := range channelMentionInTextRe.FindAllStringSubmatch(m.Content, -1) {
+		mentions.Channels = append(mentions.Channels, match[1])
+	}
+	return mentions
+}
+
+// newExecutionContext builds the part of the context shared by every entry point
+// Guild, channel and member details come from the state cache and are left empty when they aren't cached
+func newExecutionContext(s *discordgo.Session, source, invocationID, guildID, channelID string, user *discordgo.User, member *discordgo.Member) ExecutionContext {
+	ctx := ExecutionContext{
+		InvocationID: invocationID,
+		Source:       source,
+		Channel:      ContextChannel{ID: channelID},
+		Mentions:     ContextMentions{Users: []ContextUser{}, Roles: []string{}, Channels: []string{}},
+	}
+
+	var guild *discordgo.Guild
+	if guildID != "" {
+		ctx.Guild = &ContextGuild{ID: guildID}
+		if cached, err := s.State.Guild(guildID); err == nil {
+			guild = cached
+			ctx.Guild.Name = guild.Name
+			ctx.Guild.Locale = guild.PreferredLocale
+			ctx.Guild.OwnerID = guild.OwnerID
+		}
+		if member == nil && user != nil {
+			if cached, err := s.State.Member(guildID, user.ID); err == nil {
+				member = cached
+			}
+		}
+	}
+	if user == nil && member != nil {
+		user = member.User
+	}
+
+	ctx.User = newContextUser(user, member)
+	if guildID != "" && member != nil {
+		ctx.Member = newContextMember(member, guild)
+	}
+
+	if channelID != "" {
+		if channel, err := s.State.Channel(channelID); err == nil {
+			ctx.Channel.Name = channel.Name
+			ctx.Channel.Type = int(channel.Type)
+			ctx.Channel.ParentID = channel.ParentID
+		}
+	}
+	return ctx
+}
+
+// messageContext returns the context of a command run by a message
+func messageContext(s *discordgo.Session, source string, m *discordgo.Message) ExecutionContext {
+	ctx := newExecutionContext(s, source, m.ID, m.GuildID, m.ChannelID, m.Author, m.Member)
+	ctx.MessageID = m.ID
+	ctx.Mentions = newContextMentions(m)
+	return ctx
+}
+
+// interactionContext returns the context of a command run by an interaction
+func interactionContext(s *discordgo.Session, source string, i *discordgo.InteractionCreate) ExecutionContext {
+	ctx := newExecutionContext(s, source, i.ID, i.GuildID, i.ChannelID, interactionUser(i), i.Member)
+	ctx.Locale = string(i.Locale)
+	if i.Message != nil {
+		ctx.MessageID = i.Message.ID
+	}
+	if ctx.Guild != nil && ctx.Guild.Locale == "" && i.GuildLocale != nil {
+		ctx.Guild.Locale = string(*i.GuildLocale)
+	}
+	return ctx
+}
+
+// eventContext returns the context of a command run by a schedule or an event rather than a user's action
+func eventContext(s *discordgo.Session, source, guildID, channelID string, user *discordgo.User) ExecutionContext {
+	return newExecutionContext(s, source, newInvocationID(time.Now()), guildID, channelID, user, nil)
+}
@@ -0,0 +1,132 @@
+package nelchanbot
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// contextTestSession returns a session whose state has a guild with a member and a channel
+func contextTestSession(t *testing.T) *discordgo.Session {
+	t.Helper()
+	state := discordgo.NewState()
+	guild := &discordgo.Guild{
+		ID:              "g1",
+		Name:            "ねるちゃんの部屋",
+		PreferredLocale: "ja",
+		OwnerID:         "1",
+		Roles:           []*discordgo.Role{{ID: "r1", Name: "管理人"}},
+	}
+	if err := state.GuildAdd(guild); err != nil {
+		t.Fatal(err)
+	}
+	if err := state.ChannelAdd(&discordgo.Channel{ID: "10", GuildID: "g1", Name: "雑談", Type: discordgo.ChannelTypeGuildText, ParentID: "5"}); err != nil {
+		t.Fatal(err)
+	}
+	member := &discordgo.Member{
+		GuildID:  "g1",
+		User:     &discordgo.User{ID: "100", Username: "neru", GlobalName: "ねる"},
+		Nick:     "ねるちゃん",
+		Roles:    []string{"r1", "r2"},
+		JoinedAt: time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC),
+	}
+	if err := state.MemberAdd(member); err != nil {
+		t.Fatal(err)
+	}
+	return &discordgo.Session{State: state}
+}
+
+func TestMessageContext(t *testing.T) {
+	s := contextTestSession(t)
+	m := &discordgo.Message{
+		ID:           "900",
+		ChannelID:    "10",
+		GuildID:      "g1",
+		Content:      "<@200> <#11> と <#12> を見て <@&r1>",
+		Author:       &discordgo.User{ID: "100", Username: "neru", GlobalName: "ねる"},
+		Member:       &discordgo.Member{Nick: "ねるちゃん", Roles: []string{"r1"}},
+		Mentions:     []*discordgo.User{{ID: "200", Username: "sh1ma"}},
+		MentionRoles: []string{"r1"},
+	}
+
+	ctx := messageContext(s, RunSourceMessage, m)
+	if ctx.InvocationID != "900" || ctx.MessageID != "900" || ctx.Source != RunSourceMessage {
+		t.Errorf("messageContext() ids = %q %q %q", ctx.InvocationID, ctx.MessageID, ctx.Source)
+	}
+	if ctx.User.DisplayName != "ねるちゃん" {
+		t.Errorf("messageContext() display name = %q, want the nickname", ctx.User.DisplayName)
+	}
+	if ctx.Member == nil || len(ctx.Member.Roles) != 1 || ctx.Member.Roles[0] != (ContextRole{ID: "r1", Name: "管理人"}) {
+		t.Errorf("messageContext() member = %+v, want role r1 named from the guild", ctx.Member)
+	}
+	if ctx.Guild == nil || ctx.Guild.Name != "ねるちゃんの部屋" || ctx.Guild.Locale != "ja" {
+		t.Errorf("messageContext() guild = %+v", ctx.Guild)
+	}
+	if ctx.Channel != (ContextChannel{ID: "10", Name: "雑談", Type: int(discordgo.ChannelTypeGuildText), ParentID: "5"}) {
+		t.Errorf("messageContext() channel = %+v", ctx.Channel)
+	}
+	if len(ctx.Mentions.Users) != 1 || ctx.Mentions.Users[0].DisplayName != "sh1ma" {
+		t.Errorf("messageContext() mentioned users = %+v", ctx.Mentions.Users)
+	}
+	if len(ctx.Mentions.Channels) != 2 || ctx.Mentions.Channels[0] != "11" || ctx.Mentions.Channels[1] != "12" {
+		t.Errorf("messageContext() mentioned channels = %v, want [11 12]", ctx.Mentions.Channels)
+	}
+
+	vars := ctx.Vars()
+	want := map[string]string{
+		"invocation_id": "900",
+		"username":      "ねる",
+		"user_id":       "100",
+		"display_name":  "ねるちゃん",
+		"nickname":      "ねるちゃん",
+		"guild_id":      "g1",
+		"guild_name":    "ねるちゃんの部屋",
+		"channel_id":    "10",
+		"channel_name":  "雑談",
+		"message_id":    "900",
+		"source":        RunSourceMessage,
+	}
+	for key, value := range want {
+		if vars[key] != value {
+			t.Errorf("Vars()[%q] = %q, want %q", key, vars[key], value)
+		}
+	}
+}
+
+func TestEventContext(t *testing.T) {
+	s := contextTestSession(t)
+
+	// The member is looked up from the state
+	ctx := eventContext(s, RunSourceSchedule, "g1", "10", &discordgo.User{ID: "100", Username: "neru"})
+	if ctx.Member == nil || ctx.Member.Nickname != "ねるちゃん" || ctx.Member.JoinedAt != "2026-01-02T03:04:05Z" {
+		t.Errorf("eventContext() member = %+v", ctx.Member)
+	}
+	if _, err := strconv.ParseInt(ctx.InvocationID, 10, 64); err != nil {
+		t.Errorf("eventContext() invocation ID = %q, want a snowflake", ctx.InvocationID)
+	}
+
+	// Every var is present in DMs too
+	ctx = eventContext(s, RunSourceSchedule, "", "20", nil)
+	vars := ctx.Vars()
+	for _, key := range []string{"username", "user_id", "nickname", "guild_id", "guild_name", "channel_name", "message_id", "locale"} {
+		if value, ok := vars[key]; !ok || value != "" {
+			t.Errorf("Vars()[%q] = %q, %v, want an empty string", key, value, ok)
+		}
+	}
+	if ctx.Guild != nil || ctx.Member != nil {
+		t.Errorf("eventContext() in DMs = %+v, want no guild or member", ctx)
+	}
+}
+
+func TestNewInvocationID(t *testing.T) {
+	now := time.Date(2026, 10, 18, 0, 0, 0, 0, time.UTC)
+	id, err := strconv.ParseInt(newInvocationID(now), 10, 64)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := id>>22 + discordEpoch; got != now.UnixMilli() {
+		t.Errorf("newInvocationID() timestamp = %d, want %d", got, now.UnixMilli())
+	}
+}
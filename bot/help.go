@@ -0,0 +1,314 @@
+package nelchanbot
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+const (
+	// helpComponentPrefix is the custom_id prefix of the help catalog buttons ("help:page:<n>")
+	helpComponentPrefix = "help"
+	// helpPageSize is the number of commands shown per catalog page
+	helpPageSize = 10
+	// maxHelpCommands is the maximum number of registered commands listed in the catalog
+	maxHelpCommands = 500
+)
+
+// helpEntry is one command in the help catalog
+type helpEntry struct {
+	Name        string
+	Description string
+	Builtin     bool
+	IsCode      bool
+}
+
+// invocation returns how the command is invoked in chat: "!name" for code and built-in commands, "name" for text commands
+func (e helpEntry) invocation() string {
+	if e.Builtin {
+		return "!" + e.Name
+	}
+	return commandInvocation(e.Name, e.IsCode)
+}
+
+// commandInvocation returns how a registered command is invoked in chat
+func commandInvocation(name string, isCode bool) string {
+	if isCode {
+		return "!" + name
+	}
+	return name
+}
+
+// helpEntries lists the built-in commands followed by the registered commands
+func (n *Nelchan) helpEntries() ([]helpEntry, error) {
+	var entries []helpEntry
+	for _, cmd := range n.CommandRouter.Builtins() {
+		entries = append(entries, helpEntry{
+			Name:        cmd.Name,
+			Description: cmd.Description,
+			Builtin:     true,
+		})
+	}
+
+	commands, err := n.CommandAPIClient.ListCommands(ListCommandsRequest{
+		Limit: maxHelpCommands,
+	})
+	if err != nil {
+		return entries, err
+	}
+	for _, cmd := range commands {
+		entries = append(entries, helpEntry{
+			Name:        cmd.Name,
+			Description: cmd.Description,
+			IsCode:      cmd.IsCode,
+		})
+	}
+	return entries, nil
+}
+
+// RenderHelpPage renders one page of the help catalog with its pagination buttons
+func RenderHelpPage(entries []helpEntry, page int) (*discordgo.MessageEmbed, []discordgo.MessageComponent) {
+	pages := pageCount(len(entries), helpPageSize)
+	page = clampPage(page, pages)
+
+	start := page * helpPageSize
+	end := min(start+helpPageSize, len(entries))
+
+	var b strings.Builder
+	for idx, entry := range entries[start:end] {
+		// Section headings where the built-in commands start and end
+		if idx == 0 || entries[start+idx-1].Builtin != entry.Builtin {
+			if idx > 0 {
+				b.WriteString("\n")
+			}
+			if entry.Builtin {
+				b.WriteString("**ビルトインコマンド**\n")
+			} else {
+				b.WriteString("**登録済みコマンド**\n")
+			}
+		}
+
+		fmt.Fprintf(&b, "`%s`", entry.invocation())
+		if entry.Description != "" {
+			b.WriteString(" " + entry.Description)
+		}
+		b.WriteString("\n")
+	}
+
+	embed := &discordgo.MessageEmbed{
+		Title:       "ねるちゃん コマンド一覧",
+		Description: strings.TrimSuffix(b.String(), "\n"),
+		Footer: &discordgo.MessageEmbedFooter{
+			Text: fmt.Sprintf("ページ %d/%d ・ !help <コマンド名> で詳細を表示", page+1, pages),
+		},
+	}
+	return embed, paginationButtons(helpComponentPrefix+":page", page, pages)
+}
+
+// RenderBuiltinHelp renders the detail page of a built-in command
+func RenderBuiltinHelp(cmd BuiltinCommand) *discordgo.MessageEmbed {
+	embed := &discordgo.MessageEmbed{
+		Title:       "!" + cmd.Name,
+		Description: cmd.Description,
+		Fields: []*discordgo.MessageEmbedField{
+			{Name: "使い方", Value: "`" + cmd.Usage + "`"},
+			{Name: "種類", Value: "ビルトインコマンド", Inline: true},
+		},
+	}
+	if len(cmd.Aliases) > 0 {
+		embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{
+			Name:   "エイリアス",
+			Value:  formatCodeList(cmd.Aliases),
+			Inline: true,
+		})
+	}
+	return embed
+}
+
+// RenderCommandHelp renders the detail page of a registered command
+func RenderCommandHelp(info *GetCommandInfo, args []ArgOption, aliases []CommandAlias) *discordgo.MessageEmbed {
+	entry := helpEntry{Name: info.Name, IsCode: info.IsCode}
+
+	description := info.Metadata.Description
+	if description == "" {
+		description = "説明はありません"
+	}
+
+	embed := &discordgo.MessageEmbed{
+		Title:       entry.invocation(),
+		Description: description,
+	}
+
+	switch {
+	case info.Metadata.Usage != "":
+		embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{Name: "使い方", Value: info.Metadata.Usage})
+	case args != nil:
+		embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{Name: "使い方", Value: FormatArgsUsage(info.Name, args)})
+	}
+
+	kind := "テキストコマンド"
+	if info.IsCode {
+		kind = "コードコマンド"
+	}
+	embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{Name: "種類", Value: kind, Inline: true})
+
+	if info.Metadata.Category != "" {
+		embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{Name: "カテゴリ", Value: info.Metadata.Category, Inline: true})
+	}
+	if len(info.Metadata.Tags) > 0 {
+		embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{Name: "タグ", Value: strings.Join(info.Metadata.Tags, ", "), Inline: true})
+	}
+	if info.AuthorID != "" {
+		embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{Name: "登録者", Value: fmt.Sprintf("<@%s>", info.AuthorID), Inline: true})
+	}
+	if len(aliases) > 0 {
+		names := make([]string, len(aliases))
+		for idx, alias := range aliases {
+			names[idx] = alias.Alias
+		}
+		embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{Name: "エイリアス", Value: formatCodeList(names), Inline: true})
+	}
+	return embed
+}
+
+// formatCodeList formats names as a comma separated list of inline code
+func formatCodeList(names []string) string {
+	quoted := make([]string, len(names))
+	for idx, name := range names {
+		quoted[idx] = "`" + name + "`"
+	}
+	return strings.Join(quoted, ", ")
+}
+
+// helpDetail renders the detail page of a built-in or registered command
+// Returns nil if no such command exists
+func (n *Nelchan) helpDetail(name string) (*discordgo.MessageEmbed, error) {
+	name = strings.TrimPrefix(name, "!")
+	if cmd, ok := n.CommandRouter.FindBuiltin(name); ok {
+		return RenderBuiltinHelp(cmd), nil
+	}
+
+	info, err := n.CommandAPIClient.GetCommand(GetCommandRequest{
+		CommandName: n.CommandRouter.ResolveAlias(name),
+	})
+	if err != nil || info == nil {
+		return nil, err
+	}
+
+	aliases, err := n.CommandAPIClient.ListAliases(ListAliasesRequest{
+		CommandName: info.Name,
+	})
+	if err != nil {
+		fmt.Println("error listing aliases for help:", err)
+	}
+
+	var args []ArgOption
+	if info.IsCode {
+		args = n.commandArgsSchema(info)
+	}
+	return RenderCommandHelp(info, args, aliases), nil
+}
+
+// helpMessage builds the help catalog, or the detail page when a command name is given
+func (n *Nelchan) helpMessage(name string) (*discordgo.MessageEmbed, []discordgo.MessageComponent, error) {
+	if name != "" {
+		embed, err := n.helpDetail(name)
+		if err != nil {
+			return nil, nil, err
+		}
+		if embed == nil {
+			return nil, nil, fmt.Errorf("コマンド「%s」は見つかりませんでした", name)
+		}
+		return embed, nil, nil
+	}
+
+	entries, err := n.helpEntries()
+	if err != nil {
+		// The built-in commands can still be listed
+		fmt.Println("error listing commands for help:", err)
+	}
+	embed, components := RenderHelpPage(entries, 0)
+	return embed, components, nil
+}
+
+// handleHelpCommand handles the !help command
+// Usage: !help - Show the command catalog
+// Usage: !help <command_name> - Show the details of a command
+func (n *Nelchan) handleHelpCommand(s *discordgo.Session, m *discordgo.MessageCreate, cmd *SlashCommand) {
+	embed, components, err := n.helpMessage(cmd.GetArg(0))
+	if err != nil {
+		_, _ = s.ChannelMessageSend(m.ChannelID, err.Error())
+		return
+	}
+
+	_, err = s.ChannelMessageSendComplex(m.ChannelID, &discordgo.MessageSend{
+		Embeds:     []*discordgo.MessageEmbed{embed},
+		Components: components,
+	})
+	if err != nil {
+		fmt.Println("error sending help:", err)
+	}
+}
+
+// handleHelpSlashCommand handles the /help slash command
+func (n *Nelchan) handleHelpSlashCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	var commandNameOpt string
+	for _, opt := range i.ApplicationCommandData().Options {
+		if opt.Name == "command_name" {
+			commandNameOpt = opt.StringValue()
+		}
+	}
+
+	embed, components, err := n.helpMessage(commandNameOpt)
+	if err != nil {
+		_ = s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseChannelMessageWithSource,
+			Data: &discordgo.InteractionResponseData{
+				Content: err.Error(),
+				Flags:   discordgo.MessageFlagsEphemeral,
+			},
+		})
+		return
+	}
+
+	err = s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Embeds:     []*discordgo.MessageEmbed{embed},
+			Components: components,
+		},
+	})
+	if err != nil {
+		fmt.Printf("error responding to /help: %v\n", err)
+	}
+}
+
+// handleHelpComponent handles the pagination buttons of the help catalog ("help:page:<n>")
+func (n *Nelchan) handleHelpComponent(s *discordgo.Session, i *discordgo.InteractionCreate, args []string) {
+	if len(args) != 2 || args[0] != "page" {
+		return
+	}
+	page, err := strconv.Atoi(args[1])
+	if err != nil {
+		return
+	}
+
+	entries, err := n.helpEntries()
+	if err != nil {
+		fmt.Println("error listing commands for help:", err)
+	}
+	embed, components := RenderHelpPage(entries, page)
+
+	err = s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseUpdateMessage,
+		Data: &discordgo.InteractionResponseData{
+			Embeds:     []*discordgo.MessageEmbed{embed},
+			Components: components,
+		},
+	})
+	if err != nil {
+		fmt.Printf("error updating help page: %v\n", err)
+	}
+}
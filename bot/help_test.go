@@ -0,0 +1,124 @@
+package nelchanbot
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func TestCommandRouterFindBuiltin(t *testing.T) {
+	r := NewCommandRouter(NewCommandParser(), nil)
+	r.AddBuiltin(BuiltinCommand{Name: "register", Aliases: []string{"reg"}}, nil)
+	r.AddBuiltin(BuiltinCommand{Name: "show"}, nil)
+
+	tests := []struct {
+		name   string
+		want   string
+		wantOK bool
+	}{
+		{name: "register", want: "register", wantOK: true},
+		{name: "reg", want: "register", wantOK: true},
+		{name: "show", want: "show", wantOK: true},
+		{name: "unknown", wantOK: false},
+	}
+	for _, tt := range tests {
+		got, ok := r.FindBuiltin(tt.name)
+		if ok != tt.wantOK || got.Name != tt.want {
+			t.Errorf("FindBuiltin(%q) = %q, %v, want %q, %v", tt.name, got.Name, ok, tt.want, tt.wantOK)
+		}
+	}
+
+	if !r.HasCommand("reg") {
+		t.Error("HasCommand(\"reg\") = false, want true")
+	}
+	if got := len(r.Builtins()); got != 2 {
+		t.Errorf("len(Builtins()) = %d, want 2", got)
+	}
+}
+
+func TestRenderHelpPage(t *testing.T) {
+	entries := []helpEntry{
+		{Name: "register", Description: "テキストコマンドを登録", Builtin: true},
+		{Name: "show", Builtin: true},
+	}
+	for idx := 0; idx < 12; idx++ {
+		entries = append(entries, helpEntry{Name: fmt.Sprintf("cmd%02d", idx), IsCode: idx%2 == 0})
+	}
+
+	tests := []struct {
+		name         string
+		page         int
+		wantContains []string
+		wantMissing  []string
+		wantFooter   string
+		wantPrev     bool
+		wantNext     bool
+	}{
+		{
+			name:         "first page",
+			page:         0,
+			wantContains: []string{"**ビルトインコマンド**", "`!register` テキストコマンドを登録", "`!show`", "**登録済みコマンド**", "`!cmd00`", "`cmd01`", "`cmd07`"},
+			wantMissing:  []string{"cmd08"},
+			wantFooter:   "ページ 1/2",
+			wantNext:     true,
+		},
+		{
+			name:         "last page",
+			page:         1,
+			wantContains: []string{"**登録済みコマンド**", "`!cmd08`", "`cmd11`"},
+			wantMissing:  []string{"ビルトインコマンド", "cmd07"},
+			wantFooter:   "ページ 2/2",
+			wantPrev:     true,
+		},
+		{
+			name:       "out of range page is clamped",
+			page:       5,
+			wantFooter: "ページ 2/2",
+			wantPrev:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			embed, components := RenderHelpPage(entries, tt.page)
+			for _, want := range tt.wantContains {
+				if !strings.Contains(embed.Description, want) {
+					t.Errorf("description does not contain %q:\n%s", want, embed.Description)
+				}
+			}
+			for _, missing := range tt.wantMissing {
+				if strings.Contains(embed.Description, missing) {
+					t.Errorf("description unexpectedly contains %q:\n%s", missing, embed.Description)
+				}
+			}
+			if !strings.HasPrefix(embed.Footer.Text, tt.wantFooter) {
+				t.Errorf("footer = %q, want prefix %q", embed.Footer.Text, tt.wantFooter)
+			}
+
+			if len(components) != 1 {
+				t.Fatalf("len(components) = %d, want 1", len(components))
+			}
+			buttons := components[0].(discordgo.ActionsRow).Components
+			prev := buttons[0].(discordgo.Button)
+			next := buttons[1].(discordgo.Button)
+			if prev.Disabled == tt.wantPrev {
+				t.Errorf("prev disabled = %v, want %v", prev.Disabled, !tt.wantPrev)
+			}
+			if next.Disabled == tt.wantNext {
+				t.Errorf("next disabled = %v, want %v", next.Disabled, !tt.wantNext)
+			}
+		})
+	}
+}
+
+func TestRenderHelpPageSinglePage(t *testing.T) {
+	embed, components := RenderHelpPage([]helpEntry{{Name: "help", Builtin: true}}, 0)
+	if components != nil {
+		t.Errorf("components = %v, want nil", components)
+	}
+	if embed.Footer.Text != "ページ 1/1 ・ !help <コマンド名> で詳細を表示" {
+		t.Errorf("footer = %q", embed.Footer.Text)
+	}
+}
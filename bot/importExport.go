@@ -0,0 +1,244 @@
+package nelchanbot
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// handleExportSlashCommand handles the /export slash command
+// Attaches every command as a JSON or zip bundle
+func (n *Nelchan) handleExportSlashCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	format := BundleFormatJSON
+	for _, opt := range i.ApplicationCommandData().Options {
+		if opt.Name == "format" {
+			format = opt.StringValue()
+		}
+	}
+
+	// Defer response as exporting every command may take a while
+	err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Flags: discordgo.MessageFlagsEphemeral,
+		},
+	})
+	if err != nil {
+		fmt.Printf("error deferring interaction response: %v\n", err)
+		return
+	}
+
+	commands, err := n.CommandAPIClient.ExportCommands()
+	if err != nil {
+		fmt.Println("error exporting commands,", err)
+		editInteractionContent(s, i, fmt.Sprintf("エラー: %s", err.Error()))
+		return
+	}
+
+	bundle := NewCommandBundle(commands, time.Now())
+	fileName, data, err := EncodeCommandBundle(bundle, format)
+	if err != nil {
+		fmt.Println("error encoding bundle,", err)
+		editInteractionContent(s, i, fmt.Sprintf("エラー: %s", err.Error()))
+		return
+	}
+
+	contentType := "application/json"
+	if format == BundleFormatZip {
+		contentType = "application/zip"
+	}
+	content := fmt.Sprintf("%d 件のコマンドをエクスポートしました。`/import` で読み込めます", len(bundle.Commands))
+	_, err = s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+		Content: &content,
+		Files: []*discordgo.File{
+			{
+				Name:        fileName,
+				ContentType: contentType,
+				Reader:      bytes.NewReader(data),
+			},
+		},
+	})
+	if err != nil {
+		fmt.Printf("error editing interaction response: %v\n", err)
+	}
+}
+
+// handleImportSlashCommand handles the /import slash command
+// Reads a bundle from the attachment and registers its commands under the chosen conflict policy.
+// Only admins can import, as a bundle may create many commands at once
+func (n *Nelchan) handleImportSlashCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	if !n.isInteractionAdmin(i) {
+		_ = s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseChannelMessageWithSource,
+			Data: &discordgo.InteractionResponseData{
+				Content: "コマンドの読み込みはサーバーの管理者だけができます",
+				Flags:   discordgo.MessageFlagsEphemeral,
+			},
+		})
+		return
+	}
+
+	data := i.ApplicationCommandData()
+
+	policy := ImportPolicySkip
+	dryRun := false
+	var attachment *discordgo.MessageAttachment
+	for _, opt := range data.Options {
+		switch opt.Name {
+		case "file":
+			if id, ok := opt.Value.(string); ok && data.Resolved != nil {
+				attachment = data.Resolved.Attachments[id]
+			}
+		case "policy":
+			policy = opt.StringValue()
+		case "dry_run":
+			dryRun = opt.BoolValue()
+		}
+	}
+
+	// Defer response as importing may register many commands
+	err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
+	})
+	if err != nil {
+		fmt.Printf("error deferring interaction response: %v\n", err)
+		return
+	}
+
+	if attachment == nil {
+		editInteractionContent(s, i, "バンドルのファイルを添付してください")
+		return
+	}
+	if attachment.Size > maxBundleSize {
+		editInteractionContent(s, i, fmt.Sprintf("ファイルが大きすぎます（最大 %d MB）", maxBundleSize>>20))
+		return
+	}
+
+	raw, err := downloadAttachment(attachment.URL, maxBundleSize)
+	if err != nil {
+		fmt.Println("error downloading bundle,", err)
+		editInteractionContent(s, i, fmt.Sprintf("エラー: %s", err.Error()))
+		return
+	}
+
+	bundle, err := DecodeCommandBundle(raw)
+	if err != nil {
+		editInteractionContent(s, i, fmt.Sprintf("バンドルを読み込めません:\n%s", err.Error()))
+		return
+	}
+
+	user := interactionUser(i)
+	admin := n.isInteractionAdmin(i)
+	planner := importPlanner{
+		lookup: func(name string) (*GetCommandInfo, error) {
+			return n.CommandAPIClient.GetCommand(GetCommandRequest{CommandName: name})
+		},
+		checkName: n.checkNewCommandName,
+		isAlias: func(name string) bool {
+			return n.CommandRouter.ResolveAlias(name) != name
+		},
+		canOverwrite: func(info *GetCommandInfo) bool {
+			return canManageCommand(info, user.ID, admin)
+		},
+	}
+
+	actions, err := planner.plan(bundle.Commands, policy)
+	if err != nil {
+		fmt.Println("error planning import,", err)
+		editInteractionContent(s, i, fmt.Sprintf("エラー: %s", err.Error()))
+		return
+	}
+
+	failed := make(map[string]error)
+	if !dryRun {
+		for _, action := range actions {
+			if action.Kind == ImportSkip {
+				continue
+			}
+			if err := n.importCommand(s, i.GuildID, user.ID, admin, action); err != nil {
+				fmt.Printf("error importing command %s: %v\n", action.Target, err)
+				failed[action.Target] = err
+			}
+		}
+	}
+
+	result := FormatImportResult(actions, failed, dryRun)
+	if utf8.RuneCountInString(result) <= maxMessageLength {
+		editInteractionContent(s, i, result)
+		return
+	}
+
+	// Too long for a message, attach the full result
+	summary, _, _ := strings.Cut(result, "\n")
+	_, err = s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+		Content: &summary,
+		Files: []*discordgo.File{
+			{
+				Name:        "import.txt",
+				ContentType: "text/plain",
+				Reader:      strings.NewReader(result),
+			},
+		},
+	})
+	if err != nil {
+		fmt.Printf("error editing interaction response: %v\n", err)
+	}
+}
+
+// importCommand registers one bundled command as planned, owned by the importing user
+// The slash command is registered or updated like on registration and edit
+func (n *Nelchan) importCommand(s *discordgo.Session, guildID, userID string, admin bool, action ImportAction) error {
+	cmd := action.Command
+	metadata := cmd.Metadata
+
+	request := RegisterCommandRequest{
+		CommandName:    action.Target,
+		CommandContent: cmd.Content,
+		IsCode:         cmd.IsCode(),
+		AuthorID:       userID,
+		Metadata:       &metadata,
+		Admin:          admin,
+	}
+	if !cmd.IsCode() {
+		request.Responses = cmd.Responses
+		request.ResponseMode = cmd.ResponseMode
+	}
+	var newSpec slashCommandSpec
+	if cmd.IsCode() {
+		request.ArgsSchema = cmd.ArgsSchema
+		newSpec = slashCommandSpec{
+			Description: slashCommandDescription(action.Target, metadata),
+			Args:        cmd.ArgsSchema,
+		}
+	}
+
+	if err := n.CommandAPIClient.RegisterCommand(request); err != nil {
+		return err
+	}
+
+	// A registered command takes over its name from an alias
+	n.CommandRouter.RemoveAlias(action.Target)
+
+	var oldSpec slashCommandSpec
+	if action.Existing != nil {
+		oldSpec = n.slashCommandSpecOf(action.Existing)
+	}
+	if err := n.syncSlashCommand(s, guildID, action.Target, oldSpec, newSpec); err != nil {
+		return fmt.Errorf("スラッシュコマンドの登録に失敗: %w", err)
+	}
+	return nil
+}
+
+// editInteractionContent replaces the deferred response of an interaction with a message
+func editInteractionContent(s *discordgo.Session, i *discordgo.InteractionCreate, content string) {
+	_, err := s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+		Content: &content,
+	})
+	if err != nil {
+		fmt.Printf("error editing interaction response: %v\n", err)
+	}
+}
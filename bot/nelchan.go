@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"strings"
+	"time"
 	"unicode/utf8"
 
 	"github.com/bwmarrin/discordgo"
@@ -13,6 +14,8 @@ type NelchanConfig struct {
 	Env            string
 	CodeSandboxURL string
 	BotOwnerUserID string
+	// MemberEvents enables the privileged guild members intent, needed for member join and leave triggers
+	MemberEvents bool
 }
 
 type Nelchan struct {
@@ -21,9 +24,13 @@ type Nelchan struct {
 	CommandAPIClient *CommandAPIClient
 	CommandParser    *CommandParser
 	CommandRouter    *CommandRouter
+	Scheduler        *Scheduler
+	Reminders        *ReminderQueue
+	Triggers         *TriggerRegistry
+	Responders       *ResponderRegistry
 }
 
-// builtinSlashCommands defines the built-in slash commands to register on startup
+// builtinSlashCommands defines the built-in slash commands and message context menu to register on startup
 var builtinSlashCommands = []*discordgo.ApplicationCommand{
 	{
 		Name:        "register",
@@ -41,17 +48,359 @@ var builtinSlashCommands = []*discordgo.ApplicationCommand{
 				Description: "登録するテキスト",
 				Required:    true,
 			},
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "description",
+				Description: "コマンドの説明",
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "usage",
+				Description: "使い方",
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "tags",
+				Description: "タグ（カンマ区切り）",
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "category",
+				Description: "カテゴリ",
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionBoolean,
+				Name:        "append",
+				Description: "既存のコマンドに応答として追加する",
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionInteger,
+				Name:        "weight",
+				Description: "応答の重み（weighted のとき）",
+				MinValue:    &minResponseWeight,
+				MaxValue:    maxResponseWeight,
+			},
 		},
 	},
 	{
 		Name:        "set_mention",
 		Description: "メンション時に実行するコマンドを設定します",
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:         discordgo.ApplicationCommandOptionString,
+				Name:         "command_name",
+				Description:  "コマンド名（省略で現在の設定を表示、clearでクリア）",
+				Required:     false,
+				Autocomplete: true,
+			},
+		},
+	},
+	{
+		Name:        "unregister",
+		Description: "コマンドを削除します（ロック中は登録者または管理者のみ）",
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:         discordgo.ApplicationCommandOptionString,
+				Name:         "command_name",
+				Description:  "削除するコマンド名",
+				Required:     true,
+				Autocomplete: true,
+			},
+		},
+	},
+	{
+		Name:        "help",
+		Description: "コマンドの一覧や詳細を表示します",
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:         discordgo.ApplicationCommandOptionString,
+				Name:         "command_name",
+				Description:  "詳細を表示するコマンド名（省略で一覧）",
+				Required:     false,
+				Autocomplete: true,
+			},
+		},
+	},
+	{
+		Name:        "stats",
+		Description: "コマンドの利用統計を表示します",
+	},
+	{
+		Name:        "export",
+		Description: "すべてのコマンドをファイルに書き出します",
 		Options: []*discordgo.ApplicationCommandOption{
 			{
 				Type:        discordgo.ApplicationCommandOptionString,
-				Name:        "command_name",
-				Description: "コマンド名（省略で現在の設定を表示、clearでクリア）",
-				Required:    false,
+				Name:        "format",
+				Description: "ファイル形式（省略で json）",
+				Choices: []*discordgo.ApplicationCommandOptionChoice{
+					{Name: "json", Value: BundleFormatJSON},
+					{Name: "zip", Value: BundleFormatZip},
+				},
+			},
+		},
+	},
+	{
+		Name:        "import",
+		Description: "【管理者専用】/export で書き出したファイルからコマンドを読み込みます",
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionAttachment,
+				Name:        "file",
+				Description: "/export で書き出した json または zip",
+				Required:    true,
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "policy",
+				Description: "同じ名前のコマンドがあるとき（省略で skip）",
+				Choices: []*discordgo.ApplicationCommandOptionChoice{
+					{Name: "skip: 既存のコマンドを残す", Value: ImportPolicySkip},
+					{Name: "overwrite: 上書きする", Value: ImportPolicyOverwrite},
+					{Name: "rename: 別の名前で作成する", Value: ImportPolicyRename},
+				},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionBoolean,
+				Name:        "dry_run",
+				Description: "変更せずに結果だけを表示する",
+			},
+		},
+	},
+	{
+		Name:        "schedule",
+		Description: "コードコマンドを定期的に実行します",
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "add",
+				Description: "スケジュールを登録します",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:         discordgo.ApplicationCommandOptionString,
+						Name:         "command_name",
+						Description:  "実行するコードコマンド",
+						Required:     true,
+						Autocomplete: true,
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "cron",
+						Description: "cron 式「分 時 日 月 曜日」（例: 0 9 * * 1-5）",
+						Required:    true,
+					},
+					{
+						Type:         discordgo.ApplicationCommandOptionChannel,
+						Name:         "channel",
+						Description:  "結果を送るチャンネル（省略でこのチャンネル）",
+						ChannelTypes: []discordgo.ChannelType{discordgo.ChannelTypeGuildText},
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "args",
+						Description: "コマンドに渡す引数",
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "missed",
+						Description: "停止中に過ぎた実行（省略で run_once）",
+						Choices: []*discordgo.ApplicationCommandOptionChoice{
+							{Name: "run_once: 再開時に1回だけ実行する", Value: MissedPolicyRunOnce},
+							{Name: "skip: 実行しない", Value: MissedPolicySkip},
+						},
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "timezone",
+						Description: "タイムゾーン（省略で !timezone の設定、未設定なら " + defaultTimezone + "）",
+					},
+				},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "list",
+				Description: "このサーバーのスケジュールを表示します",
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "remove",
+				Description: "スケジュールを削除します（登録者または管理者のみ）",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionInteger,
+						Name:        "id",
+						Description: "/schedule list に表示される番号",
+						Required:    true,
+					},
+				},
+			},
+		},
+	},
+	{
+		Name:        "remind",
+		Description: "指定した日時にリマインドします",
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "when",
+				Description: "いつ（例: 10分後, 明日9時, in 2h, 2026-10-20 18:00）",
+				Required:    true,
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "text",
+				Description: "リマインドする内容",
+				Required:    true,
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionBoolean,
+				Name:        "dm",
+				Description: "DM でお知らせする",
+			},
+		},
+	},
+	{
+		Name:        "trigger",
+		Description: "【管理者専用】イベントでコードコマンドを実行します",
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "add",
+				Description: "イベントトリガーを登録します",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "event",
+						Description: "コマンドを実行するイベント",
+						Required:    true,
+						Choices: []*discordgo.ApplicationCommandOptionChoice{
+							{Name: "リアクション追加", Value: TriggerEventReactionAdd},
+							{Name: "リアクション削除", Value: TriggerEventReactionRemove},
+							{Name: "メンバー参加", Value: TriggerEventMemberJoin},
+							{Name: "メンバー退出", Value: TriggerEventMemberLeave},
+							{Name: "スレッド作成", Value: TriggerEventThreadCreate},
+							{Name: "ボイスチャンネルの状態変化", Value: TriggerEventVoiceState},
+						},
+					},
+					{
+						Type:         discordgo.ApplicationCommandOptionString,
+						Name:         "command_name",
+						Description:  "実行するコードコマンド",
+						Required:     true,
+						Autocomplete: true,
+					},
+					{
+						Type:         discordgo.ApplicationCommandOptionChannel,
+						Name:         "channel",
+						Description:  "結果を送るチャンネル（省略でイベントが起きたチャンネル）",
+						ChannelTypes: []discordgo.ChannelType{discordgo.ChannelTypeGuildText},
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "emoji",
+						Description: "リアクションの絵文字で絞り込む",
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "message_id",
+						Description: "リアクションされたメッセージの ID で絞り込む",
+					},
+				},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "list",
+				Description: "このサーバーのイベントトリガーを表示します",
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "remove",
+				Description: "イベントトリガーを削除します",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionInteger,
+						Name:        "id",
+						Description: "/trigger list に表示される番号",
+						Required:    true,
+					},
+				},
+			},
+		},
+	},
+	{
+		Name:        "responder",
+		Description: "メッセージのパターンに自動で応答するコマンドを設定します",
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "add",
+				Description: "自動応答を登録します",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "mode",
+						Description: "パターンとメッセージの照合方法",
+						Required:    true,
+						Choices: []*discordgo.ApplicationCommandOptionChoice{
+							{Name: "exact: 最初の単語が一致（残りは引数）", Value: ResponderModeExact},
+							{Name: "prefix: 前方一致（残りは引数）", Value: ResponderModePrefix},
+							{Name: "contains: 部分一致", Value: ResponderModeContains},
+							{Name: "regex: 正規表現（キャプチャは引数）", Value: ResponderModeRegex},
+							{Name: "whole: メッセージ全体が一致", Value: ResponderModeWhole},
+						},
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "pattern",
+						Description: "照合するパターン",
+						Required:    true,
+						MaxLength:   maxResponderPatternLength,
+					},
+					{
+						Type:         discordgo.ApplicationCommandOptionString,
+						Name:         "command_name",
+						Description:  "応答するコマンド",
+						Required:     true,
+						Autocomplete: true,
+					},
+				},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "list",
+				Description: "このサーバーの自動応答を表示します",
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "remove",
+				Description: "自動応答を削除します（登録者または管理者のみ）",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionInteger,
+						Name:        "id",
+						Description: "/responder list に表示される番号",
+						Required:    true,
+					},
+				},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "channel",
+				Description: "チャンネルの自動応答を有効・無効にします（管理者のみ）",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionBoolean,
+						Name:        "enabled",
+						Description: "自動応答を有効にする",
+						Required:    true,
+					},
+					{
+						Type:         discordgo.ApplicationCommandOptionChannel,
+						Name:         "channel",
+						Description:  "設定するチャンネル（省略でこのチャンネル）",
+						ChannelTypes: []discordgo.ChannelType{discordgo.ChannelTypeGuildText},
+					},
+				},
 			},
 		},
 	},
@@ -63,6 +412,10 @@ var builtinSlashCommands = []*discordgo.ApplicationCommand{
 		Name:        "register-builtin-commands",
 		Description: "【管理者専用】ビルトインコマンドを再登録します",
 	},
+	{
+		Type: discordgo.MessageApplicationCommand,
+		Name: messageRunCommandName,
+	},
 }
 
 func NewNelchan() (*Nelchan, error) {
@@ -95,10 +448,14 @@ func NewNelchan() (*Nelchan, error) {
 
 	botOwnerUserID := os.Getenv("BOT_OWNER_USER_ID")
 
+	// Server Members Intent must also be enabled in the developer portal, or the gateway refuses to connect
+	memberEvents := os.Getenv("ENABLE_MEMBER_EVENTS") == "true"
+
 	config := NelchanConfig{
 		Env:            env,
 		CodeSandboxURL: codeSandboxURL,
 		BotOwnerUserID: botOwnerUserID,
+		MemberEvents:   memberEvents,
 	}
 
 	commandAPIClient := NewCommandAPIClient(codeSandboxURL, nelchanAPIKey)
@@ -111,19 +468,168 @@ func NewNelchan() (*Nelchan, error) {
 		CommandAPIClient: commandAPIClient,
 		CommandParser:    commandParser,
 		CommandRouter:    commandRouter,
+		Scheduler:        NewScheduler(),
+		Triggers:         NewTriggerRegistry(),
+		Responders:       NewResponderRegistry(),
 	}
+	n.Reminders = NewReminderQueue(n.deliverReminder)
 
 	// Register built-in commands
 	commandRouter.
-		AddCommand("register", n.handleRegisterCommand).
-		AddCommand("reg", n.handleRegisterCommand).
-		AddCommand("register_code", n.handleRegisterCodeCommand).
-		AddCommand("regc", n.handleRegisterCodeCommand).
-		AddCommand("smart_register", n.handleSmartRegisterCommand).
-		AddCommand("sreg", n.handleSmartRegisterCommand).
-		AddCommand("exec", n.handleExecCommand).
-		AddCommand("show", n.handleShowCommand).
-		AddCommand("set_mention", n.handleSetMentionCommand).
+		AddBuiltin(BuiltinCommand{
+			Name:        "register",
+			Aliases:     []string{"reg"},
+			Usage:       "!register <コマンド名> <テキスト>",
+			Description: "テキストコマンドを登録します",
+		}, n.handleRegisterCommand).
+		AddBuiltin(BuiltinCommand{
+			Name:        "register_more",
+			Aliases:     []string{"regm"},
+			Usage:       "!register_more <コマンド名> [--weight=重み] <テキスト>",
+			Description: "テキストコマンドに応答を追加します（なければ登録します）",
+		}, n.handleRegisterMoreCommand).
+		AddBuiltin(BuiltinCommand{
+			Name:        "responses",
+			Usage:       "!responses <コマンド名>",
+			Description: "テキストコマンドの応答の一覧を表示します",
+		}, n.handleResponsesCommand).
+		AddBuiltin(BuiltinCommand{
+			Name:        "remove_response",
+			Usage:       "!remove_response <コマンド名> <番号>",
+			Description: "テキストコマンドの応答を 1 つ削除します",
+		}, n.handleRemoveResponseCommand).
+		AddBuiltin(BuiltinCommand{
+			Name:        "response_mode",
+			Usage:       "!response_mode <コマンド名> [random|round_robin|weighted]",
+			Description: "テキストコマンドの応答の選び方を表示・設定します",
+		}, n.handleResponseModeCommand).
+		AddBuiltin(BuiltinCommand{
+			Name:        "register_code",
+			Aliases:     []string{"regc"},
+			Usage:       "!register_code <コマンド名> <コード>",
+			Description: "Python のコードコマンドを登録します",
+		}, n.handleRegisterCodeCommand).
+		AddBuiltin(BuiltinCommand{
+			Name:        "smart_register",
+			Aliases:     []string{"sreg"},
+			Usage:       "!smart_register <コマンド名> <説明>",
+			Description: "説明文からコードを生成してコマンドを登録します",
+		}, n.handleSmartRegisterCommand).
+		AddBuiltin(BuiltinCommand{
+			Name:        "exec",
+			Usage:       "!exec <コマンド名> [引数...]",
+			Description: "コードコマンドを実行します",
+		}, n.handleExecCommand).
+		AddBuiltin(BuiltinCommand{
+			Name:        "show",
+			Usage:       "!show <コマンド名>",
+			Description: "コマンドの内容を表示します",
+		}, n.handleShowCommand).
+		AddBuiltin(BuiltinCommand{
+			Name:        "help",
+			Usage:       "!help [コマンド名]",
+			Description: "コマンドの一覧や詳細を表示します",
+		}, n.handleHelpCommand).
+		AddBuiltin(BuiltinCommand{
+			Name:        "list",
+			Usage:       "!list [text|code] [@ユーザー|me] [name|recent|popular|author]",
+			Description: "登録済みのコマンドを絞り込み・並べ替えて一覧表示します",
+		}, n.handleListCommand).
+		AddBuiltin(BuiltinCommand{
+			Name:        "search",
+			Usage:       "!search <キーワード>",
+			Description: "コマンドを名前・説明・内容から検索します",
+		}, n.handleSearchCommand).
+		AddBuiltin(BuiltinCommand{
+			Name:        "set_mention",
+			Usage:       "!set_mention [コマンド名|clear]",
+			Description: "メンション時に実行するコマンドを設定します",
+		}, n.handleSetMentionCommand).
+		AddBuiltin(BuiltinCommand{
+			Name:        "edit",
+			Usage:       "!edit <コマンド名> <内容>",
+			Description: "コマンドの内容を書き換えます",
+		}, n.handleEditCommand).
+		AddBuiltin(BuiltinCommand{
+			Name:        "history",
+			Usage:       "!history <コマンド名>",
+			Description: "コマンドの編集履歴を表示します",
+		}, n.handleHistoryCommand).
+		AddBuiltin(BuiltinCommand{
+			Name:        "rollback",
+			Usage:       "!rollback <コマンド名> <バージョン>",
+			Description: "コマンドを以前のバージョンに戻します",
+		}, n.handleRollbackCommand).
+		AddBuiltin(BuiltinCommand{
+			Name:        "diff",
+			Usage:       "!diff <コマンド名> [v1] [v2]",
+			Description: "バージョン間の差分を表示します",
+		}, n.handleDiffCommand).
+		AddBuiltin(BuiltinCommand{
+			Name:        "rename",
+			Usage:       "!rename <コマンド名> <新しい名前>",
+			Description: "コマンドの名前を変更します",
+		}, n.handleRenameCommand).
+		AddBuiltin(BuiltinCommand{
+			Name:        "alias",
+			Usage:       "!alias <エイリアス> <コマンド名>",
+			Description: "コマンドにエイリアスを付けます",
+		}, n.handleAliasCommand).
+		AddBuiltin(BuiltinCommand{
+			Name:        "unalias",
+			Usage:       "!unalias <エイリアス>",
+			Description: "エイリアスを削除します",
+		}, n.handleUnaliasCommand).
+		AddBuiltin(BuiltinCommand{
+			Name:        "aliases",
+			Usage:       "!aliases [コマンド名]",
+			Description: "エイリアスの一覧を表示します",
+		}, n.handleAliasesCommand).
+		AddBuiltin(BuiltinCommand{
+			Name:        "owner",
+			Usage:       "!owner <コマンド名>",
+			Description: "コマンドの登録者とロック状態を表示します",
+		}, n.handleOwnerCommand).
+		AddBuiltin(BuiltinCommand{
+			Name:        "lock",
+			Usage:       "!lock <コマンド名>",
+			Description: "コマンドを登録者と管理者だけが変更できるようにします",
+		}, n.handleLockCommand).
+		AddBuiltin(BuiltinCommand{
+			Name:        "unlock",
+			Usage:       "!unlock <コマンド名>",
+			Description: "コマンドを誰でも変更できるようにします",
+		}, n.handleUnlockCommand).
+		AddBuiltin(BuiltinCommand{
+			Name:        "transfer",
+			Usage:       "!transfer <コマンド名> <@ユーザー>",
+			Description: "コマンドの登録者を変更します",
+		}, n.handleTransferCommand).
+		AddBuiltin(BuiltinCommand{
+			Name:        "remind",
+			Usage:       "!remind [dm] <いつ> <内容>",
+			Description: "指定した日時にリマインドします（例: 10分後, 明日9時, in 2h）",
+		}, n.handleRemindCommand).
+		AddBuiltin(BuiltinCommand{
+			Name:        "reminders",
+			Usage:       "!reminders",
+			Description: "自分のリマインダーの一覧を表示します",
+		}, n.handleRemindersCommand).
+		AddBuiltin(BuiltinCommand{
+			Name:        "unremind",
+			Usage:       "!unremind <番号>",
+			Description: "リマインダーを取り消します",
+		}, n.handleUnremindCommand).
+		AddBuiltin(BuiltinCommand{
+			Name:        "timezone",
+			Usage:       "!timezone [タイムゾーン|clear]",
+			Description: "リマインダーの日時を読むタイムゾーンを表示・設定します",
+		}, n.handleTimezoneCommand).
+		AddBuiltin(BuiltinCommand{
+			Name:        "unregister",
+			Usage:       "!unregister <コマンド名>",
+			Description: "コマンドを削除します",
+		}, n.handleUnregisterCommand).
 		SetCodeFallback(n.handleDynamicCodeCommand).
 		SetTextFallback(n.handleTextCommand).
 		SetMentionHandler(n.handleMention)
@@ -135,6 +641,7 @@ func (n *Nelchan) PrintConfig() {
 	fmt.Println("ねるちゃんの設定:")
 	fmt.Println("Env:", n.Config.Env)
 	fmt.Println("CodeSandboxURL:", n.Config.CodeSandboxURL)
+	fmt.Println("MemberEvents:", n.Config.MemberEvents)
 }
 
 func (n *Nelchan) SetIntents(intents discordgo.Intent) {
@@ -158,8 +665,23 @@ func (n *Nelchan) Start() error {
 	// Register ready handler to register slash commands on startup
 	n.Discord.AddHandler(n.handleReady)
 
-	// Set intents for guild messages
-	n.SetIntents(discordgo.IntentsGuildMessages)
+	// Register gateway event handlers for event triggers
+	n.Discord.AddHandler(n.handleReactionAdd)
+	n.Discord.AddHandler(n.handleReactionRemove)
+	n.Discord.AddHandler(n.handleMemberJoin)
+	n.Discord.AddHandler(n.handleMemberLeave)
+	n.Discord.AddHandler(n.handleThreadCreate)
+	n.Discord.AddHandler(n.handleVoiceStateUpdate)
+
+	// Set intents for guild messages and the events triggers can run on
+	intents := discordgo.IntentsGuildMessages |
+		discordgo.IntentsGuilds |
+		discordgo.IntentsGuildMessageReactions |
+		discordgo.IntentsGuildVoiceStates
+	if n.Config.MemberEvents {
+		intents |= discordgo.IntentsGuildMembers
+	}
+	n.SetIntents(intents)
 
 	err := n.Discord.Open()
 	if err != nil {
@@ -175,6 +697,22 @@ func (n *Nelchan) handleReady(s *discordgo.Session, r *discordgo.Ready) {
 
 	// Register built-in slash commands globally
 	n.registerBuiltinSlashCommands(s)
+
+	// Load user defined aliases for the command router
+	n.loadAliases()
+
+	// Load schedules, ready also fires on reconnects so the ticker is only started once
+	n.loadSchedules()
+	n.Scheduler.Start(scheduleTickInterval, n.runDueSchedules)
+
+	// Reminders that came due while the bot was offline are delivered right away
+	n.loadReminders()
+
+	// Load event triggers
+	n.loadTriggers()
+
+	// Load auto responders, which are matched against messages locally
+	n.loadResponders()
 }
 
 // registerBuiltinSlashCommands registers built-in slash commands globally
@@ -197,8 +735,7 @@ func (n *Nelchan) registerBuiltinSlashCommands(s *discordgo.Session) {
 	for _, cmd := range builtinSlashCommands {
 		existing, exists := existingByName[cmd.Name]
 		if exists {
-			// Check if options count differs (simple check for update)
-			if len(existing.Options) != len(cmd.Options) {
+			if builtinSlashCommandChanged(existing, cmd) {
 				// Update the command
 				_, err := s.ApplicationCommandEdit(s.State.User.ID, "", existing.ID, cmd)
 				if err != nil {
@@ -207,7 +744,7 @@ func (n *Nelchan) registerBuiltinSlashCommands(s *discordgo.Session) {
 				}
 				fmt.Printf("updated global slash command /%s (options: %d -> %d)\n", cmd.Name, len(existing.Options), len(cmd.Options))
 			} else {
-				fmt.Printf("global slash command /%s already exists with same options, skipping\n", cmd.Name)
+				fmt.Printf("global slash command /%s is up to date, skipping\n", cmd.Name)
 			}
 			continue
 		}
@@ -221,8 +758,31 @@ func (n *Nelchan) registerBuiltinSlashCommands(s *discordgo.Session) {
 	}
 }
 
+// builtinSlashCommandChanged reports whether a registered command differs from its built-in definition
+func builtinSlashCommandChanged(existing, cmd *discordgo.ApplicationCommand) bool {
+	return existing.Description != cmd.Description || slashOptionsChanged(existing.Options, cmd.Options)
+}
+
+// slashOptionsChanged reports whether registered options differ from their definitions, including subcommand options
+func slashOptionsChanged(existing, options []*discordgo.ApplicationCommandOption) bool {
+	if len(existing) != len(options) {
+		return true
+	}
+	for idx, opt := range options {
+		got := existing[idx]
+		if got.Name != opt.Name || got.Type != opt.Type || got.Description != opt.Description ||
+			got.Required != opt.Required || got.Autocomplete != opt.Autocomplete ||
+			slashOptionsChanged(got.Options, opt.Options) {
+			return true
+		}
+	}
+	return false
+}
+
 func (n *Nelchan) Close() error {
 	fmt.Println("ねるちゃんを停止します...")
+	n.Scheduler.Stop()
+	n.Reminders.Stop()
 	err := n.Discord.Close()
 	if err != nil {
 		return fmt.Errorf("ねるちゃんの停止に失敗しました: %w", err)
@@ -254,11 +814,23 @@ func (n *Nelchan) handleRegisterCodeCommand(s *discordgo.Session, m *discordgo.M
 
 	fmt.Printf("register_code command: name=%s, code=%s\n", commandName, code)
 
+	admin := n.isAdmin(s, m.Author.ID, m.ChannelID)
+	if !n.allowOverwrite(s, m, commandName, admin) {
+		return
+	}
+
+	// The args comment is stored as the command's schema and used to validate "!" invocations
+	args := n.CommandParser.ExtractArgsFromComment(code)
+	metadata := n.CommandParser.ExtractMetadataFromComment(code)
+
 	err := n.CommandAPIClient.RegisterCommand(RegisterCommandRequest{
 		CommandName:    commandName,
 		CommandContent: code,
 		IsCode:         true,
 		AuthorID:       m.Author.ID,
+		ArgsSchema:     args,
+		Metadata:       &metadata,
+		Admin:          admin,
 	})
 	if err != nil {
 		fmt.Println("error registering command,", err)
@@ -266,10 +838,12 @@ func (n *Nelchan) handleRegisterCodeCommand(s *discordgo.Session, m *discordgo.M
 		return
 	}
 
-	// Check for args comment and register as slash command if present
-	args := n.CommandParser.ExtractArgsFromComment(code)
+	// A registered command takes over its name from an alias
+	n.CommandRouter.RemoveAlias(commandName)
+
+	// Register as slash command if args comment is present
 	if args != nil {
-		err := n.registerSlashCommand(s, m.GuildID, commandName, args)
+		err := n.registerSlashCommand(s, m.GuildID, commandName, slashCommandDescription(commandName, metadata), args)
 		if err != nil {
 			fmt.Printf("error registering slash command: %v\n", err)
 			_, _ = s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("コードコマンド「%s」を登録しました！\n⚠️ スラッシュコマンドの登録に失敗: %s", commandName, err.Error()))
@@ -283,46 +857,117 @@ func (n *Nelchan) handleRegisterCodeCommand(s *discordgo.Session, m *discordgo.M
 }
 
 // registerSlashCommand registers a Discord application command for the given code command
-func (n *Nelchan) registerSlashCommand(s *discordgo.Session, guildID, commandName string, args []ArgOption) error {
+func (n *Nelchan) registerSlashCommand(s *discordgo.Session, guildID, commandName, description string, args []ArgOption) error {
 	options := make([]*discordgo.ApplicationCommandOption, len(args))
 	for i, arg := range args {
-		optionType := argTypeToDiscordType(arg.Type)
-		description := arg.Description
-		if description == "" {
-			description = arg.Name // Use name as fallback description
-		}
-		options[i] = &discordgo.ApplicationCommandOption{
-			Type:        optionType,
-			Name:        arg.Name,
-			Description: description,
-			Required:    arg.Required,
-		}
+		options[i] = argToDiscordOption(arg)
 	}
 
 	appCmd := &discordgo.ApplicationCommand{
 		Name:        commandName,
-		Description: fmt.Sprintf("ねるちゃんコマンド: %s", commandName),
+		Description: description,
 		Options:     options,
 	}
 
-	_, err := s.ApplicationCommandCreate(s.State.User.ID, guildID, appCmd)
+	created, err := s.ApplicationCommandCreate(s.State.User.ID, guildID, appCmd)
 	if err != nil {
 		return fmt.Errorf("failed to create application command: %w", err)
 	}
 
 	fmt.Printf("registered slash command: /%s with %d options\n", commandName, len(options))
+
+	// Remember where the application command lives so that it can be deleted with the command
+	err = n.CommandAPIClient.RecordSlashCommand(SlashCommandRecord{
+		CommandName:          commandName,
+		GuildID:              guildID,
+		ApplicationCommandID: created.ID,
+	})
+	if err != nil {
+		fmt.Printf("error recording slash command /%s: %v\n", commandName, err)
+	}
 	return nil
 }
 
+// maxSlashDescriptionLength is the maximum length of a slash command description
+const maxSlashDescriptionLength = 100
+
+// slashCommandDescription returns the slash command description of a code command
+// Falls back to a generic description when the command has none
+func slashCommandDescription(commandName string, metadata CommandMetadata) string {
+	description := metadata.Description
+	if description == "" {
+		return fmt.Sprintf("ねるちゃんコマンド: %s", commandName)
+	}
+	if utf8.RuneCountInString(description) > maxSlashDescriptionLength {
+		description = string([]rune(description)[:maxSlashDescriptionLength-1]) + "…"
+	}
+	return description
+}
+
+// argToDiscordOption converts an ArgOption to a Discord ApplicationCommandOption
+func argToDiscordOption(arg ArgOption) *discordgo.ApplicationCommandOption {
+	description := arg.Description
+	if description == "" {
+		description = arg.Name // Use name as fallback description
+	}
+
+	option := &discordgo.ApplicationCommandOption{
+		Type:        argTypeToDiscordType(arg.Type),
+		Name:        arg.Name,
+		Description: description,
+		Required:    arg.Required,
+		MinValue:    arg.MinValue,
+		MinLength:   arg.MinLength,
+	}
+	if arg.MaxValue != nil {
+		option.MaxValue = *arg.MaxValue
+	}
+	if arg.MaxLength != nil {
+		option.MaxLength = *arg.MaxLength
+	}
+
+	// Discord rejects options that have both choices and autocomplete
+	if arg.Autocomplete != nil && len(arg.Choices) == 0 {
+		option.Autocomplete = true
+	}
+
+	for _, choice := range arg.Choices {
+		// Choice values must have the option's type, e.g. integers for integer options
+		value, err := convertArgType(arg.Type, normalizeSchemaValue(choice.Value))
+		if err != nil {
+			fmt.Printf("skipping invalid choice %v for option %s: %v\n", choice.Value, arg.Name, err)
+			continue
+		}
+		option.Choices = append(option.Choices, &discordgo.ApplicationCommandOptionChoice{
+			Name:  choice.Name,
+			Value: value,
+		})
+	}
+
+	return option
+}
+
 // argTypeToDiscordType converts ArgOption type string to Discord ApplicationCommandOptionType
 func argTypeToDiscordType(t string) discordgo.ApplicationCommandOptionType {
 	switch t {
 	case "string":
 		return discordgo.ApplicationCommandOptionString
-	case "number", "integer":
+	case "integer":
 		return discordgo.ApplicationCommandOptionInteger
+	case "number":
+		return discordgo.ApplicationCommandOptionNumber
 	case "boolean", "bool":
 		return discordgo.ApplicationCommandOptionBoolean
+	case "user":
+		return discordgo.ApplicationCommandOptionUser
+	case "channel":
+		return discordgo.ApplicationCommandOptionChannel
+	case "role":
+		return discordgo.ApplicationCommandOptionRole
+	case "mentionable":
+		return discordgo.ApplicationCommandOptionMentionable
+	case "attachment":
+		return discordgo.ApplicationCommandOptionAttachment
 	default:
 		return discordgo.ApplicationCommandOptionString
 	}
@@ -349,6 +994,11 @@ func (n *Nelchan) handleSmartRegisterCommand(s *discordgo.Session, m *discordgo.
 
 	fmt.Printf("sreg command: name=%s, description=%s\n", commandName, description)
 
+	admin := n.isAdmin(s, m.Author.ID, m.ChannelID)
+	if !n.allowOverwrite(s, m, commandName, admin) {
+		return
+	}
+
 	// Show "typing" indicator while processing
 	_ = s.ChannelTyping(m.ChannelID)
 
@@ -356,6 +1006,7 @@ func (n *Nelchan) handleSmartRegisterCommand(s *discordgo.Session, m *discordgo.
 		CommandName: commandName,
 		Description: description,
 		AuthorID:    m.Author.ID,
+		Admin:       admin,
 	})
 	if err != nil {
 		fmt.Println("error smart registering command,", err)
@@ -363,6 +1014,9 @@ func (n *Nelchan) handleSmartRegisterCommand(s *discordgo.Session, m *discordgo.
 		return
 	}
 
+	// A registered command takes over its name from an alias
+	n.CommandRouter.RemoveAlias(result.CommandName)
+
 	// Format success message with generated code and usage
 	message := fmt.Sprintf("コマンド「%s」を登録しました！\n\n**使い方:**\n%s\n\n**生成されたコード:**\n```python\n%s\n```",
 		result.CommandName,
@@ -396,11 +1050,17 @@ func (n *Nelchan) handleRegisterCommand(s *discordgo.Session, m *discordgo.Messa
 
 	fmt.Printf("register command: name=%s, text=%s\n", commandName, text)
 
+	admin := n.isAdmin(s, m.Author.ID, m.ChannelID)
+	if !n.allowOverwrite(s, m, commandName, admin) {
+		return
+	}
+
 	err := n.CommandAPIClient.RegisterCommand(RegisterCommandRequest{
 		CommandName:    commandName,
 		CommandContent: text,
 		IsCode:         false,
 		AuthorID:       m.Author.ID,
+		Admin:          admin,
 	})
 	if err != nil {
 		fmt.Println("error registering command,", err)
@@ -408,6 +1068,9 @@ func (n *Nelchan) handleRegisterCommand(s *discordgo.Session, m *discordgo.Messa
 		return
 	}
 
+	// A registered command takes over its name from an alias
+	n.CommandRouter.RemoveAlias(commandName)
+
 	_, _ = s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("コマンド「%s」を登録しました！", commandName))
 }
 
@@ -424,19 +1087,44 @@ func (n *Nelchan) handleExecCommand(s *discordgo.Session, m *discordgo.MessageCr
 		return
 	}
 
-	vars := map[string]string{
-		"username":    m.Author.GlobalName,
-		"user_id":     m.Author.ID,
-		"user_avatar": m.Author.Avatar,
-		"channel_id":  m.ChannelID,
+	info := n.lookupCodeCommand(commandName)
+	if info == nil {
+		// Code command not found, don't respond
+		return
+	}
+
+	_, raw := CutFirstField(n.CommandParser.ExtractArgsText(m.Content))
+	positional, named, err := n.parseCodeCommandArgs(info, raw)
+	if err != nil {
+		_, _ = s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("引数の解析に失敗しました: %s", err.Error()))
+		return
 	}
 
-	result, err := n.CommandAPIClient.RunCommand(RunCommandRequest{
+	named, ok := n.resolveCodeCommandArgs(s, m, info, named)
+	if !ok {
+		return
+	}
+
+	ctx := messageContext(s, RunSourceExec, m.Message)
+
+	request := withMessageInput(s, m.Message, RunCommandRequest{
 		CommandName: commandName,
 		IsCode:      true,
-		Vars:        vars,
-		Args:        cmd.Args,
+		Vars:        ctx.Vars(),
+		Args:        append([]string{commandName}, positional...),
+		Named:       named,
+		Context:     &ctx,
 	})
+
+	start := time.Now()
+	result, err := n.CommandAPIClient.RunCommand(request)
+	n.recordCommandRun(CommandRun{
+		CommandName: commandName,
+		UserID:      m.Author.ID,
+		ChannelID:   m.ChannelID,
+		GuildID:     m.GuildID,
+		Source:      RunSourceExec,
+	}, start, result, err)
 	if err != nil {
 		fmt.Println("error running command,", err)
 		_, _ = s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("エラー: %s", err.Error()))
@@ -448,7 +1136,7 @@ func (n *Nelchan) handleExecCommand(s *discordgo.Session, m *discordgo.MessageCr
 		return
 	}
 
-	err = n.sendMessage(s, m.ChannelID, result.Content)
+	err = n.sendCommandOutput(s, m.ChannelID, result)
 	if err != nil {
 		fmt.Println("error sending message,", err)
 		return
@@ -459,22 +1147,45 @@ func (n *Nelchan) handleExecCommand(s *discordgo.Session, m *discordgo.MessageCr
 
 // handleDynamicCodeCommand handles code commands that are not registered as built-in commands
 func (n *Nelchan) handleDynamicCodeCommand(s *discordgo.Session, m *discordgo.MessageCreate, cmd *SlashCommand) {
-	vars := map[string]string{
-		"username":    m.Author.GlobalName,
-		"user_id":     m.Author.ID,
-		"user_avatar": m.Author.Avatar,
-		"channel_id":  m.ChannelID,
+	info := n.lookupCodeCommand(cmd.Name)
+	if info == nil {
+		// Code command not found, don't respond
+		return
+	}
+
+	raw := n.CommandParser.ExtractArgsText(m.Content)
+	args, named, err := n.parseCodeCommandArgs(info, raw)
+	if err != nil {
+		_, _ = s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("引数の解析に失敗しました: %s", err.Error()))
+		return
 	}
 
-	args := cmd.Args
+	named, ok := n.resolveCodeCommandArgs(s, m, info, named)
+	if !ok {
+		return
+	}
 
-	result, err := n.CommandAPIClient.RunCommand(RunCommandRequest{
+	ctx := messageContext(s, RunSourceMessage, m.Message)
+
+	request := withMessageInput(s, m.Message, RunCommandRequest{
 		CommandName: cmd.Name,
 		IsCode:      true,
-		Vars:        vars,
+		Vars:        ctx.Vars(),
 		Args:        args,
+		Named:       named,
+		Context:     &ctx,
 	})
 
+	start := time.Now()
+	result, err := n.CommandAPIClient.RunCommand(request)
+	n.recordCommandRun(CommandRun{
+		CommandName: cmd.Name,
+		UserID:      m.Author.ID,
+		ChannelID:   m.ChannelID,
+		GuildID:     m.GuildID,
+		Source:      RunSourceMessage,
+	}, start, result, err)
+
 	if err != nil {
 		fmt.Println("error running command,", err)
 		_, _ = s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("エラー: %s", err.Error()))
@@ -486,7 +1197,7 @@ func (n *Nelchan) handleDynamicCodeCommand(s *discordgo.Session, m *discordgo.Me
 		return
 	}
 
-	err = n.sendMessage(s, m.ChannelID, result.Content)
+	err = n.sendCommandOutput(s, m.ChannelID, result)
 	if err != nil {
 		fmt.Println("error sending message,", err)
 		return
@@ -495,6 +1206,89 @@ func (n *Nelchan) handleDynamicCodeCommand(s *discordgo.Session, m *discordgo.Me
 	fmt.Printf("message sent: %s\n", result.Content)
 }
 
+// lookupCodeCommand fetches a code command by name
+// Returns nil if the command doesn't exist, is a text command or can't be fetched
+func (n *Nelchan) lookupCodeCommand(commandName string) *GetCommandInfo {
+	info, err := n.CommandAPIClient.GetCommand(GetCommandRequest{
+		CommandName: commandName,
+	})
+	if err != nil {
+		fmt.Println("error getting command,", err)
+		return nil
+	}
+
+	if info == nil || !info.IsCode {
+		return nil
+	}
+	return info
+}
+
+// parseCodeCommandArgs splits the raw argument text of a code command into positional and named args
+// Commands whose code contains "# parse = quoted" are tokenized shell-style, all others on whitespace.
+// Positional args are also bound to the names declared in the command's args schema.
+func (n *Nelchan) parseCodeCommandArgs(info *GetCommandInfo, raw string) ([]string, map[string]any, error) {
+	tokens := n.CommandParser.FieldTokens(raw)
+	if info != nil && n.CommandParser.ExtractParseModeFromComment(info.Content) == ParseModeQuoted {
+		var err error
+		tokens, err = n.CommandParser.SplitQuotedTokens(raw)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	positional, named := n.bindCodeCommandArgs(info, tokens)
+	return positional, named, nil
+}
+
+// bindCodeCommandArgs separates the named args a code command accepts from its positional args
+// and binds the positional args to the names declared in its args schema
+func (n *Nelchan) bindCodeCommandArgs(info *GetCommandInfo, tokens []ArgToken) ([]string, map[string]any) {
+	// Commands that take no named args get every token in args
+	schema := n.commandArgsSchema(info)
+	var allowed func(string) bool
+	if info != nil {
+		allowed = n.CommandParser.NamedArgKeys(info.Content, schema)
+	}
+	if allowed == nil {
+		return tokenTexts(tokens), map[string]any{}
+	}
+
+	positional, named := n.CommandParser.ExtractNamedArgs(tokens, allowed)
+	named = n.CommandParser.BindPositionalArgs(schema, positional, named)
+	return positional, named
+}
+
+// commandArgsSchema returns the ArgOption schema stored with a code command
+// Commands registered before schemas were stored fall back to their "# args" comment
+func (n *Nelchan) commandArgsSchema(info *GetCommandInfo) []ArgOption {
+	if info == nil {
+		return nil
+	}
+	if info.ArgsSchema != nil {
+		return info.ArgsSchema
+	}
+	return n.CommandParser.ExtractArgsFromComment(info.Content)
+}
+
+// resolveCodeCommandArgs validates named args against the command's args schema and converts them to their types
+// Message attachments are bound to attachment args first.
+// On failure it replies with the problems and a usage message, and returns false
+func (n *Nelchan) resolveCodeCommandArgs(s *discordgo.Session, m *discordgo.MessageCreate, info *GetCommandInfo, named map[string]any) (map[string]any, bool) {
+	schema := n.commandArgsSchema(info)
+	named = BindAttachmentArgs(schema, m.Attachments, named)
+
+	resolved, err := ResolveArgs(schema, named)
+	if err == nil {
+		return resolved, true
+	}
+
+	message := fmt.Sprintf("引数が正しくありません:\n%s\n\n%s", err.Error(), FormatArgsUsage(info.Name, schema))
+	if err := n.sendMessage(s, m.ChannelID, message); err != nil {
+		fmt.Println("error sending message,", err)
+	}
+	return nil, false
+}
+
 // handleShowCommand handles the !show command
 // Usage: !show <command_name>
 // Displays the content of a registered command (code as snippet, text as plain text)
@@ -532,7 +1326,18 @@ func (n *Nelchan) handleShowCommand(s *discordgo.Session, m *discordgo.MessageCr
 	} else {
 		// Output as plain text
 		message = fmt.Sprintf("コマンド「%s」のテキスト:\n%s", commandName, result.Content)
+		if len(result.Responses) > 1 {
+			message += fmt.Sprintf("\n（ほかに %d 件の応答があります。`!responses %s` で一覧を表示します）", len(result.Responses)-1, commandName)
+		}
+	}
+	if !result.Metadata.IsEmpty() {
+		message = FormatCommandMetadata(result.Metadata) + "\n" + message
+	}
+	lastUsed := "未実行"
+	if result.LastUsedAt != "" {
+		lastUsed = result.LastUsedAt
 	}
+	message = fmt.Sprintf("最終実行: %s\n%s", lastUsed, message)
 
 	err = n.sendMessage(s, m.ChannelID, message)
 	if err != nil {
@@ -544,12 +1349,25 @@ func (n *Nelchan) handleShowCommand(s *discordgo.Session, m *discordgo.MessageCr
 }
 
 // handleTextCommand handles text commands (without ! prefix)
+// Auto responders matching the message take precedence over a command named by its first word
 func (n *Nelchan) handleTextCommand(s *discordgo.Session, m *discordgo.MessageCreate, cmd *SlashCommand) {
+	if n.tryAutoResponder(s, m) {
+		return
+	}
+
+	start := time.Now()
 	result, err := n.CommandAPIClient.RunCommand(RunCommandRequest{
 		CommandName: cmd.Name,
 		IsCode:      false,
 		Vars:        nil,
 	})
+	n.recordCommandRun(CommandRun{
+		CommandName: cmd.Name,
+		UserID:      m.Author.ID,
+		ChannelID:   m.ChannelID,
+		GuildID:     m.GuildID,
+		Source:      RunSourceText,
+	}, start, result, err)
 	// Silently ignore errors and not found (404) for text commands
 	if err != nil || result == nil {
 		return
@@ -557,7 +1375,7 @@ func (n *Nelchan) handleTextCommand(s *discordgo.Session, m *discordgo.MessageCr
 
 	fmt.Printf("text command fired: %s\n", cmd.Name)
 
-	err = n.sendMessage(s, m.ChannelID, result.Content)
+	err = n.sendMessage(s, m.ChannelID, n.renderTextCommand(s, m, pickTextResponse(result), cmd.Args))
 	if err != nil {
 		fmt.Println("error sending message,", err)
 		return
@@ -626,28 +1444,51 @@ func (n *Nelchan) handleMention(s *discordgo.Session, m *discordgo.MessageCreate
 		return
 	}
 
+	info := n.lookupCodeCommand(*mentionCmd)
+	if info == nil {
+		fmt.Printf("mention command %s not found\n", *mentionCmd)
+		_, _ = s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("メンションコマンド `%s` が見つかりません", *mentionCmd))
+		return
+	}
+
 	fmt.Printf("executing mention command: %s with args: %s\n", *mentionCmd, args)
 
 	// Show "typing" indicator while processing
 	_ = s.ChannelTyping(m.ChannelID)
 
-	vars := map[string]string{
-		"username":    m.Author.GlobalName,
-		"user_id":     m.Author.ID,
-		"user_avatar": m.Author.Avatar,
-		"channel_id":  m.ChannelID,
+	ctx := messageContext(s, RunSourceMention, m.Message)
+
+	// Split args into positional and named args
+	argSlice, named, err := n.parseCodeCommandArgs(info, args)
+	if err != nil {
+		_, _ = s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("引数の解析に失敗しました: %s", err.Error()))
+		return
 	}
 
-	// Split args into slice
-	argSlice := strings.Fields(args)
+	named, ok := n.resolveCodeCommandArgs(s, m, info, named)
+	if !ok {
+		return
+	}
 
-	result, err := n.CommandAPIClient.RunCommand(RunCommandRequest{
+	request := withMessageInput(s, m.Message, RunCommandRequest{
 		CommandName: *mentionCmd,
 		IsCode:      true,
-		Vars:        vars,
+		Vars:        ctx.Vars(),
 		Args:        argSlice,
+		Named:       named,
+		Context:     &ctx,
 	})
 
+	start := time.Now()
+	result, err := n.CommandAPIClient.RunCommand(request)
+	n.recordCommandRun(CommandRun{
+		CommandName: *mentionCmd,
+		UserID:      m.Author.ID,
+		ChannelID:   m.ChannelID,
+		GuildID:     m.GuildID,
+		Source:      RunSourceMention,
+	}, start, result, err)
+
 	if err != nil {
 		fmt.Println("error running mention command:", err)
 		_, _ = s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("エラー: %s", err.Error()))
@@ -660,7 +1501,7 @@ func (n *Nelchan) handleMention(s *discordgo.Session, m *discordgo.MessageCreate
 		return
 	}
 
-	err = n.sendMessage(s, m.ChannelID, result.Content)
+	err = n.sendCommandOutput(s, m.ChannelID, result)
 	if err != nil {
 		fmt.Println("error sending message:", err)
 		return
@@ -674,6 +1515,12 @@ const maxMessageLength = 2000
 // sendMessage sends a message to the specified channel.
 // If the content exceeds Discord's 2000 character limit, it sends the content as a text file attachment.
 func (n *Nelchan) sendMessage(s *discordgo.Session, channelID, content string) error {
+	return n.sendMessageOrFile(s, channelID, content, "result.txt", content)
+}
+
+// sendMessageOrFile sends content as a message to the specified channel.
+// If the content exceeds Discord's 2000 character limit, it sends fileContent as a file attachment named fileName instead.
+func (n *Nelchan) sendMessageOrFile(s *discordgo.Session, channelID, content, fileName, fileContent string) error {
 	if utf8.RuneCountInString(content) <= maxMessageLength {
 		_, err := s.ChannelMessageSend(channelID, content)
 		return err
@@ -684,9 +1531,9 @@ func (n *Nelchan) sendMessage(s *discordgo.Session, channelID, content string) e
 		Content: "結果が長すぎるためファイルとして送信します",
 		Files: []*discordgo.File{
 			{
-				Name:        "result.txt",
+				Name:        fileName,
 				ContentType: "text/plain",
-				Reader:      strings.NewReader(content),
+				Reader:      strings.NewReader(fileContent),
 			},
 		},
 	})
@@ -695,6 +1542,21 @@ func (n *Nelchan) sendMessage(s *discordgo.Session, channelID, content string) e
 
 // handleInteraction handles Discord slash command interactions
 func (n *Nelchan) handleInteraction(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	if i.Type == discordgo.InteractionApplicationCommandAutocomplete {
+		n.handleAutocomplete(s, i)
+		return
+	}
+
+	if i.Type == discordgo.InteractionMessageComponent {
+		n.handleComponent(s, i)
+		return
+	}
+
+	if i.Type == discordgo.InteractionModalSubmit {
+		n.handleModalSubmit(s, i)
+		return
+	}
+
 	if i.Type != discordgo.InteractionApplicationCommand {
 		return
 	}
@@ -710,12 +1572,42 @@ func (n *Nelchan) handleInteraction(s *discordgo.Session, i *discordgo.Interacti
 	case "set_mention":
 		n.handleSetMentionSlashCommand(s, i)
 		return
+	case "unregister":
+		n.handleUnregisterSlashCommand(s, i)
+		return
+	case "help":
+		n.handleHelpSlashCommand(s, i)
+		return
+	case "stats":
+		n.handleStatsSlashCommand(s, i)
+		return
+	case "export":
+		n.handleExportSlashCommand(s, i)
+		return
+	case "import":
+		n.handleImportSlashCommand(s, i)
+		return
+	case "schedule":
+		n.handleScheduleSlashCommand(s, i)
+		return
+	case "remind":
+		n.handleRemindSlashCommand(s, i)
+		return
+	case "trigger":
+		n.handleTriggerSlashCommand(s, i)
+		return
+	case "responder":
+		n.handleResponderSlashCommand(s, i)
+		return
 	case "reset-slash-commands":
 		n.handleResetSlashCommandsCommand(s, i)
 		return
 	case "register-builtin-commands":
 		n.handleRegisterBuiltinCommandsCommand(s, i)
 		return
+	case messageRunCommandName:
+		n.handleMessageRunCommand(s, i)
+		return
 	}
 
 	// Handle dynamic code commands
@@ -742,23 +1634,76 @@ func (n *Nelchan) handleRegisterSlashCommand(s *discordgo.Session, i *discordgo.
 
 	// Extract options
 	var commandNameOpt, textOpt string
+	var appendOpt bool
+	var weightOpt int
+	var metadata CommandMetadata
 	for _, opt := range data.Options {
 		switch opt.Name {
 		case "command_name":
 			commandNameOpt = opt.StringValue()
 		case "text":
 			textOpt = opt.StringValue()
+		case "append":
+			appendOpt = opt.BoolValue()
+		case "weight":
+			weightOpt = int(opt.IntValue())
+		case "description":
+			metadata.Description = opt.StringValue()
+		case "usage":
+			metadata.Usage = opt.StringValue()
+		case "tags":
+			metadata.Tags = ParseTags(opt.StringValue())
+		case "category":
+			metadata.Category = opt.StringValue()
 		}
 	}
 	fmt.Printf("DEBUG /register: commandNameOpt=%s, textOpt=%s\n", commandNameOpt, textOpt)
 
-	// Register the command
-	err := n.CommandAPIClient.RegisterCommand(RegisterCommandRequest{
+	// Append mode adds another response to the command instead of replacing it
+	if appendOpt {
+		message, err := n.appendTextResponse(commandNameOpt, textOpt, weightOpt, user.ID, n.isInteractionAdmin(i))
+		if err != nil {
+			fmt.Printf("error adding response via slash: %v\n", err)
+			message = fmt.Sprintf("エラー: %s", err.Error())
+		}
+		_ = s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseChannelMessageWithSource,
+			Data: &discordgo.InteractionResponseData{
+				Content: message,
+			},
+		})
+		return
+	}
+
+	admin := n.isInteractionAdmin(i)
+	message, err := n.checkOverwrite(commandNameOpt, user.ID, admin)
+	if err != nil {
+		fmt.Printf("error getting command via slash: %v\n", err)
+		message = fmt.Sprintf("エラー: %s", err.Error())
+	}
+	if message != "" {
+		_ = s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseChannelMessageWithSource,
+			Data: &discordgo.InteractionResponseData{
+				Content: message,
+			},
+		})
+		return
+	}
+
+	// Register the command, keeping the stored metadata unless some was given
+	request := RegisterCommandRequest{
 		CommandName:    commandNameOpt,
 		CommandContent: textOpt,
 		IsCode:         false,
 		AuthorID:       user.ID,
-	})
+		Weight:         weightOpt,
+		Admin:          admin,
+	}
+	if !metadata.IsEmpty() {
+		request.Metadata = &metadata
+	}
+	err = n.CommandAPIClient.RegisterCommand(request)
 
 	if err != nil {
 		fmt.Printf("error registering command via slash: %v\n", err)
@@ -771,6 +1716,9 @@ func (n *Nelchan) handleRegisterSlashCommand(s *discordgo.Session, i *discordgo.
 		return
 	}
 
+	// A registered command takes over its name from an alias
+	n.CommandRouter.RemoveAlias(commandNameOpt)
+
 	_ = s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
 		Type: discordgo.InteractionResponseChannelMessageWithSource,
 		Data: &discordgo.InteractionResponseData{
@@ -873,23 +1821,14 @@ func (n *Nelchan) handleDynamicSlashCommand(s *discordgo.Session, i *discordgo.I
 
 	// Extract args from interaction options
 	args := make([]string, len(data.Options))
+	named := make(map[string]any, len(data.Options))
 	for idx, opt := range data.Options {
 		args[idx] = fmt.Sprintf("%v", opt.Value)
+		named[opt.Name] = slashOptionValue(data, opt)
 	}
 
-	// Get user info
-	var user *discordgo.User
-	if i.Member != nil {
-		user = i.Member.User
-	} else {
-		user = i.User
-	}
-
-	vars := map[string]string{
-		"username":    user.GlobalName,
-		"user_id":     user.ID,
-		"user_avatar": user.Avatar,
-	}
+	user := interactionUser(i)
+	ctx := interactionContext(s, RunSourceSlash, i)
 
 	// Defer response to allow longer processing time
 	err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
@@ -900,13 +1839,36 @@ func (n *Nelchan) handleDynamicSlashCommand(s *discordgo.Session, i *discordgo.I
 		return
 	}
 
+	// Convert option values to the types declared in the args schema and apply defaults
+	if info := n.lookupCodeCommand(commandName); info != nil {
+		schema := n.commandArgsSchema(info)
+		resolved, err := ResolveArgs(schema, named)
+		if err != nil {
+			_, _ = s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+				Content: stringPtr(fmt.Sprintf("引数が正しくありません:\n%s", err.Error())),
+			})
+			return
+		}
+		named = resolved
+	}
+
 	// Run the command
+	start := time.Now()
 	result, err := n.CommandAPIClient.RunCommand(RunCommandRequest{
 		CommandName: commandName,
 		IsCode:      true,
-		Vars:        vars,
+		Vars:        ctx.Vars(),
 		Args:        args,
+		Named:       named,
+		Context:     &ctx,
 	})
+	n.recordCommandRun(CommandRun{
+		CommandName: commandName,
+		UserID:      user.ID,
+		ChannelID:   i.ChannelID,
+		GuildID:     i.GuildID,
+		Source:      RunSourceSlash,
+	}, start, result, err)
 
 	if err != nil {
 		fmt.Printf("error running slash command: %v\n", err)
@@ -924,21 +1886,24 @@ func (n *Nelchan) handleDynamicSlashCommand(s *discordgo.Session, i *discordgo.I
 	}
 
 	// Edit the deferred response with the result
-	content := result.Content
-	if utf8.RuneCountInString(content) > maxMessageLength {
-		content = content[:maxMessageLength-3] + "..."
-	}
-
-	_, err = s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
-		Content: &content,
-	})
-	if err != nil {
-		fmt.Printf("error editing interaction response: %v\n", err)
-	}
+	n.editInteractionOutput(s, i, result)
 
 	fmt.Printf("slash command executed: /%s\n", commandName)
 }
 
+// slashOptionValue returns the value of a slash command option as passed to code commands
+// Attachments are resolved to their metadata, every other option keeps its raw value
+func slashOptionValue(data discordgo.ApplicationCommandInteractionData, opt *discordgo.ApplicationCommandInteractionDataOption) any {
+	if opt.Type == discordgo.ApplicationCommandOptionAttachment && data.Resolved != nil {
+		if id, ok := opt.Value.(string); ok {
+			if attachment, exists := data.Resolved.Attachments[id]; exists {
+				return attachmentArgValue(attachment)
+			}
+		}
+	}
+	return opt.Value
+}
+
 func stringPtr(s string) *string {
 	return &s
 }
@@ -0,0 +1,604 @@
+package nelchanbot
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// outputEnvelopeVersion is the value of "nelchan" marking a line of output as structured output
+const outputEnvelopeVersion = 1
+
+// Discord limits structured output is validated against
+const (
+	maxOutputEmbeds           = 10
+	maxEmbedTitleLength       = 256
+	maxEmbedDescriptionLength = 4096
+	maxEmbedFields            = 25
+	maxEmbedFieldNameLength   = 256
+	maxEmbedFieldValueLength  = 1024
+	maxEmbedFooterLength      = 2048
+	maxEmbedAuthorLength      = 256
+	maxEmbedTotalLength       = 6000 // characters of all embeds of a message together
+	maxOutputFiles            = 10
+	maxUploadBytes            = 10 << 20 // total size of the files of a message without a boosted server
+	maxOutputReactions        = 20
+	maxOutputButtons          = 25
+	maxButtonsPerRow          = 5
+	maxButtonLabelLength      = 80
+	maxCustomIDLength         = 100
+)
+
+// runButtonComponentPrefix is the custom_id prefix of buttons that run a code command
+const runButtonComponentPrefix = "run"
+
+// reactionEmojiRe matches a custom emoji as written in a message, reactions take "name:id"
+var reactionEmojiRe = regexp.MustCompile(`^<a?:(\w+:\d+)>$`)
+
+// buttonStyles are the button styles structured output may use
+var buttonStyles = map[string]discordgo.ButtonStyle{
+	"":          discordgo.SecondaryButton,
+	"primary":   discordgo.PrimaryButton,
+	"secondary": discordgo.SecondaryButton,
+	"success":   discordgo.SuccessButton,
+	"danger":    discordgo.DangerButton,
+}
+
+// CommandOutput is the structured output of a code command
+// A code command opts in by printing it as JSON with "nelchan": 1 on the last line of its output
+//
+//	{"nelchan": 1, "content": "...", "embeds": [...], "files": [...], "reactions": ["👍"], "components": [...]}
+type CommandOutput struct {
+	Nelchan         int                               `json:"nelchan"`
+	Content         string                            `json:"content,omitempty"`
+	Embeds          []*discordgo.MessageEmbed         `json:"embeds,omitempty"`
+	Files           []OutputFile                      `json:"files,omitempty"`
+	AllowedMentions *discordgo.MessageAllowedMentions `json:"allowed_mentions,omitempty"`
+	Reactions       []string                          `json:"reactions,omitempty"`
+	Components      []OutputButton                    `json:"components,omitempty"`
+}
+
+// OutputFile is a file attached by structured output
+type OutputFile struct {
+	Name        string `json:"name"`
+	ContentType string `json:"content_type,omitempty"`
+	Data        string `json:"data"` // base64
+}
+
+// OutputButton is a button added by structured output
+// A button either opens URL or runs Command with Args as the user who clicked it
+type OutputButton struct {
+	Label    string   `json:"label"`
+	Style    string   `json:"style,omitempty"` // primary, secondary, success or danger; ignored for links
+	Emoji    string   `json:"emoji,omitempty"`
+	URL      string   `json:"url,omitempty"`
+	Command  string   `json:"command,omitempty"`
+	Args     []string `json:"args,omitempty"`
+	Disabled bool     `json:"disabled,omitempty"`
+}
+
+// ParseCommandOutput finds structured output in the output of a code command
+// The envelope is either the whole output, which may be indented JSON, or its last line.
+// Text printed before an envelope on the last line is put in front of its content.
+// Returns nil for plain output.
+func ParseCommandOutput(stdout string) *CommandOutput {
+	trimmed := strings.TrimSpace(stdout)
+	if output := parseOutputEnvelope(trimmed); output != nil {
+		return output
+	}
+
+	idx := strings.LastIndexByte(trimmed, '\n')
+	if idx < 0 {
+		return nil
+	}
+	output := parseOutputEnvelope(strings.TrimSpace(trimmed[idx+1:]))
+	if output == nil {
+		return nil
+	}
+	if before := strings.TrimSpace(trimmed[:idx]); before != "" {
+		output.Content = strings.TrimSpace(before + "\n" + output.Content)
+	}
+	return output
+}
+
+// parseOutputEnvelope decodes a structured output envelope, nil if s isn't one
+func parseOutputEnvelope(s string) *CommandOutput {
+	if !strings.HasPrefix(s, "{") {
+		return nil
+	}
+	var output CommandOutput
+	if err := json.Unmarshal([]byte(s), &output); err != nil || output.Nelchan != outputEnvelopeVersion {
+		return nil
+	}
+	return &output
+}
+
+// Validate checks structured output against Discord's limits, reporting every problem at once
+// limit is the upload limit in bytes of the channel the output is sent to
+func (o *CommandOutput) Validate(limit int) error {
+	var problems []string
+	add := func(format string, args ...any) {
+		problems = append(problems, fmt.Sprintf(format, args...))
+	}
+
+	if utf8.RuneCountInString(o.Content) > maxMessageLength {
+		add("content は %d 文字までです", maxMessageLength)
+	}
+	if o.Content == "" && len(o.Embeds) == 0 && len(o.Files) == 0 {
+		add("content・embeds・files のどれかが必要です")
+	}
+
+	if len(o.Embeds) > maxOutputEmbeds {
+		add("embeds は %d 個までです", maxOutputEmbeds)
+	}
+	total := 0
+	for idx, embed := range o.Embeds {
+		if embed == nil {
+			add("embeds[%d] が空です", idx)
+			continue
+		}
+		total += embedLength(embed)
+		for _, problem := range validateEmbed(embed) {
+			add("embeds[%d]: %s", idx, problem)
+		}
+	}
+	if total > maxEmbedTotalLength {
+		add("embeds の文字数は合計 %d 文字までです", maxEmbedTotalLength)
+	}
+
+	if len(o.Files) > maxOutputFiles {
+		add("files は %d 個までです", maxOutputFiles)
+	}
+	size := 0
+	for idx, file := range o.Files {
+		data, err := base64.StdEncoding.DecodeString(file.Data)
+		switch {
+		case file.Name == "":
+			add("files[%d] に name がありません", idx)
+		case err != nil:
+			add("files[%d] の data が base64 ではありません", idx)
+		}
+		size += len(data)
+	}
+	if size > limit {
+		add("files の大きさは合計 %d MB までです", limit>>20)
+	}
+
+	if len(o.Reactions) > maxOutputReactions {
+		add("reactions は %d 個までです", maxOutputReactions)
+	}
+	for idx, emoji := range o.Reactions {
+		if strings.TrimSpace(emoji) == "" {
+			add("reactions[%d] が空です", idx)
+		}
+	}
+
+	if len(o.Components) > maxOutputButtons {
+		add("components は %d 個までです", maxOutputButtons)
+	}
+	for idx, button := range o.Components {
+		for _, problem := range validateButton(button) {
+			add("components[%d]: %s", idx, problem)
+		}
+	}
+
+	if len(problems) > 0 {
+		return errors.New(strings.Join(problems, "\n"))
+	}
+	return nil
+}
+
+// validateEmbed checks the text limits of an embed
+func validateEmbed(embed *discordgo.MessageEmbed) []string {
+	var problems []string
+	check := func(name, value string, limit int) {
+		if utf8.RuneCountInString(value) > limit {
+			problems = append(problems, fmt.Sprintf("%s は %d 文字までです", name, limit))
+		}
+	}
+
+	check("title", embed.Title, maxEmbedTitleLength)
+	check("description", embed.Description, maxEmbedDescriptionLength)
+	if embed.Footer != nil {
+		check("footer.text", embed.Footer.Text, maxEmbedFooterLength)
+	}
+	if embed.Author != nil {
+		check("author.name", embed.Author.Name, maxEmbedAuthorLength)
+	}
+	if len(embed.Fields) > maxEmbedFields {
+		problems = append(problems, fmt.Sprintf("fields は %d 個までです", maxEmbedFields))
+	}
+	for idx, field := range embed.Fields {
+		if field == nil || field.Name == "" || field.Value == "" {
+			problems = append(problems, fmt.Sprintf("fields[%d] には name と value が必要です", idx))
+			continue
+		}
+		check(fmt.Sprintf("fields[%d].name", idx), field.Name, maxEmbedFieldNameLength)
+		check(fmt.Sprintf("fields[%d].value", idx), field.Value, maxEmbedFieldValueLength)
+	}
+	if embed.Timestamp != "" {
+		if _, err := time.Parse(time.RFC3339, embed.Timestamp); err != nil {
+			problems = append(problems, "timestamp は ISO 8601 形式で指定してください")
+		}
+	}
+	return problems
+}
+
+// embedLength counts the characters of an embed toward the limit of all embeds of a message
+func embedLength(embed *discordgo.MessageEmbed) int {
+	length := utf8.RuneCountInString(embed.Title) + utf8.RuneCountInString(embed.Description)
+	if embed.Footer != nil {
+		length += utf8.RuneCountInString(embed.Footer.Text)
+	}
+	if embed.Author != nil {
+		length += utf8.RuneCountInString(embed.Author.Name)
+	}
+	for _, field := range embed.Fields {
+		if field != nil {
+			length += utf8.RuneCountInString(field.Name) + utf8.RuneCountInString(field.Value)
+		}
+	}
+	return length
+}
+
+// validateButton checks a button of structured output
+func validateButton(button OutputButton) []string {
+	var problems []string
+	if button.Label == "" && button.Emoji == "" {
+		problems = append(problems, "label か emoji が必要です")
+	}
+	if utf8.RuneCountInString(button.Label) > maxButtonLabelLength {
+		problems = append(problems, fmt.Sprintf("label は %d 文字までです", maxButtonLabelLength))
+	}
+
+	switch {
+	case button.URL != "" && button.Command != "":
+		problems = append(problems, "url と command は同時に指定できません")
+	case button.URL != "":
+		if u, err := url.Parse(button.URL); err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+			problems = append(problems, "url は http(s) の URL で指定してください")
+		}
+	case button.Command != "":
+		if _, ok := buttonStyles[button.Style]; !ok {
+			problems = append(problems, fmt.Sprintf("style が不明です: %q", button.Style))
+		}
+		if strings.Contains(button.Command, ":") || strings.Contains(strings.Join(button.Args, ""), ":") {
+			problems = append(problems, "command と args に「:」は使えません")
+		}
+		if len(runButtonCustomID(button)) > maxCustomIDLength {
+			problems = append(problems, fmt.Sprintf("command と args は合わせて約 %d 文字までです", maxCustomIDLength))
+		}
+	default:
+		problems = append(problems, "url か command が必要です")
+	}
+	return problems
+}
+
+// runButtonCustomID returns the custom_id of a button running a code command: "run:<command>:<args...>"
+func runButtonCustomID(button OutputButton) string {
+	return strings.Join(append([]string{runButtonComponentPrefix, button.Command}, button.Args...), ":")
+}
+
+// buttonEmoji converts the emoji of a button, a unicode emoji or a custom emoji as written in a message
+func buttonEmoji(s string) *discordgo.ComponentEmoji {
+	if s == "" {
+		return nil
+	}
+	if m := reactionEmojiRe.FindStringSubmatch(s); m != nil {
+		name, id, _ := strings.Cut(m[1], ":")
+		return &discordgo.ComponentEmoji{Name: name, ID: id, Animated: strings.HasPrefix(s, "<a:")}
+	}
+	return &discordgo.ComponentEmoji{Name: s}
+}
+
+// messageComponents lays the buttons of structured output out in rows
+func (o *CommandOutput) messageComponents() []discordgo.MessageComponent {
+	var rows []discordgo.MessageComponent
+	for start := 0; start < len(o.Components); start += maxButtonsPerRow {
+		var row discordgo.ActionsRow
+		for _, b := range o.Components[start:min(start+maxButtonsPerRow, len(o.Components))] {
+			button := discordgo.Button{
+				Label:    b.Label,
+				Emoji:    buttonEmoji(b.Emoji),
+				Disabled: b.Disabled,
+			}
+			if b.URL != "" {
+				button.Style = discordgo.LinkButton
+				button.URL = b.URL
+			} else {
+				button.Style = buttonStyles[b.Style]
+				button.CustomID = runButtonCustomID(b)
+			}
+			row.Components = append(row.Components, button)
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+// messageFiles decodes the files of structured output, which Validate has checked
+func (o *CommandOutput) messageFiles() []*discordgo.File {
+	files := make([]*discordgo.File, 0, len(o.Files))
+	for _, file := range o.Files {
+		data, _ := base64.StdEncoding.DecodeString(file.Data)
+		files = append(files, &discordgo.File{
+			Name:        file.Name,
+			ContentType: file.ContentType,
+			Reader:      bytes.NewReader(data),
+		})
+	}
+	return files
+}
+
+// filesSize returns the total size of the files of structured output, which Validate has checked
+func (o *CommandOutput) filesSize() int {
+	size := 0
+	for _, file := range o.Files {
+		size += base64.StdEncoding.DecodedLen(len(file.Data))
+	}
+	return size
+}
+
+// MessageSend converts structured output into a message
+func (o *CommandOutput) MessageSend() *discordgo.MessageSend {
+	return &discordgo.MessageSend{
+		Content:         o.Content,
+		Embeds:          o.Embeds,
+		Files:           o.messageFiles(),
+		AllowedMentions: o.AllowedMentions,
+		Components:      o.messageComponents(),
+	}
+}
+
+// reactionEmojiID converts a reaction of structured output into the form MessageReactionAdd takes
+func reactionEmojiID(s string) string {
+	s = strings.TrimSpace(s)
+	if m := reactionEmojiRe.FindStringSubmatch(s); m != nil {
+		return m[1]
+	}
+	return s
+}
+
+// addOutputReactions adds the reactions of structured output to the message it was sent as
+func addOutputReactions(s *discordgo.Session, message *discordgo.Message, reactions []string) {
+	for _, emoji := range reactions {
+		if err := s.MessageReactionAdd(message.ChannelID, message.ID, reactionEmojiID(emoji)); err != nil {
+			fmt.Printf("error adding reaction %s: %v\n", emoji, err)
+		}
+	}
+}
+
+// invalidOutputMessage is the reply when a code command printed structured output Discord would reject
+func invalidOutputMessage(err error) string {
+	return fmt.Sprintf("コマンドの出力の形式が正しくありません:\n%s", err.Error())
+}
+
+// Upload limits of servers boosted to level 2 and 3
+const (
+	maxUploadBytesTier2 = 50 << 20
+	maxUploadBytesTier3 = 100 << 20
+)
+
+// uploadLimit returns the total size of the files a message may have in a guild, which depends on its boost level
+func uploadLimit(s *discordgo.Session, guildID string) int {
+	if guildID == "" {
+		return maxUploadBytes
+	}
+	guild, err := s.State.Guild(guildID)
+	if err != nil {
+		return maxUploadBytes
+	}
+	switch guild.PremiumTier {
+	case discordgo.PremiumTier2:
+		return maxUploadBytesTier2
+	case discordgo.PremiumTier3:
+		return maxUploadBytesTier3
+	default:
+		return maxUploadBytes
+	}
+}
+
+// channelUploadLimit returns the total size of the files a message may have in a channel
+func channelUploadLimit(s *discordgo.Session, channelID string) int {
+	if channel, err := s.State.Channel(channelID); err == nil {
+		return uploadLimit(s, channel.GuildID)
+	}
+	return maxUploadBytes
+}
+
+// formatFileSize formats a file size for messages, such as "1.5 MB"
+func formatFileSize(size int) string {
+	switch {
+	case size >= 1<<20:
+		return fmt.Sprintf("%.1f MB", float64(size)/(1<<20))
+	case size >= 1<<10:
+		return fmt.Sprintf("%.1f KB", float64(size)/(1<<10))
+	default:
+		return fmt.Sprintf("%d B", size)
+	}
+}
+
+// attachArtifacts adds the files saved by a code command to the files of a message, which take used bytes
+// Artifacts beyond the upload limit or the number of files of a message are left out and listed in the returned note
+func attachArtifacts(files []*discordgo.File, used int, artifacts []CommandArtifact, limit int) ([]*discordgo.File, string) {
+	var skipped []string
+	for _, artifact := range artifacts {
+		if artifact.Data == nil || len(files) >= maxOutputFiles || used+len(artifact.Data) > limit {
+			skipped = append(skipped, fmt.Sprintf("「%s」(%s)", artifact.Name, formatFileSize(artifact.Size)))
+			continue
+		}
+		used += len(artifact.Data)
+		files = append(files, &discordgo.File{
+			Name:        artifact.Name,
+			ContentType: artifact.ContentType,
+			Reader:      bytes.NewReader(artifact.Data),
+		})
+	}
+
+	if len(skipped) == 0 {
+		return files, ""
+	}
+	return files, fmt.Sprintf("⚠️ ファイル%s は、1 つのメッセージで送れる上限（%d 個・合計 %d MB）を超えるため送信できませんでした",
+		strings.Join(skipped, "、"), maxOutputFiles, limit>>20)
+}
+
+// commandOutputMessage builds the message for the result of a code command
+// Plain output longer than a message is sent as result.txt, structured output with its embeds, files and buttons,
+// and the artifacts of the run are attached to either.
+// Also returns the reactions to add once the message is sent and a note about the artifacts that were left out.
+func commandOutputMessage(result *CommandResult, limit int) (*discordgo.MessageSend, []string, string) {
+	var message *discordgo.MessageSend
+	var reactions []string
+	used := 0
+
+	if output := ParseCommandOutput(result.Content); output != nil {
+		if err := output.Validate(limit); err != nil {
+			return &discordgo.MessageSend{Content: invalidOutputMessage(err)}, nil, ""
+		}
+		message = output.MessageSend()
+		reactions = output.Reactions
+		used = output.filesSize()
+	} else if utf8.RuneCountInString(result.Content) > maxMessageLength {
+		message = &discordgo.MessageSend{
+			Content: "結果が長すぎるためファイルとして送信します",
+			Files: []*discordgo.File{
+				{
+					Name:        "result.txt",
+					ContentType: "text/plain",
+					Reader:      strings.NewReader(result.Content),
+				},
+			},
+		}
+		used = len(result.Content)
+	} else {
+		message = &discordgo.MessageSend{Content: result.Content}
+	}
+
+	files, note := attachArtifacts(message.Files, used, result.Artifacts, limit)
+	message.Files = files
+	if message.Content == "" && len(message.Embeds) == 0 && len(message.Files) == 0 {
+		// Every artifact was left out, so the note is all there is to send
+		message.Content, note = note, ""
+	}
+	return message, reactions, note
+}
+
+// sendCommandOutput sends the result of a code command to a channel
+func (n *Nelchan) sendCommandOutput(s *discordgo.Session, channelID string, result *CommandResult) error {
+	message, reactions, note := commandOutputMessage(result, channelUploadLimit(s, channelID))
+
+	sent, err := s.ChannelMessageSendComplex(channelID, message)
+	if err != nil {
+		return err
+	}
+	addOutputReactions(s, sent, reactions)
+
+	if note != "" {
+		_, err = s.ChannelMessageSend(channelID, note)
+	}
+	return err
+}
+
+// editInteractionOutput replaces the deferred response of an interaction with the result of a code command
+func (n *Nelchan) editInteractionOutput(s *discordgo.Session, i *discordgo.InteractionCreate, result *CommandResult) {
+	message, reactions, note := commandOutputMessage(result, uploadLimit(s, i.GuildID))
+
+	edit := &discordgo.WebhookEdit{
+		Content:         &message.Content,
+		Files:           message.Files,
+		AllowedMentions: message.AllowedMentions,
+	}
+	if len(message.Embeds) > 0 {
+		edit.Embeds = &message.Embeds
+	}
+	if len(message.Components) > 0 {
+		edit.Components = &message.Components
+	}
+	sent, err := s.InteractionResponseEdit(i.Interaction, edit)
+	if err != nil {
+		fmt.Printf("error editing interaction response: %v\n", err)
+		return
+	}
+	addOutputReactions(s, sent, reactions)
+
+	if note != "" {
+		if _, err := s.FollowupMessageCreate(i.Interaction, true, &discordgo.WebhookParams{Content: note}); err != nil {
+			fmt.Printf("error sending followup message: %v\n", err)
+		}
+	}
+}
+
+// handleRunButton handles a button of structured output that runs a code command
+// The command runs as the user who clicked the button and its output is posted as a new message
+func (n *Nelchan) handleRunButton(s *discordgo.Session, i *discordgo.InteractionCreate, args []string) {
+	commandName := args[0]
+	if commandName == "" {
+		return
+	}
+
+	err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
+	})
+	if err != nil {
+		fmt.Printf("error deferring interaction response: %v\n", err)
+		return
+	}
+
+	info := n.lookupCodeCommand(commandName)
+	if info == nil {
+		editInteractionContent(s, i, fmt.Sprintf("コマンド「%s」は見つかりませんでした", commandName))
+		return
+	}
+
+	// Button args are already split, each one is a token
+	tokens := make([]ArgToken, len(args)-1)
+	for idx, arg := range args[1:] {
+		tokens[idx] = ArgToken{Text: arg}
+	}
+	positional, named := n.bindCodeCommandArgs(info, tokens)
+	schema := n.commandArgsSchema(info)
+	named, err = ResolveArgs(schema, named)
+	if err != nil {
+		editInteractionContent(s, i, fmt.Sprintf("引数が正しくありません:\n%s\n\n%s", err.Error(), FormatArgsUsage(info.Name, schema)))
+		return
+	}
+
+	user := interactionUser(i)
+	ctx := interactionContext(s, RunSourceButton, i)
+
+	start := time.Now()
+	result, err := n.CommandAPIClient.RunCommand(RunCommandRequest{
+		CommandName: commandName,
+		IsCode:      true,
+		Vars:        ctx.Vars(),
+		Args:        positional,
+		Named:       named,
+		Context:     &ctx,
+	})
+	n.recordCommandRun(CommandRun{
+		CommandName: commandName,
+		UserID:      user.ID,
+		ChannelID:   i.ChannelID,
+		GuildID:     i.GuildID,
+		Source:      RunSourceButton,
+	}, start, result, err)
+
+	switch {
+	case err != nil:
+		fmt.Printf("error running button command %s: %v\n", commandName, err)
+		editInteractionContent(s, i, fmt.Sprintf("エラー: %s", err.Error()))
+	case result == nil:
+		editInteractionContent(s, i, fmt.Sprintf("コマンド「%s」は見つかりませんでした", commandName))
+	default:
+		n.editInteractionOutput(s, i, result)
+	}
+}
@@ -0,0 +1,215 @@
+package nelchanbot
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func TestParseCommandOutput(t *testing.T) {
+	tests := []struct {
+		name        string
+		stdout      string
+		wantNil     bool
+		wantContent string
+	}{
+		{"plain text", "こんにちは", true, ""},
+		{"json without marker", `{"content": "hi"}`, true, ""},
+		{"other version", `{"nelchan": 2, "content": "hi"}`, true, ""},
+		{"broken json", `{"nelchan": 1, "content": `, true, ""},
+		{"envelope", `{"nelchan": 1, "content": "hi"}` + "\n", false, "hi"},
+		{"prints before the envelope", "計算中...\n結果:\n" + `{"nelchan": 1, "content": "42"}`, false, "計算中...\n結果:\n42"},
+		{"indented envelope", "{\n  \"nelchan\": 1,\n  \"content\": \"hi\"\n}", false, "hi"},
+		{"envelope not on the last line", `{"nelchan": 1, "content": "hi"}` + "\nおわり", true, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ParseCommandOutput(tt.stdout)
+			if tt.wantNil {
+				if got != nil {
+					t.Errorf("ParseCommandOutput() = %+v, want nil", got)
+				}
+				return
+			}
+			if got == nil {
+				t.Fatal("ParseCommandOutput() = nil, want output")
+			}
+			if got.Content != tt.wantContent {
+				t.Errorf("ParseCommandOutput() content = %q, want %q", got.Content, tt.wantContent)
+			}
+		})
+	}
+}
+
+func TestCommandOutputValidate(t *testing.T) {
+	valid := ParseCommandOutput(`{"nelchan": 1,
+		"embeds": [{"title": "天気", "color": 3447003, "fields": [{"name": "東京", "value": "晴れ", "inline": true}], "timestamp": "2026-10-18T12:00:00Z"}],
+		"files": [{"name": "a.txt", "data": "` + base64.StdEncoding.EncodeToString([]byte("hello")) + `"}],
+		"reactions": ["👍", "<:nel:123456789012345678>"],
+		"components": [{"label": "もう一回", "command": "dice", "args": ["6"], "style": "primary"}, {"label": "詳しく", "url": "https://example.com"}]}`)
+	if valid == nil {
+		t.Fatal("ParseCommandOutput() = nil")
+	}
+	if err := valid.Validate(maxUploadBytes); err != nil {
+		t.Errorf("Validate() error = %v", err)
+	}
+
+	// Files follow the upload limit of the channel
+	large := CommandOutput{Files: []OutputFile{{Name: "a.bin", Data: base64.StdEncoding.EncodeToString(make([]byte, maxUploadBytes+1))}}}
+	if err := large.Validate(maxUploadBytesTier2); err != nil {
+		t.Errorf("Validate() in a boosted server error = %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		output  CommandOutput
+		wantErr string
+	}{
+		{"empty", CommandOutput{}, "どれかが必要です"},
+		{"long content", CommandOutput{Content: strings.Repeat("あ", maxMessageLength+1)}, "content は"},
+		{"too many embeds", CommandOutput{Embeds: make([]*discordgo.MessageEmbed, maxOutputEmbeds+1)}, "embeds は"},
+		{"long title", CommandOutput{Embeds: []*discordgo.MessageEmbed{{Title: strings.Repeat("a", maxEmbedTitleLength+1)}}}, "embeds[0]: title"},
+		{"field without value", CommandOutput{Embeds: []*discordgo.MessageEmbed{{Fields: []*discordgo.MessageEmbedField{{Name: "a"}}}}}, "fields[0] には name と value"},
+		{"embeds total", CommandOutput{Embeds: []*discordgo.MessageEmbed{
+			{Description: strings.Repeat("a", maxEmbedDescriptionLength)},
+			{Description: strings.Repeat("a", maxEmbedDescriptionLength)},
+		}}, "合計 6000 文字"},
+		{"bad timestamp", CommandOutput{Embeds: []*discordgo.MessageEmbed{{Title: "a", Timestamp: "昨日"}}}, "timestamp"},
+		{"file not base64", CommandOutput{Files: []OutputFile{{Name: "a.png", Data: "!!"}}}, "base64"},
+		{"file without name", CommandOutput{Files: []OutputFile{{Data: "aGVsbG8="}}}, "name がありません"},
+		{"files too large", CommandOutput{Files: []OutputFile{{Name: "a.bin", Data: base64.StdEncoding.EncodeToString(make([]byte, maxUploadBytes+1))}}}, "MB まで"},
+		{"button without action", CommandOutput{Content: "a", Components: []OutputButton{{Label: "a"}}}, "url か command"},
+		{"button with both", CommandOutput{Content: "a", Components: []OutputButton{{Label: "a", URL: "https://example.com", Command: "dice"}}}, "同時に"},
+		{"button with bad url", CommandOutput{Content: "a", Components: []OutputButton{{Label: "a", URL: "javascript:alert(1)"}}}, "http(s)"},
+		{"button with colon", CommandOutput{Content: "a", Components: []OutputButton{{Label: "a", Command: "dice", Args: []string{"1:2"}}}}, "「:」"},
+		{"button with long args", CommandOutput{Content: "a", Components: []OutputButton{{Label: "a", Command: "dice", Args: []string{strings.Repeat("a", maxCustomIDLength)}}}}, "文字まで"},
+		{"button with unknown style", CommandOutput{Content: "a", Components: []OutputButton{{Label: "a", Command: "dice", Style: "blurple"}}}, "style"},
+		{"too many reactions", CommandOutput{Content: "a", Reactions: make([]string, maxOutputReactions+1)}, "reactions は"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.output.Validate(maxUploadBytes)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Validate() error = %v, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCommandOutputMessageSend(t *testing.T) {
+	output := CommandOutput{Content: "結果"}
+	for idx := range 7 {
+		output.Components = append(output.Components, OutputButton{Label: fmt.Sprint(idx), Command: "dice", Args: []string{fmt.Sprint(idx)}})
+	}
+	output.Components[6] = OutputButton{Label: "リンク", URL: "https://example.com", Emoji: "<a:nel:123>"}
+
+	message := output.MessageSend()
+	if len(message.Components) != 2 {
+		t.Fatalf("MessageSend() rows = %d, want 2", len(message.Components))
+	}
+	first := message.Components[0].(discordgo.ActionsRow).Components
+	if len(first) != maxButtonsPerRow {
+		t.Errorf("first row has %d buttons, want %d", len(first), maxButtonsPerRow)
+	}
+	if button := first[1].(discordgo.Button); button.CustomID != "run:dice:1" || button.Style != discordgo.SecondaryButton {
+		t.Errorf("button = %+v, want custom_id run:dice:1 and the secondary style", button)
+	}
+	link := message.Components[1].(discordgo.ActionsRow).Components[1].(discordgo.Button)
+	if link.Style != discordgo.LinkButton || link.CustomID != "" || link.Emoji == nil || link.Emoji.ID != "123" || !link.Emoji.Animated {
+		t.Errorf("link button = %+v", link)
+	}
+}
+
+func TestReactionEmojiID(t *testing.T) {
+	tests := map[string]string{
+		"👍":                          "👍",
+		" 🎉 ":                        "🎉",
+		"<:nel:123456789012345678>":  "nel:123456789012345678",
+		"<a:nel:123456789012345678>": "nel:123456789012345678",
+		"nel:123456789012345678":     "nel:123456789012345678",
+	}
+	for input, want := range tests {
+		if got := reactionEmojiID(input); got != want {
+			t.Errorf("reactionEmojiID(%q) = %q, want %q", input, got, want)
+		}
+	}
+}
+
+func TestCommandOutputMessage(t *testing.T) {
+	png := CommandArtifact{Name: "plot.png", ContentType: "image/png", Size: 4, Data: []byte("\x89PNG")}
+	large := CommandArtifact{Name: "large.bin", Size: 2 << 20, Data: make([]byte, 2<<20)}
+	unread := CommandArtifact{Name: "huge.mp4", Size: 200 << 20}
+
+	tests := []struct {
+		name        string
+		result      CommandResult
+		limit       int
+		wantContent string
+		wantFiles   []string
+		wantNote    string
+	}{
+		{"plain", CommandResult{Content: "こんにちは"}, maxUploadBytes, "こんにちは", nil, ""},
+		{"long plain", CommandResult{Content: strings.Repeat("あ", maxMessageLength+1)}, maxUploadBytes, "結果が長すぎるためファイルとして送信します", []string{"result.txt"}, ""},
+		{"plain with artifact", CommandResult{Content: "グラフです", Artifacts: []CommandArtifact{png}}, maxUploadBytes, "グラフです", []string{"plot.png"}, ""},
+		{"structured with artifact", CommandResult{
+			Content:   `{"nelchan": 1, "content": "結果", "files": [{"name": "a.txt", "data": "aGVsbG8="}]}`,
+			Artifacts: []CommandArtifact{png},
+		}, maxUploadBytes, "結果", []string{"a.txt", "plot.png"}, ""},
+		{"artifact over the limit", CommandResult{Content: "結果", Artifacts: []CommandArtifact{png, large}}, 1 << 20, "結果", []string{"plot.png"}, "「large.bin」(2.0 MB)"},
+		{"artifact too large to read", CommandResult{Content: "結果", Artifacts: []CommandArtifact{unread}}, maxUploadBytes, "結果", nil, "「huge.mp4」(200.0 MB)"},
+		{"only a skipped artifact", CommandResult{Artifacts: []CommandArtifact{unread}}, maxUploadBytes, "⚠️ ファイル「huge.mp4」", nil, ""},
+		{"invalid structured output", CommandResult{Content: `{"nelchan": 1}`, Artifacts: []CommandArtifact{png}}, maxUploadBytes, "コマンドの出力の形式が正しくありません", nil, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			message, _, note := commandOutputMessage(&tt.result, tt.limit)
+			if !strings.HasPrefix(message.Content, tt.wantContent) {
+				t.Errorf("content = %q, want it to start with %q", message.Content, tt.wantContent)
+			}
+			var names []string
+			for _, file := range message.Files {
+				names = append(names, file.Name)
+			}
+			if strings.Join(names, ",") != strings.Join(tt.wantFiles, ",") {
+				t.Errorf("files = %v, want %v", names, tt.wantFiles)
+			}
+			if tt.wantNote == "" && note != "" || !strings.Contains(note, tt.wantNote) {
+				t.Errorf("note = %q, want it to contain %q", note, tt.wantNote)
+			}
+		})
+	}
+}
+
+func TestAttachArtifactsFileCount(t *testing.T) {
+	artifacts := make([]CommandArtifact, maxOutputFiles+2)
+	for idx := range artifacts {
+		artifacts[idx] = CommandArtifact{Name: fmt.Sprintf("%d.png", idx), Size: 1, Data: []byte{0}}
+	}
+	files, note := attachArtifacts(nil, 0, artifacts, maxUploadBytes)
+	if len(files) != maxOutputFiles {
+		t.Errorf("attachArtifacts() files = %d, want %d", len(files), maxOutputFiles)
+	}
+	if !strings.Contains(note, "「10.png」") || !strings.Contains(note, "「11.png」") {
+		t.Errorf("attachArtifacts() note = %q, want the two files left out", note)
+	}
+}
+
+func TestFormatFileSize(t *testing.T) {
+	tests := map[int]string{
+		512:           "512 B",
+		1536:          "1.5 KB",
+		10 << 20:      "10.0 MB",
+		(3 << 20) / 2: "1.5 MB",
+	}
+	for size, want := range tests {
+		if got := formatFileSize(size); got != want {
+			t.Errorf("formatFileSize(%d) = %q, want %q", size, got, want)
+		}
+	}
+}
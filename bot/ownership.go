@@ -0,0 +1,166 @@
+package nelchanbot
+
+import (
+	"fmt"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// FormatCommandOwner formats the owner and lock state of a command
+// Example:
+//
+//	コマンド「dice」
+//	登録者: <@123>
+//	状態: 🔒 ロック中（登録者と管理者だけが変更できます）
+func FormatCommandOwner(info *GetCommandInfo) string {
+	owner := "不明"
+	if info.AuthorID != "" {
+		owner = fmt.Sprintf("<@%s>", info.AuthorID)
+	}
+
+	state := "🔒 ロック中（登録者と管理者だけが変更できます）"
+	if !info.Locked {
+		state = "🔓 ロック解除（誰でも変更できます）"
+	}
+	return fmt.Sprintf("コマンド「%s」\n登録者: %s\n状態: %s", info.Name, owner, state)
+}
+
+// handleOwnerCommand handles the !owner command
+// Usage: !owner <command_name> - Show the owner and lock state of a command
+func (n *Nelchan) handleOwnerCommand(s *discordgo.Session, m *discordgo.MessageCreate, cmd *SlashCommand) {
+	commandName := cmd.GetArg(0)
+	if commandName == "" {
+		_, _ = s.ChannelMessageSend(m.ChannelID, "使い方: !owner <コマンド名>")
+		return
+	}
+
+	info, err := n.CommandAPIClient.GetCommand(GetCommandRequest{
+		CommandName: commandName,
+	})
+	if err != nil {
+		fmt.Println("error getting command,", err)
+		_, _ = s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("エラー: %s", err.Error()))
+		return
+	}
+	if info == nil {
+		_, _ = s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("コマンド「%s」は見つかりませんでした", commandName))
+		return
+	}
+
+	// Show the owner without pinging them
+	_, err = s.ChannelMessageSendComplex(m.ChannelID, &discordgo.MessageSend{
+		Content:         FormatCommandOwner(info),
+		AllowedMentions: &discordgo.MessageAllowedMentions{},
+	})
+	if err != nil {
+		fmt.Println("error sending message,", err)
+	}
+}
+
+// handleLockCommand handles the !lock command
+// Usage: !lock <command_name> - Only the owner and admins may change the command
+func (n *Nelchan) handleLockCommand(s *discordgo.Session, m *discordgo.MessageCreate, cmd *SlashCommand) {
+	n.setCommandLocked(s, m, cmd.GetArg(0), true)
+}
+
+// handleUnlockCommand handles the !unlock command
+// Usage: !unlock <command_name> - Everyone may change the command
+func (n *Nelchan) handleUnlockCommand(s *discordgo.Session, m *discordgo.MessageCreate, cmd *SlashCommand) {
+	n.setCommandLocked(s, m, cmd.GetArg(0), false)
+}
+
+// setCommandLocked locks or unlocks a command owned by the author of the message
+func (n *Nelchan) setCommandLocked(s *discordgo.Session, m *discordgo.MessageCreate, commandName string, locked bool) {
+	if commandName == "" {
+		if locked {
+			_, _ = s.ChannelMessageSend(m.ChannelID, "使い方: !lock <コマンド名>")
+		} else {
+			_, _ = s.ChannelMessageSend(m.ChannelID, "使い方: !unlock <コマンド名>")
+		}
+		return
+	}
+
+	info, ok := n.getOwnedCommand(s, m, commandName)
+	if !ok {
+		return
+	}
+
+	if info.Locked == locked {
+		if locked {
+			_, _ = s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("コマンド「%s」はすでにロックされています", commandName))
+		} else {
+			_, _ = s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("コマンド「%s」はロックされていません", commandName))
+		}
+		return
+	}
+
+	updated, err := n.CommandAPIClient.SetCommandLocked(SetCommandLockedRequest{
+		CommandName: commandName,
+		Locked:      locked,
+	})
+	if err != nil {
+		fmt.Println("error locking command,", err)
+		_, _ = s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("エラー: %s", err.Error()))
+		return
+	}
+	if !updated {
+		_, _ = s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("コマンド「%s」は見つかりませんでした", commandName))
+		return
+	}
+
+	if locked {
+		_, _ = s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("🔒 コマンド「%s」をロックしました。登録者と管理者だけが変更できます", commandName))
+	} else {
+		_, _ = s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("🔓 コマンド「%s」のロックを解除しました。誰でも変更できます", commandName))
+	}
+}
+
+// handleTransferCommand handles the !transfer command
+// Usage: !transfer <command_name> <@user> - Make another user the owner of a command
+func (n *Nelchan) handleTransferCommand(s *discordgo.Session, m *discordgo.MessageCreate, cmd *SlashCommand) {
+	commandName := cmd.GetArg(0)
+	if commandName == "" || cmd.GetArg(1) == "" {
+		_, _ = s.ChannelMessageSend(m.ChannelID, "使い方: !transfer <コマンド名> <@ユーザー>")
+		return
+	}
+
+	newOwnerID, err := parseSnowflakeArg(cmd.GetArg(1), userMentionRe, "ユーザー")
+	if err != nil {
+		_, _ = s.ChannelMessageSend(m.ChannelID, err.Error())
+		return
+	}
+
+	info, ok := n.getOwnedCommand(s, m, commandName)
+	if !ok {
+		return
+	}
+	if info.AuthorID == newOwnerID {
+		_, _ = s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("<@%s> はすでにコマンド「%s」の登録者です", newOwnerID, commandName))
+		return
+	}
+
+	updated, err := n.CommandAPIClient.TransferCommand(TransferCommandRequest{
+		CommandName: commandName,
+		AuthorID:    newOwnerID,
+	})
+	if err != nil {
+		fmt.Println("error transferring command,", err)
+		_, _ = s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("エラー: %s", err.Error()))
+		return
+	}
+	if !updated {
+		_, _ = s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("コマンド「%s」は見つかりませんでした", commandName))
+		return
+	}
+
+	// Let the new owner know
+	_, err = s.ChannelMessageSendComplex(m.ChannelID, &discordgo.MessageSend{
+		Content: fmt.Sprintf("コマンド「%s」の登録者を <@%s> に変更しました", commandName, newOwnerID),
+		AllowedMentions: &discordgo.MessageAllowedMentions{
+			Users: []string{newOwnerID},
+		},
+	})
+	if err != nil {
+		fmt.Println("error sending message,", err)
+	}
+}
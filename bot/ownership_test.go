@@ -0,0 +1,30 @@
+package nelchanbot
+
+import "testing"
+
+func TestFormatCommandOwner(t *testing.T) {
+	tests := []struct {
+		name string
+		info *GetCommandInfo
+		want string
+	}{
+		{
+			name: "locked",
+			info: &GetCommandInfo{Name: "dice", AuthorID: "123", Locked: true},
+			want: "コマンド「dice」\n登録者: <@123>\n状態: 🔒 ロック中（登録者と管理者だけが変更できます）",
+		},
+		{
+			name: "unlocked without author",
+			info: &GetCommandInfo{Name: "hello"},
+			want: "コマンド「hello」\n登録者: 不明\n状態: 🔓 ロック解除（誰でも変更できます）",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := FormatCommandOwner(tt.info); got != tt.want {
+				t.Errorf("FormatCommandOwner() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
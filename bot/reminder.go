@@ -0,0 +1,492 @@
+package nelchanbot
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+const (
+	// maxRemindersPerUser limits the pending reminders of a user
+	maxRemindersPerUser = 25
+	// maxReminderTextLength is the maximum length of the text of a reminder
+	maxReminderTextLength = 1000
+	// maxReminderDelay is how far ahead a reminder can be set
+	maxReminderDelay = 366 * 24 * time.Hour
+	// reminderLateThreshold is how late a reminder is delivered before it says it's late
+	reminderLateThreshold = time.Minute
+	// maxReminderTextDisplayLength is the length texts are truncated to in !reminders
+	maxReminderTextDisplayLength = 50
+	// firedReminderRetention is how long delivered reminders are remembered, far longer than a reload takes
+	firedReminderRetention = time.Hour
+)
+
+// ReminderQueue delivers reminders at their time using a timer per reminder
+type ReminderQueue struct {
+	mu      sync.Mutex
+	timers  map[int64]*time.Timer
+	fired   map[int64]time.Time // when reminders were delivered, so reloading doesn't deliver them again
+	deliver func(Reminder)
+}
+
+// NewReminderQueue creates an empty queue that calls deliver when a reminder is due
+func NewReminderQueue(deliver func(Reminder)) *ReminderQueue {
+	return &ReminderQueue{
+		timers:  make(map[int64]*time.Timer),
+		fired:   make(map[int64]time.Time),
+		deliver: deliver,
+	}
+}
+
+// Load replaces the queued reminders
+// Reminders whose time already passed, e.g. while the bot was offline, are delivered right away.
+// Delivered reminders missing from reminders were removed from the backend and are forgotten
+func (q *ReminderQueue) Load(reminders []Reminder, now time.Time) []error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for _, timer := range q.timers {
+		timer.Stop()
+	}
+	q.timers = make(map[int64]*time.Timer, len(reminders))
+
+	fired := make(map[int64]time.Time)
+	var errs []error
+	for _, reminder := range reminders {
+		if at, ok := q.fired[reminder.ID]; ok {
+			fired[reminder.ID] = at
+			continue
+		}
+		if err := q.add(reminder, now); err != nil {
+			errs = append(errs, fmt.Errorf("reminder %d: %w", reminder.ID, err))
+		}
+	}
+	q.fired = fired
+	return errs
+}
+
+// Add queues a reminder
+func (q *ReminderQueue) Add(reminder Reminder, now time.Time) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.add(reminder, now)
+}
+
+// add queues a reminder, q.mu must be held
+func (q *ReminderQueue) add(reminder Reminder, now time.Time) error {
+	at, err := time.Parse(time.RFC3339, reminder.RemindAt)
+	if err != nil {
+		return fmt.Errorf("日時が不正です: %w", err)
+	}
+
+	if timer, ok := q.timers[reminder.ID]; ok {
+		timer.Stop()
+	}
+	q.timers[reminder.ID] = time.AfterFunc(max(at.Sub(now), 0), func() {
+		q.fire(reminder)
+	})
+	return nil
+}
+
+// fire delivers a reminder unless it was removed in the meantime
+// Reminders delivered more than firedReminderRetention ago are forgotten
+func (q *ReminderQueue) fire(reminder Reminder) {
+	q.mu.Lock()
+	if _, ok := q.timers[reminder.ID]; !ok {
+		q.mu.Unlock()
+		return
+	}
+	delete(q.timers, reminder.ID)
+	now := time.Now()
+	maps.DeleteFunc(q.fired, func(_ int64, at time.Time) bool {
+		return now.Sub(at) > firedReminderRetention
+	})
+	q.fired[reminder.ID] = now
+	q.mu.Unlock()
+
+	q.deliver(reminder)
+}
+
+// Remove removes a queued reminder
+// Returns false if it wasn't queued
+func (q *ReminderQueue) Remove(id int64) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	timer, ok := q.timers[id]
+	if ok {
+		timer.Stop()
+		delete(q.timers, id)
+	}
+	return ok
+}
+
+// Stop stops every timer, reminders are delivered after the next Load
+func (q *ReminderQueue) Stop() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for _, timer := range q.timers {
+		timer.Stop()
+	}
+	q.timers = make(map[int64]*time.Timer)
+}
+
+// loadReminders loads every pending reminder from the backend into the queue
+func (n *Nelchan) loadReminders() {
+	reminders, err := n.CommandAPIClient.ListReminders(ListRemindersRequest{})
+	if err != nil {
+		fmt.Println("error loading reminders:", err)
+		return
+	}
+
+	for _, err := range n.Reminders.Load(reminders, time.Now()) {
+		fmt.Println("error loading reminder:", err)
+	}
+	fmt.Printf("loaded %d reminders\n", len(reminders))
+}
+
+// FormatReminderDelivery formats the message a reminder is delivered with
+// Reminders delivered late, e.g. after a restart, say when they were due
+func FormatReminderDelivery(reminder Reminder, now time.Time) string {
+	message := fmt.Sprintf("⏰ <@%s> リマインダー: %s", reminder.UserID, reminder.Text)
+	if at, err := time.Parse(time.RFC3339, reminder.RemindAt); err == nil && now.Sub(at) > reminderLateThreshold {
+		message += fmt.Sprintf("\n（<t:%d:f> の予定でしたが、遅れてお届けしました）", at.Unix())
+	}
+	return message
+}
+
+// deliverReminder sends a due reminder and removes it from the backend
+// DMs that can't be sent fall back to the channel the reminder was set in.
+// Reminders that can't be sent at all stay in the backend and are retried after a restart
+func (n *Nelchan) deliverReminder(reminder Reminder) {
+	s := n.Discord
+	message := &discordgo.MessageSend{
+		Content: FormatReminderDelivery(reminder, time.Now()),
+		AllowedMentions: &discordgo.MessageAllowedMentions{
+			Users: []string{reminder.UserID},
+		},
+	}
+
+	sent := false
+	if reminder.Delivery == ReminderDeliveryDM {
+		channel, err := s.UserChannelCreate(reminder.UserID)
+		if err == nil {
+			_, err = s.ChannelMessageSendComplex(channel.ID, message)
+		}
+		if err != nil {
+			fmt.Printf("error sending reminder %d as DM, falling back to the channel: %v\n", reminder.ID, err)
+		}
+		sent = err == nil
+	}
+
+	if !sent {
+		if reminder.MessageID != "" {
+			// Still send the reminder when the message was deleted
+			failIfNotExists := false
+			message.Reference = &discordgo.MessageReference{
+				MessageID:       reminder.MessageID,
+				ChannelID:       reminder.ChannelID,
+				GuildID:         reminder.GuildID,
+				FailIfNotExists: &failIfNotExists,
+			}
+		}
+		if _, err := s.ChannelMessageSendComplex(reminder.ChannelID, message); err != nil {
+			fmt.Printf("error sending reminder %d: %v\n", reminder.ID, err)
+			return
+		}
+	}
+
+	if _, err := n.CommandAPIClient.RemoveReminder(RemoveReminderRequest{ID: reminder.ID}); err != nil {
+		fmt.Printf("error removing delivered reminder %d: %v\n", reminder.ID, err)
+	}
+	fmt.Printf("delivered reminder %d to %s\n", reminder.ID, reminder.UserID)
+}
+
+// userTimezone returns the timezone a user has set, or defaultTimezone
+func (n *Nelchan) userTimezone(userID string) string {
+	timezone, err := n.CommandAPIClient.GetUserTimezone(userID)
+	if err != nil {
+		fmt.Println("error getting user timezone:", err)
+	}
+	if timezone == "" {
+		return defaultTimezone
+	}
+	return timezone
+}
+
+// userLocation returns the location of the timezone a user has set, or of defaultTimezone
+func (n *Nelchan) userLocation(userID string) *time.Location {
+	timezone := n.userTimezone(userID)
+	loc, err := time.LoadLocation(timezone)
+	if err != nil {
+		fmt.Printf("error loading timezone %s of %s: %v\n", timezone, userID, err)
+		loc, _ = time.LoadLocation(defaultTimezone)
+	}
+	return loc
+}
+
+// addReminder validates and stores a reminder due at at
+// Returns the message to show to the user
+func (n *Nelchan) addReminder(reminder Reminder, at, now time.Time) (string, error) {
+	reminder.Text = strings.TrimSpace(reminder.Text)
+	switch {
+	case reminder.Text == "":
+		return "リマインダーの内容を指定してください", nil
+	case utf8.RuneCountInString(reminder.Text) > maxReminderTextLength:
+		return fmt.Sprintf("リマインダーの内容は %d 文字までです", maxReminderTextLength), nil
+	case !at.After(now):
+		return fmt.Sprintf("<t:%d:f> は過去の日時です", at.Unix()), nil
+	case at.Sub(now) > maxReminderDelay:
+		return "リマインダーは 1 年先まで設定できます", nil
+	}
+
+	existing, err := n.CommandAPIClient.ListReminders(ListRemindersRequest{UserID: reminder.UserID})
+	if err != nil {
+		return "", err
+	}
+	if len(existing) >= maxRemindersPerUser {
+		return fmt.Sprintf("リマインダーは 1 人 %d 件までです。`!unremind <番号>` で取り消してから設定してください", maxRemindersPerUser), nil
+	}
+
+	reminder.RemindAt = at.UTC().Format(time.RFC3339)
+	created, err := n.CommandAPIClient.AddReminder(reminder)
+	if err != nil {
+		return "", err
+	}
+	if err := n.Reminders.Add(*created, now); err != nil {
+		fmt.Printf("error queueing reminder %d: %v\n", created.ID, err)
+	}
+
+	fmt.Printf("added reminder %d for %s at %s\n", created.ID, created.UserID, created.RemindAt)
+	message := fmt.Sprintf("⏰ <t:%d:f>（<t:%d:R>）にお知らせします（番号 `#%d`）", at.Unix(), at.Unix(), created.ID)
+	if reminder.Delivery == ReminderDeliveryDM {
+		message += "。DM でお送りします"
+	}
+	return message, nil
+}
+
+// handleRemindCommand handles the !remind command
+// Usage: !remind [dm] <when> <text>
+// The reminder is delivered as a reply to the message, or as a DM when "dm" is given
+func (n *Nelchan) handleRemindCommand(s *discordgo.Session, m *discordgo.MessageCreate, _ *SlashCommand) {
+	raw := n.CommandParser.ExtractArgsText(m.Content)
+	delivery := ReminderDeliveryReply
+	if fields := strings.Fields(raw); len(fields) > 0 && strings.EqualFold(fields[0], "dm") {
+		delivery = ReminderDeliveryDM
+		raw = skipFields(raw, 1)
+	}
+	if strings.TrimSpace(raw) == "" {
+		_, _ = s.ChannelMessageSend(m.ChannelID, "使い方: !remind [dm] <いつ> <内容>（例: !remind 10分後 お湯を止める）")
+		return
+	}
+
+	now := time.Now().In(n.userLocation(m.Author.ID))
+	at, text, err := SplitReminderArgs(raw, now)
+	if err != nil {
+		_, _ = s.ChannelMessageSend(m.ChannelID, err.Error())
+		return
+	}
+
+	message, err := n.addReminder(Reminder{
+		UserID:    m.Author.ID,
+		GuildID:   m.GuildID,
+		ChannelID: m.ChannelID,
+		MessageID: m.ID,
+		Text:      text,
+		Delivery:  delivery,
+	}, at, now)
+	if err != nil {
+		fmt.Println("error adding reminder,", err)
+		message = fmt.Sprintf("エラー: %s", err.Error())
+	}
+	_, _ = s.ChannelMessageSend(m.ChannelID, message)
+}
+
+// handleRemindSlashCommand handles the /remind slash command
+// The reminder is delivered to the channel, or as a DM when dm is true
+func (n *Nelchan) handleRemindSlashCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	var when, text string
+	delivery := ReminderDeliveryReply
+	for _, opt := range i.ApplicationCommandData().Options {
+		switch opt.Name {
+		case "when":
+			when = opt.StringValue()
+		case "text":
+			text = opt.StringValue()
+		case "dm":
+			if opt.BoolValue() {
+				delivery = ReminderDeliveryDM
+			}
+		}
+	}
+
+	// Defer response as the timezone and existing reminders are fetched first
+	err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
+	})
+	if err != nil {
+		fmt.Printf("error deferring interaction response: %v\n", err)
+		return
+	}
+
+	user := interactionUser(i)
+	now := time.Now().In(n.userLocation(user.ID))
+	at, err := ParseReminderTime(when, now)
+	if err != nil {
+		editInteractionContent(s, i, err.Error())
+		return
+	}
+
+	message, err := n.addReminder(Reminder{
+		UserID:    user.ID,
+		GuildID:   i.GuildID,
+		ChannelID: i.ChannelID,
+		Text:      text,
+		Delivery:  delivery,
+	}, at, now)
+	if err != nil {
+		fmt.Println("error adding reminder,", err)
+		message = fmt.Sprintf("エラー: %s", err.Error())
+	}
+	editInteractionContent(s, i, message)
+}
+
+// FormatReminderList formats the pending reminders of a user for !reminders
+// Reminders that don't fit in a message are counted at the end
+func FormatReminderList(reminders []Reminder) string {
+	if len(reminders) == 0 {
+		return "リマインダーはありません。`!remind <いつ> <内容>` で設定できます"
+	}
+
+	footer := "\n`!unremind <番号>` で取り消せます"
+	var b strings.Builder
+	fmt.Fprintf(&b, "リマインダー（%d 件）", len(reminders))
+	for idx, reminder := range reminders {
+		text := strings.Join(strings.Fields(reminder.Text), " ")
+		if utf8.RuneCountInString(text) > maxReminderTextDisplayLength {
+			text = string([]rune(text)[:maxReminderTextDisplayLength-1]) + "…"
+		}
+
+		line := fmt.Sprintf("\n`#%d` ", reminder.ID)
+		if at, err := time.Parse(time.RFC3339, reminder.RemindAt); err == nil {
+			line += fmt.Sprintf("<t:%d:f>（<t:%d:R>） ", at.Unix(), at.Unix())
+		}
+		line += text
+		if reminder.Delivery == ReminderDeliveryDM {
+			line += " ・DM"
+		}
+
+		// Leave room for the footer and the count of the rest
+		if utf8.RuneCountInString(b.String()+line+footer) > maxMessageLength-20 {
+			fmt.Fprintf(&b, "\n…ほか %d 件", len(reminders)-idx)
+			break
+		}
+		b.WriteString(line)
+	}
+	b.WriteString(footer)
+	return b.String()
+}
+
+// handleRemindersCommand handles the !reminders command
+// Lists the pending reminders of the user
+func (n *Nelchan) handleRemindersCommand(s *discordgo.Session, m *discordgo.MessageCreate, _ *SlashCommand) {
+	reminders, err := n.CommandAPIClient.ListReminders(ListRemindersRequest{UserID: m.Author.ID})
+	if err != nil {
+		fmt.Println("error listing reminders,", err)
+		_, _ = s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("エラー: %s", err.Error()))
+		return
+	}
+
+	// Texts may contain mentions, don't notify anyone
+	_, err = s.ChannelMessageSendComplex(m.ChannelID, &discordgo.MessageSend{
+		Content:         FormatReminderList(reminders),
+		AllowedMentions: &discordgo.MessageAllowedMentions{},
+	})
+	if err != nil {
+		fmt.Println("error sending message,", err)
+	}
+}
+
+// handleUnremindCommand handles the !unremind command
+// Usage: !unremind <id>
+// Only reminders of the user can be cancelled
+func (n *Nelchan) handleUnremindCommand(s *discordgo.Session, m *discordgo.MessageCreate, cmd *SlashCommand) {
+	id, err := strconv.ParseInt(strings.TrimPrefix(cmd.GetArg(0), "#"), 10, 64)
+	if err != nil {
+		_, _ = s.ChannelMessageSend(m.ChannelID, "使い方: !unremind <番号>（番号は !reminders で確認できます）")
+		return
+	}
+
+	reminders, err := n.CommandAPIClient.ListReminders(ListRemindersRequest{UserID: m.Author.ID})
+	if err != nil {
+		fmt.Println("error listing reminders,", err)
+		_, _ = s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("エラー: %s", err.Error()))
+		return
+	}
+	notFound := fmt.Sprintf("リマインダー `#%d` は見つかりませんでした", id)
+	if !slices.ContainsFunc(reminders, func(reminder Reminder) bool { return reminder.ID == id }) {
+		_, _ = s.ChannelMessageSend(m.ChannelID, notFound)
+		return
+	}
+
+	n.Reminders.Remove(id)
+	removed, err := n.CommandAPIClient.RemoveReminder(RemoveReminderRequest{ID: id})
+	switch {
+	case err != nil:
+		fmt.Println("error removing reminder,", err)
+		_, _ = s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("エラー: %s", err.Error()))
+	case !removed:
+		_, _ = s.ChannelMessageSend(m.ChannelID, notFound)
+	default:
+		_, _ = s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("リマインダー `#%d` を取り消しました", id))
+	}
+}
+
+// handleTimezoneCommand handles the !timezone command
+// Usage: !timezone [timezone|clear]
+// Shows or sets the timezone reminder times are read in
+func (n *Nelchan) handleTimezoneCommand(s *discordgo.Session, m *discordgo.MessageCreate, cmd *SlashCommand) {
+	arg := cmd.GetArg(0)
+	if arg == "" {
+		timezone, err := n.CommandAPIClient.GetUserTimezone(m.Author.ID)
+		if err != nil {
+			fmt.Println("error getting user timezone,", err)
+			_, _ = s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("エラー: %s", err.Error()))
+			return
+		}
+		if timezone == "" {
+			_, _ = s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("タイムゾーンは未設定です（%s として扱います）。`!timezone <タイムゾーン>` で設定できます", defaultTimezone))
+			return
+		}
+		_, _ = s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("タイムゾーン: %s", timezone))
+		return
+	}
+
+	request := UserTimezoneRequest{UserID: m.Author.ID}
+	message := fmt.Sprintf("タイムゾーンの設定を解除しました（%s として扱います）", defaultTimezone)
+	if arg != "clear" {
+		loc, err := time.LoadLocation(arg)
+		if err != nil || arg == "Local" {
+			_, _ = s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("タイムゾーン「%s」が見つかりません（例: Asia/Tokyo, America/New_York, UTC）", arg))
+			return
+		}
+		timezone := loc.String()
+		request.Timezone = &timezone
+		message = fmt.Sprintf("タイムゾーンを %s に設定しました（現在 %s）", timezone, time.Now().In(loc).Format("2006-01-02 15:04"))
+	}
+
+	if err := n.CommandAPIClient.SetUserTimezone(request); err != nil {
+		fmt.Println("error setting user timezone,", err)
+		_, _ = s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("エラー: %s", err.Error()))
+		return
+	}
+	_, _ = s.ChannelMessageSend(m.ChannelID, message)
+}
@@ -0,0 +1,266 @@
+package nelchanbot
+
+import (
+	"errors"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// defaultTimezone is used for schedules and users that haven't set a timezone
+const defaultTimezone = "Asia/Tokyo"
+
+// maxReminderTimeFields is the most whitespace separated fields a time expression may span, as in "in 1 hour 30 minutes"
+const maxReminderTimeFields = 5
+
+// defaultReminderHour is the hour used when only a day is given, as in "明日" or "2026-10-20"
+const defaultReminderHour = 9
+
+// errReminderTime is returned when a text isn't a time expression
+var errReminderTime = errors.New("日時を読み取れません（例: 10分後, 明日9時, in 2h, tomorrow 9am, 2026-10-20 18:00）")
+
+// reminderTimeNormalizer turns full-width digits and symbols into ASCII
+var reminderTimeNormalizer = strings.NewReplacer(
+	"０", "0", "１", "1", "２", "2", "３", "3", "４", "4",
+	"５", "5", "６", "6", "７", "7", "８", "8", "９", "9",
+	"：", ":", "／", "/", "　", " ",
+)
+
+// Units of relative time expressions; longer names come first so they win over their prefixes
+var (
+	japaneseDurationRe = regexp.MustCompile(`(\d+)(週間|日|時間|分|秒)`)
+	englishDurationRe  = regexp.MustCompile(`(\d+)(weeks?|w|days?|d|hours?|hrs?|h|minutes?|mins?|m|seconds?|secs?|s)`)
+)
+
+var durationUnits = map[string]time.Duration{
+	"週間": 7 * 24 * time.Hour, "日": 24 * time.Hour, "時間": time.Hour, "分": time.Minute, "秒": time.Second,
+	"week": 7 * 24 * time.Hour, "weeks": 7 * 24 * time.Hour, "w": 7 * 24 * time.Hour,
+	"day": 24 * time.Hour, "days": 24 * time.Hour, "d": 24 * time.Hour,
+	"hour": time.Hour, "hours": time.Hour, "hr": time.Hour, "hrs": time.Hour, "h": time.Hour,
+	"minute": time.Minute, "minutes": time.Minute, "min": time.Minute, "mins": time.Minute, "m": time.Minute,
+	"second": time.Second, "seconds": time.Second, "sec": time.Second, "secs": time.Second, "s": time.Second,
+}
+
+// Day and time parts of absolute time expressions
+var (
+	relativeDayRe   = regexp.MustCompile(`^(今日|きょう|today|明後日|あさって|明日|あした|あす|tomorrow)`)
+	japaneseDateRe  = regexp.MustCompile(`^(\d{1,2})月(\d{1,2})日`)
+	slashDateRe     = regexp.MustCompile(`^(\d{1,2})/(\d{1,2})`)
+	japaneseClockRe = regexp.MustCompile(`^(午前|午後)?(\d{1,2})時(?:(半)|(\d{1,2})分)?$`)
+	colonClockRe    = regexp.MustCompile(`^(\d{1,2}):(\d{2})$`)
+	meridiemClockRe = regexp.MustCompile(`^(\d{1,2})(?::(\d{2}))?(am|pm)$`)
+)
+
+var relativeDays = map[string]int{
+	"今日": 0, "きょう": 0, "today": 0,
+	"明日": 1, "あした": 1, "あす": 1, "tomorrow": 1,
+	"明後日": 2, "あさって": 2,
+}
+
+// Layouts of ISO 8601 style dates, parsed in the user's timezone unless they carry an offset
+var isoLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04",
+	"2006/01/02 15:04",
+}
+
+var isoDateLayouts = []string{
+	"2006-01-02",
+	"2006/01/02",
+}
+
+// ParseReminderTime parses a time expression relative to now, in now's location
+// Accepted forms are relative ("10分後", "1時間30分後", "in 2h", "in 10 minutes"),
+// a day and/or time ("明日9時", "午後3時半", "10月20日", "18:30", "tomorrow 9am", "at 7pm")
+// and ISO dates ("2026-10-20", "2026-10-20 18:00", "2026-10-20T18:00:00+09:00").
+// A time without a day that already passed today means tomorrow and a day without a time means 9:00.
+func ParseReminderTime(expr string, now time.Time) (time.Time, error) {
+	expr = strings.ToLower(strings.TrimSpace(reminderTimeNormalizer.Replace(expr)))
+	expr = strings.TrimSuffix(expr, "に")
+	if expr == "" {
+		return time.Time{}, errReminderTime
+	}
+
+	loc := now.Location()
+	for _, layout := range isoLayouts {
+		if t, err := time.ParseInLocation(layout, strings.ToUpper(expr), loc); err == nil {
+			return t, nil
+		}
+	}
+	for _, layout := range isoDateLayouts {
+		if t, err := time.ParseInLocation(layout, expr, loc); err == nil {
+			return t.Add(defaultReminderHour * time.Hour), nil
+		}
+	}
+
+	if rest, ok := strings.CutSuffix(expr, "後"); ok {
+		return parseDuration(japaneseDurationRe, rest, now)
+	}
+	if rest, ok := strings.CutPrefix(expr, "in "); ok {
+		return parseDuration(englishDurationRe, rest, now)
+	}
+
+	return parseDayAndClock(expr, now)
+}
+
+// parseDuration adds a duration such as "1時間30分" or "2 hours 30 minutes" to now
+// Every part of the text must be a number followed by a unit of re
+func parseDuration(re *regexp.Regexp, text string, now time.Time) (time.Time, error) {
+	text = strings.Join(strings.Fields(text), "")
+	matches := re.FindAllStringSubmatchIndex(text, -1)
+	if len(matches) == 0 {
+		return time.Time{}, errReminderTime
+	}
+
+	var total time.Duration
+	end := 0
+	for _, match := range matches {
+		if match[0] != end {
+			return time.Time{}, errReminderTime
+		}
+		end = match[1]
+
+		n, err := strconv.Atoi(text[match[2]:match[3]])
+		if err != nil {
+			return time.Time{}, errReminderTime
+		}
+		total += time.Duration(n) * durationUnits[text[match[4]:match[5]]]
+	}
+	if end != len(text) || total <= 0 {
+		return time.Time{}, errReminderTime
+	}
+	return now.Add(total), nil
+}
+
+// parseDayAndClock parses an optional day ("明日", "10月20日", "10/20") followed by an optional time of day
+func parseDayAndClock(expr string, now time.Time) (time.Time, error) {
+	year, month, day := now.Date()
+	hasDay, hasDate := false, false
+
+	if m := relativeDayRe.FindStringSubmatch(expr); m != nil {
+		day += relativeDays[m[1]]
+		hasDay = true
+		expr = expr[len(m[0]):]
+	} else if m := japaneseDateRe.FindStringSubmatch(expr); m != nil {
+		month, day = time.Month(atoi(m[1])), atoi(m[2])
+		hasDay, hasDate = true, true
+		expr = expr[len(m[0]):]
+	} else if m := slashDateRe.FindStringSubmatch(expr); m != nil {
+		month, day = time.Month(atoi(m[1])), atoi(m[2])
+		hasDay, hasDate = true, true
+		expr = expr[len(m[0]):]
+	}
+	if hasDate && (month < 1 || month > 12 || day < 1 || day > 31) {
+		return time.Time{}, errReminderTime
+	}
+
+	expr = strings.TrimSpace(expr)
+	expr = strings.TrimSpace(strings.TrimPrefix(expr, "at "))
+	expr = strings.TrimPrefix(expr, "の")
+
+	hour, minute := defaultReminderHour, 0
+	if expr != "" {
+		var ok bool
+		if hour, minute, ok = parseClock(expr); !ok {
+			return time.Time{}, errReminderTime
+		}
+	} else if !hasDay {
+		return time.Time{}, errReminderTime
+	}
+
+	t := time.Date(year, month, day, hour, minute, 0, 0, now.Location())
+	if !t.After(now) {
+		switch {
+		case hasDate:
+			t = t.AddDate(1, 0, 0)
+		case !hasDay:
+			t = t.AddDate(0, 0, 1)
+		}
+	}
+	return t, nil
+}
+
+// parseClock parses a time of day such as "9時", "午後3時半", "18:30" or "7pm"
+func parseClock(expr string) (hour, minute int, ok bool) {
+	switch m := japaneseClockRe.FindStringSubmatch(expr); {
+	case m != nil:
+		hour = atoi(m[2])
+		if m[3] != "" {
+			minute = 30
+		} else if m[4] != "" {
+			minute = atoi(m[4])
+		}
+		if m[1] != "" {
+			if hour > 12 {
+				return 0, 0, false
+			}
+			// 午前12時 is midnight and 午後12時 noon, like 12am and 12pm
+			switch {
+			case m[1] == "午前" && hour == 12:
+				hour = 0
+			case m[1] == "午後" && hour < 12:
+				hour += 12
+			}
+		}
+	case colonClockRe.MatchString(expr):
+		m := colonClockRe.FindStringSubmatch(expr)
+		hour, minute = atoi(m[1]), atoi(m[2])
+	case meridiemClockRe.MatchString(expr):
+		m := meridiemClockRe.FindStringSubmatch(expr)
+		hour = atoi(m[1])
+		if m[2] != "" {
+			minute = atoi(m[2])
+		}
+		if hour < 1 || hour > 12 {
+			return 0, 0, false
+		}
+		hour %= 12
+		if m[3] == "pm" {
+			hour += 12
+		}
+	default:
+		return 0, 0, false
+	}
+
+	if hour > 23 || minute > 59 {
+		return 0, 0, false
+	}
+	return hour, minute, true
+}
+
+// atoi converts digits matched by a regexp, which can't fail
+func atoi(s string) int {
+	n, _ := strconv.Atoi(s)
+	return n
+}
+
+// SplitReminderArgs splits "<when> <text>" into the time and the text of a reminder
+// The longest run of leading fields that is a time expression is used, so "in 2 hours 散歩" works
+func SplitReminderArgs(args string, now time.Time) (time.Time, string, error) {
+	fields := strings.Fields(args)
+	for k := min(maxReminderTimeFields, len(fields)); k >= 1; k-- {
+		t, err := ParseReminderTime(strings.Join(fields[:k], " "), now)
+		if err != nil {
+			continue
+		}
+		return t, skipFields(args, k), nil
+	}
+	return time.Time{}, "", errReminderTime
+}
+
+// skipFields returns s without its first k whitespace separated fields, keeping the spacing of the rest
+func skipFields(s string, k int) string {
+	s = strings.TrimLeftFunc(s, unicode.IsSpace)
+	for range k {
+		idx := strings.IndexFunc(s, unicode.IsSpace)
+		if idx < 0 {
+			return ""
+		}
+		s = strings.TrimLeftFunc(s[idx:], unicode.IsSpace)
+	}
+	return s
+}
@@ -0,0 +1,107 @@
+package nelchanbot
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseReminderTime(t *testing.T) {
+	tokyo, err := time.LoadLocation("Asia/Tokyo")
+	if err != nil {
+		t.Fatalf("LoadLocation() error = %v", err)
+	}
+	now := time.Date(2026, 10, 18, 14, 20, 0, 0, tokyo)
+
+	tests := []struct {
+		expr string
+		want time.Time
+	}{
+		{"10分後", now.Add(10 * time.Minute)},
+		{"１０分後に", now.Add(10 * time.Minute)},
+		{"1時間30分後", now.Add(90 * time.Minute)},
+		{"2日後", now.AddDate(0, 0, 2)},
+		{"1週間後", now.AddDate(0, 0, 7)},
+		{"in 2h", now.Add(2 * time.Hour)},
+		{"in 1h30m", now.Add(90 * time.Minute)},
+		{"in 2 hours", now.Add(2 * time.Hour)},
+		{"in 1 hour 15 minutes", now.Add(75 * time.Minute)},
+		{"in 45 secs", now.Add(45 * time.Second)},
+		{"明日9時", time.Date(2026, 10, 19, 9, 0, 0, 0, tokyo)},
+		{"明日の9時半", time.Date(2026, 10, 19, 9, 30, 0, 0, tokyo)},
+		{"明日", time.Date(2026, 10, 19, 9, 0, 0, 0, tokyo)},
+		{"明後日18:30", time.Date(2026, 10, 20, 18, 30, 0, 0, tokyo)},
+		{"午後3時", time.Date(2026, 10, 18, 15, 0, 0, 0, tokyo)},
+		// Already passed today
+		{"9時15分", time.Date(2026, 10, 19, 9, 15, 0, 0, tokyo)},
+		{"18:00", time.Date(2026, 10, 18, 18, 0, 0, 0, tokyo)},
+		{"10月20日", time.Date(2026, 10, 20, 9, 0, 0, 0, tokyo)},
+		{"10/1 8時", time.Date(2027, 10, 1, 8, 0, 0, 0, tokyo)},
+		{"tomorrow 9am", time.Date(2026, 10, 19, 9, 0, 0, 0, tokyo)},
+		{"tomorrow at 12:30pm", time.Date(2026, 10, 19, 12, 30, 0, 0, tokyo)},
+		{"at 7pm", time.Date(2026, 10, 18, 19, 0, 0, 0, tokyo)},
+		{"12am", time.Date(2026, 10, 19, 0, 0, 0, 0, tokyo)},
+		{"午前12時", time.Date(2026, 10, 19, 0, 0, 0, 0, tokyo)},
+		{"12pm", time.Date(2026, 10, 19, 12, 0, 0, 0, tokyo)},
+		{"午後12時", time.Date(2026, 10, 19, 12, 0, 0, 0, tokyo)},
+		{"2026-10-20", time.Date(2026, 10, 20, 9, 0, 0, 0, tokyo)},
+		{"2026-10-20 18:00", time.Date(2026, 10, 20, 18, 0, 0, 0, tokyo)},
+		{"2026-10-20T18:00", time.Date(2026, 10, 20, 18, 0, 0, 0, tokyo)},
+		{"2026-10-20T09:00:00Z", time.Date(2026, 10, 20, 9, 0, 0, 0, time.UTC)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			got, err := ParseReminderTime(tt.expr, now)
+			if err != nil {
+				t.Fatalf("ParseReminderTime() error = %v", err)
+			}
+			if !got.Equal(tt.want) {
+				t.Errorf("ParseReminderTime() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseReminderTimeInvalid(t *testing.T) {
+	now := time.Date(2026, 10, 18, 14, 20, 0, 0, time.UTC)
+
+	for _, expr := range []string{"", "後", "10分", "in", "in 2 months", "25時", "午後13時", "13pm", "9:75", "13/1", "ごはん"} {
+		t.Run(expr, func(t *testing.T) {
+			if got, err := ParseReminderTime(expr, now); err == nil {
+				t.Errorf("ParseReminderTime() = %v, want an error", got)
+			}
+		})
+	}
+}
+
+func TestSplitReminderArgs(t *testing.T) {
+	now := time.Date(2026, 10, 18, 14, 20, 0, 0, time.UTC)
+
+	tests := []struct {
+		args     string
+		wantTime time.Time
+		wantText string
+	}{
+		{"10分後 お湯を止める", now.Add(10 * time.Minute), "お湯を止める"},
+		{"in 1 hour 30 minutes 散歩\n水を持っていく", now.Add(90 * time.Minute), "散歩\n水を持っていく"},
+		{"明日 9時 歯医者", time.Date(2026, 10, 19, 9, 0, 0, 0, time.UTC), "歯医者"},
+		{"2026-10-20 18:00 締め切り", time.Date(2026, 10, 20, 18, 0, 0, 0, time.UTC), "締め切り"},
+		{"in 2h", now.Add(2 * time.Hour), ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.args, func(t *testing.T) {
+			gotTime, gotText, err := SplitReminderArgs(tt.args, now)
+			if err != nil {
+				t.Fatalf("SplitReminderArgs() error = %v", err)
+			}
+			if !gotTime.Equal(tt.wantTime) || gotText != tt.wantText {
+				t.Errorf("SplitReminderArgs() = (%v, %q), want (%v, %q)", gotTime, gotText, tt.wantTime, tt.wantText)
+			}
+		})
+	}
+
+	if _, _, err := SplitReminderArgs("そのうち 掃除", now); err == nil {
+		t.Error("SplitReminderArgs() without a time expression, want an error")
+	}
+}
@@ -0,0 +1,127 @@
+package nelchanbot
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReminderQueue(t *testing.T) {
+	delivered := make(chan int64, 4)
+	queue := NewReminderQueue(func(reminder Reminder) {
+		delivered <- reminder.ID
+	})
+	defer queue.Stop()
+
+	now := time.Now()
+	past := Reminder{ID: 1, RemindAt: now.Add(-time.Hour).UTC().Format(time.RFC3339)}
+	future := Reminder{ID: 2, RemindAt: now.Add(time.Hour).UTC().Format(time.RFC3339)}
+	invalid := Reminder{ID: 3, RemindAt: "someday"}
+
+	if errs := queue.Load([]Reminder{past, future, invalid}, now); len(errs) != 1 {
+		t.Errorf("Load() errors = %v, want 1", errs)
+	}
+
+	select {
+	case id := <-delivered:
+		if id != 1 {
+			t.Errorf("delivered reminder %d, want 1", id)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("overdue reminder wasn't delivered")
+	}
+
+	// Reloading doesn't deliver it again
+	queue.Load([]Reminder{past, future}, now)
+	if !queue.Remove(2) {
+		t.Error("Remove(2) = false, want true")
+	}
+	if queue.Remove(2) {
+		t.Error("Remove(2) again = true, want false")
+	}
+
+	select {
+	case id := <-delivered:
+		t.Errorf("delivered reminder %d after reload, want none", id)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	// Reminders removed from the backend are forgotten
+	queue.Load([]Reminder{future}, now)
+	queue.mu.Lock()
+	remembered := len(queue.fired)
+	queue.mu.Unlock()
+	if remembered != 0 {
+		t.Errorf("fired reminders after they were removed = %d, want 0", remembered)
+	}
+}
+
+func TestReminderQueueForgetsOldFiredReminders(t *testing.T) {
+	delivered := make(chan int64, 1)
+	queue := NewReminderQueue(func(reminder Reminder) {
+		delivered <- reminder.ID
+	})
+	defer queue.Stop()
+
+	queue.mu.Lock()
+	queue.fired[1] = time.Now().Add(-2 * firedReminderRetention)
+	queue.mu.Unlock()
+
+	now := time.Now()
+	if err := queue.Add(Reminder{ID: 2, RemindAt: now.UTC().Format(time.RFC3339)}, now); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-delivered:
+	case <-time.After(time.Second):
+		t.Fatal("reminder wasn't delivered")
+	}
+
+	queue.mu.Lock()
+	defer queue.mu.Unlock()
+	if _, ok := queue.fired[1]; ok {
+		t.Error("reminder delivered long ago is still remembered")
+	}
+	if _, ok := queue.fired[2]; !ok {
+		t.Error("reminder just delivered isn't remembered")
+	}
+}
+
+func TestFormatReminderDelivery(t *testing.T) {
+	reminder := Reminder{UserID: "100", Text: "お湯を止める", RemindAt: "2026-10-18T05:30:00Z"}
+	at := time.Date(2026, 10, 18, 5, 30, 0, 0, time.UTC)
+
+	if got, want := FormatReminderDelivery(reminder, at.Add(10*time.Second)), "⏰ <@100> リマインダー: お湯を止める"; got != want {
+		t.Errorf("FormatReminderDelivery() = %q, want %q", got, want)
+	}
+	if got := FormatReminderDelivery(reminder, at.Add(time.Hour)); !strings.Contains(got, "<t:1792301400:f> の予定でしたが、遅れてお届けしました") {
+		t.Errorf("FormatReminderDelivery() late = %q", got)
+	}
+}
+
+func TestFormatReminderList(t *testing.T) {
+	if got := FormatReminderList(nil); !strings.Contains(got, "リマインダーはありません") {
+		t.Errorf("FormatReminderList(nil) = %q", got)
+	}
+
+	got := FormatReminderList([]Reminder{
+		{ID: 1, Text: "歯医者", RemindAt: "2026-10-19T00:00:00Z"},
+		{ID: 2, Text: strings.Repeat("あ", 60), RemindAt: "2026-10-20T00:00:00Z", Delivery: ReminderDeliveryDM},
+	})
+	want := "リマインダー（2 件）\n" +
+		"`#1` <t:1792368000:f>（<t:1792368000:R>） 歯医者\n" +
+		"`#2` <t:1792454400:f>（<t:1792454400:R>） " + strings.Repeat("あ", 49) + "… ・DM\n" +
+		"`!unremind <番号>` で取り消せます"
+	if got != want {
+		t.Errorf("FormatReminderList() = %q, want %q", got, want)
+	}
+
+	many := make([]Reminder, 25)
+	for idx := range many {
+		many[idx] = Reminder{ID: int64(idx + 1), Text: strings.Repeat("x", 50), RemindAt: "2026-10-19T00:00:00Z"}
+	}
+	got = FormatReminderList(many)
+	if len([]rune(got)) > maxMessageLength || !strings.Contains(got, "…ほか") {
+		t.Errorf("FormatReminderList() of many = %d runes, want at most %d with the rest counted", len([]rune(got)), maxMessageLength)
+	}
+}
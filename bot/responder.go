@@ -0,0 +1,482 @@
+package nelchanbot
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+const (
+	// maxRespondersPerGuild limits the auto responders of a guild
+	maxRespondersPerGuild = 50
+	// maxResponderPatternLength limits the length of a pattern in characters
+	maxResponderPatternLength = 200
+	// responderCooldown is how long a responder waits before responding again in the same channel
+	responderCooldown = 3 * time.Second
+)
+
+// responderModeNames are the names of the responder modes shown to users
+var responderModeNames = map[string]string{
+	ResponderModeExact:    "最初の単語",
+	ResponderModePrefix:   "前方一致",
+	ResponderModeContains: "部分一致",
+	ResponderModeRegex:    "正規表現",
+	ResponderModeWhole:    "完全一致",
+}
+
+// CompileResponderPattern validates the pattern of an auto responder
+// Regex patterns are compiled, other modes return a nil regexp
+func CompileResponderPattern(mode, pattern string) (*regexp.Regexp, error) {
+	if _, ok := responderModeNames[mode]; !ok {
+		return nil, fmt.Errorf("モード「%s」には対応していません", mode)
+	}
+	if strings.TrimSpace(pattern) == "" {
+		return nil, errors.New("パターンを指定してください")
+	}
+	if utf8.RuneCountInString(pattern) > maxResponderPatternLength {
+		return nil, fmt.Errorf("パターンは %d 文字までです", maxResponderPatternLength)
+	}
+
+	switch mode {
+	case ResponderModeExact:
+		if len(strings.Fields(pattern)) != 1 {
+			return nil, errors.New("exact モードのパターンには空白を含められません")
+		}
+	case ResponderModeRegex:
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("正規表現が正しくありません: %w", err)
+		}
+		return re, nil
+	}
+	return nil, nil
+}
+
+// ResponderMatch is a message matched by an auto responder
+type ResponderMatch struct {
+	Responder AutoResponder
+	Args      []string          // words after the pattern, or the capture groups of a regex
+	Named     map[string]string // named capture groups of a regex
+}
+
+// compiledResponder is an auto responder with its regex compiled once
+type compiledResponder struct {
+	AutoResponder
+	re *regexp.Regexp
+}
+
+// match matches content, which has surrounding whitespace trimmed, against the pattern of the responder
+func (c compiledResponder) match(content string) (ResponderMatch, bool) {
+	result := ResponderMatch{Responder: c.AutoResponder}
+
+	switch c.Mode {
+	case ResponderModeExact:
+		first, rest := CutFirstField(content)
+		if first != c.Pattern {
+			return result, false
+		}
+		result.Args = strings.Fields(rest)
+	case ResponderModePrefix:
+		rest, ok := strings.CutPrefix(content, c.Pattern)
+		if !ok {
+			return result, false
+		}
+		result.Args = strings.Fields(rest)
+	case ResponderModeContains:
+		if !strings.Contains(content, c.Pattern) {
+			return result, false
+		}
+	case ResponderModeRegex:
+		groups := c.re.FindStringSubmatch(content)
+		if groups == nil {
+			return result, false
+		}
+		result.Args = groups[1:]
+		for idx, name := range c.re.SubexpNames() {
+			if name == "" {
+				continue
+			}
+			if result.Named == nil {
+				result.Named = make(map[string]string)
+			}
+			result.Named[name] = groups[idx]
+		}
+	case ResponderModeWhole:
+		if content != c.Pattern {
+			return result, false
+		}
+	default:
+		return result, false
+	}
+	return result, true
+}
+
+// ResponderRegistry keeps the auto responders of every guild and the channels they are enabled in
+type ResponderRegistry struct {
+	mu       sync.Mutex
+	byGuild  map[string][]compiledResponder
+	channels map[string]bool
+	lastRun  map[string]time.Time // keyed by responder ID and channel ID
+}
+
+// NewResponderRegistry creates a registry without responders
+func NewResponderRegistry() *ResponderRegistry {
+	return &ResponderRegistry{
+		byGuild:  make(map[string][]compiledResponder),
+		channels: make(map[string]bool),
+		lastRun:  make(map[string]time.Time),
+	}
+}
+
+// Set replaces every responder and enabled channel
+// Responders whose pattern doesn't compile are left out and reported
+func (r *ResponderRegistry) Set(responders []AutoResponder, channels []AutoResponderChannel) []error {
+	var errs []error
+	byGuild := make(map[string][]compiledResponder)
+	for _, responder := range responders {
+		re, err := CompileResponderPattern(responder.Mode, responder.Pattern)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("responder %d: %w", responder.ID, err))
+			continue
+		}
+		byGuild[responder.GuildID] = append(byGuild[responder.GuildID], compiledResponder{AutoResponder: responder, re: re})
+	}
+
+	enabled := make(map[string]bool, len(channels))
+	for _, channel := range channels {
+		enabled[channel.ChannelID] = true
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.byGuild = byGuild
+	r.channels = enabled
+	return errs
+}
+
+// Enabled reports whether auto responders are enabled in a channel
+func (r *ResponderRegistry) Enabled(channelID string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.channels[channelID]
+}
+
+// Match returns the first responder of the guild, in the order they were added, matching a message in a channel
+// Nothing matches in channels where auto responders aren't enabled, or while the matched responder is cooling down
+func (r *ResponderRegistry) Match(guildID, channelID, content string, now time.Time) (ResponderMatch, bool) {
+	content = strings.TrimSpace(content)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if content == "" || !r.channels[channelID] {
+		return ResponderMatch{}, false
+	}
+
+	for _, responder := range r.byGuild[guildID] {
+		match, ok := responder.match(content)
+		if !ok {
+			continue
+		}
+
+		key := fmt.Sprintf("%d:%s", responder.ID, channelID)
+		if now.Sub(r.lastRun[key]) < responderCooldown {
+			return ResponderMatch{}, false
+		}
+		r.lastRun[key] = now
+		return match, true
+	}
+	return ResponderMatch{}, false
+}
+
+// loadResponders loads every auto responder and enabled channel from the backend into the registry
+func (n *Nelchan) loadResponders() {
+	responders, channels, err := n.CommandAPIClient.ListAutoResponders(ListAutoRespondersRequest{})
+	if err != nil {
+		fmt.Println("error loading auto responders:", err)
+		return
+	}
+
+	for _, err := range n.Responders.Set(responders, channels) {
+		fmt.Println("error loading auto responder:", err)
+	}
+	fmt.Printf("loaded %d auto responders in %d channels\n", len(responders), len(channels))
+}
+
+// tryAutoResponder runs the auto responder matching a message, if any
+// Returns false if no responder matched, so the message is handled as a text command
+func (n *Nelchan) tryAutoResponder(s *discordgo.Session, m *discordgo.MessageCreate) bool {
+	// Responding to bots could make two bots respond to each other forever
+	if m.GuildID == "" || m.Author.Bot {
+		return false
+	}
+
+	match, ok := n.Responders.Match(m.GuildID, m.ChannelID, m.Content, time.Now())
+	if !ok {
+		return false
+	}
+
+	fmt.Printf("auto responder %d matched: %s\n", match.Responder.ID, match.Responder.CommandName)
+	n.runAutoResponder(s, m, match)
+	return true
+}
+
+// runAutoResponder runs the command of a matched auto responder and posts its output
+// Like text commands, failures are only logged so that ordinary conversation isn't interrupted
+func (n *Nelchan) runAutoResponder(s *discordgo.Session, m *discordgo.MessageCreate, match ResponderMatch) {
+	request := RunCommandRequest{
+		CommandName: match.Responder.CommandName,
+		IsCode:      match.Responder.IsCode,
+	}
+
+	if match.Responder.IsCode {
+		info := n.lookupCodeCommand(match.Responder.CommandName)
+		if info == nil {
+			return
+		}
+
+		named := make(map[string]any, len(match.Named))
+		for name, value := range match.Named {
+			named[name] = value
+		}
+		schema := n.commandArgsSchema(info)
+		named = n.CommandParser.BindPositionalArgs(schema, match.Args, named)
+		resolved, err := ResolveArgs(schema, named)
+		if err != nil {
+			fmt.Printf("auto responder %d: invalid args for %s: %v\n", match.Responder.ID, info.Name, err)
+			return
+		}
+
+		request.Args = match.Args
+		request.Named = resolved
+		ctx := messageContext(s, RunSourceAuto, m.Message)
+		request.Vars = ctx.Vars()
+		request.Context = &ctx
+	}
+
+	start := time.Now()
+	result, err := n.CommandAPIClient.RunCommand(request)
+	n.recordCommandRun(CommandRun{
+		CommandName: match.Responder.CommandName,
+		UserID:      m.Author.ID,
+		ChannelID:   m.ChannelID,
+		GuildID:     m.GuildID,
+		Source:      RunSourceAuto,
+	}, start, result, err)
+	if err != nil {
+		fmt.Printf("error running auto responder %d: %v\n", match.Responder.ID, err)
+		return
+	}
+	if result == nil {
+		return
+	}
+	if match.Responder.IsCode {
+		if strings.TrimSpace(result.Content) == "" && len(result.Artifacts) == 0 {
+			return
+		}
+		err = n.sendCommandOutput(s, m.ChannelID, result)
+	} else {
+		content := n.renderTextCommand(s, m, pickTextResponse(result), match.Args)
+		if strings.TrimSpace(content) == "" {
+			return
+		}
+		err = n.sendMessage(s, m.ChannelID, content)
+	}
+	if err != nil {
+		fmt.Println("error sending message,", err)
+	}
+}
+
+// FormatResponderList formats the auto responders of a guild for /responder list
+// enabled tells whether they are enabled in the channel the list is shown in
+func FormatResponderList(responders []AutoResponder, enabled bool) string {
+	status := "このチャンネルでは自動応答は無効です。`/responder channel` で有効にできます（管理者のみ）"
+	if enabled {
+		status = "このチャンネルでは自動応答が有効です"
+	}
+	if len(responders) == 0 {
+		return "自動応答はありません。`/responder add` で登録できます\n" + status
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "自動応答（%d 件）", len(responders))
+	for _, responder := range responders {
+		fmt.Fprintf(&b, "\n`#%d` %s `%s` → `%s`", responder.ID, responderModeNames[responder.Mode],
+			strings.ReplaceAll(responder.Pattern, "`", "'"), commandInvocation(responder.CommandName, responder.IsCode))
+	}
+	b.WriteString("\n" + status)
+	return b.String()
+}
+
+// handleResponderSlashCommand handles the /responder slash command and its add, list, remove and channel subcommands
+func (n *Nelchan) handleResponderSlashCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	data := i.ApplicationCommandData()
+	if len(data.Options) == 0 {
+		return
+	}
+	sub := data.Options[0]
+
+	// Defer response as responders are validated and stored in the backend
+	err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
+	})
+	if err != nil {
+		fmt.Printf("error deferring interaction response: %v\n", err)
+		return
+	}
+
+	if i.GuildID == "" {
+		editInteractionContent(s, i, "自動応答はサーバー内でのみ使えます")
+		return
+	}
+
+	var message string
+	switch sub.Name {
+	case "add":
+		message, err = n.addResponder(i, sub.Options)
+	case "list":
+		var responders []AutoResponder
+		responders, _, err = n.CommandAPIClient.ListAutoResponders(ListAutoRespondersRequest{GuildID: i.GuildID})
+		message = FormatResponderList(responders, n.Responders.Enabled(i.ChannelID))
+	case "remove":
+		message, err = n.removeResponder(i, sub.Options)
+	case "channel":
+		message, err = n.setResponderChannel(i, sub.Options)
+	}
+	if err != nil {
+		fmt.Printf("error handling /responder %s: %v\n", sub.Name, err)
+		message = fmt.Sprintf("エラー: %s", err.Error())
+	}
+
+	editInteractionContent(s, i, message)
+	fmt.Printf("slash command /responder %s executed\n", sub.Name)
+}
+
+// addResponder validates and stores an auto responder from the options of /responder add
+// Returns the message to show to the user
+func (n *Nelchan) addResponder(i *discordgo.InteractionCreate, options []*discordgo.ApplicationCommandInteractionDataOption) (string, error) {
+	responder := AutoResponder{
+		GuildID:  i.GuildID,
+		AuthorID: interactionUser(i).ID,
+	}
+	for _, opt := range options {
+		switch opt.Name {
+		case "mode":
+			responder.Mode = opt.StringValue()
+		case "pattern":
+			responder.Pattern = strings.TrimSpace(opt.StringValue())
+		case "command_name":
+			responder.CommandName = n.CommandRouter.ResolveAlias(opt.StringValue())
+		}
+	}
+
+	if _, err := CompileResponderPattern(responder.Mode, responder.Pattern); err != nil {
+		return err.Error(), nil
+	}
+
+	existing, _, err := n.CommandAPIClient.ListAutoResponders(ListAutoRespondersRequest{GuildID: i.GuildID})
+	if err != nil {
+		return "", err
+	}
+	if len(existing) >= maxRespondersPerGuild {
+		return fmt.Sprintf("このサーバーの自動応答は %d 件までです。`/responder remove` で削除してから登録してください", maxRespondersPerGuild), nil
+	}
+
+	created, err := n.CommandAPIClient.AddAutoResponder(responder)
+	if err != nil {
+		return "", err
+	}
+	if created == nil {
+		return fmt.Sprintf("コマンド「%s」は見つかりませんでした", responder.CommandName), nil
+	}
+	n.loadResponders()
+
+	fmt.Printf("added auto responder %d: %s %q -> %s by %s\n", created.ID, created.Mode, created.Pattern, created.CommandName, created.AuthorID)
+	message := fmt.Sprintf("自動応答 `#%d` を登録しました: %s `%s` → `%s`", created.ID, responderModeNames[created.Mode],
+		strings.ReplaceAll(created.Pattern, "`", "'"), commandInvocation(created.CommandName, created.IsCode))
+	if !n.Responders.Enabled(i.ChannelID) {
+		message += "\nこのチャンネルでは自動応答が無効です。管理者が `/responder channel` で有効にすると応答します"
+	}
+	return message, nil
+}
+
+// removeResponder removes an auto responder of the guild given to /responder remove
+// Only the author of the responder or an admin can remove it
+func (n *Nelchan) removeResponder(i *discordgo.InteractionCreate, options []*discordgo.ApplicationCommandInteractionDataOption) (string, error) {
+	var id int64
+	for _, opt := range options {
+		if opt.Name == "id" {
+			id = opt.IntValue()
+		}
+	}
+
+	// Look the responder up in this guild so responders of other guilds can't be removed
+	responders, _, err := n.CommandAPIClient.ListAutoResponders(ListAutoRespondersRequest{GuildID: i.GuildID})
+	if err != nil {
+		return "", err
+	}
+	idx := slices.IndexFunc(responders, func(responder AutoResponder) bool {
+		return responder.ID == id
+	})
+	if idx < 0 {
+		return fmt.Sprintf("自動応答 `#%d` は見つかりませんでした", id), nil
+	}
+
+	user := interactionUser(i)
+	if responders[idx].AuthorID != user.ID && !n.isInteractionAdmin(i) {
+		return fmt.Sprintf("自動応答 `#%d` を削除できるのは登録者か管理者だけです", id), nil
+	}
+
+	removed, err := n.CommandAPIClient.RemoveAutoResponder(RemoveAutoResponderRequest{ID: id})
+	if err != nil {
+		return "", err
+	}
+	n.loadResponders()
+	if !removed {
+		return fmt.Sprintf("自動応答 `#%d` は見つかりませんでした", id), nil
+	}
+
+	fmt.Printf("removed auto responder %d by %s\n", id, user.ID)
+	return fmt.Sprintf("自動応答 `#%d` を削除しました", id), nil
+}
+
+// setResponderChannel enables or disables auto responders in a channel for /responder channel
+// Only admins can change where auto responders respond
+func (n *Nelchan) setResponderChannel(i *discordgo.InteractionCreate, options []*discordgo.ApplicationCommandInteractionDataOption) (string, error) {
+	if !n.isInteractionAdmin(i) {
+		return "自動応答を有効にするチャンネルはサーバーの管理者だけが設定できます", nil
+	}
+
+	request := SetAutoResponderChannelRequest{
+		ChannelID: i.ChannelID,
+		GuildID:   i.GuildID,
+		UserID:    interactionUser(i).ID,
+	}
+	for _, opt := range options {
+		switch opt.Name {
+		case "enabled":
+			request.Enabled = opt.BoolValue()
+		case "channel":
+			if id, ok := opt.Value.(string); ok {
+				request.ChannelID = id
+			}
+		}
+	}
+
+	if err := n.CommandAPIClient.SetAutoResponderChannel(request); err != nil {
+		return "", err
+	}
+	n.loadResponders()
+
+	fmt.Printf("auto responders in %s set to %v by %s\n", request.ChannelID, request.Enabled, request.UserID)
+	if request.Enabled {
+		return fmt.Sprintf("<#%s> で自動応答を有効にしました", request.ChannelID), nil
+	}
+	return fmt.Sprintf("<#%s> で自動応答を無効にしました", request.ChannelID), nil
+}
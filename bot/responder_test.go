@@ -0,0 +1,133 @@
+package nelchanbot
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCompileResponderPattern(t *testing.T) {
+	valid := []struct{ mode, pattern string }{
+		{ResponderModeExact, "おはよう"},
+		{ResponderModePrefix, "天気 "},
+		{ResponderModeContains, "ねるちゃん"},
+		{ResponderModeRegex, `(\d+)d(\d+)`},
+		{ResponderModeWhole, "ぬるぽ"},
+	}
+	for _, tt := range valid {
+		if _, err := CompileResponderPattern(tt.mode, tt.pattern); err != nil {
+			t.Errorf("CompileResponderPattern(%q, %q) error = %v", tt.mode, tt.pattern, err)
+		}
+	}
+
+	invalid := []struct{ mode, pattern string }{
+		{"fuzzy", "おはよう"},
+		{ResponderModeContains, " "},
+		{ResponderModeExact, "おはよう ございます"},
+		{ResponderModeRegex, `(\d+`},
+		{ResponderModeWhole, strings.Repeat("あ", maxResponderPatternLength+1)},
+	}
+	for _, tt := range invalid {
+		if _, err := CompileResponderPattern(tt.mode, tt.pattern); err == nil {
+			t.Errorf("CompileResponderPattern(%q, %q) want an error", tt.mode, tt.pattern)
+		}
+	}
+}
+
+func TestResponderRegistryMatch(t *testing.T) {
+	registry := NewResponderRegistry()
+	errs := registry.Set([]AutoResponder{
+		{ID: 1, GuildID: "g1", Mode: ResponderModeWhole, Pattern: "ぬるぽ", CommandName: "garu"},
+		{ID: 2, GuildID: "g1", Mode: ResponderModeExact, Pattern: "天気", CommandName: "weather", IsCode: true},
+		{ID: 3, GuildID: "g1", Mode: ResponderModeRegex, Pattern: `(?P<count>\d+)d(?P<sides>\d+)`, CommandName: "dice", IsCode: true},
+		{ID: 4, GuildID: "g1", Mode: ResponderModePrefix, Pattern: "ねるちゃん、", CommandName: "talk", IsCode: true},
+		{ID: 5, GuildID: "g1", Mode: ResponderModeContains, Pattern: "おなかすいた", CommandName: "food"},
+		{ID: 6, GuildID: "g1", Mode: ResponderModeRegex, Pattern: `(`, CommandName: "broken"},
+		{ID: 7, GuildID: "g2", Mode: ResponderModeContains, Pattern: "おなかすいた", CommandName: "other"},
+	}, []AutoResponderChannel{{ChannelID: "c1", GuildID: "g1"}, {ChannelID: "c2", GuildID: "g2"}})
+	if len(errs) != 1 {
+		t.Errorf("Set() errors = %v, want 1 for the broken regex", errs)
+	}
+
+	tests := []struct {
+		name      string
+		channelID string
+		content   string
+		wantID    int64
+		wantArgs  []string
+		wantNamed map[string]string
+	}{
+		{"whole", "c1", " ぬるぽ ", 1, nil, nil},
+		{"whole with more text", "c1", "ぬるぽって何", 0, nil, nil},
+		{"exact with args", "c1", "天気 東京 明日", 2, []string{"東京", "明日"}, nil},
+		{"exact needs a word boundary", "c1", "天気予報", 0, nil, nil},
+		{"regex captures", "c1", "2d6 振って", 3, []string{"2", "6"}, map[string]string{"count": "2", "sides": "6"}},
+		{"prefix without spaces", "c1", "ねるちゃん、今日は何の日？", 4, []string{"今日は何の日？"}, nil},
+		{"contains in a sentence", "c1", "あーおなかすいたなあ", 5, nil, nil},
+		{"disabled channel", "c3", "ぬるぽ", 0, nil, nil},
+	}
+
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			match, ok := registry.Match("g1", tt.channelID, tt.content, now)
+			if tt.wantID == 0 {
+				if ok {
+					t.Errorf("Match() = responder %d, want none", match.Responder.ID)
+				}
+				return
+			}
+			if !ok || match.Responder.ID != tt.wantID {
+				t.Fatalf("Match() = %v, %v, want responder %d", match.Responder.ID, ok, tt.wantID)
+			}
+			if !slices.Equal(match.Args, tt.wantArgs) {
+				t.Errorf("Match() args = %q, want %q", match.Args, tt.wantArgs)
+			}
+			for name, value := range tt.wantNamed {
+				if match.Named[name] != value {
+					t.Errorf("Match() named[%q] = %q, want %q", name, match.Named[name], value)
+				}
+			}
+		})
+	}
+}
+
+func TestResponderRegistryCooldown(t *testing.T) {
+	registry := NewResponderRegistry()
+	registry.Set([]AutoResponder{
+		{ID: 1, GuildID: "g1", Mode: ResponderModeContains, Pattern: "草"},
+	}, []AutoResponderChannel{{ChannelID: "c1", GuildID: "g1"}, {ChannelID: "c2", GuildID: "g1"}})
+
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	if _, ok := registry.Match("g1", "c1", "草", now); !ok {
+		t.Fatal("Match() first time = false, want true")
+	}
+	if _, ok := registry.Match("g1", "c1", "草", now.Add(time.Second)); ok {
+		t.Error("Match() while cooling down = true, want false")
+	}
+	if _, ok := registry.Match("g1", "c2", "草", now.Add(time.Second)); !ok {
+		t.Error("Match() in another channel = false, want true")
+	}
+	if _, ok := registry.Match("g1", "c1", "草", now.Add(responderCooldown)); !ok {
+		t.Error("Match() after the cooldown = false, want true")
+	}
+}
+
+func TestFormatResponderList(t *testing.T) {
+	if got := FormatResponderList(nil, false); !strings.Contains(got, "自動応答はありません") || !strings.Contains(got, "無効") {
+		t.Errorf("FormatResponderList(nil) = %q", got)
+	}
+
+	got := FormatResponderList([]AutoResponder{
+		{ID: 1, Mode: ResponderModeWhole, Pattern: "ぬるぽ", CommandName: "garu"},
+		{ID: 2, Mode: ResponderModeRegex, Pattern: "`(\\d+)`", CommandName: "dice", IsCode: true},
+	}, true)
+	want := "自動応答（2 件）\n" +
+		"`#1` 完全一致 `ぬるぽ` → `" + commandInvocation("garu", false) + "`\n" +
+		"`#2` 正規表現 `'(\\d+)'` → `" + commandInvocation("dice", true) + "`\n" +
+		"このチャンネルでは自動応答が有効です"
+	if got != want {
+		t.Errorf("FormatResponderList() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,500 @@
+package nelchanbot
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+const (
+	// scheduleTickInterval is how often due schedules are checked
+	scheduleTickInterval = 20 * time.Second
+	// scheduleGracePeriod is how late a run may start before it counts as missed
+	scheduleGracePeriod = 2 * time.Minute
+	// maxSchedulesPerGuild limits the schedules of a guild
+	maxSchedulesPerGuild = 20
+	// maxScheduleArgsDisplayLength is the length args are truncated to in /schedule list
+	maxScheduleArgsDisplayLength = 30
+)
+
+// scheduleEntry is a schedule with its parsed cron expression and next run time
+type scheduleEntry struct {
+	Schedule
+	cron *CronSchedule
+	loc  *time.Location
+	next time.Time
+}
+
+// newScheduleEntry parses a stored schedule
+// Schedules without a stored next run time start from the first match after now
+func newScheduleEntry(schedule Schedule, now time.Time) (*scheduleEntry, error) {
+	cron, err := ParseCron(schedule.Cron)
+	if err != nil {
+		return nil, err
+	}
+	loc, err := time.LoadLocation(schedule.Timezone)
+	if err != nil {
+		return nil, fmt.Errorf("タイムゾーン「%s」が見つかりません", schedule.Timezone)
+	}
+
+	next := cron.Next(now.In(loc))
+	if schedule.NextRunAt != nil {
+		if next, err = time.Parse(time.RFC3339, *schedule.NextRunAt); err != nil {
+			return nil, fmt.Errorf("次回実行日時が不正です: %w", err)
+		}
+	}
+	if next.IsZero() {
+		return nil, fmt.Errorf("cron 式「%s」に一致する日時がありません", schedule.Cron)
+	}
+
+	return &scheduleEntry{Schedule: schedule, cron: cron, loc: loc, next: next}, nil
+}
+
+// ScheduledRun is a schedule that came due
+type ScheduledRun struct {
+	Schedule
+	Run  bool      // false when the run was missed and the schedule skips missed runs
+	Next time.Time // next run time after this one
+}
+
+// Scheduler keeps the schedules in memory and tells which of them are due
+type Scheduler struct {
+	mu      sync.Mutex
+	entries map[int64]*scheduleEntry
+
+	startOnce sync.Once
+	stop      chan struct{}
+}
+
+// NewScheduler creates a scheduler without schedules
+func NewScheduler() *Scheduler {
+	return &Scheduler{
+		entries: make(map[int64]*scheduleEntry),
+		stop:    make(chan struct{}),
+	}
+}
+
+// Load replaces the schedules
+// Schedules that can't be parsed are left out and returned as errors.
+// A schedule already in memory keeps its next run time if it is later than the stored one,
+// since the next run time of a run that just came due may not be stored yet
+func (sc *Scheduler) Load(schedules []Schedule, now time.Time) []error {
+	entries := make(map[int64]*scheduleEntry, len(schedules))
+	var errs []error
+	for _, schedule := range schedules {
+		entry, err := newScheduleEntry(schedule, now)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("schedule %d: %w", schedule.ID, err))
+			continue
+		}
+		entries[schedule.ID] = entry
+	}
+
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	for id, entry := range entries {
+		if old, ok := sc.entries[id]; ok && old.Cron == entry.Cron && old.Timezone == entry.Timezone && old.next.After(entry.next) {
+			entry.next = old.next
+		}
+	}
+	sc.entries = entries
+	return errs
+}
+
+// Add adds or replaces a schedule
+func (sc *Scheduler) Add(schedule Schedule, now time.Time) error {
+	entry, err := newScheduleEntry(schedule, now)
+	if err != nil {
+		return err
+	}
+
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	sc.entries[schedule.ID] = entry
+	return nil
+}
+
+// Remove removes a schedule
+func (sc *Scheduler) Remove(id int64) {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	delete(sc.entries, id)
+}
+
+// Due returns the schedules whose next run time has come, ordered by ID, and advances them to their next run
+// Runs later than scheduleGracePeriod were missed, e.g. while the bot was offline:
+// all missed runs of a schedule come due once and are only run under MissedPolicyRunOnce
+func (sc *Scheduler) Due(now time.Time) []ScheduledRun {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+
+	var runs []ScheduledRun
+	for id, entry := range sc.entries {
+		if entry.next.After(now) {
+			continue
+		}
+
+		missed := now.Sub(entry.next) > scheduleGracePeriod
+		entry.next = entry.cron.Next(now.In(entry.loc))
+		runs = append(runs, ScheduledRun{
+			Schedule: entry.Schedule,
+			Run:      !missed || entry.MissedPolicy != MissedPolicySkip,
+			Next:     entry.next,
+		})
+
+		if entry.next.IsZero() {
+			delete(sc.entries, id)
+		}
+	}
+
+	slices.SortFunc(runs, func(a, b ScheduledRun) int {
+		return cmp.Compare(a.ID, b.ID)
+	})
+	return runs
+}
+
+// Start calls tick with the current time every interval until Stop is called
+// Calls after the first do nothing
+func (sc *Scheduler) Start(interval time.Duration, tick func(now time.Time)) {
+	sc.startOnce.Do(func() {
+		go func() {
+			ticker := time.NewTicker(interval)
+			defer ticker.Stop()
+			for {
+				select {
+				case now := <-ticker.C:
+					tick(now)
+				case <-sc.stop:
+					return
+				}
+			}
+		}()
+	})
+}
+
+// Stop stops the ticker started by Start
+func (sc *Scheduler) Stop() {
+	close(sc.stop)
+}
+
+// loadSchedules loads every schedule from the backend into the scheduler
+func (n *Nelchan) loadSchedules() {
+	schedules, err := n.CommandAPIClient.ListSchedules(ListSchedulesRequest{})
+	if err != nil {
+		fmt.Println("error loading schedules:", err)
+		return
+	}
+
+	for _, err := range n.Scheduler.Load(schedules, time.Now()) {
+		fmt.Println("error loading schedule:", err)
+	}
+	fmt.Printf("loaded %d schedules\n", len(schedules))
+}
+
+// runDueSchedules runs the schedules that came due
+func (n *Nelchan) runDueSchedules(now time.Time) {
+	for _, run := range n.Scheduler.Due(now) {
+		go n.runScheduled(run, now)
+	}
+}
+
+// runScheduled stores the next run time of a due schedule and runs it unless it was skipped
+// The next run time is stored first so a restart during the run doesn't run it again
+func (n *Nelchan) runScheduled(run ScheduledRun, now time.Time) {
+	if !run.Next.IsZero() {
+		request := ScheduleRanRequest{
+			ID:        run.ID,
+			NextRunAt: run.Next.UTC().Format(time.RFC3339),
+		}
+		if run.Run {
+			request.LastRunAt = now.UTC().Format(time.RFC3339)
+		}
+		if _, err := n.CommandAPIClient.MarkScheduleRan(request); err != nil {
+			fmt.Printf("error storing run of schedule %d: %v\n", run.ID, err)
+		}
+	}
+
+	if !run.Run {
+		fmt.Printf("skipped missed run of schedule %d (%s)\n", run.ID, run.CommandName)
+		return
+	}
+	n.runSchedule(n.Discord, run.Schedule)
+}
+
+// runSchedule runs the code command of a schedule as its creator and posts the result to its channel
+func (n *Nelchan) runSchedule(s *discordgo.Session, schedule Schedule) {
+	info := n.lookupCodeCommand(schedule.CommandName)
+	if info == nil {
+		fmt.Printf("command %s of schedule %d not found\n", schedule.CommandName, schedule.ID)
+		return
+	}
+
+	args, named, err := n.scheduleArgs(info, schedule.Args)
+	if err != nil {
+		message := fmt.Sprintf("スケジュール #%d の引数が正しくありません:\n%s", schedule.ID, err.Error())
+		if err := n.sendMessage(s, schedule.ChannelID, message); err != nil {
+			fmt.Println("error sending message,", err)
+		}
+		return
+	}
+
+	user, err := s.User(schedule.AuthorID)
+	if err != nil {
+		fmt.Printf("error getting author of schedule %d: %v\n", schedule.ID, err)
+		user = &discordgo.User{ID: schedule.AuthorID}
+	}
+	ctx := eventContext(s, RunSourceSchedule, schedule.GuildID, schedule.ChannelID, user)
+
+	start := time.Now()
+	result, err := n.CommandAPIClient.RunCommand(RunCommandRequest{
+		CommandName: schedule.CommandName,
+		IsCode:      true,
+		Vars:        ctx.Vars(),
+		Args:        args,
+		Named:       named,
+		Context:     &ctx,
+	})
+	n.recordCommandRun(CommandRun{
+		CommandName: schedule.CommandName,
+		UserID:      schedule.AuthorID,
+		ChannelID:   schedule.ChannelID,
+		GuildID:     schedule.GuildID,
+		Source:      RunSourceSchedule,
+	}, start, result, err)
+
+	if err != nil {
+		fmt.Printf("error running schedule %d: %v\n", schedule.ID, err)
+		_, _ = s.ChannelMessageSend(schedule.ChannelID, fmt.Sprintf("エラー: %s", err.Error()))
+		return
+	}
+
+	if result == nil {
+		// Code command not found, don't respond
+		return
+	}
+
+	if err := n.sendCommandOutput(s, schedule.ChannelID, result); err != nil {
+		fmt.Println("error sending message,", err)
+		return
+	}
+	fmt.Printf("schedule %d ran %s\n", schedule.ID, schedule.CommandName)
+}
+
+// scheduleArgs parses the stored argument text of a schedule like "!<command> <args>" and checks it against the args schema
+func (n *Nelchan) scheduleArgs(info *GetCommandInfo, raw string) ([]string, map[string]any, error) {
+	args, named, err := n.parseCodeCommandArgs(info, raw)
+	if err != nil {
+		return nil, nil, err
+	}
+	named, err = ResolveArgs(n.commandArgsSchema(info), named)
+	if err != nil {
+		return nil, nil, err
+	}
+	return args, named, nil
+}
+
+// FormatScheduleList formats the schedules of a guild for /schedule list
+func FormatScheduleList(schedules []Schedule) string {
+	if len(schedules) == 0 {
+		return "スケジュールはありません。`/schedule add` で登録できます"
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "スケジュール（%d 件）", len(schedules))
+	for _, schedule := range schedules {
+		invocation := "!" + schedule.CommandName
+		if schedule.Args != "" {
+			args := schedule.Args
+			if utf8.RuneCountInString(args) > maxScheduleArgsDisplayLength {
+				args = string([]rune(args)[:maxScheduleArgsDisplayLength-1]) + "…"
+			}
+			invocation += " " + args
+		}
+
+		fmt.Fprintf(&b, "\n`#%d` `%s` → <#%s>　`%s`（%s）", schedule.ID, invocation, schedule.ChannelID, schedule.Cron, schedule.Timezone)
+		if schedule.NextRunAt != nil {
+			if next, err := time.Parse(time.RFC3339, *schedule.NextRunAt); err == nil {
+				fmt.Fprintf(&b, " 次回 <t:%d:f>", next.Unix())
+			}
+		}
+		if schedule.MissedPolicy == MissedPolicySkip {
+			b.WriteString(" ・取りこぼしは実行しない")
+		}
+	}
+	return b.String()
+}
+
+// handleScheduleSlashCommand handles the /schedule slash command and its add, list and remove subcommands
+func (n *Nelchan) handleScheduleSlashCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	data := i.ApplicationCommandData()
+	if len(data.Options) == 0 {
+		return
+	}
+	sub := data.Options[0]
+
+	// Defer response as schedules are validated and stored in the backend
+	err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
+	})
+	if err != nil {
+		fmt.Printf("error deferring interaction response: %v\n", err)
+		return
+	}
+
+	if i.GuildID == "" {
+		editInteractionContent(s, i, "スケジュールはサーバー内でのみ使えます")
+		return
+	}
+
+	var message string
+	switch sub.Name {
+	case "add":
+		message, err = n.addSchedule(s, i, sub.Options)
+	case "list":
+		var schedules []Schedule
+		schedules, err = n.CommandAPIClient.ListSchedules(ListSchedulesRequest{GuildID: i.GuildID})
+		message = FormatScheduleList(schedules)
+	case "remove":
+		message, err = n.removeSchedule(i, sub.Options)
+	}
+	if err != nil {
+		fmt.Printf("error handling /schedule %s: %v\n", sub.Name, err)
+		message = fmt.Sprintf("エラー: %s", err.Error())
+	}
+
+	editInteractionContent(s, i, message)
+	fmt.Printf("slash command /schedule %s executed\n", sub.Name)
+}
+
+// addSchedule validates and stores a schedule from the options of /schedule add
+// Returns the message to show to the user
+func (n *Nelchan) addSchedule(s *discordgo.Session, i *discordgo.InteractionCreate, options []*discordgo.ApplicationCommandInteractionDataOption) (string, error) {
+	request := AddScheduleRequest{
+		GuildID:      i.GuildID,
+		ChannelID:    i.ChannelID,
+		MissedPolicy: MissedPolicyRunOnce,
+		AuthorID:     interactionUser(i).ID,
+	}
+	request.Timezone = n.userTimezone(request.AuthorID)
+	for _, opt := range options {
+		switch opt.Name {
+		case "command_name":
+			request.CommandName = n.CommandRouter.ResolveAlias(opt.StringValue())
+		case "cron":
+			request.Cron = strings.TrimSpace(opt.StringValue())
+		case "channel":
+			if id, ok := opt.Value.(string); ok {
+				request.ChannelID = id
+			}
+		case "args":
+			request.Args = strings.TrimSpace(opt.StringValue())
+		case "missed":
+			request.MissedPolicy = opt.StringValue()
+		case "timezone":
+			request.Timezone = strings.TrimSpace(opt.StringValue())
+		}
+	}
+
+	// Runs post as the bot, so only schedule them where the creator could post themselves
+	permissions, err := s.UserChannelPermissions(request.AuthorID, request.ChannelID)
+	if err != nil {
+		return "", err
+	}
+	const sendPermissions = discordgo.PermissionViewChannel | discordgo.PermissionSendMessages
+	if permissions&sendPermissions != sendPermissions {
+		return fmt.Sprintf("<#%s> にメッセージを送信する権限がないため、スケジュールを登録できません", request.ChannelID), nil
+	}
+
+	cron, err := ParseCron(request.Cron)
+	if err != nil {
+		return fmt.Sprintf("cron 式が正しくありません: %s", err.Error()), nil
+	}
+	loc, err := time.LoadLocation(request.Timezone)
+	if err != nil {
+		return fmt.Sprintf("タイムゾーン「%s」が見つかりません（例: Asia/Tokyo, UTC）", request.Timezone), nil
+	}
+	now := time.Now()
+	next := cron.Next(now.In(loc))
+	if next.IsZero() {
+		return fmt.Sprintf("cron 式「%s」に一致する日時がありません", request.Cron), nil
+	}
+	request.NextRunAt = next.UTC().Format(time.RFC3339)
+
+	info := n.lookupCodeCommand(request.CommandName)
+	if info == nil {
+		return fmt.Sprintf("コードコマンド「%s」は見つかりませんでした", request.CommandName), nil
+	}
+	if _, _, err := n.scheduleArgs(info, request.Args); err != nil {
+		return fmt.Sprintf("引数が正しくありません:\n%s\n\n%s", err.Error(), FormatArgsUsage(info.Name, n.commandArgsSchema(info))), nil
+	}
+
+	existing, err := n.CommandAPIClient.ListSchedules(ListSchedulesRequest{GuildID: i.GuildID})
+	if err != nil {
+		return "", err
+	}
+	if len(existing) >= maxSchedulesPerGuild {
+		return fmt.Sprintf("このサーバーのスケジュールは %d 件までです。`/schedule remove` で削除してから登録してください", maxSchedulesPerGuild), nil
+	}
+
+	schedule, err := n.CommandAPIClient.AddSchedule(request)
+	if err != nil {
+		return "", err
+	}
+	if schedule == nil {
+		return fmt.Sprintf("コードコマンド「%s」は見つかりませんでした", request.CommandName), nil
+	}
+	if err := n.Scheduler.Add(*schedule, now); err != nil {
+		fmt.Printf("error adding schedule %d: %v\n", schedule.ID, err)
+	}
+
+	fmt.Printf("added schedule %d: %s %q in %s by %s\n", schedule.ID, schedule.CommandName, schedule.Cron, schedule.ChannelID, schedule.AuthorID)
+	return fmt.Sprintf("スケジュール `#%d` を登録しました: `%s` を <#%s> で実行します（次回 <t:%d:f>）",
+		schedule.ID, commandInvocation(schedule.CommandName, true), schedule.ChannelID, next.Unix()), nil
+}
+
+// removeSchedule removes a schedule of the guild given to /schedule remove
+// Only the creator of the schedule and admins can remove it
+// Returns the message to show to the user
+func (n *Nelchan) removeSchedule(i *discordgo.InteractionCreate, options []*discordgo.ApplicationCommandInteractionDataOption) (string, error) {
+	var id int64
+	for _, opt := range options {
+		if opt.Name == "id" {
+			id = opt.IntValue()
+		}
+	}
+
+	// Look the schedule up in this guild so schedules of other guilds can't be removed
+	schedules, err := n.CommandAPIClient.ListSchedules(ListSchedulesRequest{GuildID: i.GuildID})
+	if err != nil {
+		return "", err
+	}
+	idx := slices.IndexFunc(schedules, func(schedule Schedule) bool {
+		return schedule.ID == id
+	})
+	if idx < 0 {
+		return fmt.Sprintf("スケジュール `#%d` は見つかりませんでした", id), nil
+	}
+
+	user := interactionUser(i)
+	if schedules[idx].AuthorID != user.ID && !n.isInteractionAdmin(i) {
+		return fmt.Sprintf("スケジュール `#%d` を削除できるのは登録者か管理者だけです", id), nil
+	}
+
+	removed, err := n.CommandAPIClient.RemoveSchedule(RemoveScheduleRequest{ID: id})
+	if err != nil {
+		return "", err
+	}
+	n.Scheduler.Remove(id)
+	if !removed {
+		return fmt.Sprintf("スケジュール `#%d` は見つかりませんでした", id), nil
+	}
+
+	fmt.Printf("removed schedule %d by %s\n", id, user.ID)
+	return fmt.Sprintf("スケジュール `#%d`（`%s` `%s`）を削除しました", id, commandInvocation(schedules[idx].CommandName, true), schedules[idx].Cron), nil
+}
@@ -0,0 +1,105 @@
+package nelchanbot
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSchedulerDue(t *testing.T) {
+	now := time.Date(2026, 10, 18, 9, 0, 10, 0, time.UTC)
+	at := func(tm time.Time) *string {
+		s := tm.Format(time.RFC3339)
+		return &s
+	}
+
+	scheduler := NewScheduler()
+	errs := scheduler.Load([]Schedule{
+		// On time
+		{ID: 1, Cron: "0 9 * * *", Timezone: "UTC", MissedPolicy: MissedPolicySkip, NextRunAt: at(now.Add(-10 * time.Second))},
+		// Missed while offline
+		{ID: 2, Cron: "0 * * * *", Timezone: "UTC", MissedPolicy: MissedPolicySkip, NextRunAt: at(now.Add(-3 * time.Hour))},
+		{ID: 3, Cron: "0 * * * *", Timezone: "UTC", MissedPolicy: MissedPolicyRunOnce, NextRunAt: at(now.Add(-3 * time.Hour))},
+		// Not yet
+		{ID: 4, Cron: "0 10 * * *", Timezone: "UTC", MissedPolicy: MissedPolicyRunOnce},
+		{ID: 5, Cron: "0 9 * *", Timezone: "UTC"},
+		{ID: 6, Cron: "0 9 * * *", Timezone: "Mars/Olympus"},
+	}, now)
+	if len(errs) != 2 {
+		t.Errorf("Load() errors = %v, want 2", errs)
+	}
+
+	runs := scheduler.Due(now)
+	want := []struct {
+		id  int64
+		run bool
+	}{{1, true}, {2, false}, {3, true}}
+	if len(runs) != len(want) {
+		t.Fatalf("Due() = %+v, want %d runs", runs, len(want))
+	}
+	for idx, w := range want {
+		if runs[idx].ID != w.id || runs[idx].Run != w.run {
+			t.Errorf("Due()[%d] = (%d, %v), want (%d, %v)", idx, runs[idx].ID, runs[idx].Run, w.id, w.run)
+		}
+	}
+	if want := time.Date(2026, 10, 19, 9, 0, 0, 0, time.UTC); !runs[0].Next.Equal(want) {
+		t.Errorf("Due()[0].Next = %v, want %v", runs[0].Next, want)
+	}
+	if want := time.Date(2026, 10, 18, 10, 0, 0, 0, time.UTC); !runs[2].Next.Equal(want) {
+		t.Errorf("Due()[2].Next = %v, want %v", runs[2].Next, want)
+	}
+
+	// Advanced schedules don't come due again
+	if runs := scheduler.Due(now.Add(time.Minute)); len(runs) != 0 {
+		t.Errorf("Due() again = %+v, want none", runs)
+	}
+
+	runs = scheduler.Due(time.Date(2026, 10, 18, 10, 0, 5, 0, time.UTC))
+	if len(runs) != 3 {
+		t.Errorf("Due() at 10:00 = %+v, want schedules 2, 3 and 4", runs)
+	}
+}
+
+func TestSchedulerLoadKeepsAdvancedRuns(t *testing.T) {
+	now := time.Date(2026, 10, 18, 9, 0, 10, 0, time.UTC)
+	stored := now.Add(-10 * time.Second).Format(time.RFC3339)
+	schedules := []Schedule{{ID: 1, Cron: "0 9 * * *", Timezone: "UTC", NextRunAt: &stored}}
+
+	scheduler := NewScheduler()
+	scheduler.Load(schedules, now)
+	if runs := scheduler.Due(now); len(runs) != 1 {
+		t.Fatalf("Due() = %+v, want 1 run", runs)
+	}
+
+	// Reloading before the next run time is stored doesn't roll the schedule back
+	scheduler.Load(schedules, now)
+	if runs := scheduler.Due(now); len(runs) != 0 {
+		t.Errorf("Due() after reload = %+v, want none", runs)
+	}
+
+	// A changed cron expression starts from its stored next run time
+	changed := []Schedule{{ID: 1, Cron: "* * * * *", Timezone: "UTC", NextRunAt: &stored}}
+	scheduler.Load(changed, now)
+	if runs := scheduler.Due(now); len(runs) != 1 {
+		t.Errorf("Due() after changing the cron = %+v, want 1 run", runs)
+	}
+}
+
+func TestFormatScheduleList(t *testing.T) {
+	if got := FormatScheduleList(nil); !strings.Contains(got, "スケジュールはありません") {
+		t.Errorf("FormatScheduleList(nil) = %q", got)
+	}
+
+	next := "2026-10-19T00:00:00Z"
+	got := FormatScheduleList([]Schedule{
+		{ID: 1, CommandName: "weather", Args: "tokyo", ChannelID: "10", Cron: "0 9 * * *", Timezone: "Asia/Tokyo", MissedPolicy: MissedPolicyRunOnce, NextRunAt: &next},
+		{ID: 2, CommandName: "dice", Args: strings.Repeat("a", 40), ChannelID: "11", Cron: "@hourly", Timezone: "UTC", MissedPolicy: MissedPolicySkip},
+	})
+
+	want := "スケジュール（2 件）\n" +
+		"`#1` `!weather tokyo` → <#10>　`0 9 * * *`（Asia/Tokyo） 次回 <t:1792368000:f>\n" +
+		"`#2` `!dice " + strings.Repeat("a", 29) + "…` → <#11>　`@hourly`（UTC） ・取りこぼしは実行しない"
+	if got != want {
+		t.Errorf("FormatScheduleList() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,166 @@
+package nelchanbot
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// sparklineBlocks are the bar heights used by Sparkline, from lowest to highest
+var sparklineBlocks = []rune("▁▂▃▄▅▆▇█")
+
+// recordCommandRun records a run of a registered command in the background
+// Nothing is recorded when the command wasn't found (nil result without error)
+func (n *Nelchan) recordCommandRun(run CommandRun, start time.Time, result *CommandResult, err error) {
+	if result == nil && err == nil {
+		return
+	}
+
+	run.LatencyMs = time.Since(start).Milliseconds()
+	run.Success = err == nil
+	if err != nil {
+		run.Error = err.Error()
+	}
+
+	go func() {
+		if err := n.CommandAPIClient.RecordCommandRun(run); err != nil {
+			fmt.Println("error recording command run:", err)
+		}
+	}()
+}
+
+// DailyRuns returns the number of runs on each of the last days days, oldest first and ending today (UTC)
+func DailyRuns(daily []DailyRunCount, now time.Time, days int) []int {
+	byDate := make(map[string]int, len(daily))
+	for _, day := range daily {
+		byDate[day.Date] = day.Runs
+	}
+
+	today := now.UTC()
+	runs := make([]int, days)
+	for idx := range runs {
+		date := today.AddDate(0, 0, idx-days+1).Format(time.DateOnly)
+		runs[idx] = byDate[date]
+	}
+	return runs
+}
+
+// Sparkline renders values as a bar chart of block characters scaled to the largest value
+func Sparkline(values []int) string {
+	peak := 0
+	for _, v := range values {
+		peak = max(peak, v)
+	}
+
+	var b strings.Builder
+	for _, v := range values {
+		level := 0
+		if peak > 0 {
+			level = v * (len(sparklineBlocks) - 1) / peak
+		}
+		b.WriteRune(sparklineBlocks[level])
+	}
+	return b.String()
+}
+
+// formatTrend formats the total runs of a period and its change from the period before
+func formatTrend(current, previous []int) string {
+	total, previousTotal := sum(current), sum(previous)
+	line := fmt.Sprintf("%s %d回", Sparkline(current), total)
+	if previous == nil {
+		return line
+	}
+
+	switch {
+	case previousTotal == 0 && total == 0:
+		return line
+	case previousTotal == 0:
+		return line + "（前期間: 0回）"
+	}
+	change := (total - previousTotal) * 100 / previousTotal
+	return line + fmt.Sprintf("（前期間比 %+d%%）", change)
+}
+
+// sum returns the sum of values
+func sum(values []int) int {
+	total := 0
+	for _, v := range values {
+		total += v
+	}
+	return total
+}
+
+// RenderCommandStats renders the usage statistics as an embed
+func RenderCommandStats(stats *CommandStats, now time.Time) *discordgo.MessageEmbed {
+	embed := &discordgo.MessageEmbed{
+		Title:  "コマンドの利用統計（直近30日間）",
+		Fields: []*discordgo.MessageEmbedField{},
+	}
+
+	// The statistics cover 30 days, so the previous period is only available for the 7 day trend
+	runs := DailyRuns(stats.Daily, now, 30)
+	embed.Fields = append(embed.Fields,
+		&discordgo.MessageEmbedField{Name: "直近7日間", Value: formatTrend(runs[23:], runs[16:23])},
+		&discordgo.MessageEmbedField{Name: "直近30日間", Value: formatTrend(runs, nil)},
+	)
+
+	if len(stats.TopCommands) == 0 {
+		embed.Description = "まだ実行記録がありません"
+		return embed
+	}
+
+	var b strings.Builder
+	for idx, cmd := range stats.TopCommands {
+		fmt.Fprintf(&b, "%d. `%s` %d回（平均 %dms）\n", idx+1, cmd.Name, cmd.Runs, cmd.AvgLatencyMs)
+	}
+	embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{Name: "よく使われるコマンド", Value: b.String()})
+
+	if len(stats.TopAuthors) > 0 {
+		b.Reset()
+		for idx, author := range stats.TopAuthors {
+			fmt.Fprintf(&b, "%d. <@%s> %d回（%dコマンド）\n", idx+1, author.AuthorID, author.Runs, author.Commands)
+		}
+		embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{Name: "よく使われている登録者", Value: b.String()})
+	}
+
+	if len(stats.FailureRates) > 0 {
+		b.Reset()
+		for _, cmd := range stats.FailureRates {
+			fmt.Fprintf(&b, "`%s` %d%%（%d/%d回）\n", cmd.Name, cmd.Failures*100/cmd.Runs, cmd.Failures, cmd.Runs)
+		}
+		embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{Name: "失敗率の高いコマンド", Value: b.String()})
+	}
+
+	return embed
+}
+
+// handleStatsSlashCommand handles the /stats slash command
+func (n *Nelchan) handleStatsSlashCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	stats, err := n.CommandAPIClient.GetCommandStats(CommandStatsRequest{
+		GuildID: i.GuildID,
+	})
+	if err != nil || stats == nil {
+		fmt.Printf("error getting command stats: %v\n", err)
+		_ = s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseChannelMessageWithSource,
+			Data: &discordgo.InteractionResponseData{
+				Content: "統計の取得に失敗しました",
+				Flags:   discordgo.MessageFlagsEphemeral,
+			},
+		})
+		return
+	}
+
+	err = s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Embeds:          []*discordgo.MessageEmbed{RenderCommandStats(stats, time.Now())},
+			AllowedMentions: &discordgo.MessageAllowedMentions{},
+		},
+	})
+	if err != nil {
+		fmt.Printf("error responding to /stats: %v\n", err)
+	}
+}
@@ -0,0 +1,83 @@
+package nelchanbot
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDailyRuns(t *testing.T) {
+	now := time.Date(2026, 10, 18, 15, 0, 0, 0, time.UTC)
+	daily := []DailyRunCount{
+		{Date: "2026-10-14", Runs: 3},
+		{Date: "2026-10-16", Runs: 1},
+		{Date: "2026-10-18", Runs: 5},
+		{Date: "2026-09-01", Runs: 9}, // outside the window
+	}
+
+	got := DailyRuns(daily, now, 5)
+	want := []int{3, 0, 1, 0, 5}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("DailyRuns() = %v, want %v", got, want)
+	}
+}
+
+func TestSparkline(t *testing.T) {
+	tests := []struct {
+		name   string
+		values []int
+		want   string
+	}{
+		{name: "empty", values: nil, want: ""},
+		{name: "all zero", values: []int{0, 0, 0}, want: "▁▁▁"},
+		{name: "scaled to peak", values: []int{0, 7, 14}, want: "▁▄█"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Sparkline(tt.values); got != tt.want {
+				t.Errorf("Sparkline(%v) = %q, want %q", tt.values, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRenderCommandStats(t *testing.T) {
+	now := time.Date(2026, 10, 18, 15, 0, 0, 0, time.UTC)
+	stats := &CommandStats{
+		TopCommands:  []CommandRunCount{{Name: "dice", Runs: 12, Failures: 3, AvgLatencyMs: 250}},
+		TopAuthors:   []AuthorRunCount{{AuthorID: "123", Runs: 12, Commands: 1}},
+		FailureRates: []CommandRunCount{{Name: "dice", Runs: 12, Failures: 3}},
+		Daily: []DailyRunCount{
+			{Date: "2026-10-10", Runs: 4}, // previous 7 days
+			{Date: "2026-10-17", Runs: 2},
+			{Date: "2026-10-18", Runs: 4},
+		},
+	}
+
+	embed := RenderCommandStats(stats, now)
+	fields := map[string]string{}
+	for _, field := range embed.Fields {
+		fields[field.Name] = field.Value
+	}
+
+	tests := map[string]string{
+		"直近7日間":       "6回（前期間比 +50%）",
+		"直近30日間":      "10回",
+		"よく使われるコマンド":  "1. `dice` 12回（平均 250ms）",
+		"よく使われている登録者": "1. <@123> 12回（1コマンド）",
+		"失敗率の高いコマンド":  "`dice` 25%（3/12回）",
+	}
+	for name, want := range tests {
+		if !strings.Contains(fields[name], want) {
+			t.Errorf("field %q = %q, want it to contain %q", name, fields[name], want)
+		}
+	}
+}
+
+func TestRenderCommandStatsEmpty(t *testing.T) {
+	embed := RenderCommandStats(&CommandStats{}, time.Now())
+	if embed.Description != "まだ実行記録がありません" {
+		t.Errorf("description = %q", embed.Description)
+	}
+}
@@ -0,0 +1,199 @@
+package nelchanbot
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// maxTemplateDepth limits how deeply random choices may nest, as in "{a|{b|c}}"
+const maxTemplateDepth = 5
+
+// templateArgRe matches positional arg placeholders such as "arg1"
+var templateArgRe = regexp.MustCompile(`^arg([1-9]\d*)$`)
+
+// japaneseWeekdays are the names of the weekdays for {weekday}
+var japaneseWeekdays = [...]string{"日曜日", "月曜日", "火曜日", "水曜日", "木曜日", "金曜日", "土曜日"}
+
+// mentionEscaper breaks up mentions so that text from users can't ping anyone
+// A zero width space after @ keeps "@everyone", "<@id>" and "<@&id>" readable but inert
+var mentionEscaper = strings.NewReplacer("@", "@\u200b")
+
+// TemplateContext holds the values placeholders of a text command are replaced with
+type TemplateContext struct {
+	Username    string
+	UserID      string
+	ChannelID   string
+	ChannelName string
+	Args        []string
+	// Now returns the current time in the user's timezone, only called for date placeholders
+	Now func() time.Time
+	// IntN picks random choices, rand.IntN if nil
+	IntN func(n int) int
+}
+
+// RenderTemplate replaces the placeholders in the content of a text command
+//
+//	{username} {user_id} {user}          name, ID and mention of the user
+//	{channel} {channel_id} {channel_name} mention, ID and name of the channel
+//	{arg1} {arg2} ... {args}              positional args, or all of them separated by spaces
+//	{date} {time} {weekday} {timestamp}   the current date and time
+//	{a|b|c}                               one of the choices at random, which may contain placeholders
+//
+// Unknown placeholders, such as braces in JSON, are left as they are. {{ and }} write literal braces.
+// Mentions in the username and args are escaped, only the template itself can ping.
+func RenderTemplate(content string, ctx TemplateContext) string {
+	if ctx.IntN == nil {
+		ctx.IntN = rand.IntN
+	}
+	if ctx.Now == nil {
+		ctx.Now = time.Now
+	}
+	return ctx.render(content, 0)
+}
+
+// render replaces the placeholders in s, depth is how deeply s is nested in random choices
+func (ctx *TemplateContext) render(s string, depth int) string {
+	var b strings.Builder
+	for i := 0; i < len(s); {
+		switch {
+		case strings.HasPrefix(s[i:], "{{"):
+			b.WriteByte('{')
+			i += 2
+			continue
+		case strings.HasPrefix(s[i:], "}}"):
+			b.WriteByte('}')
+			i += 2
+			continue
+		case s[i] == '{':
+			end := matchingBrace(s, i)
+			if end < 0 {
+				break
+			}
+			if value, ok := ctx.expand(s[i+1:end], depth); ok {
+				b.WriteString(value)
+			} else {
+				b.WriteString(s[i : end+1])
+			}
+			i = end + 1
+			continue
+		}
+		b.WriteByte(s[i])
+		i++
+	}
+	return b.String()
+}
+
+// matchingBrace returns the index of the brace closing the one at start, or -1
+// Placeholders don't span lines, so an unclosed brace at the end of a line is plain text
+func matchingBrace(s string, start int) int {
+	depth := 0
+	for i := start; i < len(s); i++ {
+		switch s[i] {
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		case '\n':
+			return -1
+		}
+	}
+	return -1
+}
+
+// splitChoices splits the inside of a placeholder on the | that aren't nested in braces
+func splitChoices(s string) []string {
+	var choices []string
+	depth, last := 0, 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '{':
+			depth++
+		case '}':
+			depth--
+		case '|':
+			if depth == 0 {
+				choices = append(choices, s[last:i])
+				last = i + 1
+			}
+		}
+	}
+	return append(choices, s[last:])
+}
+
+// expand returns the value of the placeholder {inner}, false if it isn't a placeholder
+func (ctx *TemplateContext) expand(inner string, depth int) (string, bool) {
+	if choices := splitChoices(inner); len(choices) > 1 {
+		if depth >= maxTemplateDepth {
+			return "", false
+		}
+		return ctx.render(choices[ctx.IntN(len(choices))], depth+1), true
+	}
+
+	switch name := strings.TrimSpace(inner); name {
+	case "username":
+		return mentionEscaper.Replace(ctx.Username), true
+	case "user_id":
+		return ctx.UserID, true
+	case "user":
+		return fmt.Sprintf("<@%s>", ctx.UserID), true
+	case "channel":
+		return fmt.Sprintf("<#%s>", ctx.ChannelID), true
+	case "channel_id":
+		return ctx.ChannelID, true
+	case "channel_name":
+		return ctx.ChannelName, true
+	case "args":
+		return mentionEscaper.Replace(strings.Join(ctx.Args, " ")), true
+	case "date":
+		return ctx.Now().Format("2006/01/02"), true
+	case "time":
+		return ctx.Now().Format("15:04"), true
+	case "weekday":
+		return japaneseWeekdays[ctx.Now().Weekday()], true
+	case "timestamp":
+		return fmt.Sprintf("<t:%d:f>", ctx.Now().Unix()), true
+	default:
+		m := templateArgRe.FindStringSubmatch(name)
+		if m == nil {
+			return "", false
+		}
+		idx, err := strconv.Atoi(m[1])
+		if err != nil || idx > len(ctx.Args) {
+			return "", true
+		}
+		return mentionEscaper.Replace(ctx.Args[idx-1]), true
+	}
+}
+
+// renderTextCommand renders the content of a text command run by a message
+func (n *Nelchan) renderTextCommand(s *discordgo.Session, m *discordgo.MessageCreate, content string, args []string) string {
+	if !strings.Contains(content, "{") {
+		return content
+	}
+
+	ctx := TemplateContext{
+		Username:  m.Author.GlobalName,
+		UserID:    m.Author.ID,
+		ChannelID: m.ChannelID,
+		Args:      args,
+		Now: func() time.Time {
+			return time.Now().In(n.userLocation(m.Author.ID))
+		},
+	}
+	if ctx.Username == "" {
+		ctx.Username = m.Author.Username
+	}
+	if channel, err := s.State.Channel(m.ChannelID); err == nil {
+		ctx.ChannelName = channel.Name
+	}
+	return RenderTemplate(content, ctx)
+}
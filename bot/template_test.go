@@ -0,0 +1,78 @@
+package nelchanbot
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRenderTemplate(t *testing.T) {
+	now := time.Date(2026, 10, 18, 14, 5, 0, 0, time.UTC)
+	ctx := TemplateContext{
+		Username:    "ねる",
+		UserID:      "100",
+		ChannelID:   "10",
+		ChannelName: "雑談",
+		Args:        []string{"東京", "晴れ"},
+		Now:         func() time.Time { return now },
+		// Always pick the last choice
+		IntN: func(n int) int { return n - 1 },
+	}
+
+	tests := []struct {
+		content string
+		want    string
+	}{
+		{"こんにちは {username} さん！", "こんにちは ねる さん！"},
+		{"{user} ({user_id})", "<@100> (100)"},
+		{"{channel} {channel_id} #{channel_name}", "<#10> 10 #雑談"},
+		{"{arg1} は {arg2}、{arg3}", "東京 は 晴れ、"},
+		{"[{args}]", "[東京 晴れ]"},
+		{"{ username }", "ねる"},
+		{"{大吉|中吉|凶}", "凶"},
+		{"{a|{username}}", "ねる"},
+		{"{a|{b|{user_id}}}", "100"},
+		{"{date} {time} {weekday}", "2026/10/18 14:05 日曜日"},
+		{"{timestamp}", "<t:1792332300:f>"},
+		// Text that isn't a placeholder is kept
+		{`{"key": "value"}`, `{"key": "value"}`},
+		{"{unknown} {arg0}", "{unknown} {arg0}"},
+		{"{{username}}", "{username}"},
+		{"{ 閉じていない\n}", "{ 閉じていない\n}"},
+		{"閉じていない {", "閉じていない {"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.content, func(t *testing.T) {
+			if got := RenderTemplate(tt.content, ctx); got != tt.want {
+				t.Errorf("RenderTemplate() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRenderTemplateChoices(t *testing.T) {
+	seen := make(map[string]bool)
+	for pick := range 3 {
+		got := RenderTemplate("{グー|チョキ|パー}", TemplateContext{IntN: func(n int) int { return pick % n }})
+		seen[got] = true
+	}
+	for _, want := range []string{"グー", "チョキ", "パー"} {
+		if !seen[want] {
+			t.Errorf("RenderTemplate() never picked %q, got %v", want, seen)
+		}
+	}
+}
+
+func TestRenderTemplateEscapesMentions(t *testing.T) {
+	ctx := TemplateContext{
+		Username: "@here",
+		UserID:   "100",
+		Args:     []string{"@everyone", "<@&5>"},
+	}
+
+	got := RenderTemplate("{user} {username} {arg1} {args} <@200>", ctx)
+	want := "<@100> @\u200bhere @\u200beveryone @\u200beveryone <@\u200b&5> <@200>"
+	if got != want {
+		t.Errorf("RenderTemplate() = %q, want %q", got, want)
+	}
+}
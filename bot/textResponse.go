@@ -0,0 +1,324 @@
+package nelchanbot
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+const (
+	maxTextResponses         = 50   // responses a text command may hold
+	maxResponseWeight        = 1000 // largest weight of a response
+	maxResponsePreviewLength = 80   // characters of a response shown by !responses
+)
+
+// minResponseWeight is the smallest weight of a response, a variable as slash command options take its address
+var minResponseWeight float64 = 1
+
+// responseWeightFlagPrefix sets the weight of a response added by !register_more
+const responseWeightFlagPrefix = "--weight="
+
+// codeCommandResponseFormat is the reply when a response command is used on a code command
+const codeCommandResponseFormat = "コマンド「%s」はコードコマンドなので応答は 1 つだけです"
+
+// responseModeNames are the names of the response modes shown to users
+var responseModeNames = map[string]string{
+	ResponseModeRandom:     "ランダム",
+	ResponseModeRoundRobin: "順番",
+	ResponseModeWeighted:   "重み付き",
+}
+
+// textResponses returns the responses of a text command, its content if the backend sent none
+func textResponses(info *GetCommandInfo) []TextResponse {
+	if len(info.Responses) > 0 {
+		return info.Responses
+	}
+	return []TextResponse{{Text: info.Content, Weight: 1}}
+}
+
+// PickTextResponse returns the response of a text command to send for a run
+// intN picks random responses, as rand.IntN
+func PickTextResponse(result *CommandResult, intN func(n int) int) string {
+	responses := result.Responses
+	if len(responses) < 2 {
+		return result.Content
+	}
+
+	switch result.ResponseMode {
+	case ResponseModeRoundRobin:
+		// The backend advances the cursor on every run, so it may be past the end
+		return responses[max(result.ResponseCursor, 0)%len(responses)].Text
+	case ResponseModeWeighted:
+		total := 0
+		for _, response := range responses {
+			total += max(response.Weight, 1)
+		}
+		pick := intN(total)
+		for _, response := range responses {
+			pick -= max(response.Weight, 1)
+			if pick < 0 {
+				return response.Text
+			}
+		}
+		return responses[len(responses)-1].Text
+	default:
+		return responses[intN(len(responses))].Text
+	}
+}
+
+// CutResponseWeight splits a leading --weight=N off the text of !register_more
+// The weight is 0 when the text doesn't start with one
+func CutResponseWeight(text string) (int, string, error) {
+	first, rest := CutFirstField(text)
+	value, ok := strings.CutPrefix(first, responseWeightFlagPrefix)
+	if !ok {
+		return 0, text, nil
+	}
+	weight, err := strconv.Atoi(value)
+	if err != nil || weight < 1 || weight > maxResponseWeight {
+		return 0, "", fmt.Errorf("重みは 1〜%d の整数で指定してください: %q", maxResponseWeight, value)
+	}
+	return weight, rest, nil
+}
+
+// FormatTextResponses formats the responses of a text command for !responses
+// The chance of each response is shown for the weighted mode
+func FormatTextResponses(info *GetCommandInfo) string {
+	responses := textResponses(info)
+	mode := info.ResponseMode
+	if responseModeNames[mode] == "" {
+		mode = ResponseModeRandom
+	}
+
+	total := 0
+	for _, response := range responses {
+		total += max(response.Weight, 1)
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "コマンド「%s」の応答（%d 件、選び方: %s）", info.Name, len(responses), responseModeNames[mode])
+	for idx, response := range responses {
+		preview := strings.Join(strings.Fields(response.Text), " ")
+		if utf8.RuneCountInString(preview) > maxResponsePreviewLength {
+			preview = string([]rune(preview)[:maxResponsePreviewLength-1]) + "…"
+		}
+		fmt.Fprintf(&b, "\n`%d.` %s", idx+1, preview)
+		if mode == ResponseModeWeighted {
+			weight := max(response.Weight, 1)
+			fmt.Fprintf(&b, "（重み %d、%.0f%%）", weight, float64(weight)*100/float64(total))
+		}
+	}
+	return b.String()
+}
+
+// handleRegisterMoreCommand handles the !register_more command
+// Usage: !register_more <command_name> [--weight=N] <text> - Add a response to a text command, registering it if missing
+func (n *Nelchan) handleRegisterMoreCommand(s *discordgo.Session, m *discordgo.MessageCreate, _ *SlashCommand) {
+	const usage = "使い方: !register_more <コマンド名> [--weight=重み] <テキスト>"
+
+	// Re-parse with body support for text commands
+	cmd := n.CommandParser.ParseSlashCommandWithBody(m.Content, 2)
+	if cmd == nil || len(cmd.Args) < 2 {
+		_, _ = s.ChannelMessageSend(m.ChannelID, usage)
+		return
+	}
+
+	commandName := cmd.GetArg(0)
+	weight, text, err := CutResponseWeight(cmd.GetArg(1))
+	if err != nil {
+		_, _ = s.ChannelMessageSend(m.ChannelID, err.Error())
+		return
+	}
+	if commandName == "" || text == "" {
+		_, _ = s.ChannelMessageSend(m.ChannelID, usage)
+		return
+	}
+
+	message, err := n.appendTextResponse(commandName, text, weight, m.Author.ID, n.isAdmin(s, m.Author.ID, m.ChannelID))
+	if err != nil {
+		fmt.Println("error adding response,", err)
+		message = fmt.Sprintf("エラー: %s", err.Error())
+	}
+	_, _ = s.ChannelMessageSend(m.ChannelID, message)
+}
+
+// appendTextResponse adds a response to a text command, or registers the command if it doesn't exist
+// Returns the message to show to the user
+func (n *Nelchan) appendTextResponse(commandName, text string, weight int, userID string, admin bool) (string, error) {
+	info, err := n.CommandAPIClient.GetCommand(GetCommandRequest{
+		CommandName: commandName,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	if info != nil {
+		switch {
+		case info.IsCode:
+			return fmt.Sprintf("コマンド「%s」はコードコマンドなので応答を追加できません", commandName), nil
+		case !canManageCommand(info, userID, admin):
+			return fmt.Sprintf("コマンド「%s」を変更できるのは登録者か管理者だけです", commandName), nil
+		case len(textResponses(info)) >= maxTextResponses:
+			return fmt.Sprintf("コマンド「%s」の応答は %d 件までです", commandName, maxTextResponses), nil
+		}
+	}
+
+	err = n.CommandAPIClient.RegisterCommand(RegisterCommandRequest{
+		CommandName:    commandName,
+		CommandContent: text,
+		IsCode:         false,
+		AuthorID:       userID,
+		Append:         info != nil,
+		Weight:         weight,
+		Admin:          admin,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	if info == nil {
+		// A registered command takes over its name from an alias
+		n.CommandRouter.RemoveAlias(commandName)
+		return fmt.Sprintf("コマンド「%s」を登録しました！", commandName), nil
+	}
+	return fmt.Sprintf("コマンド「%s」に応答を追加しました！（%d 件目）", commandName, len(textResponses(info))+1), nil
+}
+
+// handleResponsesCommand handles the !responses command
+// Usage: !responses <command_name> - List the responses of a text command
+func (n *Nelchan) handleResponsesCommand(s *discordgo.Session, m *discordgo.MessageCreate, cmd *SlashCommand) {
+	commandName := cmd.GetArg(0)
+	if commandName == "" {
+		_, _ = s.ChannelMessageSend(m.ChannelID, "使い方: !responses <コマンド名>")
+		return
+	}
+
+	info, err := n.CommandAPIClient.GetCommand(GetCommandRequest{
+		CommandName: commandName,
+	})
+	if err != nil {
+		fmt.Println("error getting command,", err)
+		_, _ = s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("エラー: %s", err.Error()))
+		return
+	}
+	if info == nil {
+		_, _ = s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("コマンド「%s」は見つかりませんでした", commandName))
+		return
+	}
+	if info.IsCode {
+		_, _ = s.ChannelMessageSend(m.ChannelID, fmt.Sprintf(codeCommandResponseFormat, commandName))
+		return
+	}
+
+	if err := n.sendMessage(s, m.ChannelID, FormatTextResponses(info)); err != nil {
+		fmt.Println("error sending message,", err)
+	}
+}
+
+// handleRemoveResponseCommand handles the !remove_response command
+// Usage: !remove_response <command_name> <number> - Remove a response of a text command, numbered as in !responses
+func (n *Nelchan) handleRemoveResponseCommand(s *discordgo.Session, m *discordgo.MessageCreate, cmd *SlashCommand) {
+	const usage = "使い方: !remove_response <コマンド名> <番号>"
+
+	commandName := cmd.GetArg(0)
+	index, err := strconv.Atoi(cmd.GetArg(1))
+	if commandName == "" || err != nil {
+		_, _ = s.ChannelMessageSend(m.ChannelID, usage)
+		return
+	}
+
+	info, ok := n.getManageableCommand(s, m, commandName)
+	if !ok {
+		return
+	}
+	if info.IsCode {
+		_, _ = s.ChannelMessageSend(m.ChannelID, fmt.Sprintf(codeCommandResponseFormat, commandName))
+		return
+	}
+
+	responses := textResponses(info)
+	switch {
+	case index < 1 || index > len(responses):
+		_, _ = s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("応答の番号は 1〜%d で指定してください", len(responses)))
+		return
+	case len(responses) == 1:
+		_, _ = s.ChannelMessageSend(m.ChannelID, "最後の応答は削除できません。コマンドごと削除するには !unregister を使ってください")
+		return
+	}
+
+	removed, err := n.CommandAPIClient.RemoveTextResponse(RemoveTextResponseRequest{
+		CommandName: commandName,
+		Index:       index,
+	})
+	if err != nil {
+		fmt.Println("error removing response,", err)
+		_, _ = s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("エラー: %s", err.Error()))
+		return
+	}
+	if !removed {
+		_, _ = s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("コマンド「%s」の応答 %d は見つかりませんでした", commandName, index))
+		return
+	}
+
+	_, _ = s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("コマンド「%s」の応答 %d を削除しました", commandName, index))
+}
+
+// handleResponseModeCommand handles the !response_mode command
+// Usage: !response_mode <command_name> - Show how a text command picks a response
+// Usage: !response_mode <command_name> <random|round_robin|weighted> - Change it
+func (n *Nelchan) handleResponseModeCommand(s *discordgo.Session, m *discordgo.MessageCreate, cmd *SlashCommand) {
+	const usage = "使い方: !response_mode <コマンド名> [random|round_robin|weighted]"
+
+	commandName, mode := cmd.GetArg(0), cmd.GetArg(1)
+	if commandName == "" {
+		_, _ = s.ChannelMessageSend(m.ChannelID, usage)
+		return
+	}
+	if mode != "" && responseModeNames[mode] == "" {
+		_, _ = s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("不明な選び方です: %s\n%s", mode, usage))
+		return
+	}
+
+	info, ok := n.getManageableCommand(s, m, commandName)
+	if !ok {
+		return
+	}
+	if info.IsCode {
+		_, _ = s.ChannelMessageSend(m.ChannelID, fmt.Sprintf(codeCommandResponseFormat, commandName))
+		return
+	}
+
+	if mode == "" {
+		current := info.ResponseMode
+		if responseModeNames[current] == "" {
+			current = ResponseModeRandom
+		}
+		_, _ = s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("コマンド「%s」の応答の選び方: %s (`%s`)", commandName, responseModeNames[current], current))
+		return
+	}
+
+	updated, err := n.CommandAPIClient.SetTextResponseMode(SetTextResponseModeRequest{
+		CommandName: commandName,
+		Mode:        mode,
+	})
+	if err != nil {
+		fmt.Println("error setting response mode,", err)
+		_, _ = s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("エラー: %s", err.Error()))
+		return
+	}
+	if !updated {
+		_, _ = s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("コマンド「%s」は見つかりませんでした", commandName))
+		return
+	}
+
+	_, _ = s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("コマンド「%s」の応答の選び方を「%s」にしました", commandName, responseModeNames[mode]))
+}
+
+// pickTextResponse picks the response to send for a run of a text command
+func pickTextResponse(result *CommandResult) string {
+	return PickTextResponse(result, rand.IntN)
+}
@@ -0,0 +1,103 @@
+package nelchanbot
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPickTextResponse(t *testing.T) {
+	responses := []TextResponse{{Text: "大吉", Weight: 1}, {Text: "中吉", Weight: 2}, {Text: "凶", Weight: 7}}
+	last := func(n int) int { return n - 1 }
+
+	tests := []struct {
+		name   string
+		result CommandResult
+		intN   func(int) int
+		want   string
+	}{
+		{"single response", CommandResult{Content: "こんにちは"}, last, "こんにちは"},
+		{"random", CommandResult{Responses: responses, ResponseMode: ResponseModeRandom}, last, "凶"},
+		{"empty mode is random", CommandResult{Responses: responses}, func(int) int { return 1 }, "中吉"},
+		{"round robin", CommandResult{Responses: responses, ResponseMode: ResponseModeRoundRobin, ResponseCursor: 1}, last, "中吉"},
+		{"round robin wraps", CommandResult{Responses: responses, ResponseMode: ResponseModeRoundRobin, ResponseCursor: 4}, last, "中吉"},
+		{"weighted first", CommandResult{Responses: responses, ResponseMode: ResponseModeWeighted}, func(int) int { return 0 }, "大吉"},
+		{"weighted second", CommandResult{Responses: responses, ResponseMode: ResponseModeWeighted}, func(int) int { return 2 }, "中吉"},
+		{"weighted last", CommandResult{Responses: responses, ResponseMode: ResponseModeWeighted}, func(int) int { return 3 }, "凶"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := PickTextResponse(&tt.result, tt.intN); got != tt.want {
+				t.Errorf("PickTextResponse() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPickTextResponseWeights(t *testing.T) {
+	result := CommandResult{
+		Responses:    []TextResponse{{Text: "a", Weight: 1}, {Text: "b", Weight: 3}, {Text: "c", Weight: 0}},
+		ResponseMode: ResponseModeWeighted,
+	}
+
+	// Every pick below the total weight maps to one response, a weight of 0 counts as 1
+	counts := make(map[string]int)
+	for pick := range 5 {
+		counts[PickTextResponse(&result, func(n int) int {
+			if n != 5 {
+				t.Fatalf("intN(%d), want the total weight 5", n)
+			}
+			return pick
+		})]++
+	}
+	if counts["a"] != 1 || counts["b"] != 3 || counts["c"] != 1 {
+		t.Errorf("PickTextResponse() counts = %v, want a:1 b:3 c:1", counts)
+	}
+}
+
+func TestCutResponseWeight(t *testing.T) {
+	tests := []struct {
+		text       string
+		wantWeight int
+		wantText   string
+		wantErr    bool
+	}{
+		{"大吉です", 0, "大吉です", false},
+		{"--weight=3 大吉です", 3, "大吉です", false},
+		{"--weight=2 複数行\nの応答", 2, "複数行\nの応答", false},
+		{"--weight=0 大吉", 0, "", true},
+		{"--weight=abc 大吉", 0, "", true},
+		{"--weight=1001 大吉", 0, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			weight, text, err := CutResponseWeight(tt.text)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("CutResponseWeight() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if weight != tt.wantWeight || text != tt.wantText {
+				t.Errorf("CutResponseWeight() = %d, %q, want %d, %q", weight, text, tt.wantWeight, tt.wantText)
+			}
+		})
+	}
+}
+
+func TestFormatTextResponses(t *testing.T) {
+	got := FormatTextResponses(&GetCommandInfo{Name: "hello", Content: "こんにちは"})
+	if want := "コマンド「hello」の応答（1 件、選び方: ランダム）\n`1.` こんにちは"; got != want {
+		t.Errorf("FormatTextResponses() = %q, want %q", got, want)
+	}
+
+	got = FormatTextResponses(&GetCommandInfo{
+		Name:         "omikuji",
+		Responses:    []TextResponse{{Text: "大吉\nおめでとう", Weight: 1}, {Text: strings.Repeat("凶", 100), Weight: 3}},
+		ResponseMode: ResponseModeWeighted,
+	})
+	want := "コマンド「omikuji」の応答（2 件、選び方: 重み付き）\n" +
+		"`1.` 大吉 おめでとう（重み 1、25%）\n" +
+		"`2.` " + strings.Repeat("凶", maxResponsePreviewLength-1) + "…（重み 3、75%）"
+	if got != want {
+		t.Errorf("FormatTextResponses() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,541 @@
+package nelchanbot
+
+import (
+	"cmp"
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// maxTriggersPerGuild limits the event triggers of a guild
+const maxTriggersPerGuild = 25
+
+// customEmojiRe matches a custom emoji as typed in a message, such as <:nel:123> or <a:nel:123>
+var customEmojiRe = regexp.MustCompile(`^<a?:\w+:(\d+)>$`)
+
+// messageLinkRe matches a link to a message and captures its ID
+var messageLinkRe = regexp.MustCompile(`^https://(?:\w+\.)?discord(?:app)?\.com/channels/(?:\d+|@me)/\d+/(\d+)$`)
+
+// triggerEventNames are the names of the trigger events shown to users
+var triggerEventNames = map[string]string{
+	TriggerEventReactionAdd:    "リアクション追加",
+	TriggerEventReactionRemove: "リアクション削除",
+	TriggerEventMemberJoin:     "メンバー参加",
+	TriggerEventMemberLeave:    "メンバー退出",
+	TriggerEventThreadCreate:   "スレッド作成",
+	TriggerEventVoiceState:     "ボイスチャンネルの状態変化",
+}
+
+// isReactionEvent reports whether event is a reaction event, which can be filtered by message and emoji
+func isReactionEvent(event string) bool {
+	return event == TriggerEventReactionAdd || event == TriggerEventReactionRemove
+}
+
+// isMemberEvent reports whether event needs the privileged guild members intent
+func isMemberEvent(event string) bool {
+	return event == TriggerEventMemberJoin || event == TriggerEventMemberLeave
+}
+
+// NormalizeTriggerEmoji turns an emoji as typed by a user into the form reactions are matched with:
+// the ID of a custom emoji, or the unicode emoji itself
+func NormalizeTriggerEmoji(s string) string {
+	s = strings.TrimSpace(s)
+	if m := customEmojiRe.FindStringSubmatch(s); m != nil {
+		return m[1]
+	}
+	return s
+}
+
+// NormalizeTriggerMessageID takes the message ID from a message link, such as copied with "メッセージリンクをコピー"
+func NormalizeTriggerMessageID(s string) string {
+	s = strings.TrimSpace(s)
+	if m := messageLinkRe.FindStringSubmatch(s); m != nil {
+		return m[1]
+	}
+	return s
+}
+
+// TriggerRegistry keeps the event triggers of every guild in memory
+type TriggerRegistry struct {
+	mu      sync.RWMutex
+	byGuild map[string][]EventTrigger
+}
+
+// NewTriggerRegistry creates a registry without triggers
+func NewTriggerRegistry() *TriggerRegistry {
+	return &TriggerRegistry{
+		byGuild: make(map[string][]EventTrigger),
+	}
+}
+
+// Set replaces every trigger
+func (r *TriggerRegistry) Set(triggers []EventTrigger) {
+	byGuild := make(map[string][]EventTrigger)
+	for _, trigger := range triggers {
+		byGuild[trigger.GuildID] = append(byGuild[trigger.GuildID], trigger)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.byGuild = byGuild
+}
+
+// Match returns the triggers of a guild for an event
+// For reaction events messageID and emoji are matched against the filters of the triggers
+func (r *TriggerRegistry) Match(guildID, event, messageID string, emoji *discordgo.Emoji) []EventTrigger {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var matched []EventTrigger
+	for _, trigger := range r.byGuild[guildID] {
+		if trigger.Event != event {
+			continue
+		}
+		if trigger.MessageID != "" && trigger.MessageID != messageID {
+			continue
+		}
+		if trigger.Emoji != "" && (emoji == nil || (trigger.Emoji != emoji.ID && trigger.Emoji != emoji.Name)) {
+			continue
+		}
+		matched = append(matched, trigger)
+	}
+	return matched
+}
+
+// loadTriggers loads every event trigger from the backend into the registry
+func (n *Nelchan) loadTriggers() {
+	triggers, err := n.CommandAPIClient.ListEventTriggers(ListEventTriggersRequest{})
+	if err != nil {
+		fmt.Println("error loading event triggers:", err)
+		return
+	}
+
+	n.Triggers.Set(triggers)
+	fmt.Printf("loaded %d event triggers\n", len(triggers))
+}
+
+// userTriggerVars returns the vars describing the user who caused an event
+func userTriggerVars(event, guildID string, user *discordgo.User) map[string]string {
+	vars := map[string]string{
+		"event":    event,
+		"guild_id": guildID,
+	}
+	if user != nil {
+		vars["username"] = cmp.Or(user.GlobalName, user.Username)
+		vars["user_id"] = user.ID
+		vars["user_avatar"] = user.Avatar
+	}
+	return vars
+}
+
+// reactionTriggerVars returns the vars of a reaction event
+func reactionTriggerVars(event string, r *discordgo.MessageReaction, user *discordgo.User) map[string]string {
+	vars := userTriggerVars(event, r.GuildID, user)
+	vars["user_id"] = r.UserID
+	vars["channel_id"] = r.ChannelID
+	vars["message_id"] = r.MessageID
+	vars["emoji"] = r.Emoji.MessageFormat()
+	vars["emoji_name"] = r.Emoji.Name
+	vars["emoji_id"] = r.Emoji.ID
+	return vars
+}
+
+// threadTriggerVars returns the vars of a thread create event
+func threadTriggerVars(thread *discordgo.Channel, owner *discordgo.User) map[string]string {
+	vars := userTriggerVars(TriggerEventThreadCreate, thread.GuildID, owner)
+	vars["user_id"] = thread.OwnerID
+	vars["channel_id"] = thread.ID
+	vars["thread_id"] = thread.ID
+	vars["thread_name"] = thread.Name
+	vars["parent_id"] = thread.ParentID
+	return vars
+}
+
+// VoiceAction describes a voice state change: "join", "leave", "move" between channels,
+// or "update" for changes such as muting within a channel
+func VoiceAction(beforeChannelID, afterChannelID string) string {
+	switch {
+	case beforeChannelID == "" && afterChannelID != "":
+		return "join"
+	case beforeChannelID != "" && afterChannelID == "":
+		return "leave"
+	case beforeChannelID != afterChannelID:
+		return "move"
+	default:
+		return "update"
+	}
+}
+
+// voiceTriggerVars returns the vars of a voice state change
+// before is nil when the previous state isn't known, e.g. for users who were in a channel before the bot started
+func voiceTriggerVars(v *discordgo.VoiceState, before *discordgo.VoiceState, user *discordgo.User) map[string]string {
+	beforeChannelID := ""
+	if before != nil {
+		beforeChannelID = before.ChannelID
+	}
+
+	vars := userTriggerVars(TriggerEventVoiceState, v.GuildID, user)
+	vars["user_id"] = v.UserID
+	vars["channel_id"] = cmp.Or(v.ChannelID, beforeChannelID)
+	vars["voice_channel_id"] = v.ChannelID
+	vars["voice_before_channel_id"] = beforeChannelID
+	vars["voice_action"] = VoiceAction(beforeChannelID, v.ChannelID)
+	vars["voice_self_mute"] = fmt.Sprint(v.SelfMute)
+	vars["voice_self_deaf"] = fmt.Sprint(v.SelfDeaf)
+	return vars
+}
+
+// eventUser returns the user with the ID from the state cache, or fetches it
+func (n *Nelchan) eventUser(s *discordgo.Session, guildID, userID string) *discordgo.User {
+	if member, err := s.State.Member(guildID, userID); err == nil && member.User != nil {
+		return member.User
+	}
+	user, err := s.User(userID)
+	if err != nil {
+		fmt.Printf("error getting user %s: %v\n", userID, err)
+		return nil
+	}
+	return user
+}
+
+// handleReactionAdd runs the triggers of reactions added to messages
+func (n *Nelchan) handleReactionAdd(s *discordgo.Session, r *discordgo.MessageReactionAdd) {
+	var user *discordgo.User
+	if r.Member != nil {
+		user = r.Member.User
+	}
+	n.handleReaction(s, TriggerEventReactionAdd, r.MessageReaction, user)
+}
+
+// handleReactionRemove runs the triggers of reactions removed from messages
+func (n *Nelchan) handleReactionRemove(s *discordgo.Session, r *discordgo.MessageReactionRemove) {
+	n.handleReaction(s, TriggerEventReactionRemove, r.MessageReaction, nil)
+}
+
+// handleReaction runs the triggers of a reaction event
+func (n *Nelchan) handleReaction(s *discordgo.Session, event string, r *discordgo.MessageReaction, user *discordgo.User) {
+	triggers := n.Triggers.Match(r.GuildID, event, r.MessageID, &r.Emoji)
+	if len(triggers) == 0 {
+		return
+	}
+
+	if user == nil {
+		user = n.eventUser(s, r.GuildID, r.UserID)
+	}
+	if user != nil && user.Bot {
+		return
+	}
+	ctx := eventContext(s, RunSourceEvent, r.GuildID, r.ChannelID, user)
+	n.runTriggers(s, triggers, ctx, reactionTriggerVars(event, r, user), r.ChannelID)
+}
+
+// handleMemberJoin runs the triggers of members joining a guild
+// Output goes to the system channel of the guild unless the trigger has a channel
+func (n *Nelchan) handleMemberJoin(s *discordgo.Session, m *discordgo.GuildMemberAdd) {
+	n.handleMember(s, TriggerEventMemberJoin, m.Member)
+}
+
+// handleMemberLeave runs the triggers of members leaving a guild
+func (n *Nelchan) handleMemberLeave(s *discordgo.Session, m *discordgo.GuildMemberRemove) {
+	n.handleMember(s, TriggerEventMemberLeave, m.Member)
+}
+
+// handleMember runs the triggers of a member event
+func (n *Nelchan) handleMember(s *discordgo.Session, event string, member *discordgo.Member) {
+	if member.User == nil || member.User.Bot {
+		return
+	}
+	triggers := n.Triggers.Match(member.GuildID, event, "", nil)
+	if len(triggers) == 0 {
+		return
+	}
+
+	channelID := ""
+	if guild, err := s.State.Guild(member.GuildID); err == nil {
+		channelID = guild.SystemChannelID
+	}
+	ctx := eventContext(s, RunSourceEvent, member.GuildID, channelID, member.User)
+	vars := userTriggerVars(event, member.GuildID, member.User)
+	vars["channel_id"] = channelID
+	n.runTriggers(s, triggers, ctx, vars, channelID)
+}
+
+// handleThreadCreate runs the triggers of threads being created
+// Output goes to the new thread unless the trigger has a channel
+func (n *Nelchan) handleThreadCreate(s *discordgo.Session, t *discordgo.ThreadCreate) {
+	// Thread create is also sent when the bot is added to an existing thread
+	if !t.NewlyCreated {
+		return
+	}
+	triggers := n.Triggers.Match(t.GuildID, TriggerEventThreadCreate, "", nil)
+	if len(triggers) == 0 {
+		return
+	}
+
+	owner := n.eventUser(s, t.GuildID, t.OwnerID)
+	if owner != nil && owner.Bot {
+		return
+	}
+	ctx := eventContext(s, RunSourceEvent, t.GuildID, t.ID, owner)
+	n.runTriggers(s, triggers, ctx, threadTriggerVars(t.Channel, owner), t.ID)
+}
+
+// handleVoiceStateUpdate runs the triggers of users joining, leaving or changing their state in voice channels
+// Output goes to the text chat of the voice channel unless the trigger has a channel
+func (n *Nelchan) handleVoiceStateUpdate(s *discordgo.Session, v *discordgo.VoiceStateUpdate) {
+	triggers := n.Triggers.Match(v.GuildID, TriggerEventVoiceState, "", nil)
+	if len(triggers) == 0 {
+		return
+	}
+
+	var user *discordgo.User
+	if v.Member != nil {
+		user = v.Member.User
+	} else {
+		user = n.eventUser(s, v.GuildID, v.UserID)
+	}
+	if user != nil && user.Bot {
+		return
+	}
+
+	vars := voiceTriggerVars(v.VoiceState, v.BeforeUpdate, user)
+	ctx := eventContext(s, RunSourceEvent, v.GuildID, vars["channel_id"], user)
+	n.runTriggers(s, triggers, ctx, vars, vars["channel_id"])
+}
+
+// runTriggers runs the code command of each trigger with the context and vars of the event
+// Each run gets its own invocation ID
+// Output is posted to the channel of the trigger, or channelID; empty output isn't posted
+func (n *Nelchan) runTriggers(s *discordgo.Session, triggers []EventTrigger, ctx ExecutionContext, eventVars map[string]string, channelID string) {
+	for _, trigger := range triggers {
+		runCtx := ctx
+		runCtx.InvocationID = newInvocationID(time.Now())
+		vars := runCtx.Vars()
+		maps.Copy(vars, eventVars)
+		go n.runTrigger(s, trigger, runCtx, vars, cmp.Or(trigger.ChannelID, channelID))
+	}
+}
+
+// runTrigger runs the code command of one trigger and posts its output to channelID
+func (n *Nelchan) runTrigger(s *discordgo.Session, trigger EventTrigger, ctx ExecutionContext, vars map[string]string, channelID string) {
+	start := time.Now()
+	result, err := n.CommandAPIClient.RunCommand(RunCommandRequest{
+		CommandName: trigger.CommandName,
+		IsCode:      true,
+		Vars:        vars,
+		Context:     &ctx,
+	})
+	n.recordCommandRun(CommandRun{
+		CommandName: trigger.CommandName,
+		UserID:      vars["user_id"],
+		ChannelID:   channelID,
+		GuildID:     trigger.GuildID,
+		Source:      RunSourceEvent,
+	}, start, result, err)
+
+	if err != nil {
+		fmt.Printf("error running trigger %d (%s): %v\n", trigger.ID, trigger.CommandName, err)
+		return
+	}
+	if result == nil || (strings.TrimSpace(result.Content) == "" && len(result.Artifacts) == 0) {
+		return
+	}
+	if channelID == "" {
+		fmt.Printf("trigger %d (%s) has output but no channel to post it to\n", trigger.ID, trigger.CommandName)
+		return
+	}
+
+	if err := n.sendCommandOutput(s, channelID, result); err != nil {
+		fmt.Println("error sending message,", err)
+		return
+	}
+	fmt.Printf("trigger %d ran %s on %s\n", trigger.ID, trigger.CommandName, trigger.Event)
+}
+
+// FormatTriggerList formats the event triggers of a guild for /trigger list
+func FormatTriggerList(triggers []EventTrigger) string {
+	if len(triggers) == 0 {
+		return "イベントトリガーはありません。`/trigger add` で登録できます"
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "イベントトリガー（%d 件）", len(triggers))
+	for _, trigger := range triggers {
+		fmt.Fprintf(&b, "\n`#%d` %s → `%s`", trigger.ID, triggerEventNames[trigger.Event], commandInvocation(trigger.CommandName, true))
+		b.WriteString(formatTriggerFilter(trigger))
+		if trigger.ChannelID != "" {
+			fmt.Fprintf(&b, " 出力先 <#%s>", trigger.ChannelID)
+		}
+	}
+	return b.String()
+}
+
+// formatTriggerFilter formats the message and emoji filters of a reaction trigger
+func formatTriggerFilter(trigger EventTrigger) string {
+	var filters []string
+	if trigger.Emoji != "" {
+		emoji := trigger.Emoji
+		if snowflakeRe.MatchString(emoji) {
+			emoji = fmt.Sprintf("<:_:%s>", emoji)
+		}
+		filters = append(filters, emoji)
+	}
+	if trigger.MessageID != "" {
+		filters = append(filters, fmt.Sprintf("メッセージ %s", trigger.MessageID))
+	}
+	if len(filters) == 0 {
+		return ""
+	}
+	return "（" + strings.Join(filters, "、") + "）"
+}
+
+// handleTriggerSlashCommand handles the /trigger slash command and its add, list and remove subcommands
+// Only admins can manage triggers
+func (n *Nelchan) handleTriggerSlashCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	data := i.ApplicationCommandData()
+	if len(data.Options) == 0 {
+		return
+	}
+	sub := data.Options[0]
+
+	if i.GuildID == "" || !n.isInteractionAdmin(i) {
+		_ = s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseChannelMessageWithSource,
+			Data: &discordgo.InteractionResponseData{
+				Content: "イベントトリガーはサーバーの管理者だけが設定できます",
+				Flags:   discordgo.MessageFlagsEphemeral,
+			},
+		})
+		return
+	}
+
+	// Defer response as triggers are validated and stored in the backend
+	err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
+	})
+	if err != nil {
+		fmt.Printf("error deferring interaction response: %v\n", err)
+		return
+	}
+
+	var message string
+	switch sub.Name {
+	case "add":
+		message, err = n.addTrigger(i, sub.Options)
+	case "list":
+		var triggers []EventTrigger
+		triggers, err = n.CommandAPIClient.ListEventTriggers(ListEventTriggersRequest{GuildID: i.GuildID})
+		message = FormatTriggerList(triggers)
+	case "remove":
+		message, err = n.removeTrigger(i, sub.Options)
+	}
+	if err != nil {
+		fmt.Printf("error handling /trigger %s: %v\n", sub.Name, err)
+		message = fmt.Sprintf("エラー: %s", err.Error())
+	}
+
+	editInteractionContent(s, i, message)
+	fmt.Printf("slash command /trigger %s executed\n", sub.Name)
+}
+
+// addTrigger validates and stores an event trigger from the options of /trigger add
+// Returns the message to show to the user
+func (n *Nelchan) addTrigger(i *discordgo.InteractionCreate, options []*discordgo.ApplicationCommandInteractionDataOption) (string, error) {
+	trigger := EventTrigger{
+		GuildID:  i.GuildID,
+		AuthorID: interactionUser(i).ID,
+	}
+	for _, opt := range options {
+		switch opt.Name {
+		case "event":
+			trigger.Event = opt.StringValue()
+		case "command_name":
+			trigger.CommandName = n.CommandRouter.ResolveAlias(opt.StringValue())
+		case "channel":
+			if id, ok := opt.Value.(string); ok {
+				trigger.ChannelID = id
+			}
+		case "emoji":
+			trigger.Emoji = NormalizeTriggerEmoji(opt.StringValue())
+		case "message_id":
+			trigger.MessageID = NormalizeTriggerMessageID(opt.StringValue())
+		}
+	}
+
+	if _, ok := triggerEventNames[trigger.Event]; !ok {
+		return fmt.Sprintf("イベント「%s」には対応していません", trigger.Event), nil
+	}
+	if !isReactionEvent(trigger.Event) && (trigger.Emoji != "" || trigger.MessageID != "") {
+		return "`emoji` と `message_id` はリアクションのイベントでだけ指定できます", nil
+	}
+	if trigger.MessageID != "" && !snowflakeRe.MatchString(trigger.MessageID) {
+		return "`message_id` にはメッセージの ID かリンクを指定してください", nil
+	}
+	if n.lookupCodeCommand(trigger.CommandName) == nil {
+		return fmt.Sprintf("コードコマンド「%s」は見つかりませんでした", trigger.CommandName), nil
+	}
+
+	existing, err := n.CommandAPIClient.ListEventTriggers(ListEventTriggersRequest{GuildID: i.GuildID})
+	if err != nil {
+		return "", err
+	}
+	if len(existing) >= maxTriggersPerGuild {
+		return fmt.Sprintf("このサーバーのイベントトリガーは %d 件までです。`/trigger remove` で削除してから登録してください", maxTriggersPerGuild), nil
+	}
+
+	created, err := n.CommandAPIClient.AddEventTrigger(trigger)
+	if err != nil {
+		return "", err
+	}
+	if created == nil {
+		return fmt.Sprintf("コードコマンド「%s」は見つかりませんでした", trigger.CommandName), nil
+	}
+	n.loadTriggers()
+
+	fmt.Printf("added event trigger %d: %s on %s by %s\n", created.ID, created.CommandName, created.Event, created.AuthorID)
+	message := fmt.Sprintf("イベントトリガー `#%d` を登録しました: %s%s で `%s` を実行します",
+		created.ID, triggerEventNames[created.Event], formatTriggerFilter(*created), commandInvocation(created.CommandName, true))
+	if isMemberEvent(created.Event) && !n.Config.MemberEvents {
+		message += "\n⚠️ メンバーの参加・退出を受け取るには、Bot の設定で Server Members Intent を有効にし、`ENABLE_MEMBER_EVENTS=true` で起動してください"
+	}
+	return message, nil
+}
+
+// removeTrigger removes an event trigger of the guild given to /trigger remove
+// Returns the message to show to the user
+func (n *Nelchan) removeTrigger(i *discordgo.InteractionCreate, options []*discordgo.ApplicationCommandInteractionDataOption) (string, error) {
+	var id int64
+	for _, opt := range options {
+		if opt.Name == "id" {
+			id = opt.IntValue()
+		}
+	}
+
+	// Look the trigger up in this guild so triggers of other guilds can't be removed
+	triggers, err := n.CommandAPIClient.ListEventTriggers(ListEventTriggersRequest{GuildID: i.GuildID})
+	if err != nil {
+		return "", err
+	}
+	notFound := fmt.Sprintf("イベントトリガー `#%d` は見つかりませんでした", id)
+	if !slices.ContainsFunc(triggers, func(trigger EventTrigger) bool { return trigger.ID == id }) {
+		return notFound, nil
+	}
+
+	removed, err := n.CommandAPIClient.RemoveEventTrigger(RemoveEventTriggerRequest{ID: id})
+	if err != nil {
+		return "", err
+	}
+	n.loadTriggers()
+	if !removed {
+		return notFound, nil
+	}
+
+	fmt.Printf("removed event trigger %d by %s\n", id, interactionUser(i).ID)
+	return fmt.Sprintf("イベントトリガー `#%d` を削除しました", id), nil
+}
@@ -0,0 +1,151 @@
+package nelchanbot
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func TestTriggerRegistryMatch(t *testing.T) {
+	registry := NewTriggerRegistry()
+	registry.Set([]EventTrigger{
+		{ID: 1, GuildID: "g1", Event: TriggerEventReactionAdd},
+		{ID: 2, GuildID: "g1", Event: TriggerEventReactionAdd, Emoji: "👍"},
+		{ID: 3, GuildID: "g1", Event: TriggerEventReactionAdd, Emoji: "555", MessageID: "900"},
+		{ID: 4, GuildID: "g1", Event: TriggerEventMemberJoin},
+		{ID: 5, GuildID: "g2", Event: TriggerEventReactionAdd},
+	})
+
+	ids := func(triggers []EventTrigger) []int64 {
+		var ids []int64
+		for _, trigger := range triggers {
+			ids = append(ids, trigger.ID)
+		}
+		return ids
+	}
+
+	tests := []struct {
+		name      string
+		guildID   string
+		event     string
+		messageID string
+		emoji     *discordgo.Emoji
+		want      []int64
+	}{
+		{"unicode emoji", "g1", TriggerEventReactionAdd, "800", &discordgo.Emoji{Name: "👍"}, []int64{1, 2}},
+		{"custom emoji on other message", "g1", TriggerEventReactionAdd, "800", &discordgo.Emoji{ID: "555", Name: "nel"}, []int64{1}},
+		{"custom emoji on message", "g1", TriggerEventReactionAdd, "900", &discordgo.Emoji{ID: "555", Name: "nel"}, []int64{1, 3}},
+		{"other event", "g1", TriggerEventReactionRemove, "900", &discordgo.Emoji{Name: "👍"}, nil},
+		{"member join", "g1", TriggerEventMemberJoin, "", nil, []int64{4}},
+		{"other guild", "g3", TriggerEventReactionAdd, "900", &discordgo.Emoji{Name: "👍"}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ids(registry.Match(tt.guildID, tt.event, tt.messageID, tt.emoji))
+			if len(got) != len(tt.want) {
+				t.Fatalf("Match() = %v, want %v", got, tt.want)
+			}
+			for idx := range got {
+				if got[idx] != tt.want[idx] {
+					t.Fatalf("Match() = %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestNormalizeTriggerInput(t *testing.T) {
+	for input, want := range map[string]string{
+		" 👍 ":         "👍",
+		"<:nel:123>":  "123",
+		"<a:nel:456>": "456",
+	} {
+		if got := NormalizeTriggerEmoji(input); got != want {
+			t.Errorf("NormalizeTriggerEmoji(%q) = %q, want %q", input, got, want)
+		}
+	}
+
+	for input, want := range map[string]string{
+		"900":                                  "900",
+		"https://discord.com/channels/1/2/900": "900",
+		"https://ptb.discord.com/channels/1/2/900": "900",
+	} {
+		if got := NormalizeTriggerMessageID(input); got != want {
+			t.Errorf("NormalizeTriggerMessageID(%q) = %q, want %q", input, got, want)
+		}
+	}
+}
+
+func TestVoiceTriggerVars(t *testing.T) {
+	user := &discordgo.User{ID: "100", GlobalName: "ねる"}
+
+	tests := []struct {
+		name        string
+		before      *discordgo.VoiceState
+		after       string
+		wantAction  string
+		wantChannel string
+	}{
+		{"join", nil, "10", "join", "10"},
+		{"leave", &discordgo.VoiceState{ChannelID: "10"}, "", "leave", "10"},
+		{"move", &discordgo.VoiceState{ChannelID: "10"}, "20", "move", "20"},
+		{"mute", &discordgo.VoiceState{ChannelID: "10"}, "10", "update", "10"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			vars := voiceTriggerVars(&discordgo.VoiceState{GuildID: "g1", UserID: "100", ChannelID: tt.after}, tt.before, user)
+			if vars["voice_action"] != tt.wantAction || vars["channel_id"] != tt.wantChannel {
+				t.Errorf("voiceTriggerVars() action = %q, channel = %q, want %q, %q", vars["voice_action"], vars["channel_id"], tt.wantAction, tt.wantChannel)
+			}
+			if vars["event"] != TriggerEventVoiceState || vars["username"] != "ねる" || vars["user_id"] != "100" {
+				t.Errorf("voiceTriggerVars() = %v", vars)
+			}
+		})
+	}
+}
+
+func TestReactionTriggerVars(t *testing.T) {
+	vars := reactionTriggerVars(TriggerEventReactionAdd, &discordgo.MessageReaction{
+		UserID:    "100",
+		MessageID: "900",
+		ChannelID: "10",
+		GuildID:   "g1",
+		Emoji:     discordgo.Emoji{ID: "555", Name: "nel"},
+	}, nil)
+
+	want := map[string]string{
+		"event":      TriggerEventReactionAdd,
+		"guild_id":   "g1",
+		"channel_id": "10",
+		"user_id":    "100",
+		"message_id": "900",
+		"emoji":      "<:nel:555>",
+		"emoji_name": "nel",
+		"emoji_id":   "555",
+	}
+	for key, value := range want {
+		if vars[key] != value {
+			t.Errorf("reactionTriggerVars()[%q] = %q, want %q", key, vars[key], value)
+		}
+	}
+}
+
+func TestFormatTriggerList(t *testing.T) {
+	if got := FormatTriggerList(nil); !strings.Contains(got, "イベントトリガーはありません") {
+		t.Errorf("FormatTriggerList(nil) = %q", got)
+	}
+
+	got := FormatTriggerList([]EventTrigger{
+		{ID: 1, Event: TriggerEventReactionAdd, CommandName: "role", Emoji: "555", MessageID: "900"},
+		{ID: 2, Event: TriggerEventMemberJoin, CommandName: "welcome", ChannelID: "10"},
+	})
+	want := "イベントトリガー（2 件）\n" +
+		"`#1` リアクション追加 → `" + commandInvocation("role", true) + "`（<:_:555>、メッセージ 900）\n" +
+		"`#2` メンバー参加 → `" + commandInvocation("welcome", true) + "` 出力先 <#10>"
+	if got != want {
+		t.Errorf("FormatTriggerList() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,5 @@
+-- Migration: 0004_args_schema.sql
+-- Date: 2026-10-18
+-- Description: Store the "# args" schema of code commands
+
+ALTER TABLE commands ADD COLUMN args_schema TEXT;
@@ -0,0 +1,14 @@
+-- Migration: 0005_slash_commands.sql
+-- Date: 2026-10-18
+-- Description: Track the Discord application commands created for code commands
+
+CREATE TABLE IF NOT EXISTS slash_commands (
+    id TEXT PRIMARY KEY,
+    command_id TEXT NOT NULL,
+    guild_id TEXT NOT NULL,
+    application_command_id TEXT NOT NULL,
+    created_at TEXT DEFAULT (datetime('now')),
+    UNIQUE (command_id, guild_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_slash_commands_command ON slash_commands(command_id);
@@ -0,0 +1,33 @@
+-- Migration: 0006_command_versions.sql
+-- Date: 2026-10-18
+-- Description: Keep every registered revision of a command and track when commands change
+
+CREATE TABLE IF NOT EXISTS command_versions (
+    id TEXT PRIMARY KEY,
+    command_id TEXT NOT NULL,
+    version INTEGER NOT NULL,
+    is_code INTEGER NOT NULL,
+    content TEXT NOT NULL,
+    args_schema TEXT,
+    author_id TEXT NOT NULL,
+    created_at TEXT NOT NULL DEFAULT (datetime('now')),
+    UNIQUE (command_id, version)
+);
+
+CREATE INDEX IF NOT EXISTS idx_command_versions_command ON command_versions(command_id);
+
+ALTER TABLE commands ADD COLUMN created_at TEXT;
+ALTER TABLE commands ADD COLUMN updated_at TEXT;
+
+UPDATE commands SET created_at = datetime('now'), updated_at = datetime('now');
+
+-- Existing contents become version 1
+INSERT INTO command_versions (id, command_id, version, is_code, content, args_schema, author_id)
+SELECT lower(hex(randomblob(16))), c.id, 1, 1, co.code, c.args_schema, c.author_id
+FROM commands c
+INNER JOIN codes co ON c.id = co.command_id;
+
+INSERT INTO command_versions (id, command_id, version, is_code, content, args_schema, author_id)
+SELECT lower(hex(randomblob(16))), c.id, 1, 0, d.text, NULL, c.author_id
+FROM commands c
+INNER JOIN dictionaries d ON c.id = d.command_id;
@@ -0,0 +1,12 @@
+-- Migration: 0007_command_aliases.sql
+-- Date: 2026-10-18
+-- Description: User defined aliases for registered commands
+
+CREATE TABLE IF NOT EXISTS command_aliases (
+    alias TEXT PRIMARY KEY,
+    command_id TEXT NOT NULL,
+    author_id TEXT NOT NULL,
+    created_at TEXT NOT NULL DEFAULT (datetime('now'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_command_aliases_command ON command_aliases(command_id);
@@ -0,0 +1,8 @@
+-- Migration: 0008_command_metadata.sql
+-- Date: 2026-10-18
+-- Description: Optional description, usage, tags and category of commands
+
+ALTER TABLE commands ADD COLUMN description TEXT;
+ALTER TABLE commands ADD COLUMN usage TEXT;
+ALTER TABLE commands ADD COLUMN tags TEXT; -- JSON array of strings
+ALTER TABLE commands ADD COLUMN category TEXT;
@@ -0,0 +1,6 @@
+-- Migration: 0009_command_use_count.sql
+-- Date: 2026-10-18
+-- Description: Count command runs so listings can be sorted by popularity
+
+ALTER TABLE commands ADD COLUMN use_count INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE commands ADD COLUMN last_used_at TEXT;
@@ -0,0 +1,19 @@
+-- Migration: 0010_command_runs.sql
+-- Date: 2026-10-18
+-- Description: Record every command run for usage statistics
+
+CREATE TABLE IF NOT EXISTS command_runs (
+    id TEXT PRIMARY KEY,
+    command_name TEXT NOT NULL,
+    user_id TEXT NOT NULL,
+    channel_id TEXT,
+    guild_id TEXT,
+    source TEXT NOT NULL, -- message, text, exec, mention or slash
+    latency_ms INTEGER NOT NULL,
+    success INTEGER NOT NULL,
+    error TEXT,
+    created_at TEXT NOT NULL DEFAULT (datetime('now'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_command_runs_created_at ON command_runs(created_at);
+CREATE INDEX IF NOT EXISTS idx_command_runs_command ON command_runs(command_name);
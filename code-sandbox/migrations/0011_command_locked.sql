@@ -0,0 +1,5 @@
+-- Migration: 0011_command_locked.sql
+-- Date: 2026-10-18
+-- Description: Lock commands so only their owner and admins can change them (unlocked commands are open to everyone)
+
+ALTER TABLE commands ADD COLUMN locked INTEGER NOT NULL DEFAULT 1;
@@ -0,0 +1,21 @@
+-- Migration: 0012_schedules.sql
+-- Date: 2026-10-18
+-- Description: Run code commands on a cron schedule and post the result to a channel
+
+CREATE TABLE IF NOT EXISTS schedules (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    command_id TEXT NOT NULL,
+    guild_id TEXT,
+    channel_id TEXT NOT NULL,
+    cron TEXT NOT NULL,
+    timezone TEXT NOT NULL,
+    args TEXT NOT NULL DEFAULT '',
+    missed_policy TEXT NOT NULL DEFAULT 'run_once', -- skip or run_once
+    author_id TEXT NOT NULL,
+    next_run_at TEXT, -- RFC 3339 (UTC), computed by the bot
+    last_run_at TEXT,
+    created_at TEXT NOT NULL DEFAULT (datetime('now'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_schedules_command ON schedules(command_id);
+CREATE INDEX IF NOT EXISTS idx_schedules_guild ON schedules(guild_id);
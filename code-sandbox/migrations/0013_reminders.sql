@@ -0,0 +1,23 @@
+-- Migration: 0013_reminders.sql
+-- Date: 2026-10-18
+-- Description: Reminders delivered by the bot and per-user settings such as the timezone
+
+CREATE TABLE IF NOT EXISTS reminders (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id TEXT NOT NULL,
+    guild_id TEXT,
+    channel_id TEXT NOT NULL,
+    message_id TEXT, -- message to reply to, NULL when set from a slash command
+    text TEXT NOT NULL,
+    remind_at TEXT NOT NULL, -- RFC 3339 (UTC)
+    delivery TEXT NOT NULL DEFAULT 'reply', -- reply or dm
+    created_at TEXT NOT NULL DEFAULT (datetime('now'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_reminders_user ON reminders(user_id);
+
+CREATE TABLE IF NOT EXISTS user_settings (
+    user_id TEXT PRIMARY KEY,
+    timezone TEXT,
+    updated_at TEXT NOT NULL DEFAULT (datetime('now'))
+);
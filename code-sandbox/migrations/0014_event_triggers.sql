@@ -0,0 +1,18 @@
+-- Migration: 0014_event_triggers.sql
+-- Date: 2026-10-18
+-- Description: Run code commands on gateway events such as reactions, member joins and voice state changes
+
+CREATE TABLE IF NOT EXISTS event_triggers (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    guild_id TEXT NOT NULL,
+    event TEXT NOT NULL, -- reaction_add, reaction_remove, member_join, member_leave, thread_create or voice_state
+    command_id TEXT NOT NULL,
+    channel_id TEXT, -- where output is posted, NULL for the channel of the event
+    message_id TEXT, -- reaction events: only reactions on this message
+    emoji TEXT, -- reaction events: only this emoji (unicode or custom emoji ID)
+    author_id TEXT NOT NULL,
+    created_at TEXT NOT NULL DEFAULT (datetime('now'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_event_triggers_guild ON event_triggers(guild_id);
+CREATE INDEX IF NOT EXISTS idx_event_triggers_command ON event_triggers(command_id);
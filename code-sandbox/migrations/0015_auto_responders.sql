@@ -0,0 +1,24 @@
+-- Migration: 0015_auto_responders.sql
+-- Date: 2026-10-18
+-- Description: Respond to messages matching a pattern with a command, in channels where auto responders are enabled
+
+CREATE TABLE IF NOT EXISTS auto_responders (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    guild_id TEXT NOT NULL,
+    mode TEXT NOT NULL, -- exact, prefix, contains, regex or whole
+    pattern TEXT NOT NULL,
+    command_id TEXT NOT NULL,
+    author_id TEXT NOT NULL,
+    created_at TEXT NOT NULL DEFAULT (datetime('now'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_auto_responders_guild ON auto_responders(guild_id);
+CREATE INDEX IF NOT EXISTS idx_auto_responders_command ON auto_responders(command_id);
+
+-- Channels where auto responders are enabled, they are off everywhere else
+CREATE TABLE IF NOT EXISTS auto_responder_channels (
+    channel_id TEXT PRIMARY KEY,
+    guild_id TEXT NOT NULL,
+    enabled_by TEXT NOT NULL,
+    created_at TEXT NOT NULL DEFAULT (datetime('now'))
+);
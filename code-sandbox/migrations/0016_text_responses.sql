@@ -0,0 +1,12 @@
+-- Migration: 0016_text_responses.sql
+-- Date: 2026-10-18
+-- Description: Let a text command hold several weighted responses, picked at random, round-robin or by weight
+
+-- Every dictionaries row of a text command is one of its responses
+ALTER TABLE dictionaries ADD COLUMN weight INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE dictionaries ADD COLUMN position INTEGER NOT NULL DEFAULT 0;
+
+CREATE INDEX IF NOT EXISTS idx_dictionaries_command ON dictionaries(command_id, position);
+
+ALTER TABLE commands ADD COLUMN response_mode TEXT NOT NULL DEFAULT 'random'; -- random, round_robin or weighted
+ALTER TABLE commands ADD COLUMN response_cursor INTEGER NOT NULL DEFAULT 0; -- next response of round_robin
@@ -0,0 +1,86 @@
+/**
+ * コードコマンドの成果物（画像などのファイル）のサービス
+ * コードは save_file() で実行ごとのディレクトリにファイルを書き、マニフェストに一覧を残す
+ * 実行後にマニフェストに載っているファイルを読み出して Bot に返す
+ */
+import type { getSandbox } from "@cloudflare/sandbox"
+
+type Sandbox = ReturnType<typeof getSandbox>
+
+/**
+ * コードコマンドの成果物
+ * data は base64、大きすぎて読み出さなかったファイルは data を持たない
+ */
+export type CommandArtifact = {
+  name: string
+  content_type: string
+  size: number
+  data?: string
+}
+
+/**
+ * 成果物の一覧を書くファイル名
+ */
+export const artifactManifestName = ".nelchan-manifest.json"
+
+/**
+ * 1 回の実行で読み出す成果物の合計サイズの上限
+ * base64 にすると約 1.33 倍になるため、Worker のメモリ上限（128 MB）に収まるよう抑える
+ * これを超えるファイルは data なしで返し、Bot が上限超過として扱う
+ */
+const maxArtifactBytes = 25 * 1024 * 1024
+
+/**
+ * 実行ごとの成果物のディレクトリ（入力ファイルも置く）
+ */
+export const artifactDir = (runId: string) => `/tmp/nelchan-artifacts/${runId}`
+
+/**
+ * 実行で保存された成果物を読み出す
+ * save_file() が呼ばれなかった場合は空の配列を返す
+ */
+export async function collectArtifacts(
+  sandbox: Sandbox,
+  runId: string
+): Promise<CommandArtifact[]> {
+  const dir = artifactDir(runId)
+
+  let entries: { name: string; content_type: string; size: number }[]
+  try {
+    const manifest = await sandbox.readFile(`${dir}/${artifactManifestName}`)
+    entries = JSON.parse(manifest.content)
+  } catch {
+    // マニフェストがない = 成果物なし
+    return []
+  }
+
+  const artifacts: CommandArtifact[] = []
+  let total = 0
+  for (const entry of entries) {
+    // 上限を超えるファイルは読み出さず、後続の小さいファイルは読み出す
+    if (total + entry.size > maxArtifactBytes) {
+      artifacts.push({ ...entry })
+      continue
+    }
+    const file = await sandbox.readFile(`${dir}/${entry.name}`, {
+      encoding: "base64",
+    })
+    total += entry.size
+    artifacts.push({ ...entry, data: file.content })
+  }
+  return artifacts
+}
+
+/**
+ * 実行ごとのディレクトリ（入力ファイルと成果物）を削除
+ * サンドボックスは使い回すため、実行が失敗しても必ず呼ぶ（他のユーザーの実行から読めないようにする）
+ */
+export async function removeRunDir(
+  sandbox: Sandbox,
+  runId: string
+): Promise<void> {
+  const dir = artifactDir(runId)
+  await sandbox.exec(`rm -rf ${dir}`).catch((error: unknown) => {
+    console.error("[removeRunDir] failed to remove run directory: ", error)
+  })
+}
@@ -0,0 +1,172 @@
+/**
+ * 自動応答サービス
+ * メッセージがパターンに一致したときに実行するコマンドと、自動応答を有効にしたチャンネルを保存・取得する
+ * メッセージとパターンの照合とコマンドの実行はボット側で行う
+ */
+
+/**
+ * 自動応答
+ */
+export type AutoResponder = {
+  id: number
+  guild_id: string
+  // exact, prefix, contains, regex, whole
+  mode: string
+  pattern: string
+  command_name: string
+  // 応答するコマンドがコードコマンドかどうか
+  is_code: boolean
+  author_id: string
+  created_at: string
+}
+
+/**
+ * 自動応答の登録内容
+ */
+export type NewAutoResponder = {
+  guild_id: string
+  mode: string
+  pattern: string
+  command_name: string
+  author_id: string
+}
+
+/**
+ * 自動応答を有効にしたチャンネル
+ */
+export type AutoResponderChannel = {
+  channel_id: string
+  guild_id: string
+  enabled_by: string
+  created_at: string
+}
+
+/**
+ * 自動応答を一覧取得
+ * guildId を指定するとそのサーバーの自動応答だけを返す
+ */
+export async function listAutoResponders(
+  env: Env,
+  guildId?: string
+): Promise<AutoResponder[]> {
+  const where = guildId ? "WHERE r.guild_id = ?" : ""
+  const bindings = guildId ? [guildId] : []
+
+  const { results } = await env.nelchan_db
+    .prepare(
+      `SELECT r.id, r.guild_id, r.mode, r.pattern, c.name AS command_name,
+              EXISTS (SELECT 1 FROM codes WHERE command_id = c.id) AS is_code,
+              r.author_id, r.created_at
+       FROM auto_responders r
+       INNER JOIN commands c ON c.id = r.command_id
+       ${where}
+       ORDER BY r.id`
+    )
+    .bind(...bindings)
+    .all<Omit<AutoResponder, "is_code"> & { is_code: number }>()
+  return results.map((row) => ({ ...row, is_code: row.is_code === 1 }))
+}
+
+/**
+ * 自動応答を登録
+ * コマンドが存在しない場合は null を返す
+ */
+export async function addAutoResponder(
+  env: Env,
+  responder: NewAutoResponder
+): Promise<AutoResponder | null> {
+  const command = await env.nelchan_db
+    .prepare(
+      `SELECT c.id, EXISTS (SELECT 1 FROM codes WHERE command_id = c.id) AS is_code
+       FROM commands c WHERE c.name = ?`
+    )
+    .bind(responder.command_name)
+    .first<{ id: string; is_code: number }>()
+
+  if (!command) {
+    return null
+  }
+
+  const created = await env.nelchan_db
+    .prepare(
+      `INSERT INTO auto_responders (guild_id, mode, pattern, command_id, author_id)
+       VALUES (?, ?, ?, ?, ?)
+       RETURNING id, guild_id, mode, pattern, author_id, created_at`
+    )
+    .bind(
+      responder.guild_id,
+      responder.mode,
+      responder.pattern,
+      command.id,
+      responder.author_id
+    )
+    .first<Omit<AutoResponder, "command_name" | "is_code">>()
+
+  if (!created) {
+    throw new Error("Failed to insert auto responder")
+  }
+  return {
+    ...created,
+    command_name: responder.command_name,
+    is_code: command.is_code === 1,
+  }
+}
+
+/**
+ * 自動応答を削除
+ * 存在しなかった場合は false を返す
+ */
+export async function removeAutoResponder(
+  env: Env,
+  id: number
+): Promise<boolean> {
+  const result = await env.nelchan_db
+    .prepare(`DELETE FROM auto_responders WHERE id = ?`)
+    .bind(id)
+    .run()
+  return result.meta.changes > 0
+}
+
+/**
+ * 自動応答を有効にしたチャンネルを一覧取得
+ */
+export async function listAutoResponderChannels(
+  env: Env
+): Promise<AutoResponderChannel[]> {
+  const { results } = await env.nelchan_db
+    .prepare(
+      `SELECT channel_id, guild_id, enabled_by, created_at
+       FROM auto_responder_channels
+       ORDER BY created_at`
+    )
+    .all<AutoResponderChannel>()
+  return results
+}
+
+/**
+ * チャンネルの自動応答を有効・無効にする
+ */
+export async function setAutoResponderChannel(
+  env: Env,
+  channelId: string,
+  guildId: string,
+  enabled: boolean,
+  userId: string
+): Promise<void> {
+  if (!enabled) {
+    await env.nelchan_db
+      .prepare(`DELETE FROM auto_responder_channels WHERE channel_id = ?`)
+      .bind(channelId)
+      .run()
+    return
+  }
+
+  await env.nelchan_db
+    .prepare(
+      `INSERT INTO auto_responder_channels (channel_id, guild_id, enabled_by)
+       VALUES (?, ?, ?)
+       ON CONFLICT(channel_id) DO NOTHING`
+    )
+    .bind(channelId, guildId, userId)
+    .run()
+}
@@ -0,0 +1,114 @@
+/**
+ * イベントトリガーサービス
+ * リアクションやメンバーの参加などのイベントで実行するコードコマンドの設定を保存・取得する
+ * イベントの受信とコマンドの実行はボット側で行う
+ */
+
+/**
+ * イベントトリガー
+ */
+export type EventTrigger = {
+  id: number
+  guild_id: string
+  // reaction_add, reaction_remove, member_join, member_leave, thread_create, voice_state
+  event: string
+  command_name: string
+  channel_id: string | null
+  message_id: string | null
+  emoji: string | null
+  author_id: string
+  created_at: string
+}
+
+/**
+ * イベントトリガーの登録内容
+ */
+export type NewEventTrigger = {
+  guild_id: string
+  event: string
+  command_name: string
+  channel_id?: string
+  message_id?: string
+  emoji?: string
+  author_id: string
+}
+
+/**
+ * イベントトリガーを一覧取得
+ * guildId を指定するとそのサーバーのトリガーだけを返す
+ */
+export async function listEventTriggers(
+  env: Env,
+  guildId?: string
+): Promise<EventTrigger[]> {
+  const where = guildId ? "WHERE t.guild_id = ?" : ""
+  const bindings = guildId ? [guildId] : []
+
+  const { results } = await env.nelchan_db
+    .prepare(
+      `SELECT t.id, t.guild_id, t.event, c.name AS command_name, t.channel_id,
+              t.message_id, t.emoji, t.author_id, t.created_at
+       FROM event_triggers t
+       INNER JOIN commands c ON c.id = t.command_id
+       ${where}
+       ORDER BY t.id`
+    )
+    .bind(...bindings)
+    .all<EventTrigger>()
+  return results
+}
+
+/**
+ * イベントトリガーを登録
+ * コマンドが存在しない場合は null を返す
+ */
+export async function addEventTrigger(
+  env: Env,
+  trigger: NewEventTrigger
+): Promise<EventTrigger | null> {
+  const command = await env.nelchan_db
+    .prepare(`SELECT id FROM commands WHERE name = ?`)
+    .bind(trigger.command_name)
+    .first<{ id: string }>()
+
+  if (!command) {
+    return null
+  }
+
+  const created = await env.nelchan_db
+    .prepare(
+      `INSERT INTO event_triggers (guild_id, event, command_id, channel_id, message_id, emoji, author_id)
+       VALUES (?, ?, ?, ?, ?, ?, ?)
+       RETURNING id, guild_id, event, channel_id, message_id, emoji, author_id, created_at`
+    )
+    .bind(
+      trigger.guild_id,
+      trigger.event,
+      command.id,
+      trigger.channel_id ?? null,
+      trigger.message_id ?? null,
+      trigger.emoji ?? null,
+      trigger.author_id
+    )
+    .first<Omit<EventTrigger, "command_name">>()
+
+  if (!created) {
+    throw new Error("Failed to insert event trigger")
+  }
+  return { ...created, command_name: trigger.command_name }
+}
+
+/**
+ * イベントトリガーを削除
+ * 存在しなかった場合は false を返す
+ */
+export async function removeEventTrigger(
+  env: Env,
+  id: number
+): Promise<boolean> {
+  const result = await env.nelchan_db
+    .prepare(`DELETE FROM event_triggers WHERE id = ?`)
+    .bind(id)
+    .run()
+  return result.meta.changes > 0
+}
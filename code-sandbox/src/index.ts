@@ -4,18 +4,34 @@ import { Hono } from "hono"
 import { bearerAuth } from "hono/bearer-auth"
 import { logger } from "hono/logger"
 import {
+  addAlias,
   autoStoreMemory,
+  canOverwriteCommand,
+  deleteCommand,
   enhancedMemoryLLM,
+  exportCommands,
   generateCodeFromDescription,
   getCommand,
+  getCommandHistory,
+  getCommandVersion,
   getMemory,
   getMentionCommand,
+  listAliases,
+  listCommands,
+  listSlashCommands,
   memoryLLM,
+  recordSlashCommand,
   registerCommand,
+  removeAlias,
+  renameCommand,
   runCommand,
+  searchCommands,
+  setCommandLocked,
   setMentionCommand,
   storeMemory,
+  transferCommand,
 } from "./usecase"
+import type { CommandMetadata, CommandSort } from "./usecase"
 import {
   storeMessage,
   storeMessages,
@@ -30,6 +46,43 @@ import type {
   FetchChannelRequest,
 } from "./types/discord"
 import { fetchChannelMessages } from "./discordClient"
+import { getCommandStats, recordCommandRun } from "./statsService"
+import type { CommandRun } from "./statsService"
+import {
+  addSchedule,
+  listSchedules,
+  markScheduleRan,
+  removeSchedule,
+} from "./scheduleService"
+import type { NewSchedule } from "./scheduleService"
+import {
+  addReminder,
+  listReminders,
+  removeReminder,
+} from "./reminderService"
+import type { NewReminder } from "./reminderService"
+import { getUserTimezone, setUserTimezone } from "./userService"
+import {
+  addEventTrigger,
+  listEventTriggers,
+  removeEventTrigger,
+} from "./eventTriggerService"
+import type { NewEventTrigger } from "./eventTriggerService"
+import {
+  addAutoResponder,
+  listAutoResponderChannels,
+  listAutoResponders,
+  removeAutoResponder,
+  setAutoResponderChannel,
+} from "./autoResponderService"
+import type { NewAutoResponder } from "./autoResponderService"
+import {
+  removeTextResponse,
+  responseModes,
+  setTextResponseMode,
+} from "./textResponseService"
+import type { TextResponse } from "./textResponseService"
+import type { CommandAttachment, RepliedMessage } from "./inputService"
 
 export { Sandbox } from "@cloudflare/sandbox"
 export { NelchanAgent } from "./agent"
@@ -59,20 +112,45 @@ type RegisterCommandRequest = {
   command_content: string
   isCode: boolean
   author_id: string
+  args_schema?: unknown[] | null
+  metadata?: CommandMetadata | null
+  append?: boolean
+  weight?: number
+  responses?: TextResponse[] | null
+  response_mode?: string | null
+  admin?: boolean
 }
 
 app.post("/register_command", async (c) => {
   const request = await c.req.json<RegisterCommandRequest>()
   console.log("[registerCommand] request: ", request)
 
-  await registerCommand(
+  const result = await registerCommand(
     c.env,
     request.command_name,
     request.command_content,
     request.isCode,
-    request.author_id
+    request.author_id,
+    {
+      argsSchema: request.args_schema,
+      metadata: request.metadata,
+      append: request.append,
+      weight: request.weight,
+      responses: request.responses,
+      responseMode: request.response_mode,
+      admin: request.admin,
+    }
   )
 
+  if (result === "locked") {
+    return c.json(
+      {
+        error: "ロックされたコマンドを変更できるのは登録者か管理者だけです",
+      },
+      403
+    )
+  }
+
   return c.json({
     error: null,
   })
@@ -83,6 +161,10 @@ type RunCommandRequest = {
   is_code: boolean
   vars: Record<string, string>
   args: string[]
+  named?: Record<string, unknown>
+  attachments?: CommandAttachment[]
+  replied?: RepliedMessage
+  context?: Record<string, unknown>
 }
 
 app.post("/run_command", async (c) => {
@@ -93,7 +175,13 @@ app.post("/run_command", async (c) => {
     request.command_name,
     request.is_code,
     request.vars,
-    request.args ?? []
+    request.args ?? [],
+    request.named ?? {},
+    {
+      attachments: request.attachments ?? [],
+      replied: request.replied ?? null,
+    },
+    request.context ?? {}
   )
 
   if (!command) {
@@ -182,137 +270,1181 @@ app.post("/get_command", async (c) => {
   })
 })
 
-type MemoryRequest = {
-  key: string
-  content: string
+type CommandHistoryRequest = {
+  command_name: string
+  limit?: number
 }
 
-app.post("/memory", async (c) => {
-  const request = await c.req.json<MemoryRequest>()
-  console.log("[memory] request: ", request)
+app.post("/command_history", async (c) => {
+  const request = await c.req.json<CommandHistoryRequest>()
+  console.log("[getCommandHistory] request: ", request)
 
   try {
-    await storeMemory(c.env, request.key, request.content)
+    const versions = await getCommandHistory(
+      c.env,
+      request.command_name,
+      request.limit ?? 20
+    )
+    if (!versions) {
+      return c.json(
+        {
+          error: "Command not found",
+          versions: [],
+        },
+        404
+      )
+    }
     return c.json({
       error: null,
-      success: true,
+      versions,
     })
   } catch (error) {
-    console.error("[memory] error: ", error)
+    console.error("[getCommandHistory] error: ", error)
     return c.json(
       {
-        error: "Failed to store memory",
-        success: false,
+        error: "Failed to get command history",
+        versions: [],
       },
       500
     )
   }
 })
 
-type MgetRequest = {
-  query: string
-  topK?: number
+type CommandVersionRequest = {
+  command_name: string
+  version?: number
 }
 
-app.post("/mget", async (c) => {
-  const request = await c.req.json<MgetRequest>()
-  console.log("[mget] request: ", request)
+app.post("/command_version", async (c) => {
+  const request = await c.req.json<CommandVersionRequest>()
+  console.log("[getCommandVersion] request: ", request)
 
   try {
-    const results = await getMemory(c.env, request.query, request.topK ?? 3)
+    const version = await getCommandVersion(
+      c.env,
+      request.command_name,
+      request.version
+    )
+    if (!version) {
+      return c.json(
+        {
+          error: "Version not found",
+          version: null,
+        },
+        404
+      )
+    }
     return c.json({
       error: null,
-      results,
+      version,
     })
   } catch (error) {
-    console.error("[mget] error: ", error)
+    console.error("[getCommandVersion] error: ", error)
     return c.json(
       {
-        error: "Failed to get memory",
-        results: [],
+        error: "Failed to get command version",
+        version: null,
       },
       500
     )
   }
 })
 
-type AutoMemoryRequest = {
-  text: string
+type ListCommandsRequest = {
+  query?: string
+  type?: "text" | "code"
+  author_id?: string
+  sort?: CommandSort
+  limit?: number
+  offset?: number
 }
 
-app.post("/automemory", async (c) => {
-  const request = await c.req.json<AutoMemoryRequest>()
-  console.log("[automemory] request: ", request)
+app.post("/list_commands", async (c) => {
+  const request = await c.req.json<ListCommandsRequest>()
+  console.log("[listCommands] request: ", request)
 
   try {
-    const count = await autoStoreMemory(c.env, request.text)
+    const { commands, total } = await listCommands(c.env, {
+      query: request.query,
+      type: request.type,
+      authorId: request.author_id,
+      sort: request.sort,
+      limit: request.limit,
+      offset: request.offset,
+    })
     return c.json({
       error: null,
-      count,
+      commands,
+      total,
     })
   } catch (error) {
-    console.error("[automemory] error: ", error)
+    console.error("[listCommands] error: ", error)
     return c.json(
       {
-        error: "Failed to auto-store memory",
-        count: 0,
+        error: "Failed to list commands",
+        commands: [],
+        total: 0,
       },
       500
     )
   }
 })
 
-type MLLMRequest = {
-  prompt: string
-  topK?: number
+type SearchCommandsRequest = {
+  keyword: string
+  limit?: number
+  offset?: number
 }
 
-app.post("/mllm", async (c) => {
-  const request = await c.req.json<MLLMRequest>()
-  console.log("[mllm] request: ", request)
+app.post("/search_commands", async (c) => {
+  const request = await c.req.json<SearchCommandsRequest>()
+  console.log("[searchCommands] request: ", request)
+
+  if (!request.keyword) {
+    return c.json(
+      {
+        error: "keyword is required",
+        commands: [],
+        total: 0,
+      },
+      400
+    )
+  }
 
   try {
-    const output = await memoryLLM(c.env, request.prompt, request.topK ?? 3)
+    const { commands, total } = await searchCommands(
+      c.env,
+      request.keyword,
+      request.limit ?? 10,
+      request.offset ?? 0
+    )
     return c.json({
       error: null,
-      output,
+      commands,
+      total,
     })
   } catch (error) {
-    console.error("[mllm] error: ", error)
+    console.error("[searchCommands] error: ", error)
     return c.json(
       {
-        error: "Failed to generate response with memory",
-        output: null,
+        error: "Failed to search commands",
+        commands: [],
+        total: 0,
       },
       500
     )
   }
 })
 
-type SmartRegisterRequest = {
+type DeleteCommandRequest = {
+  command_name: string
+}
+
+app.post("/delete_command", async (c) => {
+  const request = await c.req.json<DeleteCommandRequest>()
+  console.log("[deleteCommand] request: ", request)
+
+  try {
+    const deleted = await deleteCommand(c.env, request.command_name)
+    if (!deleted) {
+      return c.json(
+        {
+          error: "Command not found",
+        },
+        404
+      )
+    }
+    return c.json({
+      error: null,
+    })
+  } catch (error) {
+    console.error("[deleteCommand] error: ", error)
+    return c.json(
+      {
+        error: "Failed to delete command",
+      },
+      500
+    )
+  }
+})
+
+type RenameCommandRequest = {
+  command_name: string
+  new_name: string
+}
+
+app.post("/rename_command", async (c) => {
+  const request = await c.req.json<RenameCommandRequest>()
+  console.log("[renameCommand] request: ", request)
+
+  try {
+    const result = await renameCommand(
+      c.env,
+      request.command_name,
+      request.new_name
+    )
+    if (result === "not_found") {
+      return c.json({ error: "Command not found" }, 404)
+    }
+    if (result === "conflict") {
+      return c.json({ error: "Name already in use" }, 409)
+    }
+    return c.json({
+      error: null,
+    })
+  } catch (error) {
+    console.error("[renameCommand] error: ", error)
+    return c.json(
+      {
+        error: "Failed to rename command",
+      },
+      500
+    )
+  }
+})
+
+type AliasRequest = {
+  alias: string
   command_name: string
-  description: string
   author_id: string
 }
 
-app.post("/smart_register", async (c) => {
-  const request = await c.req.json<SmartRegisterRequest>()
-  console.log("[smartRegister] request: ", request)
+app.post("/alias", async (c) => {
+  const request = await c.req.json<AliasRequest>()
+  console.log("[addAlias] request: ", request)
 
   try {
-    const generated = await generateCodeFromDescription(
+    const result = await addAlias(
       c.env,
+      request.alias,
       request.command_name,
-      request.description
+      request.author_id
+    )
+    if (result === "not_found") {
+      return c.json({ error: "Command not found" }, 404)
+    }
+    if (result === "conflict") {
+      return c.json({ error: "Name already in use" }, 409)
+    }
+    return c.json({
+      error: null,
+    })
+  } catch (error) {
+    console.error("[addAlias] error: ", error)
+    return c.json(
+      {
+        error: "Failed to add alias",
+      },
+      500
+    )
+  }
+})
+
+type RemoveAliasRequest = {
+  alias: string
+}
+
+app.post("/remove_alias", async (c) => {
+  const request = await c.req.json<RemoveAliasRequest>()
+  console.log("[removeAlias] request: ", request)
+
+  try {
+    const removed = await removeAlias(c.env, request.alias)
+    if (!removed) {
+      return c.json({ error: "Alias not found" }, 404)
+    }
+    return c.json({
+      error: null,
+    })
+  } catch (error) {
+    console.error("[removeAlias] error: ", error)
+    return c.json(
+      {
+        error: "Failed to remove alias",
+      },
+      500
+    )
+  }
+})
+
+// Every command with its content, for backups and moving commands between instances
+app.post("/export_commands", async (c) => {
+  try {
+    const commands = await exportCommands(c.env)
+    return c.json({
+      error: null,
+      commands,
+    })
+  } catch (error) {
+    console.error("[exportCommands] error: ", error)
+    return c.json(
+      {
+        error: "Failed to export commands",
+        commands: [],
+      },
+      500
     )
+  }
+})
 
-    await registerCommand(
+type LockCommandRequest = {
+  command_name: string
+  locked: boolean
+}
+
+app.post("/lock_command", async (c) => {
+  const request = await c.req.json<LockCommandRequest>()
+  console.log("[setCommandLocked] request: ", request)
+
+  try {
+    const updated = await setCommandLocked(
+      c.env,
+      request.command_name,
+      request.locked
+    )
+    if (!updated) {
+      return c.json({ error: "Command not found" }, 404)
+    }
+    return c.json({
+      error: null,
+    })
+  } catch (error) {
+    console.error("[setCommandLocked] error: ", error)
+    return c.json(
+      {
+        error: "Failed to lock command",
+      },
+      500
+    )
+  }
+})
+
+type TransferCommandRequest = {
+  command_name: string
+  author_id: string
+}
+
+app.post("/transfer_command", async (c) => {
+  const request = await c.req.json<TransferCommandRequest>()
+  console.log("[transferCommand] request: ", request)
+
+  try {
+    const updated = await transferCommand(
       c.env,
       request.command_name,
-      generated.code,
-      true, // isCode
       request.author_id
     )
+    if (!updated) {
+      return c.json({ error: "Command not found" }, 404)
+    }
+    return c.json({
+      error: null,
+    })
+  } catch (error) {
+    console.error("[transferCommand] error: ", error)
+    return c.json(
+      {
+        error: "Failed to transfer command",
+      },
+      500
+    )
+  }
+})
+
+type ListAliasesRequest = {
+  command_name?: string
+}
+
+app.post("/list_aliases", async (c) => {
+  const request = await c.req.json<ListAliasesRequest>()
+  console.log("[listAliases] request: ", request)
+
+  try {
+    const aliases = await listAliases(c.env, request.command_name ?? "")
+    return c.json({
+      error: null,
+      aliases,
+    })
+  } catch (error) {
+    console.error("[listAliases] error: ", error)
+    return c.json(
+      {
+        error: "Failed to list aliases",
+        aliases: [],
+      },
+      500
+    )
+  }
+})
+
+type SlashCommandRequest = {
+  command_name: string
+  guild_id: string
+  application_command_id: string
+}
+
+// Record the Discord application command created for a command
+app.post("/slash_command", async (c) => {
+  const request = await c.req.json<SlashCommandRequest>()
+  console.log("[recordSlashCommand] request: ", request)
+
+  try {
+    const recorded = await recordSlashCommand(
+      c.env,
+      request.command_name,
+      request.guild_id,
+      request.application_command_id
+    )
+    if (!recorded) {
+      return c.json(
+        {
+          error: "Command not found",
+        },
+        404
+      )
+    }
+    return c.json({
+      error: null,
+    })
+  } catch (error) {
+    console.error("[recordSlashCommand] error: ", error)
+    return c.json(
+      {
+        error: "Failed to record slash command",
+      },
+      500
+    )
+  }
+})
+
+// Record a run of a registered command for the usage statistics
+app.post("/record_run", async (c) => {
+  const request = await c.req.json<CommandRun>()
+
+  try {
+    await recordCommandRun(c.env, request)
+    return c.json({
+      error: null,
+    })
+  } catch (error) {
+    console.error("[recordCommandRun] error: ", error)
+    return c.json(
+      {
+        error: "Failed to record command run",
+      },
+      500
+    )
+  }
+})
+
+type CommandStatsRequest = {
+  guild_id?: string
+}
+
+app.post("/command_stats", async (c) => {
+  const request = await c.req.json<CommandStatsRequest>()
+  console.log("[getCommandStats] request: ", request)
+
+  try {
+    const stats = await getCommandStats(c.env, request.guild_id)
+    return c.json({
+      error: null,
+      stats,
+    })
+  } catch (error) {
+    console.error("[getCommandStats] error: ", error)
+    return c.json(
+      {
+        error: "Failed to get command stats",
+        stats: null,
+      },
+      500
+    )
+  }
+})
+
+type ListSchedulesRequest = {
+  guild_id?: string
+}
+
+app.post("/list_schedules", async (c) => {
+  const request = await c.req.json<ListSchedulesRequest>()
+
+  try {
+    const schedules = await listSchedules(c.env, request.guild_id)
+    return c.json({
+      error: null,
+      schedules,
+    })
+  } catch (error) {
+    console.error("[listSchedules] error: ", error)
+    return c.json(
+      {
+        error: "Failed to list schedules",
+        schedules: [],
+      },
+      500
+    )
+  }
+})
+
+app.post("/schedule", async (c) => {
+  const request = await c.req.json<NewSchedule>()
+  console.log("[addSchedule] request: ", request)
+
+  try {
+    const schedule = await addSchedule(c.env, request)
+    if (!schedule) {
+      return c.json({ error: "Command not found", schedule: null }, 404)
+    }
+    return c.json({
+      error: null,
+      schedule,
+    })
+  } catch (error) {
+    console.error("[addSchedule] error: ", error)
+    return c.json(
+      {
+        error: "Failed to add schedule",
+        schedule: null,
+      },
+      500
+    )
+  }
+})
+
+type RemoveScheduleRequest = {
+  id: number
+}
+
+app.post("/remove_schedule", async (c) => {
+  const request = await c.req.json<RemoveScheduleRequest>()
+  console.log("[removeSchedule] request: ", request)
+
+  try {
+    const removed = await removeSchedule(c.env, request.id)
+    if (!removed) {
+      return c.json({ error: "Schedule not found" }, 404)
+    }
+    return c.json({
+      error: null,
+    })
+  } catch (error) {
+    console.error("[removeSchedule] error: ", error)
+    return c.json(
+      {
+        error: "Failed to remove schedule",
+      },
+      500
+    )
+  }
+})
+
+type ScheduleRanRequest = {
+  id: number
+  last_run_at?: string
+  next_run_at: string
+}
+
+app.post("/schedule_ran", async (c) => {
+  const request = await c.req.json<ScheduleRanRequest>()
+
+  try {
+    const updated = await markScheduleRan(
+      c.env,
+      request.id,
+      request.last_run_at ?? null,
+      request.next_run_at
+    )
+    if (!updated) {
+      return c.json({ error: "Schedule not found" }, 404)
+    }
+    return c.json({
+      error: null,
+    })
+  } catch (error) {
+    console.error("[markScheduleRan] error: ", error)
+    return c.json(
+      {
+        error: "Failed to update schedule",
+      },
+      500
+    )
+  }
+})
+
+type ListRemindersRequest = {
+  user_id?: string
+}
+
+app.post("/list_reminders", async (c) => {
+  const request = await c.req.json<ListRemindersRequest>()
+
+  try {
+    const reminders = await listReminders(c.env, request.user_id)
+    return c.json({
+      error: null,
+      reminders,
+    })
+  } catch (error) {
+    console.error("[listReminders] error: ", error)
+    return c.json(
+      {
+        error: "Failed to list reminders",
+        reminders: [],
+      },
+      500
+    )
+  }
+})
+
+app.post("/reminder", async (c) => {
+  const request = await c.req.json<NewReminder>()
+  console.log("[addReminder] request: ", request)
+
+  try {
+    const reminder = await addReminder(c.env, request)
+    return c.json({
+      error: null,
+      reminder,
+    })
+  } catch (error) {
+    console.error("[addReminder] error: ", error)
+    return c.json(
+      {
+        error: "Failed to add reminder",
+        reminder: null,
+      },
+      500
+    )
+  }
+})
+
+type RemoveReminderRequest = {
+  id: number
+}
+
+app.post("/remove_reminder", async (c) => {
+  const request = await c.req.json<RemoveReminderRequest>()
+  console.log("[removeReminder] request: ", request)
+
+  try {
+    const removed = await removeReminder(c.env, request.id)
+    if (!removed) {
+      return c.json({ error: "Reminder not found" }, 404)
+    }
+    return c.json({
+      error: null,
+    })
+  } catch (error) {
+    console.error("[removeReminder] error: ", error)
+    return c.json(
+      {
+        error: "Failed to remove reminder",
+      },
+      500
+    )
+  }
+})
+
+type UserTimezoneRequest = {
+  user_id: string
+  timezone?: string | null
+}
+
+app.post("/user_timezone", async (c) => {
+  const request = await c.req.json<UserTimezoneRequest>()
+
+  try {
+    const timezone = await getUserTimezone(c.env, request.user_id)
+    return c.json({
+      error: null,
+      timezone,
+    })
+  } catch (error) {
+    console.error("[getUserTimezone] error: ", error)
+    return c.json(
+      {
+        error: "Failed to get user timezone",
+        timezone: null,
+      },
+      500
+    )
+  }
+})
+
+app.post("/set_user_timezone", async (c) => {
+  const request = await c.req.json<UserTimezoneRequest>()
+  console.log("[setUserTimezone] request: ", request)
+
+  try {
+    await setUserTimezone(c.env, request.user_id, request.timezone ?? null)
+    return c.json({
+      error: null,
+    })
+  } catch (error) {
+    console.error("[setUserTimezone] error: ", error)
+    return c.json(
+      {
+        error: "Failed to set user timezone",
+      },
+      500
+    )
+  }
+})
+
+type ListEventTriggersRequest = {
+  guild_id?: string
+}
+
+app.post("/list_event_triggers", async (c) => {
+  const request = await c.req.json<ListEventTriggersRequest>()
+
+  try {
+    const triggers = await listEventTriggers(c.env, request.guild_id)
+    return c.json({
+      error: null,
+      triggers,
+    })
+  } catch (error) {
+    console.error("[listEventTriggers] error: ", error)
+    return c.json(
+      {
+        error: "Failed to list event triggers",
+        triggers: [],
+      },
+      500
+    )
+  }
+})
+
+app.post("/event_trigger", async (c) => {
+  const request = await c.req.json<NewEventTrigger>()
+  console.log("[addEventTrigger] request: ", request)
+
+  try {
+    const trigger = await addEventTrigger(c.env, request)
+    if (!trigger) {
+      return c.json({ error: "Command not found", trigger: null }, 404)
+    }
+    return c.json({
+      error: null,
+      trigger,
+    })
+  } catch (error) {
+    console.error("[addEventTrigger] error: ", error)
+    return c.json(
+      {
+        error: "Failed to add event trigger",
+        trigger: null,
+      },
+      500
+    )
+  }
+})
+
+type RemoveEventTriggerRequest = {
+  id: number
+}
+
+app.post("/remove_event_trigger", async (c) => {
+  const request = await c.req.json<RemoveEventTriggerRequest>()
+  console.log("[removeEventTrigger] request: ", request)
+
+  try {
+    const removed = await removeEventTrigger(c.env, request.id)
+    if (!removed) {
+      return c.json({ error: "Event trigger not found" }, 404)
+    }
+    return c.json({
+      error: null,
+    })
+  } catch (error) {
+    console.error("[removeEventTrigger] error: ", error)
+    return c.json(
+      {
+        error: "Failed to remove event trigger",
+      },
+      500
+    )
+  }
+})
+
+type ListAutoRespondersRequest = {
+  guild_id?: string
+}
+
+app.post("/list_auto_responders", async (c) => {
+  const request = await c.req.json<ListAutoRespondersRequest>()
+
+  try {
+    const responders = await listAutoResponders(c.env, request.guild_id)
+    const channels = await listAutoResponderChannels(c.env)
+    return c.json({
+      error: null,
+      responders,
+      channels,
+    })
+  } catch (error) {
+    console.error("[listAutoResponders] error: ", error)
+    return c.json(
+      {
+        error: "Failed to list auto responders",
+        responders: [],
+        channels: [],
+      },
+      500
+    )
+  }
+})
+
+app.post("/auto_responder", async (c) => {
+  const request = await c.req.json<NewAutoResponder>()
+  console.log("[addAutoResponder] request: ", request)
+
+  try {
+    const responder = await addAutoResponder(c.env, request)
+    if (!responder) {
+      return c.json({ error: "Command not found", responder: null }, 404)
+    }
+    return c.json({
+      error: null,
+      responder,
+    })
+  } catch (error) {
+    console.error("[addAutoResponder] error: ", error)
+    return c.json(
+      {
+        error: "Failed to add auto responder",
+        responder: null,
+      },
+      500
+    )
+  }
+})
+
+type RemoveAutoResponderRequest = {
+  id: number
+}
+
+app.post("/remove_auto_responder", async (c) => {
+  const request = await c.req.json<RemoveAutoResponderRequest>()
+  console.log("[removeAutoResponder] request: ", request)
+
+  try {
+    const removed = await removeAutoResponder(c.env, request.id)
+    if (!removed) {
+      return c.json({ error: "Auto responder not found" }, 404)
+    }
+    return c.json({
+      error: null,
+    })
+  } catch (error) {
+    console.error("[removeAutoResponder] error: ", error)
+    return c.json(
+      {
+        error: "Failed to remove auto responder",
+      },
+      500
+    )
+  }
+})
+
+type SetAutoResponderChannelRequest = {
+  channel_id: string
+  guild_id: string
+  enabled: boolean
+  user_id: string
+}
+
+app.post("/auto_responder_channel", async (c) => {
+  const request = await c.req.json<SetAutoResponderChannelRequest>()
+  console.log("[setAutoResponderChannel] request: ", request)
+
+  try {
+    await setAutoResponderChannel(
+      c.env,
+      request.channel_id,
+      request.guild_id,
+      request.enabled,
+      request.user_id
+    )
+    return c.json({
+      error: null,
+    })
+  } catch (error) {
+    console.error("[setAutoResponderChannel] error: ", error)
+    return c.json(
+      {
+        error: "Failed to set auto responder channel",
+      },
+      500
+    )
+  }
+})
+
+type RemoveTextResponseRequest = {
+  command_name: string
+  // 1 始まりの応答番号
+  index: number
+}
+
+app.post("/remove_text_response", async (c) => {
+  const request = await c.req.json<RemoveTextResponseRequest>()
+  console.log("[removeTextResponse] request: ", request)
+
+  try {
+    const result = await removeTextResponse(
+      c.env,
+      request.command_name,
+      request.index - 1
+    )
+    if (result === "not_found") {
+      return c.json({ error: "Response not found" }, 404)
+    }
+    if (result === "last") {
+      return c.json({ error: "Cannot remove the last response" }, 400)
+    }
+    return c.json({
+      error: null,
+    })
+  } catch (error) {
+    console.error("[removeTextResponse] error: ", error)
+    return c.json(
+      {
+        error: "Failed to remove response",
+      },
+      500
+    )
+  }
+})
+
+type SetTextResponseModeRequest = {
+  command_name: string
+  mode: string
+}
+
+app.post("/text_response_mode", async (c) => {
+  const request = await c.req.json<SetTextResponseModeRequest>()
+  console.log("[setTextResponseMode] request: ", request)
+
+  if (!(responseModes as readonly string[]).includes(request.mode)) {
+    return c.json({ error: `Unknown response mode: ${request.mode}` }, 400)
+  }
+
+  try {
+    const updated = await setTextResponseMode(
+      c.env,
+      request.command_name,
+      request.mode
+    )
+    if (!updated) {
+      return c.json({ error: "Command not found" }, 404)
+    }
+    return c.json({
+      error: null,
+    })
+  } catch (error) {
+    console.error("[setTextResponseMode] error: ", error)
+    return c.json(
+      {
+        error: "Failed to set response mode",
+      },
+      500
+    )
+  }
+})
+
+type ListSlashCommandsRequest = {
+  command_name: string
+}
+
+app.post("/list_slash_commands", async (c) => {
+  const request = await c.req.json<ListSlashCommandsRequest>()
+  console.log("[listSlashCommands] request: ", request)
+
+  try {
+    const slashCommands = await listSlashCommands(c.env, request.command_name)
+    return c.json({
+      error: null,
+      slash_commands: slashCommands,
+    })
+  } catch (error) {
+    console.error("[listSlashCommands] error: ", error)
+    return c.json(
+      {
+        error: "Failed to list slash commands",
+        slash_commands: [],
+      },
+      500
+    )
+  }
+})
+
+type MemoryRequest = {
+  key: string
+  content: string
+}
+
+app.post("/memory", async (c) => {
+  const request = await c.req.json<MemoryRequest>()
+  console.log("[memory] request: ", request)
+
+  try {
+    await storeMemory(c.env, request.key, request.content)
+    return c.json({
+      error: null,
+      success: true,
+    })
+  } catch (error) {
+    console.error("[memory] error: ", error)
+    return c.json(
+      {
+        error: "Failed to store memory",
+        success: false,
+      },
+      500
+    )
+  }
+})
+
+type MgetRequest = {
+  query: string
+  topK?: number
+}
+
+app.post("/mget", async (c) => {
+  const request = await c.req.json<MgetRequest>()
+  console.log("[mget] request: ", request)
+
+  try {
+    const results = await getMemory(c.env, request.query, request.topK ?? 3)
+    return c.json({
+      error: null,
+      results,
+    })
+  } catch (error) {
+    console.error("[mget] error: ", error)
+    return c.json(
+      {
+        error: "Failed to get memory",
+        results: [],
+      },
+      500
+    )
+  }
+})
+
+type AutoMemoryRequest = {
+  text: string
+}
+
+app.post("/automemory", async (c) => {
+  const request = await c.req.json<AutoMemoryRequest>()
+  console.log("[automemory] request: ", request)
+
+  try {
+    const count = await autoStoreMemory(c.env, request.text)
+    return c.json({
+      error: null,
+      count,
+    })
+  } catch (error) {
+    console.error("[automemory] error: ", error)
+    return c.json(
+      {
+        error: "Failed to auto-store memory",
+        count: 0,
+      },
+      500
+    )
+  }
+})
+
+type MLLMRequest = {
+  prompt: string
+  topK?: number
+}
+
+app.post("/mllm", async (c) => {
+  const request = await c.req.json<MLLMRequest>()
+  console.log("[mllm] request: ", request)
+
+  try {
+    const output = await memoryLLM(c.env, request.prompt, request.topK ?? 3)
+    return c.json({
+      error: null,
+      output,
+    })
+  } catch (error) {
+    console.error("[mllm] error: ", error)
+    return c.json(
+      {
+        error: "Failed to generate response with memory",
+        output: null,
+      },
+      500
+    )
+  }
+})
+
+type SmartRegisterRequest = {
+  command_name: string
+  description: string
+  author_id: string
+  admin?: boolean
+}
+
+app.post("/smart_register", async (c) => {
+  const request = await c.req.json<SmartRegisterRequest>()
+  console.log("[smartRegister] request: ", request)
+
+  // コードを生成する前に上書きできるか確かめる
+  if (
+    !(await canOverwriteCommand(
+      c.env,
+      request.command_name,
+      request.author_id,
+      request.admin
+    ))
+  ) {
+    return c.json(
+      {
+        error: "ロックされたコマンドを変更できるのは登録者か管理者だけです",
+        command_name: null,
+        generated_code: null,
+        usage: null,
+      },
+      403
+    )
+  }
+
+  try {
+    const generated = await generateCodeFromDescription(
+      c.env,
+      request.command_name,
+      request.description
+    )
+
+    const registered = await registerCommand(
+      c.env,
+      request.command_name,
+      generated.code,
+      true, // isCode
+      request.author_id,
+      {
+        metadata: {
+          description: request.description,
+          usage: generated.usage,
+        },
+        admin: request.admin,
+      }
+    )
+    if (registered === "locked") {
+      throw new Error(
+        "ロックされたコマンドを変更できるのは登録者か管理者だけです"
+      )
+    }
 
     return c.json({
       error: null,
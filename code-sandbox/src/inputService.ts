@@ -0,0 +1,62 @@
+/**
+ * コードコマンドに渡すメッセージの入力（添付ファイル・返信先のメッセージ）のサービス
+ * 添付ファイルの中身はコードに埋め込むと大きくなるため、実行ごとのディレクトリに JSON として書き、コードから読み込む
+ */
+import type { getSandbox } from "@cloudflare/sandbox"
+import { artifactDir } from "./artifactService"
+
+type Sandbox = ReturnType<typeof getSandbox>
+
+/**
+ * コードコマンドに渡す添付ファイル
+ * data は base64、大きすぎて Bot がダウンロードしなかったファイルは data を持たない
+ */
+export type CommandAttachment = {
+  id: string
+  filename: string
+  url: string
+  content_type?: string
+  size: number
+  data?: string
+}
+
+/**
+ * 返信先のメッセージ（メッセージのコンテキストメニューでは対象のメッセージ）
+ */
+export type RepliedMessage = {
+  id: string
+  channel_id: string
+  content: string
+  author_id: string
+  author_name: string
+  author_bot: boolean
+  attachments: CommandAttachment[]
+}
+
+export type CommandInput = {
+  attachments: CommandAttachment[]
+  replied: RepliedMessage | null
+}
+
+/**
+ * 入力を書くファイル名
+ */
+export const inputFileName = ".nelchan-input.json"
+
+/**
+ * 入力を実行ごとのディレクトリに書く
+ * 添付ファイルも返信先もない場合は何もしない（コード側は空の入力として扱う）
+ * ディレクトリは実行後に removeRunDir() が削除する
+ */
+export async function writeCommandInput(
+  sandbox: Sandbox,
+  runId: string,
+  input: CommandInput
+): Promise<void> {
+  if (input.attachments.length === 0 && !input.replied) {
+    return
+  }
+  const dir = artifactDir(runId)
+  await sandbox.mkdir(dir, { recursive: true })
+  await sandbox.writeFile(`${dir}/${inputFileName}`, JSON.stringify(input))
+}
@@ -0,0 +1,99 @@
+/**
+ * リマインダーサービス
+ * リマインダーの保存と取得を行う
+ * 配信はボット側で行い、配信したリマインダーは削除される
+ */
+
+/**
+ * リマインダー
+ */
+export type Reminder = {
+  id: number
+  user_id: string
+  guild_id: string | null
+  channel_id: string
+  message_id: string | null
+  text: string
+  remind_at: string
+  // reply, dm
+  delivery: string
+  created_at: string
+}
+
+/**
+ * リマインダーの登録内容
+ */
+export type NewReminder = {
+  user_id: string
+  guild_id?: string
+  channel_id: string
+  message_id?: string
+  text: string
+  remind_at: string
+  delivery: string
+}
+
+/**
+ * リマインダーを配信日時の早い順に一覧取得
+ * userId を指定するとそのユーザーのリマインダーだけを返す
+ */
+export async function listReminders(
+  env: Env,
+  userId?: string
+): Promise<Reminder[]> {
+  const where = userId ? "WHERE user_id = ?" : ""
+  const bindings = userId ? [userId] : []
+
+  const { results } = await env.nelchan_db
+    .prepare(
+      `SELECT id, user_id, guild_id, channel_id, message_id, text, remind_at, delivery, created_at
+       FROM reminders
+       ${where}
+       ORDER BY remind_at, id`
+    )
+    .bind(...bindings)
+    .all<Reminder>()
+  return results
+}
+
+/**
+ * リマインダーを登録
+ */
+export async function addReminder(
+  env: Env,
+  reminder: NewReminder
+): Promise<Reminder> {
+  const created = await env.nelchan_db
+    .prepare(
+      `INSERT INTO reminders (user_id, guild_id, channel_id, message_id, text, remind_at, delivery)
+       VALUES (?, ?, ?, ?, ?, ?, ?)
+       RETURNING id, user_id, guild_id, channel_id, message_id, text, remind_at, delivery, created_at`
+    )
+    .bind(
+      reminder.user_id,
+      reminder.guild_id ?? null,
+      reminder.channel_id,
+      reminder.message_id ?? null,
+      reminder.text,
+      reminder.remind_at,
+      reminder.delivery
+    )
+    .first<Reminder>()
+
+  if (!created) {
+    throw new Error("Failed to insert reminder")
+  }
+  return created
+}
+
+/**
+ * リマインダーを削除
+ * 存在しなかった場合は false を返す
+ */
+export async function removeReminder(env: Env, id: number): Promise<boolean> {
+  const result = await env.nelchan_db
+    .prepare(`DELETE FROM reminders WHERE id = ?`)
+    .bind(id)
+    .run()
+  return result.meta.changes > 0
+}
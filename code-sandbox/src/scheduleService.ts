@@ -0,0 +1,137 @@
+/**
+ * スケジュールサービス
+ * コードコマンドの定期実行スケジュールの保存と取得を行う
+ * cron 式の解釈と実行はボット側で行う
+ */
+
+/**
+ * 定期実行スケジュール
+ */
+export type Schedule = {
+  id: number
+  command_name: string
+  guild_id: string | null
+  channel_id: string
+  cron: string
+  timezone: string
+  args: string
+  // skip, run_once
+  missed_policy: string
+  author_id: string
+  next_run_at: string | null
+  last_run_at: string | null
+  created_at: string
+}
+
+/**
+ * スケジュールの登録内容
+ */
+export type NewSchedule = {
+  command_name: string
+  guild_id?: string
+  channel_id: string
+  cron: string
+  timezone: string
+  args?: string
+  missed_policy: string
+  author_id: string
+  next_run_at: string
+}
+
+/**
+ * スケジュールを一覧取得
+ * guildId を指定するとそのサーバーのスケジュールだけを返す
+ */
+export async function listSchedules(
+  env: Env,
+  guildId?: string
+): Promise<Schedule[]> {
+  const where = guildId ? "WHERE s.guild_id = ?" : ""
+  const bindings = guildId ? [guildId] : []
+
+  const { results } = await env.nelchan_db
+    .prepare(
+      `SELECT s.id, c.name AS command_name, s.guild_id, s.channel_id, s.cron, s.timezone,
+              s.args, s.missed_policy, s.author_id, s.next_run_at, s.last_run_at, s.created_at
+       FROM schedules s
+       INNER JOIN commands c ON c.id = s.command_id
+       ${where}
+       ORDER BY s.id`
+    )
+    .bind(...bindings)
+    .all<Schedule>()
+  return results
+}
+
+/**
+ * スケジュールを登録
+ * コマンドが存在しない場合は null を返す
+ */
+export async function addSchedule(
+  env: Env,
+  schedule: NewSchedule
+): Promise<Schedule | null> {
+  const command = await env.nelchan_db
+    .prepare(`SELECT id FROM commands WHERE name = ?`)
+    .bind(schedule.command_name)
+    .first<{ id: string }>()
+
+  if (!command) {
+    return null
+  }
+
+  const created = await env.nelchan_db
+    .prepare(
+      `INSERT INTO schedules (command_id, guild_id, channel_id, cron, timezone, args, missed_policy, author_id, next_run_at)
+       VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+       RETURNING id, guild_id, channel_id, cron, timezone, args, missed_policy, author_id, next_run_at, last_run_at, created_at`
+    )
+    .bind(
+      command.id,
+      schedule.guild_id ?? null,
+      schedule.channel_id,
+      schedule.cron,
+      schedule.timezone,
+      schedule.args ?? "",
+      schedule.missed_policy,
+      schedule.author_id,
+      schedule.next_run_at
+    )
+    .first<Omit<Schedule, "command_name">>()
+
+  if (!created) {
+    throw new Error("Failed to insert schedule")
+  }
+  return { ...created, command_name: schedule.command_name }
+}
+
+/**
+ * スケジュールを削除
+ * 存在しなかった場合は false を返す
+ */
+export async function removeSchedule(env: Env, id: number): Promise<boolean> {
+  const result = await env.nelchan_db
+    .prepare(`DELETE FROM schedules WHERE id = ?`)
+    .bind(id)
+    .run()
+  return result.meta.changes > 0
+}
+
+/**
+ * 実行後の最終実行日時と次回実行日時を保存
+ * 存在しなかった場合は false を返す
+ */
+export async function markScheduleRan(
+  env: Env,
+  id: number,
+  lastRunAt: string | null,
+  nextRunAt: string
+): Promise<boolean> {
+  const result = await env.nelchan_db
+    .prepare(
+      `UPDATE schedules SET last_run_at = COALESCE(?, last_run_at), next_run_at = ? WHERE id = ?`
+    )
+    .bind(lastRunAt, nextRunAt, id)
+    .run()
+  return result.meta.changes > 0
+}
@@ -0,0 +1,187 @@
+/**
+ * 統計サービス
+ * コマンドの実行記録と利用統計の集計を行う
+ */
+
+/**
+ * コマンドの実行記録
+ */
+export type CommandRun = {
+  command_name: string
+  user_id: string
+  channel_id?: string
+  guild_id?: string
+  // message, text, exec, mention, slash, schedule, event, auto
+  source: string
+  latency_ms: number
+  success: boolean
+  error?: string
+}
+
+/**
+ * コマンドごとの実行回数
+ */
+export type CommandRunCount = {
+  name: string
+  runs: number
+  failures: number
+  avgLatencyMs: number
+}
+
+/**
+ * 登録者ごとの実行回数
+ */
+export type AuthorRunCount = {
+  authorId: string
+  runs: number
+  commands: number
+}
+
+/**
+ * 日ごとの実行回数
+ */
+export type DailyRunCount = {
+  date: string
+  runs: number
+  failures: number
+}
+
+/**
+ * 利用統計
+ */
+export type CommandStats = {
+  topCommands: CommandRunCount[]
+  topAuthors: AuthorRunCount[]
+  failureRates: CommandRunCount[]
+  daily: DailyRunCount[]
+}
+
+// 集計期間（日）
+const STATS_DAYS = 30
+// ランキングの件数
+const STATS_TOP = 10
+// 失敗率のランキングに載せる最小の実行回数
+const FAILURE_RATE_MIN_RUNS = 5
+
+/**
+ * コマンドの実行を記録し、実行回数と最終実行日時を更新
+ */
+export async function recordCommandRun(
+  env: Env,
+  run: CommandRun
+): Promise<void> {
+  await env.nelchan_db.batch([
+    env.nelchan_db
+      .prepare(
+        `INSERT INTO command_runs (id, command_name, user_id, channel_id, guild_id, source, latency_ms, success, error)
+         VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`
+      )
+      .bind(
+        crypto.randomUUID(),
+        run.command_name,
+        run.user_id,
+        run.channel_id ?? null,
+        run.guild_id ?? null,
+        run.source,
+        Math.round(run.latency_ms),
+        run.success ? 1 : 0,
+        run.error ?? null
+      ),
+    env.nelchan_db
+      .prepare(
+        `UPDATE commands SET use_count = use_count + 1, last_used_at = datetime('now') WHERE name = ?`
+      )
+      .bind(run.command_name),
+  ])
+}
+
+/**
+ * 直近30日間の利用統計を集計
+ * guildId を指定するとそのサーバーでの実行だけを集計する
+ */
+export async function getCommandStats(
+  env: Env,
+  guildId?: string
+): Promise<CommandStats> {
+  const since = `-${STATS_DAYS} days`
+  const where = guildId
+    ? "r.created_at >= datetime('now', ?1) AND r.guild_id = ?2"
+    : "r.created_at >= datetime('now', ?1)"
+  const bindings = guildId ? [since, guildId] : [since]
+
+  const [commands, authors, daily] = await env.nelchan_db.batch([
+    env.nelchan_db
+      .prepare(
+        `SELECT r.command_name AS name,
+                COUNT(*) AS runs,
+                SUM(CASE WHEN r.success = 0 THEN 1 ELSE 0 END) AS failures,
+                AVG(r.latency_ms) AS avg_latency_ms
+         FROM command_runs r
+         WHERE ${where}
+         GROUP BY r.command_name`
+      )
+      .bind(...bindings),
+    env.nelchan_db
+      .prepare(
+        `SELECT c.author_id,
+                COUNT(*) AS runs,
+                COUNT(DISTINCT c.id) AS commands
+         FROM command_runs r
+         INNER JOIN commands c ON c.name = r.command_name
+         WHERE ${where}
+         GROUP BY c.author_id
+         ORDER BY runs DESC
+         LIMIT ${STATS_TOP}`
+      )
+      .bind(...bindings),
+    env.nelchan_db
+      .prepare(
+        `SELECT date(r.created_at) AS date,
+                COUNT(*) AS runs,
+                SUM(CASE WHEN r.success = 0 THEN 1 ELSE 0 END) AS failures
+         FROM command_runs r
+         WHERE ${where}
+         GROUP BY date(r.created_at)
+         ORDER BY date`
+      )
+      .bind(...bindings),
+  ])
+
+  const counts = (
+    (commands.results ?? []) as {
+      name: string
+      runs: number
+      failures: number
+      avg_latency_ms: number
+    }[]
+  ).map((r) => ({
+    name: r.name,
+    runs: r.runs,
+    failures: r.failures,
+    avgLatencyMs: Math.round(r.avg_latency_ms),
+  }))
+
+  const failureRate = (c: CommandRunCount) => c.failures / c.runs
+
+  return {
+    topCommands: [...counts]
+      .sort((a, b) => b.runs - a.runs || a.name.localeCompare(b.name))
+      .slice(0, STATS_TOP),
+    topAuthors: (
+      (authors.results ?? []) as {
+        author_id: string
+        runs: number
+        commands: number
+      }[]
+    ).map((r) => ({
+      authorId: r.author_id,
+      runs: r.runs,
+      commands: r.commands,
+    })),
+    failureRates: counts
+      .filter((c) => c.runs >= FAILURE_RATE_MIN_RUNS && c.failures > 0)
+      .sort((a, b) => failureRate(b) - failureRate(a) || b.runs - a.runs)
+      .slice(0, STATS_TOP),
+    daily: (daily.results ?? []) as DailyRunCount[],
+  }
+}
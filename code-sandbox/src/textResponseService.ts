@@ -0,0 +1,166 @@
+/**
+ * テキストコマンドの応答サービス
+ * テキストコマンドは複数の応答（dictionaries の行）を持てる
+ * どの応答を返すかはボット側で response_mode に従って選ぶ
+ */
+
+/**
+ * テキストコマンドの応答
+ */
+export type TextResponse = {
+  text: string
+  weight: number
+}
+
+/**
+ * 応答の選び方
+ */
+export const responseModes = ["random", "round_robin", "weighted"] as const
+
+/**
+ * テキストコマンドの応答を登録順に取得
+ */
+export async function listTextResponses(
+  env: Env,
+  commandId: string
+): Promise<TextResponse[]> {
+  const { results } = await env.nelchan_db
+    .prepare(
+      `SELECT text, weight FROM dictionaries
+       WHERE command_id = ?
+       ORDER BY position, rowid`
+    )
+    .bind(commandId)
+    .all<TextResponse>()
+  return results
+}
+
+/**
+ * テキストコマンドの応答をすべて置き換える
+ */
+export async function replaceTextResponses(
+  env: Env,
+  commandId: string,
+  responses: TextResponse[]
+): Promise<void> {
+  await env.nelchan_db.batch([
+    env.nelchan_db
+      .prepare(`DELETE FROM dictionaries WHERE command_id = ?`)
+      .bind(commandId),
+    ...responses.map((response, position) =>
+      env.nelchan_db
+        .prepare(
+          `INSERT INTO dictionaries (id, command_id, text, weight, position) VALUES (?, ?, ?, ?, ?)`
+        )
+        .bind(
+          crypto.randomUUID(),
+          commandId,
+          response.text,
+          normalizeWeight(response.weight),
+          position
+        )
+    ),
+  ])
+}
+
+/**
+ * テキストコマンドの最後に応答を追加
+ */
+export async function appendTextResponse(
+  env: Env,
+  commandId: string,
+  response: TextResponse
+): Promise<void> {
+  await env.nelchan_db
+    .prepare(
+      `INSERT INTO dictionaries (id, command_id, text, weight, position)
+       VALUES (?, ?, ?, ?, (SELECT COALESCE(MAX(position), -1) + 1 FROM dictionaries WHERE command_id = ?))`
+    )
+    .bind(
+      crypto.randomUUID(),
+      commandId,
+      response.text,
+      normalizeWeight(response.weight),
+      commandId
+    )
+    .run()
+}
+
+/**
+ * テキストコマンドの index 番目（0 始まり）の応答を削除
+ * 最後の 1 つは削除せず "last" を返す
+ */
+export async function removeTextResponse(
+  env: Env,
+  commandName: string,
+  index: number
+): Promise<"removed" | "not_found" | "last"> {
+  const command = await env.nelchan_db
+    .prepare(`SELECT id FROM commands WHERE name = ?`)
+    .bind(commandName)
+    .first<{ id: string }>()
+  if (!command) {
+    return "not_found"
+  }
+
+  const { results } = await env.nelchan_db
+    .prepare(
+      `SELECT id FROM dictionaries WHERE command_id = ? ORDER BY position, rowid`
+    )
+    .bind(command.id)
+    .all<{ id: string }>()
+  if (index < 0 || index >= results.length) {
+    return "not_found"
+  }
+  if (results.length === 1) {
+    return "last"
+  }
+
+  await env.nelchan_db
+    .prepare(`DELETE FROM dictionaries WHERE id = ?`)
+    .bind(results[index].id)
+    .run()
+  return "removed"
+}
+
+/**
+ * テキストコマンドの応答の選び方を設定
+ * コマンドが存在しない場合は false を返す
+ */
+export async function setTextResponseMode(
+  env: Env,
+  commandName: string,
+  mode: string
+): Promise<boolean> {
+  const result = await env.nelchan_db
+    .prepare(
+      `UPDATE commands SET response_mode = ?, response_cursor = 0 WHERE name = ?`
+    )
+    .bind(mode, commandName)
+    .run()
+  return result.meta.changes > 0
+}
+
+/**
+ * round_robin で次に返す応答の番号を取得して進める
+ */
+export async function nextResponseCursor(
+  env: Env,
+  commandId: string
+): Promise<number> {
+  const row = await env.nelchan_db
+    .prepare(
+      `UPDATE commands SET response_cursor = response_cursor + 1
+       WHERE id = ?
+       RETURNING response_cursor - 1 AS cursor`
+    )
+    .bind(commandId)
+    .first<{ cursor: number }>()
+  return row?.cursor ?? 0
+}
+
+/**
+ * 重みを 1 以上の整数にする
+ */
+const normalizeWeight = (weight: number | undefined) =>
+  Number.isInteger(weight) && weight! > 0 ? weight! : 1
@@ -1,5 +1,20 @@
 import { getSandbox } from "@cloudflare/sandbox"
 import { generateEmbedding } from "./vectorService"
+import {
+  artifactDir,
+  artifactManifestName,
+  collectArtifacts,
+  removeRunDir,
+} from "./artifactService"
+import { inputFileName, writeCommandInput } from "./inputService"
+import type { CommandInput } from "./inputService"
+import {
+  appendTextResponse,
+  listTextResponses,
+  nextResponseCursor,
+  replaceTextResponses,
+} from "./textResponseService"
+import type { TextResponse } from "./textResponseService"
 
 type NelchanGetCommandResult = {
   id: string
@@ -9,6 +24,7 @@ type NelchanGetCommandResult = {
   text?: string
   code_id?: string
   code?: string
+  response_mode?: string
 }
 
 const getCommandContent = (result: NelchanGetCommandResult) => {
@@ -21,13 +37,19 @@ const getCommandContent = (result: NelchanGetCommandResult) => {
   throw new Error("No command content found")
 }
 
+// 変数名として埋め込めるキー（値はニックネームなどユーザーが決めた文字列を含むため JSON 文字列として埋め込む）
+const pythonIdentifierRe = /^[A-Za-z_][A-Za-z0-9_]*$/
+
 export const runCommand = async (
   exCtx: ExecutionContext,
   env: Env,
   commandName: string,
   isCode: boolean,
   envVars: Record<string, string>,
-  args: string[]
+  args: string[],
+  named: Record<string, unknown> = {},
+  input: CommandInput = { attachments: [], replied: null },
+  context: Record<string, unknown> = {}
 ) => {
   // Query based on command type
   const query = isCode
@@ -44,11 +66,13 @@ export const runCommand = async (
         c.id, 
         c.name, 
         c.author_id, 
+        c.response_mode, 
         d.id as dictionary_id, 
         d.text 
         FROM commands c  
         INNER JOIN dictionaries d ON c.id = d.command_id 
-        WHERE c.name = ? LIMIT 1`
+        WHERE c.name = ? 
+        ORDER BY d.position, d.rowid LIMIT 1`
 
   const result = await env.nelchan_db
     .prepare(query)
@@ -64,18 +88,79 @@ export const runCommand = async (
     const sandboxId = "nelchan-sandbox"
     console.log(`[runCommand] using sandbox: ${sandboxId}`)
     const sandbox = getSandbox(env.Sandbox, sandboxId)
+    // 実行ごとに成果物のディレクトリを分ける（サンドボックスは使い回すため）
+    const runId = crypto.randomUUID()
     try {
+      await writeCommandInput(sandbox, runId, input)
       const apiKey = env.NELCHAN_API_KEY
       const envEmbededCode = `
+import base64
+import json
 import requests
+ARTIFACT_DIR = "${artifactDir(runId)}"
 ${Object.entries(envVars)
-  .map(([key, value]) => `${key}="${value}"`)
+  .filter(([key]) => pythonIdentifierRe.test(key))
+  .map(([key, value]) => `${key} = ${JSON.stringify(String(value ?? ""))}`)
   .join("\n")}
 args = ${JSON.stringify(args)}
+kwargs = json.loads(${JSON.stringify(JSON.stringify(named))})
+ctx = json.loads(${JSON.stringify(JSON.stringify(context))})
+
+def _load_input():
+    import os
+    path = os.path.join(ARTIFACT_DIR, "${inputFileName}")
+    if not os.path.exists(path):
+        return [], None
+    with open(path) as f:
+        loaded = json.load(f)
+    def files(items):
+        return [dict(a, data=base64.b64decode(a["data"]) if a.get("data") else None) for a in items]
+    replied = loaded.get("replied")
+    if replied:
+        replied["attachments"] = files(replied.get("attachments") or [])
+    return files(loaded.get("attachments") or []), replied
+
+attachments, replied = _load_input()
 
 def cs(s: str):
     return f"\`\`\`{s}\`\`\`"
 
+def attach(name: str, data, content_type: str = None):
+    if isinstance(data, str):
+        data = data.encode()
+    file = {"name": name, "data": base64.b64encode(data).decode()}
+    if content_type:
+        file["content_type"] = content_type
+    return file
+
+def save_file(name: str, data, content_type: str = None):
+    import mimetypes, os
+    if isinstance(data, str):
+        data = data.encode()
+    name = os.path.basename(name)
+    os.makedirs(ARTIFACT_DIR, exist_ok=True)
+    with open(os.path.join(ARTIFACT_DIR, name), "wb") as f:
+        f.write(data)
+    manifest_path = os.path.join(ARTIFACT_DIR, "${artifactManifestName}")
+    manifest = []
+    if os.path.exists(manifest_path):
+        with open(manifest_path) as f:
+            manifest = [e for e in json.load(f) if e["name"] != name]
+    manifest.append({"name": name, "content_type": content_type or mimetypes.guess_type(name)[0] or "application/octet-stream", "size": len(data)})
+    with open(manifest_path, "w") as f:
+        json.dump(manifest, f)
+
+def save_figure(name: str = "figure.png", fig=None):
+    import io
+    import matplotlib.pyplot as plt
+    buf = io.BytesIO()
+    (fig or plt.gcf()).savefig(buf, format="png", bbox_inches="tight")
+    save_file(name, buf.getvalue(), "image/png")
+
+def send(content: str = None, embeds: list = None, files: list = None, reactions: list = None, components: list = None, allowed_mentions: dict = None):
+    message = {"nelchan": 1, "content": content, "embeds": embeds, "files": files, "reactions": reactions, "components": components, "allowed_mentions": allowed_mentions}
+    print(json.dumps({k: v for k, v in message.items() if v is not None}, ensure_ascii=False))
+
 def llm(prompt: str):
   return requests.post("https://my-sandbox.sh1ma.workers.dev/llm", json={"prompt": prompt}, headers={"Authorization": "Bearer ${apiKey}"}).json()["output"]
 
@@ -100,41 +185,138 @@ ${result.code}
       const executionResult = await sandbox.runCode(envEmbededCode)
       console.log("[runCommand] executionResult: ", executionResult)
       const codeResultOutput = executionResult.logs.stdout.join("\n")
+      const artifacts = await collectArtifacts(sandbox, runId)
       return {
         id: result.id,
         name: result.name,
         content: codeResultOutput,
+        artifacts,
       }
     } catch (error) {
       console.error("[runCommand] error: ", error)
       throw new Error("Error running code")
+    } finally {
+      // 入力の書き込みや実行が失敗しても、入力ファイルと成果物を残さない
+      await removeRunDir(sandbox, runId)
     }
     // destroy()を呼ばないことでコンテナを再利用する
   }
 
   if (!isCode && result.text) {
+    // 応答が複数ある場合はボット側で response_mode に従って選ぶ
+    const responses = await listTextResponses(env, result.id)
+    const responseMode = result.response_mode ?? "random"
+    const responseCursor =
+      responses.length > 1 && responseMode === "round_robin"
+        ? await nextResponseCursor(env, result.id)
+        : 0
     return {
       id: result.id,
       name: result.name,
       content: result.text,
+      responses,
+      response_mode: responseMode,
+      response_cursor: responseCursor,
     }
   }
 
   throw new Error("Invalid command content type")
 }
 
+/**
+ * Optional attributes stored alongside a command
+ */
+export type RegisterCommandOptions = {
+  // "# args" schema of a code command (ArgOption list)
+  argsSchema?: unknown[] | null
+  // Description, usage, tags and category; existing metadata is kept when omitted
+  metadata?: CommandMetadata | null
+  // Add the text as another response of an existing text command instead of replacing it
+  append?: boolean
+  // Weight of the text for weighted response mode
+  weight?: number
+  // All responses of a text command, replacing the text (used by imports)
+  responses?: TextResponse[] | null
+  // How a text command with several responses picks one
+  responseMode?: string | null
+  // The registering user is an admin, who may overwrite locked commands they don't own
+  admin?: boolean
+}
+
+/**
+ * Optional metadata describing a command
+ */
+export type CommandMetadata = {
+  description?: string
+  usage?: string
+  tags?: string[]
+  category?: string
+}
+
+/**
+ * Whether a user may register a command over the one named commandName
+ * Locked commands may only be overwritten by their owner and admins, unlocked ones by everyone
+ */
+export const canOverwriteCommand = async (
+  env: Env,
+  commandName: string,
+  authorID: string,
+  admin: boolean = false
+) => {
+  const existing = await env.nelchan_db
+    .prepare(`SELECT author_id, locked FROM commands WHERE name = ?`)
+    .bind(commandName)
+    .first<{ author_id: string | null; locked: number }>()
+
+  return (
+    !existing ||
+    existing.locked === 0 ||
+    admin ||
+    (!!existing.author_id && existing.author_id === authorID)
+  )
+}
+
+/**
+ * Register a command (upsert)
+ * @returns "locked" when the command exists and the user may not overwrite it
+ */
 export const registerCommand = async (
   env: Env,
   commandName: string,
   commandContent: string,
   isCode: boolean,
-  authorID: string
-) => {
+  authorID: string,
+  options: RegisterCommandOptions = {}
+): Promise<"registered" | "locked"> => {
+  const allowed = await canOverwriteCommand(
+    env,
+    commandName,
+    authorID,
+    options.admin
+  )
+  if (!allowed) {
+    console.log("[registerCommand] command is locked", commandName, authorID)
+    return "locked"
+  }
+
   if (isCode) {
-    await registerCodeCommand(env, commandName, commandContent, authorID)
+    await registerCodeCommand(
+      env,
+      commandName,
+      commandContent,
+      authorID,
+      options
+    )
   } else {
-    await registerTextCommand(env, commandName, commandContent, authorID)
+    await registerTextCommand(
+      env,
+      commandName,
+      commandContent,
+      authorID,
+      options
+    )
   }
+  return "registered"
 }
 
 /*
@@ -143,13 +325,19 @@ export const registerCommand = async (
  * @param commandName - The name of the command
  * @param commandContent - The content of the command
  * @param authorID - The ID of the author
+ * @param options - Optional attributes such as the args schema
  */
 export const registerCodeCommand = async (
   env: Env,
   commandName: string,
   commandContent: string,
-  authorID: string
+  authorID: string,
+  options: RegisterCommandOptions = {}
 ) => {
+  const argsSchema = options.argsSchema
+    ? JSON.stringify(options.argsSchema)
+    : null
+
   console.log("[registerCodeCommand] commandName: ", commandName)
   console.log("[registerCodeCommand] commandContent: ", commandContent)
 
@@ -178,8 +366,8 @@ export const registerCodeCommand = async (
         existingCommand.dictionary_id
       )
       await env.nelchan_db
-        .prepare(`DELETE FROM dictionaries WHERE id = ?`)
-        .bind(existingCommand.dictionary_id)
+        .prepare(`DELETE FROM dictionaries WHERE command_id = ?`)
+        .bind(existingCommand.id)
         .run()
     }
 
@@ -197,15 +385,40 @@ export const registerCodeCommand = async (
         .bind(codeID, existingCommand.id, commandContent)
         .run()
     }
+
+    await env.nelchan_db
+      .prepare(
+        `UPDATE commands SET args_schema = ?, updated_at = datetime('now') WHERE id = ?`
+      )
+      .bind(argsSchema, existingCommand.id)
+      .run()
+    await recordCommandVersion(
+      env,
+      existingCommand.id,
+      true,
+      commandContent,
+      argsSchema,
+      authorID
+    )
+    await updateCommandMetadata(env, existingCommand.id, options.metadata)
     console.log("[registerCodeCommand] command updated")
   } else {
     const commandID = crypto.randomUUID()
     const codeID = crypto.randomUUID()
 
+    // A new command takes over its name from an alias
+    await env.nelchan_db
+      .prepare(`DELETE FROM command_aliases WHERE alias = ?`)
+      .bind(commandName)
+      .run()
+
     console.log("[registerCodeCommand] INSERT commands table", commandID)
     await env.nelchan_db
-      .prepare(`INSERT INTO commands (id, name, author_id) VALUES (?, ?, ?)`)
-      .bind(commandID, commandName, authorID)
+      .prepare(
+        `INSERT INTO commands (id, name, author_id, args_schema, created_at, updated_at) 
+         VALUES (?, ?, ?, ?, datetime('now'), datetime('now'))`
+      )
+      .bind(commandID, commandName, authorID, argsSchema)
       .run()
 
     console.log("[registerCodeCommand] INSERT codes table", codeID)
@@ -213,22 +426,255 @@ export const registerCodeCommand = async (
       .prepare(`INSERT INTO codes (id, command_id, code) VALUES (?, ?, ?)`)
       .bind(codeID, commandID, commandContent)
       .run()
+    await recordCommandVersion(
+      env,
+      commandID,
+      true,
+      commandContent,
+      argsSchema,
+      authorID
+    )
+    await updateCommandMetadata(env, commandID, options.metadata)
 
     console.log("[registerCodeCommand] command registered")
   }
 }
 
+/**
+ * Replace the metadata of a command
+ * @param env - The environment
+ * @param commandID - The ID of the command
+ * @param metadata - The new metadata, or undefined to keep the current one
+ */
+const updateCommandMetadata = async (
+  env: Env,
+  commandID: string,
+  metadata: CommandMetadata | null | undefined
+) => {
+  if (metadata === undefined) {
+    return
+  }
+
+  const tags = metadata?.tags?.length ? JSON.stringify(metadata.tags) : null
+  await env.nelchan_db
+    .prepare(
+      `UPDATE commands SET description = ?, usage = ?, tags = ?, category = ? WHERE id = ?`
+    )
+    .bind(
+      metadata?.description || null,
+      metadata?.usage || null,
+      tags,
+      metadata?.category || null,
+      commandID
+    )
+    .run()
+}
+
+/**
+ * Build the metadata of a command from its columns
+ */
+const toCommandMetadata = (row: {
+  description: string | null
+  usage: string | null
+  tags: string | null
+  category: string | null
+}): CommandMetadata => {
+  const metadata: CommandMetadata = {}
+  if (row.description) {
+    metadata.description = row.description
+  }
+  if (row.usage) {
+    metadata.usage = row.usage
+  }
+  if (row.tags) {
+    try {
+      const tags = JSON.parse(row.tags)
+      if (Array.isArray(tags)) {
+        metadata.tags = tags.map(String)
+      }
+    } catch (error) {
+      console.error("[toCommandMetadata] invalid tags: ", error)
+    }
+  }
+  if (row.category) {
+    metadata.category = row.category
+  }
+  return metadata
+}
+
+/**
+ * Append an immutable version to the history of a command
+ * @param env - The environment
+ * @param commandID - The ID of the command
+ * @param isCode - Whether the version is code or text
+ * @param content - The code or text of the version
+ * @param argsSchema - The JSON encoded args schema of a code command
+ * @param authorID - The ID of the user who made the change
+ */
+const recordCommandVersion = async (
+  env: Env,
+  commandID: string,
+  isCode: boolean,
+  content: string,
+  argsSchema: string | null,
+  authorID: string
+) => {
+  await env.nelchan_db
+    .prepare(
+      `INSERT INTO command_versions (id, command_id, version, is_code, content, args_schema, author_id) 
+       SELECT ?, ?, COALESCE(MAX(version), 0) + 1, ?, ?, ?, ? 
+       FROM command_versions WHERE command_id = ?`
+    )
+    .bind(
+      crypto.randomUUID(),
+      commandID,
+      isCode ? 1 : 0,
+      content,
+      argsSchema,
+      authorID,
+      commandID
+    )
+    .run()
+}
+
+export type CommandVersion = {
+  version: number
+  isCode: boolean
+  author_id: string
+  created_at: string
+  content?: string
+  args_schema?: unknown[] | null
+}
+
+type CommandVersionRow = {
+  version: number
+  is_code: number
+  author_id: string
+  created_at: string
+  content: string
+  args_schema: string | null
+}
+
+/**
+ * List the versions of a command, newest first
+ * @param env - The environment
+ * @param commandName - The name of the command
+ * @param limit - The maximum number of versions
+ * @returns The versions without their content, or undefined if the command doesn't exist
+ */
+export const getCommandHistory = async (
+  env: Env,
+  commandName: string,
+  limit: number
+): Promise<CommandVersion[] | undefined> => {
+  const command = await env.nelchan_db
+    .prepare(`SELECT id FROM commands WHERE name = ?`)
+    .bind(commandName)
+    .first<{ id: string }>()
+
+  if (!command) {
+    return undefined
+  }
+
+  const result = await env.nelchan_db
+    .prepare(
+      `SELECT version, is_code, author_id, created_at 
+       FROM command_versions 
+       WHERE command_id = ? 
+       ORDER BY version DESC 
+       LIMIT ?`
+    )
+    .bind(command.id, limit)
+    .all<Omit<CommandVersionRow, "content" | "args_schema">>()
+
+  return (result.results ?? []).map((row) => ({
+    version: row.version,
+    isCode: row.is_code === 1,
+    author_id: row.author_id,
+    created_at: row.created_at,
+  }))
+}
+
+/**
+ * Get a single version of a command with its content
+ * @param env - The environment
+ * @param commandName - The name of the command
+ * @param version - The version number, or the latest version if omitted
+ * @returns The version or undefined if not found
+ */
+export const getCommandVersion = async (
+  env: Env,
+  commandName: string,
+  version?: number
+): Promise<CommandVersion | undefined> => {
+  const query = version
+    ? `SELECT v.version, v.is_code, v.author_id, v.created_at, v.content, v.args_schema 
+       FROM command_versions v 
+       INNER JOIN commands c ON c.id = v.command_id 
+       WHERE c.name = ? AND v.version = ?`
+    : `SELECT v.version, v.is_code, v.author_id, v.created_at, v.content, v.args_schema 
+       FROM command_versions v 
+       INNER JOIN commands c ON c.id = v.command_id 
+       WHERE c.name = ? 
+       ORDER BY v.version DESC 
+       LIMIT 1`
+
+  const statement = env.nelchan_db.prepare(query)
+  const row = await (version
+    ? statement.bind(commandName, version)
+    : statement.bind(commandName)
+  ).first<CommandVersionRow>()
+
+  if (!row) {
+    return undefined
+  }
+
+  return {
+    version: row.version,
+    isCode: row.is_code === 1,
+    author_id: row.author_id,
+    created_at: row.created_at,
+    content: row.content,
+    args_schema: parseArgsSchema(row.args_schema),
+  }
+}
+
 /*
  * Register a text command (upsert)
  * @param env - The environment
  * @param commandName - The name of the command
  * @param commandContent - The content of the command
  * @param authorID - The ID of the author
+ * @param options - Optional attributes such as the metadata
  */
 export type GetCommandResult = {
   name: string
   isCode: boolean
   content: string
+  author_id: string
+  args_schema: unknown[] | null
+  metadata: CommandMetadata
+  last_used_at: string | null
+  locked: boolean
+  // All responses of a text command, the first one is the content
+  responses?: TextResponse[]
+  response_mode?: string
+}
+
+/**
+ * Parse a JSON encoded args schema column
+ */
+const parseArgsSchema = (raw: string | null): unknown[] | null => {
+  if (!raw) {
+    return null
+  }
+  try {
+    const parsed = JSON.parse(raw)
+    return Array.isArray(parsed) ? parsed : null
+  } catch (error) {
+    console.error("[parseArgsSchema] invalid args_schema: ", error)
+    return null
+  }
 }
 
 /**
@@ -247,19 +693,44 @@ export const getCommand = async (
       `SELECT 
         c.id, 
         c.name, 
+        c.author_id, 
+        c.args_schema, 
+        c.description, 
+        c.usage, 
+        c.tags, 
+        c.category, 
+        c.last_used_at, 
+        c.locked, 
         co.code 
         FROM commands c  
         INNER JOIN codes co ON c.id = co.command_id 
         WHERE c.name = ? LIMIT 1`
     )
     .bind(commandName)
-    .first<{ id: string; name: string; code: string }>()
+    .first<{
+      id: string
+      name: string
+      author_id: string
+      args_schema: string | null
+      description: string | null
+      usage: string | null
+      tags: string | null
+      category: string | null
+      last_used_at: string | null
+      locked: number
+      code: string
+    }>()
 
   if (codeResult && codeResult.code) {
     return {
       name: codeResult.name,
       isCode: true,
       content: codeResult.code,
+      author_id: codeResult.author_id,
+      args_schema: parseArgsSchema(codeResult.args_schema),
+      metadata: toCommandMetadata(codeResult),
+      last_used_at: codeResult.last_used_at,
+      locked: codeResult.locked !== 0,
     }
   }
 
@@ -269,46 +740,664 @@ export const getCommand = async (
       `SELECT 
         c.id, 
         c.name, 
+        c.author_id, 
+        c.description, 
+        c.usage, 
+        c.tags, 
+        c.category, 
+        c.last_used_at, 
+        c.locked, 
+        c.response_mode, 
         d.text 
         FROM commands c  
         INNER JOIN dictionaries d ON c.id = d.command_id 
-        WHERE c.name = ? LIMIT 1`
+        WHERE c.name = ? 
+        ORDER BY d.position, d.rowid LIMIT 1`
     )
     .bind(commandName)
-    .first<{ id: string; name: string; text: string }>()
+    .first<{
+      id: string
+      name: string
+      author_id: string
+      description: string | null
+      usage: string | null
+      tags: string | null
+      category: string | null
+      last_used_at: string | null
+      locked: number
+      response_mode: string
+      text: string
+    }>()
 
   if (textResult && textResult.text) {
     return {
       name: textResult.name,
       isCode: false,
       content: textResult.text,
+      author_id: textResult.author_id,
+      args_schema: null,
+      metadata: toCommandMetadata(textResult),
+      last_used_at: textResult.last_used_at,
+      locked: textResult.locked !== 0,
+      responses: await listTextResponses(env, textResult.id),
+      response_mode: textResult.response_mode,
     }
   }
 
   return undefined
 }
 
+export type CommandSummary = {
+  name: string
+  isCode: boolean
+  description: string | null
+  category: string | null
+  authorId: string
+  useCount: number
+  updatedAt: string | null
+}
+
+/**
+ * A page of commands with the number of commands matching the filter
+ */
+export type CommandPage = {
+  commands: CommandSummary[]
+  total: number
+}
+
+/**
+ * Order of command listings
+ */
+export type CommandSort = "name" | "recent" | "popular" | "author"
+
+/**
+ * Filters and paging of a command listing
+ */
+export type ListCommandsOptions = {
+  // Substring of the command name (empty matches all)
+  query?: string
+  // Only text or only code commands
+  type?: "text" | "code"
+  // Only commands registered by this user
+  authorId?: string
+  sort?: CommandSort
+  limit?: number
+  offset?: number
+}
+
+/**
+ * Escape LIKE wildcards so user input is matched literally
+ */
+const escapeLike = (value: string) => value.replace(/[\\%_]/g, (c) => `\\${c}`)
+
+const commandOrderBy: Record<CommandSort, string> = {
+  name: "c.name",
+  recent: "COALESCE(c.updated_at, c.created_at) DESC, c.name",
+  popular: "c.use_count DESC, c.name",
+  author: "c.author_id, c.name",
+}
+
+type CommandSummaryRow = {
+  name: string
+  description: string | null
+  category: string | null
+  author_id: string
+  use_count: number | null
+  updated_at: string | null
+  is_code: number
+}
+
+const commandSummaryColumns = `
+        c.name, 
+        c.description, 
+        c.category, 
+        c.author_id, 
+        c.use_count, 
+        c.updated_at, 
+        CASE WHEN co.id IS NOT NULL THEN 1 ELSE 0 END AS is_code`
+
+const toCommandSummary = (r: CommandSummaryRow): CommandSummary => ({
+  name: r.name,
+  isCode: r.is_code === 1,
+  description: r.description,
+  category: r.category,
+  authorId: r.author_id,
+  useCount: r.use_count ?? 0,
+  updatedAt: r.updated_at,
+})
+
+/**
+ * List registered commands matching the filters
+ * @param env - The environment
+ * @param options - Filters, order and paging
+ * @returns The requested page and the number of matching commands
+ */
+export const listCommands = async (
+  env: Env,
+  options: ListCommandsOptions = {}
+): Promise<CommandPage> => {
+  const conditions = ["c.name LIKE ? ESCAPE '\\'"]
+  const bindings: unknown[] = [`%${escapeLike(options.query ?? "")}%`]
+  if (options.type === "code") {
+    conditions.push("co.id IS NOT NULL")
+  } else if (options.type === "text") {
+    conditions.push("co.id IS NULL")
+  }
+  if (options.authorId) {
+    conditions.push("c.author_id = ?")
+    bindings.push(options.authorId)
+  }
+  const where = conditions.join(" AND ")
+
+  const [result, count] = await env.nelchan_db.batch([
+    env.nelchan_db
+      .prepare(
+        `SELECT ${commandSummaryColumns} 
+        FROM commands c 
+        LEFT JOIN codes co ON c.id = co.command_id 
+        WHERE ${where} 
+        ORDER BY ${commandOrderBy[options.sort ?? "name"] ?? commandOrderBy.name} 
+        LIMIT ? OFFSET ?`
+      )
+      .bind(...bindings, options.limit ?? 25, options.offset ?? 0),
+    env.nelchan_db
+      .prepare(
+        `SELECT COUNT(*) AS total 
+        FROM commands c 
+        LEFT JOIN codes co ON c.id = co.command_id 
+        WHERE ${where}`
+      )
+      .bind(...bindings),
+  ])
+
+  const rows = (result.results ?? []) as CommandSummaryRow[]
+  const total = (count.results?.[0] as { total: number } | undefined)?.total ?? 0
+  return { commands: rows.map(toCommandSummary), total }
+}
+
+/**
+ * Search commands by name, description and content
+ * Name matches come first, then description matches, then content matches
+ * @param env - The environment
+ * @param keyword - Substring to search for
+ * @param limit - Maximum number of commands to return
+ * @param offset - Number of matching commands to skip
+ * @returns The requested page and the number of matching commands
+ */
+export const searchCommands = async (
+  env: Env,
+  keyword: string,
+  limit: number = 10,
+  offset: number = 0
+): Promise<CommandPage> => {
+  const pattern = `%${escapeLike(keyword)}%`
+  const from = `
+        FROM commands c 
+        LEFT JOIN codes co ON c.id = co.command_id 
+        WHERE c.name LIKE ?1 ESCAPE '\\' 
+          OR c.description LIKE ?1 ESCAPE '\\' 
+          OR co.code LIKE ?1 ESCAPE '\\' 
+          OR EXISTS (
+            SELECT 1 FROM dictionaries d 
+            WHERE d.command_id = c.id AND d.text LIKE ?1 ESCAPE '\\'
+          )`
+
+  const [result, count] = await env.nelchan_db.batch([
+    env.nelchan_db
+      .prepare(
+        `SELECT ${commandSummaryColumns} 
+        ${from} 
+        ORDER BY 
+          CASE 
+            WHEN c.name LIKE ?1 ESCAPE '\\' THEN 0 
+            WHEN c.description LIKE ?1 ESCAPE '\\' THEN 1 
+            ELSE 2 
+          END, 
+          c.name 
+        LIMIT ?2 OFFSET ?3`
+      )
+      .bind(pattern, limit, offset),
+    env.nelchan_db
+      .prepare(`SELECT COUNT(*) AS total ${from}`)
+      .bind(pattern),
+  ])
+
+  const rows = (result.results ?? []) as CommandSummaryRow[]
+  const total = (count.results?.[0] as { total: number } | undefined)?.total ?? 0
+  return { commands: rows.map(toCommandSummary), total }
+}
+
+/**
+ * Delete a command with its content, slash command records, schedules and event triggers
+ * @param env - The environment
+ * @param commandName - The name of the command
+ * @returns true if the command existed
+ */
+export const deleteCommand = async (
+  env: Env,
+  commandName: string
+): Promise<boolean> => {
+  const command = await env.nelchan_db
+    .prepare(`SELECT id FROM commands WHERE name = ?`)
+    .bind(commandName)
+    .first<{ id: string }>()
+
+  if (!command) {
+    return false
+  }
+
+  await env.nelchan_db.batch([
+    env.nelchan_db
+      .prepare(`DELETE FROM codes WHERE command_id = ?`)
+      .bind(command.id),
+    env.nelchan_db
+      .prepare(`DELETE FROM dictionaries WHERE command_id = ?`)
+      .bind(command.id),
+    env.nelchan_db
+      .prepare(`DELETE FROM slash_commands WHERE command_id = ?`)
+      .bind(command.id),
+    env.nelchan_db
+      .prepare(`DELETE FROM command_versions WHERE command_id = ?`)
+      .bind(command.id),
+    env.nelchan_db
+      .prepare(`DELETE FROM command_aliases WHERE command_id = ?`)
+      .bind(command.id),
+    env.nelchan_db
+      .prepare(`DELETE FROM schedules WHERE command_id = ?`)
+      .bind(command.id),
+    env.nelchan_db
+      .prepare(`DELETE FROM event_triggers WHERE command_id = ?`)
+      .bind(command.id),
+    env.nelchan_db
+      .prepare(`DELETE FROM auto_responders WHERE command_id = ?`)
+      .bind(command.id),
+    env.nelchan_db.prepare(`DELETE FROM commands WHERE id = ?`).bind(command.id),
+  ])
+
+  console.log("[deleteCommand] command deleted", command.id)
+  return true
+}
+
+/**
+ * Result of a rename or alias operation that can conflict with existing names
+ */
+export type NameChangeResult = "ok" | "not_found" | "conflict"
+
+/**
+ * Check whether a name is used by a command or an alias
+ */
+const isNameTaken = async (env: Env, name: string): Promise<boolean> => {
+  const taken = await env.nelchan_db
+    .prepare(
+      `SELECT name FROM commands WHERE name = ? 
+       UNION ALL 
+       SELECT alias FROM command_aliases WHERE alias = ? 
+       LIMIT 1`
+    )
+    .bind(name, name)
+    .first<{ name: string }>()
+  return taken !== null
+}
+
+/**
+ * Rename a command, keeping its content, history and aliases
+ * @param env - The environment
+ * @param commandName - The current name of the command
+ * @param newName - The new name of the command
+ */
+export const renameCommand = async (
+  env: Env,
+  commandName: string,
+  newName: string
+): Promise<NameChangeResult> => {
+  const command = await env.nelchan_db
+    .prepare(`SELECT id FROM commands WHERE name = ?`)
+    .bind(commandName)
+    .first<{ id: string }>()
+
+  if (!command) {
+    return "not_found"
+  }
+
+  if (await isNameTaken(env, newName)) {
+    return "conflict"
+  }
+
+  await env.nelchan_db
+    .prepare(
+      `UPDATE commands SET name = ?, updated_at = datetime('now') WHERE id = ?`
+    )
+    .bind(newName, command.id)
+    .run()
+
+  // Keep the mention pointing at the command
+  await env.nelchan_db
+    .prepare(
+      `UPDATE settings SET mention_command = ? WHERE mention_command = ?`
+    )
+    .bind(newName, commandName)
+    .run()
+
+  console.log("[renameCommand] command renamed", command.id, newName)
+  return "ok"
+}
+
+export type CommandAlias = {
+  alias: string
+  command_name: string
+  author_id: string
+}
+
+/**
+ * Add an alias for a command
+ * @param env - The environment
+ * @param alias - The alias
+ * @param commandName - The name of the command the alias points to
+ * @param authorID - The ID of the user who added the alias
+ */
+export const addAlias = async (
+  env: Env,
+  alias: string,
+  commandName: string,
+  authorID: string
+): Promise<NameChangeResult> => {
+  const command = await env.nelchan_db
+    .prepare(`SELECT id FROM commands WHERE name = ?`)
+    .bind(commandName)
+    .first<{ id: string }>()
+
+  if (!command) {
+    return "not_found"
+  }
+
+  if (await isNameTaken(env, alias)) {
+    return "conflict"
+  }
+
+  await env.nelchan_db
+    .prepare(
+      `INSERT INTO command_aliases (alias, command_id, author_id) VALUES (?, ?, ?)`
+    )
+    .bind(alias, command.id, authorID)
+    .run()
+
+  return "ok"
+}
+
+/**
+ * Remove an alias
+ * @param env - The environment
+ * @param alias - The alias
+ * @returns true if the alias existed
+ */
+export const removeAlias = async (env: Env, alias: string): Promise<boolean> => {
+  const result = await env.nelchan_db
+    .prepare(`DELETE FROM command_aliases WHERE alias = ?`)
+    .bind(alias)
+    .run()
+  return result.meta.changes > 0
+}
+
+/**
+ * A command with everything needed to recreate it on another instance
+ */
+export type ExportedCommand = {
+  name: string
+  isCode: boolean
+  content: string
+  args_schema: unknown[] | null
+  metadata: CommandMetadata
+  // Only set for text commands with more than one response
+  responses?: TextResponse[]
+  response_mode?: string
+}
+
+/**
+ * Get every command with its content, metadata and args schema
+ * @param env - The environment
+ * @returns Commands sorted by name
+ */
+export const exportCommands = async (env: Env): Promise<ExportedCommand[]> => {
+  const [result, dictionaries] = await env.nelchan_db.batch([
+    env.nelchan_db.prepare(
+      `SELECT 
+        c.id, 
+        c.name, 
+        c.args_schema, 
+        c.description, 
+        c.usage, 
+        c.tags, 
+        c.category, 
+        c.response_mode, 
+        co.code, 
+        (SELECT d.text FROM dictionaries d 
+          WHERE d.command_id = c.id 
+          ORDER BY d.position, d.rowid LIMIT 1) AS text 
+        FROM commands c 
+        LEFT JOIN codes co ON c.id = co.command_id 
+        WHERE co.code IS NOT NULL 
+          OR EXISTS (SELECT 1 FROM dictionaries d WHERE d.command_id = c.id) 
+        ORDER BY c.name`
+    ),
+    env.nelchan_db.prepare(
+      `SELECT command_id, text, weight FROM dictionaries ORDER BY command_id, position, rowid`
+    ),
+  ])
+
+  const responsesByCommand = new Map<string, TextResponse[]>()
+  for (const d of (dictionaries.results ?? []) as {
+    command_id: string
+    text: string
+    weight: number
+  }[]) {
+    const responses = responsesByCommand.get(d.command_id) ?? []
+    responses.push({ text: d.text, weight: d.weight })
+    responsesByCommand.set(d.command_id, responses)
+  }
+
+  return (
+    (result.results ?? []) as {
+      id: string
+      name: string
+      args_schema: string | null
+      description: string | null
+      usage: string | null
+      tags: string | null
+      category: string | null
+      response_mode: string
+      code: string | null
+      text: string | null
+    }[]
+  ).map((r) => {
+    const responses = responsesByCommand.get(r.id) ?? []
+    return {
+      name: r.name,
+      isCode: r.code !== null,
+      content: r.code ?? r.text ?? "",
+      args_schema: r.code !== null ? parseArgsSchema(r.args_schema) : null,
+      metadata: toCommandMetadata(r),
+      ...(r.code === null && responses.length > 1
+        ? { responses, response_mode: r.response_mode }
+        : {}),
+    }
+  })
+}
+
+/**
+ * Lock or unlock a command
+ * @param env - The environment
+ * @param commandName - The name of the command
+ * @param locked - true so only the owner and admins can change the command
+ * @returns false if the command doesn't exist
+ */
+export const setCommandLocked = async (
+  env: Env,
+  commandName: string,
+  locked: boolean
+): Promise<boolean> => {
+  const result = await env.nelchan_db
+    .prepare(`UPDATE commands SET locked = ? WHERE name = ?`)
+    .bind(locked ? 1 : 0, commandName)
+    .run()
+  return result.meta.changes > 0
+}
+
+/**
+ * Transfer a command to another owner
+ * @param env - The environment
+ * @param commandName - The name of the command
+ * @param authorId - The Discord user ID of the new owner
+ * @returns false if the command doesn't exist
+ */
+export const transferCommand = async (
+  env: Env,
+  commandName: string,
+  authorId: string
+): Promise<boolean> => {
+  const result = await env.nelchan_db
+    .prepare(
+      `UPDATE commands SET author_id = ?, updated_at = datetime('now') WHERE name = ?`
+    )
+    .bind(authorId, commandName)
+    .run()
+  return result.meta.changes > 0
+}
+
+/**
+ * List aliases, optionally only those of one command
+ * @param env - The environment
+ * @param commandName - The name of the command, or an empty string for every alias
+ */
+export const listAliases = async (
+  env: Env,
+  commandName: string
+): Promise<CommandAlias[]> => {
+  const query = commandName
+    ? `SELECT a.alias, c.name as command_name, a.author_id 
+       FROM command_aliases a 
+       INNER JOIN commands c ON c.id = a.command_id 
+       WHERE c.name = ? 
+       ORDER BY a.alias`
+    : `SELECT a.alias, c.name as command_name, a.author_id 
+       FROM command_aliases a 
+       INNER JOIN commands c ON c.id = a.command_id 
+       ORDER BY a.alias`
+
+  const statement = env.nelchan_db.prepare(query)
+  const result = await (commandName
+    ? statement.bind(commandName)
+    : statement
+  ).all<CommandAlias>()
+
+  return result.results ?? []
+}
+
+export type SlashCommandRecord = {
+  guild_id: string
+  application_command_id: string
+}
+
+/**
+ * Record the Discord application command created for a command in a guild
+ * @param env - The environment
+ * @param commandName - The name of the command
+ * @param guildID - The guild the application command was created in
+ * @param applicationCommandID - The ID of the application command
+ * @returns false if the command doesn't exist
+ */
+export const recordSlashCommand = async (
+  env: Env,
+  commandName: string,
+  guildID: string,
+  applicationCommandID: string
+): Promise<boolean> => {
+  const command = await env.nelchan_db
+    .prepare(`SELECT id FROM commands WHERE name = ?`)
+    .bind(commandName)
+    .first<{ id: string }>()
+
+  if (!command) {
+    return false
+  }
+
+  await env.nelchan_db
+    .prepare(
+      `INSERT INTO slash_commands (id, command_id, guild_id, application_command_id) 
+       VALUES (?, ?, ?, ?) 
+       ON CONFLICT (command_id, guild_id) 
+       DO UPDATE SET application_command_id = excluded.application_command_id`
+    )
+    .bind(crypto.randomUUID(), command.id, guildID, applicationCommandID)
+    .run()
+
+  return true
+}
+
+/**
+ * List the Discord application commands recorded for a command
+ * @param env - The environment
+ * @param commandName - The name of the command
+ */
+export const listSlashCommands = async (
+  env: Env,
+  commandName: string
+): Promise<SlashCommandRecord[]> => {
+  const result = await env.nelchan_db
+    .prepare(
+      `SELECT s.guild_id, s.application_command_id 
+       FROM slash_commands s 
+       INNER JOIN commands c ON c.id = s.command_id 
+       WHERE c.name = ?`
+    )
+    .bind(commandName)
+    .all<SlashCommandRecord>()
+
+  return result.results ?? []
+}
+
 export const registerTextCommand = async (
   env: Env,
   commandName: string,
   commandContent: string,
-  authorID: string
+  authorID: string,
+  options: RegisterCommandOptions = {}
 ) => {
   console.log("[registerTextCommand] commandName: ", commandName)
   console.log("[registerTextCommand] commandContent: ", commandContent)
 
+  const responses = options.responses?.length
+    ? options.responses
+    : [{ text: commandContent, weight: options.weight ?? 1 }]
+
   // Check if command already exists
   const existingCommand = await env.nelchan_db
     .prepare(
-      `SELECT c.id, d.id as dictionary_id, co.id as code_id FROM commands c LEFT JOIN dictionaries d ON c.id = d.command_id LEFT JOIN codes co ON c.id = co.command_id WHERE c.name = ?`
+      `SELECT c.id, co.id as code_id FROM commands c LEFT JOIN codes co ON c.id = co.command_id WHERE c.name = ?`
     )
     .bind(commandName)
     .first<{
       id: string
-      dictionary_id: string | null
       code_id: string | null
     }>()
 
+  // Append mode adds another response to an existing text command
+  if (existingCommand && options.append) {
+    if (existingCommand.code_id) {
+      throw new Error("Cannot add a response to a code command")
+    }
+    console.log(
+      "[registerTextCommand] APPEND response to existing command",
+      existingCommand.id
+    )
+    await appendTextResponse(env, existingCommand.id, responses[0])
+    await env.nelchan_db
+      .prepare(`UPDATE commands SET updated_at = datetime('now') WHERE id = ?`)
+      .bind(existingCommand.id)
+      .run()
+    await updateCommandMetadata(env, existingCommand.id, options.metadata)
+    return
+  }
+
   if (existingCommand) {
     console.log(
       "[registerTextCommand] UPDATE existing command",
@@ -325,47 +1414,82 @@ export const registerTextCommand = async (
         .prepare(`DELETE FROM codes WHERE id = ?`)
         .bind(existingCommand.code_id)
         .run()
-    }
-
-    if (existingCommand.dictionary_id) {
-      // Update existing dictionary
       await env.nelchan_db
-        .prepare(`UPDATE dictionaries SET text = ? WHERE id = ?`)
-        .bind(commandContent, existingCommand.dictionary_id)
-        .run()
-    } else {
-      // Insert new dictionary for existing command
-      const dictionaryID = crypto.randomUUID()
-      await env.nelchan_db
-        .prepare(
-          `INSERT INTO dictionaries (id, command_id, text) VALUES (?, ?, ?)`
-        )
-        .bind(dictionaryID, existingCommand.id, commandContent)
+        .prepare(`UPDATE commands SET args_schema = NULL WHERE id = ?`)
+        .bind(existingCommand.id)
         .run()
     }
+
+    await replaceTextResponses(env, existingCommand.id, responses)
+    await env.nelchan_db
+      .prepare(`UPDATE commands SET updated_at = datetime('now') WHERE id = ?`)
+      .bind(existingCommand.id)
+      .run()
+    await recordCommandVersion(
+      env,
+      existingCommand.id,
+      false,
+      commandContent,
+      null,
+      authorID
+    )
+    await updateCommandMetadata(env, existingCommand.id, options.metadata)
+    await updateResponseMode(env, existingCommand.id, options.responseMode)
     console.log("[registerTextCommand] command updated")
   } else {
     const commandID = crypto.randomUUID()
-    const dictionaryID = crypto.randomUUID()
 
-    console.log("[registerTextCommand] INSERT commands table", commandID)
+    // A new command takes over its name from an alias
     await env.nelchan_db
-      .prepare(`INSERT INTO commands (id, name, author_id) VALUES (?, ?, ?)`)
-      .bind(commandID, commandName, authorID)
+      .prepare(`DELETE FROM command_aliases WHERE alias = ?`)
+      .bind(commandName)
       .run()
 
-    console.log("[registerTextCommand] INSERT dictionaries table", dictionaryID)
+    console.log("[registerTextCommand] INSERT commands table", commandID)
     await env.nelchan_db
       .prepare(
-        `INSERT INTO dictionaries (id, command_id, text) VALUES (?, ?, ?)`
+        `INSERT INTO commands (id, name, author_id, created_at, updated_at) 
+         VALUES (?, ?, ?, datetime('now'), datetime('now'))`
       )
-      .bind(dictionaryID, commandID, commandContent)
+      .bind(commandID, commandName, authorID)
       .run()
 
+    console.log("[registerTextCommand] INSERT dictionaries table")
+    await replaceTextResponses(env, commandID, responses)
+    await recordCommandVersion(
+      env,
+      commandID,
+      false,
+      commandContent,
+      null,
+      authorID
+    )
+    await updateCommandMetadata(env, commandID, options.metadata)
+    await updateResponseMode(env, commandID, options.responseMode)
+
     console.log("[registerTextCommand] command registered")
   }
 }
 
+/**
+ * Set how a text command with several responses picks one, kept when omitted
+ */
+const updateResponseMode = async (
+  env: Env,
+  commandID: string,
+  responseMode: string | null | undefined
+) => {
+  if (!responseMode) {
+    return
+  }
+  await env.nelchan_db
+    .prepare(
+      `UPDATE commands SET response_mode = ?, response_cursor = 0 WHERE id = ?`
+    )
+    .bind(responseMode, commandID)
+    .run()
+}
+
 /**
  * Store a memory with vector embeddings for RAG
  * @param env - The environment
@@ -755,6 +1879,7 @@ export const generateCodeFromDescription = async (
 - user_id: str - コマンドを実行したユーザーのID
 - user_avatar: str - コマンドを実行したユーザーのアバターURL
 - args: list[str] - コマンドの引数リスト（例: 「!${commandName} foo bar」なら args = ["foo", "bar"]）
+- kwargs: dict - 名前付き引数（例: 「!${commandName} --loud --lang=ja count:3」なら kwargs = {"loud": True, "lang": "ja", "count": "3"}）
 
 ## 利用可能な関数（グローバルで定義済み）
 - llm(prompt: str) -> str: LLMを使ってテキストを生成する
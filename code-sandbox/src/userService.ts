@@ -195,3 +195,42 @@ export async function updateUser(
 
   console.log(`[userService] updated user: ${userId}`)
 }
+
+/**
+ * ユーザーのタイムゾーンを取得
+ * 設定されていない場合は null を返す
+ */
+export async function getUserTimezone(
+  env: Env,
+  userId: string
+): Promise<string | null> {
+  const result = await env.nelchan_db
+    .prepare(`SELECT timezone FROM user_settings WHERE user_id = ?`)
+    .bind(userId)
+    .first<{ timezone: string | null }>()
+
+  return result?.timezone ?? null
+}
+
+/**
+ * ユーザーのタイムゾーンを設定
+ * null を指定すると設定を解除する
+ */
+export async function setUserTimezone(
+  env: Env,
+  userId: string,
+  timezone: string | null
+): Promise<void> {
+  await env.nelchan_db
+    .prepare(
+      `INSERT INTO user_settings (user_id, timezone, updated_at)
+       VALUES (?, ?, datetime('now'))
+       ON CONFLICT(user_id) DO UPDATE SET
+         timezone = excluded.timezone,
+         updated_at = datetime('now')`
+    )
+    .bind(userId, timezone)
+    .run()
+
+  console.log(`[userService] set timezone of ${userId}: ${timezone}`)
+}